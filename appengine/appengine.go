@@ -29,8 +29,10 @@ import (
 	_ "github.com/cayleygraph/cayley/writer"
 
 	// Register supported query languages
+	_ "github.com/cayleygraph/cayley/query/cypher"
 	_ "github.com/cayleygraph/cayley/query/gizmo"
 	_ "github.com/cayleygraph/cayley/query/graphql"
+	_ "github.com/cayleygraph/cayley/query/gremlin"
 	_ "github.com/cayleygraph/cayley/query/mql"
 )
 
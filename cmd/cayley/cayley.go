@@ -33,6 +33,7 @@ import (
 	"github.com/cayleygraph/cayley/clog"
 	_ "github.com/cayleygraph/cayley/clog/glog"
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/version"
 	"github.com/cayleygraph/quad"
 
@@ -48,12 +49,16 @@ import (
 	_ "github.com/cayleygraph/quad/nquads"
 	_ "github.com/cayleygraph/quad/pquads"
 
+	_ "github.com/cayleygraph/cayley/quad/turtle"
+
 	// Load writer registry
 	_ "github.com/cayleygraph/cayley/writer"
 
 	// Load supported query languages
+	_ "github.com/cayleygraph/cayley/query/cypher"
 	_ "github.com/cayleygraph/cayley/query/gizmo"
 	_ "github.com/cayleygraph/cayley/query/graphql"
+	_ "github.com/cayleygraph/cayley/query/gremlin"
 	_ "github.com/cayleygraph/cayley/query/mql"
 	_ "github.com/cayleygraph/cayley/query/sexp"
 )
@@ -82,6 +87,8 @@ var (
 			graph.IgnoreDuplicates = viper.GetBool("load.ignore_duplicates")
 			graph.IgnoreMissing = viper.GetBool("load.ignore_missing")
 			quad.DefaultBatch = viper.GetInt("load.batch")
+			iterator.SpillDir = viper.GetString("query.spill_dir")
+			iterator.SpillMemoryLimit = viper.GetInt64("query.spill_memory")
 			if host, _ := cmd.Flags().GetString("pprof"); host != "" {
 				go func() {
 					if err := http.ListenAndServe(host, nil); err != nil {
@@ -137,13 +144,18 @@ func init() {
 		command.NewLoadDatabaseCmd(),
 		command.NewDumpDatabaseCmd(),
 		command.NewUpgradeCmd(),
+		command.NewReencryptCmd(),
 		command.NewReplCmd(),
 		command.NewQueryCmd(),
 		command.NewHTTPCmd(),
 		command.NewConvertCmd(),
+		command.NewDiffCmd(),
 		command.NewDedupCommand(),
 		command.NewHealthCmd(),
 		command.NewSchemaCommand(),
+		command.NewStatsCmd(),
+		command.NewAlgoCmd(),
+		command.NewProcedureCmd(),
 	)
 	rootCmd.PersistentFlags().StringP("config", "c", "", "path to an explicit configuration file")
 
@@ -178,6 +190,8 @@ func init() {
 	viper.RegisterAlias("read_only", command.KeyReadOnly)
 	viper.RegisterAlias("db_options", command.KeyOptions)
 
+	viper.SetDefault("query.spill_memory", iterator.SpillMemoryLimit)
+
 	{ // re-register standard Go flags to cobra
 		rf := rootCmd.PersistentFlags()
 		flag.CommandLine.VisitAll(func(f *flag.Flag) {
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph/algo"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+)
+
+func NewAlgoCmd() *cobra.Command {
+	var names []string
+	for _, a := range algo.List() {
+		names = append(names, fmt.Sprintf("  %-12s %s", a.Name, a.Description))
+	}
+	cmd := &cobra.Command{
+		Use:   "algo <name>",
+		Short: "Run a graph algorithm on the database.",
+		Long: "Run a graph algorithm on the database and print a value for each node, or write values back to the database.\n\n" +
+			"Algorithms:\n" + strings.Join(names, "\n"),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, ok := algo.ByName(args[0])
+			if !ok {
+				return fmt.Errorf("unknown algorithm: %q", args[0])
+			}
+			ctx := context.Background()
+			printBackendInfo()
+			h, err := openForQueries(cmd)
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+
+			var sub algo.Subgraph
+			preds, err := cmd.Flags().GetStringSlice("pred")
+			if err != nil {
+				return err
+			}
+			for _, p := range preds {
+				sub.Predicates = append(sub.Predicates, quad.IRI(p))
+			}
+			if typ, _ := iriFlag(cmd.Flags().GetString("type")); typ != "" {
+				sub.Nodes = path.StartPath(h.QuadStore).Has(quad.IRI(rdf.Type), typ)
+			}
+			sub.Literals, _ = cmd.Flags().GetBool("literals")
+
+			var opt algo.Options
+			opt.Damping, _ = cmd.Flags().GetFloat64("damping")
+			opt.Iterations, _ = cmd.Flags().GetInt("iterations")
+
+			g, err := algo.Load(ctx, h.QuadStore, sub)
+			if err != nil {
+				return err
+			}
+			clog.Infof("loaded %d nodes and %d links", g.Len(), g.Links())
+			vals, err := a.Run(ctx, g, opt)
+			if err != nil {
+				return err
+			}
+			if pred, _ := iriFlag(cmd.Flags().GetString("write-pred")); pred != "" {
+				var label quad.Value
+				if l, _ := iriFlag(cmd.Flags().GetString("write-label")); l != "" {
+					label = l
+				}
+				if err = algo.Write(ctx, h.QuadWriter, g, vals, pred, label); err != nil {
+					return err
+				}
+				clog.Infof("written %d values", len(vals))
+				return nil
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for i, v := range vals {
+				fmt.Fprintf(tw, "%s\t%v\t\n", quad.StringOf(g.Node(i)), v.Native())
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().StringSlice("pred", nil, "only follow links with given predicates")
+	cmd.Flags().String("type", "", "only include nodes of a given type")
+	cmd.Flags().Bool("literals", false, "include links to literal values")
+	cmd.Flags().String("write-pred", "", "write results to the database with a given predicate instead of printing them")
+	cmd.Flags().String("write-label", "", "label to use when writing results")
+	cmd.Flags().Float64("damping", algo.DefaultDamping, "damping factor for pagerank")
+	cmd.Flags().Int("iterations", algo.DefaultIterations, "maximal number of iterations for pagerank and labelprop")
+	cmd.Flags().Bool("init", false, "initialize the database before using it")
+	registerLoadFlags(cmd)
+	return cmd
+}
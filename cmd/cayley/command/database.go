@@ -1,6 +1,7 @@
 package command
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/cayleygraph/cayley/clog"
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/kv"
 	"github.com/cayleygraph/cayley/internal"
 	"github.com/cayleygraph/quad"
 )
@@ -34,6 +36,7 @@ const (
 	flagLoadFormat = "load_format"
 	flagDump       = "dump"
 	flagDumpFormat = "dump_format"
+	flagPatch      = "patch"
 )
 
 var ErrNotPersistent = errors.New("database type is not persistent")
@@ -41,6 +44,10 @@ var ErrNotPersistent = errors.New("database type is not persistent")
 func registerLoadFlags(cmd *cobra.Command) {
 	// TODO: allow to load multiple files
 	cmd.Flags().StringP(flagLoad, "i", "", `quad file to load after initialization (".gz" supported, "-" for stdin)`)
+	registerLoadFormatFlag(cmd)
+}
+
+func registerLoadFormatFlag(cmd *cobra.Command) {
 	var names []string
 	for _, f := range quad.Formats() {
 		if f.Reader != nil {
@@ -111,16 +118,24 @@ func NewLoadDatabaseCmd() *cobra.Command {
 			}
 			defer h.Close()
 
-			qw, err := h.NewQuadWriter()
-			if err != nil {
-				return err
-			}
-			defer qw.Close()
+			if patch, _ := cmd.Flags().GetBool(flagPatch); patch {
+				n, err := internal.LoadPatch(h.QuadWriter, load)
+				if err != nil {
+					return err
+				}
+				clog.Infof("applied %d changes from %q", n, load)
+			} else {
+				qw, err := h.NewQuadWriter()
+				if err != nil {
+					return err
+				}
+				defer qw.Close()
 
-			// TODO: check read-only flag in config before that?
-			typ, _ := cmd.Flags().GetString(flagLoadFormat)
-			if err = internal.Load(qw, quad.DefaultBatch, load, typ); err != nil {
-				return err
+				// TODO: check read-only flag in config before that?
+				typ, _ := cmd.Flags().GetString(flagLoadFormat)
+				if err = internal.Load(qw, quad.DefaultBatch, load, typ); err != nil {
+					return err
+				}
 			}
 
 			if dump, _ := cmd.Flags().GetString(flagDump); dump != "" {
@@ -133,6 +148,7 @@ func NewLoadDatabaseCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().Bool("init", false, "initialize the database before using it")
+	cmd.Flags().Bool(flagPatch, false, "treat the file as an RDF Patch and apply its transactions to the database")
 	registerLoadFlags(cmd)
 	registerDumpFlags(cmd)
 	return cmd
@@ -184,6 +200,36 @@ func NewUpgradeCmd() *cobra.Command {
 	return cmd
 }
 
+func NewReencryptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reencrypt",
+		Short: "Re-encrypt the database with a new encryption key.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printBackendInfo()
+			name := viper.GetString(KeyBackend)
+			if graph.IsRegistered(name) && !graph.IsPersistent(name) {
+				return ErrNotPersistent
+			}
+			keyFile, err := cmd.Flags().GetString("key")
+			if err != nil {
+				return err
+			} else if keyFile == "" {
+				return errors.New("new key file must be specified")
+			}
+			addr := viper.GetString(KeyAddress)
+			opts := graph.Options(viper.GetStringMap(KeyOptions))
+			clog.Infof("re-encrypting database...")
+			if _, err = kv.Reencrypt(context.Background(), name, addr, opts, keyFile); err != nil {
+				return err
+			}
+			clog.Infof("done; set %q store option to %q", kv.OptEncryptionKeyFile, keyFile)
+			return nil
+		},
+	}
+	cmd.Flags().String("key", "", "path to the file with a new encryption key")
+	return cmd
+}
+
 func printBackendInfo() {
 	name := viper.GetString(KeyBackend)
 	path := viper.GetString(KeyAddress)
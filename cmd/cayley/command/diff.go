@@ -0,0 +1,58 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph/rdfpatch"
+	"github.com/cayleygraph/cayley/internal"
+)
+
+func NewDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Compare two quad files and write the difference as an RDF Patch.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loadf, _ := cmd.Flags().GetString(flagLoadFormat)
+			old, err := internal.QuadReaderFor(args[0], loadf)
+			if err != nil {
+				return err
+			}
+			defer old.Close()
+			cur, err := internal.QuadReaderFor(args[1], loadf)
+			if err != nil {
+				return err
+			}
+			defer cur.Close()
+
+			tx, err := rdfpatch.Diff(old, cur)
+			if err != nil {
+				return err
+			}
+			clog.Infof("found %d changes", len(tx.Deltas))
+
+			var w io.Writer = os.Stdout
+			if dump, _ := cmd.Flags().GetString(flagDump); dump != "" && dump != "-" {
+				f, err := os.Create(dump)
+				if err != nil {
+					return fmt.Errorf("could not create file %q: %v", dump, err)
+				}
+				defer f.Close()
+				w = f
+			}
+			pw := rdfpatch.NewWriter(w)
+			if err = pw.WriteTransaction(tx); err != nil {
+				return err
+			}
+			return pw.Close()
+		},
+	}
+	cmd.Flags().StringP(flagDump, "o", "", `file to write the patch to (stdout by default)`)
+	registerLoadFormatFlag(cmd)
+	return cmd
+}
@@ -27,9 +27,20 @@ func NewHTTPCmd() *cobra.Command {
 			}
 			defer h.Close()
 
+			limits, err := getQueryLimits()
+			if err != nil {
+				return err
+			}
+			tokens, err := getTokenLimits()
+			if err != nil {
+				return err
+			}
 			err = chttp.SetupRoutes(h, &chttp.Config{
-				Timeout:  viper.GetDuration(keyQueryTimeout),
-				ReadOnly: viper.GetBool(KeyReadOnly),
+				Timeout:     viper.GetDuration(keyQueryTimeout),
+				ReadOnly:    viper.GetBool(KeyReadOnly),
+				Limits:      limits,
+				TokenLimits: tokens,
+				Parallelism: viper.GetInt(keyQueryParallelism),
 			})
 			if err != nil {
 				return err
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cayleygraph/cayley/query/gizmo"
+)
+
+func NewProcedureCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "procedure",
+		Short: "Manage Gizmo procedures stored in the database.",
+	}
+	root.AddCommand(
+		newProcedureListCmd(),
+		newProcedureSetCmd(),
+		newProcedureDeleteCmd(),
+	)
+	return root
+}
+
+func newProcedureListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List procedures stored in the database.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printBackendInfo()
+			h, err := openDatabase()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+
+			procs, err := gizmo.ListProcedures(context.Background(), h.QuadStore)
+			if err != nil {
+				return err
+			}
+			if body, _ := cmd.Flags().GetBool("body"); body {
+				for _, p := range procs {
+					fmt.Printf("// %s(%s)\n%s\n\n", p.Name, strings.Join(p.Params, ", "), p.Body)
+				}
+				return nil
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, p := range procs {
+				fmt.Fprintf(tw, "%s\t%s\t\n", p.Name, strings.Join(p.Params, ", "))
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().Bool("body", false, "print bodies of procedures")
+	return cmd
+}
+
+func newProcedureSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <name> [file]",
+		Short: "Create or replace a procedure.",
+		Long: "Create or replace a procedure with a body read from a file or stdin.\n\n" +
+			"The body is a Gizmo script that is called as a function with declared parameters.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data []byte
+				err  error
+			)
+			if len(args) == 2 && args[1] != "-" {
+				data, err = ioutil.ReadFile(args[1])
+			} else {
+				data, err = ioutil.ReadAll(os.Stdin)
+			}
+			if err != nil {
+				return err
+			}
+			p := gizmo.Procedure{Name: args[0], Body: string(data)}
+			if p.Params, err = cmd.Flags().GetStringSlice("param"); err != nil {
+				return err
+			}
+			if err = p.Validate(); err != nil {
+				return err
+			}
+			printBackendInfo()
+			h, err := openDatabase()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			return gizmo.SaveProcedure(context.Background(), h.QuadStore, h.QuadWriter, p)
+		},
+	}
+	cmd.Flags().StringSlice("param", nil, "parameters of the procedure, in order")
+	return cmd
+}
+
+func newProcedureDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a procedure.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printBackendInfo()
+			h, err := openDatabase()
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			return gizmo.DeleteProcedure(context.Background(), h.QuadStore, h.QuadWriter, args[0])
+		},
+	}
+}
@@ -14,14 +14,67 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/internal/repl"
 	"github.com/cayleygraph/cayley/query"
 )
 
 const (
 	keyQueryTimeout = "query.timeout"
+	keyQueryLimits  = "query.limits"
+	keyQueryTokens  = "query.tokens"
+
+	keyQueryParallelism = "query.parallelism"
 )
 
+// queryLimits is a representation of governor.Limits in the config.
+type queryLimits struct {
+	Token   string `mapstructure:"token"`
+	Steps   int64  `mapstructure:"steps"`
+	Refs    int64  `mapstructure:"refs"`
+	Results int64  `mapstructure:"results"`
+	VMTime  int64  `mapstructure:"vm_time"`
+}
+
+func (l queryLimits) Limits() governor.Limits {
+	return governor.Limits{
+		Steps:   l.Steps,
+		Refs:    l.Refs,
+		Results: l.Results,
+		VMTime:  l.VMTime,
+	}
+}
+
+// getQueryLimits returns default resource limits for queries.
+func getQueryLimits() (governor.Limits, error) {
+	var l queryLimits
+	if err := viper.UnmarshalKey(keyQueryLimits, &l); err != nil {
+		return governor.Limits{}, fmt.Errorf("cannot parse %s: %v", keyQueryLimits, err)
+	}
+	return l.Limits(), nil
+}
+
+// getTokenLimits returns resource limits for queries made with specific API tokens.
+func getTokenLimits() (map[string]governor.Limits, error) {
+	var list []queryLimits
+	if err := viper.UnmarshalKey(keyQueryTokens, &list); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", keyQueryTokens, err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]governor.Limits, len(list))
+	for _, l := range list {
+		if l.Token == "" {
+			return nil, fmt.Errorf("%s: token is not set", keyQueryTokens)
+		}
+		m[l.Token] = l.Limits()
+	}
+	return m, nil
+}
+
 func getContext() (context.Context, func()) {
 	ctx, cancel := context.WithCancel(context.Background())
 	ch := make(chan os.Signal, 1)
@@ -63,9 +116,16 @@ func NewReplCmd() *cobra.Command {
 
 			ctx, cancel := getContext()
 			defer cancel()
+			if n := viper.GetInt(keyQueryParallelism); n > 1 {
+				ctx = iterator.WithParallelism(ctx, n)
+			}
 
 			timeout := viper.GetDuration("timeout")
 			lang, _ := cmd.Flags().GetString("lang")
+			if viper.GetBool(KeyReadOnly) {
+				// queries must not modify the graph
+				return repl.Repl(ctx, &graph.Handle{QuadStore: h.QuadStore}, lang, timeout)
+			}
 			return repl.Repl(ctx, h, lang, timeout)
 		},
 	}
@@ -110,6 +170,16 @@ func NewQueryCmd() *cobra.Command {
 				ctx, cancel = context.WithTimeout(ctx, timeout)
 				defer cancel()
 			}
+			limits, err := getQueryLimits()
+			if err != nil {
+				return err
+			}
+			if !limits.IsZero() {
+				ctx = governor.WithGovernor(ctx, governor.New(limits))
+			}
+			if n := viper.GetInt(keyQueryParallelism); n > 1 {
+				ctx = iterator.WithParallelism(ctx, n)
+			}
 			lang, _ := cmd.Flags().GetString("lang")
 			limit, err := cmd.Flags().GetInt("limit")
 			if err != nil {
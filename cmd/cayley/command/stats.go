@@ -0,0 +1,69 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/quad"
+)
+
+func NewStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Print per-predicate and per-label statistics.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printBackendInfo()
+			h, err := openForQueries(cmd)
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			sp, ok := h.QuadStore.(graph.StatsProvider)
+			if !ok {
+				return fmt.Errorf("%v: backend doesn't support statistics", graph.ErrNoStats)
+			}
+			t, err := sp.StatsTable(context.Background())
+			if err == graph.ErrNoStats {
+				return fmt.Errorf("%v; initialize the database with %q option", err, "stats")
+			} else if err != nil {
+				return err
+			}
+			return printStats(os.Stdout, t)
+		},
+	}
+	cmd.Flags().Bool("init", false, "initialize the database before using it")
+	registerLoadFlags(cmd)
+	return cmd
+}
+
+func printStats(w io.Writer, t *graph.StatsTable) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	first := true
+	for _, sect := range []struct {
+		name string
+		list []graph.NamedStats
+	}{
+		{"predicate", t.Predicates},
+		{"label", t.Labels},
+	} {
+		if len(sect.list) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(tw)
+		}
+		first = false
+		fmt.Fprintf(tw, "%s\tquads\tsubjects\tobjects\tout degree\tin degree\t\n", sect.name)
+		for _, s := range sect.list {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f\t%.2f\t\n",
+				quad.StringOf(s.Value), s.Quads, s.Subjects, s.Objects, s.OutDegree(), s.InDegree())
+		}
+	}
+	return tw.Flush()
+}
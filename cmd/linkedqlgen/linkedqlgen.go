@@ -0,0 +1,269 @@
+// linkedqlgen generates a Go builder for LinkedQL queries from the registered LinkedQL types.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cayleygraph/cayley/query/linkedql"
+	// Steps are imported here so they will be registered and included in the builder
+	_ "github.com/cayleygraph/cayley/query/linkedql/steps"
+)
+
+var (
+	packageName = flag.String("pck", "builder", "package name of the generated code")
+	out         = flag.String("o", "-", "output file")
+)
+
+var (
+	pathStep     = reflect.TypeOf((*linkedql.PathStep)(nil)).Elem()
+	pathSteps    = reflect.TypeOf([]linkedql.PathStep{})
+	graphPattern = reflect.TypeOf(linkedql.GraphPattern(nil))
+)
+
+// reserved are the names that can't be used as parameters.
+var reserved = map[string]string{
+	"p":        "path",
+	"linkedql": "linkedqlArg",
+	"quad":     "value",
+	"steps":    "paths",
+	"delete":   "del",
+}
+
+func main() {
+	flag.Parse()
+
+	g := &generator{imports: map[string]struct{}{
+		"github.com/cayleygraph/cayley/query/linkedql": {},
+	}}
+	names := linkedql.RegisteredTypes()
+	sort.Strings(names)
+	for _, name := range names {
+		t, ok := linkedql.TypeByName(name)
+		if !ok {
+			panic("type is registered, but the lookup fails")
+		}
+		g.addType(t)
+	}
+	src, err := g.source()
+	if err != nil {
+		panic(err)
+	}
+	var w io.Writer = os.Stdout
+	if fname := *out; fname != "" && fname != "-" {
+		f, err := os.Create(fname)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err = w.Write(src); err != nil {
+		panic(err)
+	}
+}
+
+type param struct {
+	Name  string
+	Field string
+	Type  reflect.Type
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]struct{}
+}
+
+// qualified returns a type name qualified with a package name and records the package import.
+func (g *generator) qualified(t reflect.Type) string {
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	g.imports[t.PkgPath()] = struct{}{}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// typeString returns a type name of a parameter for a given field type.
+func (g *generator) typeString(t reflect.Type) string {
+	switch t {
+	case pathStep:
+		return "*Path"
+	case graphPattern:
+		return "linkedql.GraphPattern"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + g.typeString(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeString(t.Elem())
+	}
+	return g.qualified(t)
+}
+
+// fieldValue returns an expression that converts a parameter to a field value.
+func fieldValue(p param) string {
+	switch p.Type {
+	case pathStep:
+		return p.Name + ".step()"
+	case pathSteps:
+		return "pathSteps(" + p.Name + ")"
+	}
+	return p.Name
+}
+
+// fields returns fields of a struct with fields of embedded structs.
+func fields(t reflect.Type) []reflect.StructField {
+	var out []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			out = append(out, fields(f.Type)...)
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// literal returns a composite literal of a struct with given field values.
+func (g *generator) literal(t reflect.Type, values map[string]string) string {
+	var parts []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			parts = append(parts, f.Name+": "+g.literal(f.Type, values))
+			continue
+		}
+		if v, ok := values[f.Name]; ok {
+			parts = append(parts, f.Name+": "+v)
+		}
+	}
+	return g.qualified(t) + "{" + strings.Join(parts, ", ") + "}"
+}
+
+func paramName(field string) string {
+	r := []rune(field)
+	r[0] = unicode.ToLower(r[0])
+	name := string(r)
+	if s, ok := reserved[name]; ok {
+		return s
+	}
+	if token.Lookup(name).IsKeyword() {
+		panic("field name is a Go keyword: " + field)
+	}
+	return name
+}
+
+// comment formats a description of a type as a doc comment.
+func comment(name, desc string) string {
+	if !strings.HasPrefix(desc, name+" ") {
+		if r := []rune(desc); len(r) > 1 && unicode.IsUpper(r[0]) && unicode.IsLower(r[1]) {
+			r[0] = unicode.ToLower(r[0])
+			desc = string(r)
+		}
+		desc = name + " " + desc
+	}
+	if !strings.HasSuffix(desc, ".") {
+		desc += "."
+	}
+	var (
+		buf  bytes.Buffer
+		line = "//"
+	)
+	for _, w := range strings.Fields(desc) {
+		if len(line)+1+len(w) > 100 && line != "//" {
+			buf.WriteString(line + "\n")
+			line = "//"
+		}
+		line += " " + w
+	}
+	buf.WriteString(line + "\n")
+	return buf.String()
+}
+
+func (g *generator) addType(t reflect.Type) {
+	item, ok := reflect.New(t).Interface().(linkedql.RegistryItem)
+	if !ok {
+		return
+	}
+	isPath := reflect.PtrTo(t).Implements(pathStep)
+	var (
+		params   []param
+		hasFrom  bool
+		optional bool
+	)
+	for _, f := range fields(t) {
+		if f.Name == "From" && f.Type == pathStep {
+			hasFrom = true
+			optional = f.Tag.Get("minCardinality") == "0"
+			continue
+		}
+		params = append(params, param{Name: paramName(f.Name), Field: f.Name, Type: f.Type})
+	}
+	var args []string
+	for i, p := range params {
+		typ := g.typeString(p.Type)
+		if i == len(params)-1 && p.Type.Kind() == reflect.Slice {
+			typ = "..." + strings.TrimPrefix(typ, "[]")
+		}
+		if i != len(params)-1 && params[i+1].Type == p.Type {
+			// consecutive parameters of the same type share it
+			args = append(args, p.Name)
+			continue
+		}
+		args = append(args, p.Name+" "+typ)
+	}
+	result := "*" + g.qualified(t)
+	if isPath {
+		result = "*Path"
+	}
+	body := func(from string) string {
+		values := make(map[string]string)
+		if from != "" {
+			values["From"] = from
+		}
+		for _, p := range params {
+			values[p.Field] = fieldValue(p)
+		}
+		lit := "&" + g.literal(t, values)
+		if isPath {
+			return "return &Path{s: " + lit + "}"
+		}
+		return "return " + lit
+	}
+	doc := comment(t.Name(), item.Description())
+	sig := t.Name() + "(" + strings.Join(args, ", ") + ") " + result
+	if hasFrom {
+		fmt.Fprintf(&g.buf, "%sfunc (p *Path) %s {\n%s\n}\n\n", doc, sig, body("p.step()"))
+	}
+	if !hasFrom || optional {
+		fmt.Fprintf(&g.buf, "%sfunc %s {\n%s\n}\n\n", doc, sig, body(""))
+	}
+}
+
+func (g *generator) source() ([]byte, error) {
+	var imports []string
+	for pkg := range g.imports {
+		imports = append(imports, pkg)
+	}
+	sort.Strings(imports)
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by linkedqlgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", *packageName)
+	for _, pkg := range imports {
+		fmt.Fprintf(&buf, "\t%q\n", pkg)
+	}
+	buf.WriteString(")\n\n")
+	buf.Write(g.buf.Bytes())
+	return format.Source(buf.Bytes())
+}
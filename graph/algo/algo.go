@@ -0,0 +1,189 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+// Options are common parameters of algorithms. Zero values are replaced with defaults.
+type Options struct {
+	Damping    float64
+	Iterations int
+	Tolerance  float64
+}
+
+// Algorithm is a named graph algorithm that returns a value for each node of the graph.
+type Algorithm struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, g *Graph, opt Options) ([]quad.Value, error)
+}
+
+var algorithms = make(map[string]Algorithm)
+
+// Register adds an algorithm to the registry.
+func Register(a Algorithm) {
+	if _, ok := algorithms[a.Name]; ok {
+		panic("algorithm " + a.Name + " is already registered")
+	}
+	algorithms[a.Name] = a
+}
+
+// ByName returns a registered algorithm with a given name.
+func ByName(name string) (Algorithm, bool) {
+	a, ok := algorithms[name]
+	return a, ok
+}
+
+// List returns all registered algorithms, sorted by name.
+func List() []Algorithm {
+	out := make([]Algorithm, 0, len(algorithms))
+	for _, a := range algorithms {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func floats(arr []float64, err error) ([]quad.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := make([]quad.Value, len(arr))
+	for i, v := range arr {
+		out[i] = quad.Float(v)
+	}
+	return out, nil
+}
+
+func ints(arr []int, err error) ([]quad.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := make([]quad.Value, len(arr))
+	for i, v := range arr {
+		out[i] = quad.Int(v)
+	}
+	return out, nil
+}
+
+func init() {
+	Register(Algorithm{
+		Name:        "pagerank",
+		Description: "PageRank of each node",
+		Run: func(ctx context.Context, g *Graph, opt Options) ([]quad.Value, error) {
+			return floats(PageRank(ctx, g, opt.Damping, opt.Iterations, opt.Tolerance))
+		},
+	})
+	Register(Algorithm{
+		Name:        "wcc",
+		Description: "weakly connected component id of each node",
+		Run: func(ctx context.Context, g *Graph, _ Options) ([]quad.Value, error) {
+			return ints(WeaklyConnectedComponents(ctx, g))
+		},
+	})
+	Register(Algorithm{
+		Name:        "scc",
+		Description: "strongly connected component id of each node",
+		Run: func(ctx context.Context, g *Graph, _ Options) ([]quad.Value, error) {
+			return ints(StronglyConnectedComponents(ctx, g))
+		},
+	})
+	Register(Algorithm{
+		Name:        "degree",
+		Description: "normalized degree centrality of each node",
+		Run: func(ctx context.Context, g *Graph, _ Options) ([]quad.Value, error) {
+			return floats(DegreeCentrality(ctx, g))
+		},
+	})
+	Register(Algorithm{
+		Name:        "betweenness",
+		Description: "normalized betweenness centrality of each node",
+		Run: func(ctx context.Context, g *Graph, _ Options) ([]quad.Value, error) {
+			return floats(BetweennessCentrality(ctx, g))
+		},
+	})
+	Register(Algorithm{
+		Name:        "triangles",
+		Description: "number of triangles each node is part of",
+		Run: func(ctx context.Context, g *Graph, _ Options) ([]quad.Value, error) {
+			return ints(Triangles(ctx, g))
+		},
+	})
+	Register(Algorithm{
+		Name:        "labelprop",
+		Description: "community id of each node, detected by label propagation",
+		Run: func(ctx context.Context, g *Graph, opt Options) ([]quad.Value, error) {
+			return ints(LabelPropagation(ctx, g, opt.Iterations))
+		},
+	})
+}
+
+// Quads converts results of an algorithm to quads, linking each node to its value with a given predicate and label.
+func Quads(g *Graph, vals []quad.Value, pred quad.IRI, label quad.Value) []quad.Quad {
+	out := make([]quad.Quad, 0, len(vals))
+	for i, v := range vals {
+		out = append(out, quad.Quad{Subject: g.Node(i), Predicate: pred, Object: v, Label: label})
+	}
+	return out
+}
+
+// Write stores results of an algorithm in the graph. Quads with the same predicate and label
+// that were written by previous runs are replaced in the same transaction.
+func Write(ctx context.Context, qw graph.QuadWriter, g *Graph, vals []quad.Value, pred quad.IRI, label quad.Value) error {
+	if len(vals) != g.Len() {
+		return fmt.Errorf("algo: expected %d values, got %d", g.Len(), len(vals))
+	}
+	qs := g.qs
+	tx := graph.NewTransactionN(len(vals))
+	if p := qs.ValueOf(pred); p != nil {
+		var lkey interface{}
+		if label != nil {
+			lkey = refs.ToKey(qs.ValueOf(label))
+			if lkey == nil {
+				// label doesn't exist, so there are no old values
+				p = nil
+			}
+		}
+		if p != nil {
+			sc := qs.QuadIterator(quad.Predicate, p).Iterate()
+			for sc.Next(ctx) {
+				q := sc.Result()
+				if refs.ToKey(qs.QuadDirection(q, quad.Label)) != lkey {
+					continue
+				}
+				tx.RemoveQuad(qs.Quad(q))
+			}
+			err := sc.Err()
+			sc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, q := range Quads(g, vals, pred, label) {
+		tx.AddQuad(q)
+	}
+	return qw.ApplyTransaction(tx)
+}
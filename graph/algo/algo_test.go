@@ -0,0 +1,246 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/algo"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query/path"
+	_ "github.com/cayleygraph/cayley/writer"
+	"github.com/cayleygraph/quad"
+)
+
+func testStore() *memstore.QuadStore {
+	// a -> b -> c -> a  (cycle)
+	// c -> d -> e
+	// x -> y            (separate component)
+	return memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("b", "follows", "c", ""),
+		quad.MakeIRI("c", "follows", "a", ""),
+		quad.MakeIRI("c", "follows", "d", ""),
+		quad.MakeIRI("d", "follows", "e", ""),
+		quad.MakeIRI("x", "follows", "y", ""),
+		quad.MakeIRI("a", "likes", "x", ""),
+		quad.Make(quad.IRI("a"), quad.IRI("name"), quad.String("A"), nil),
+	)
+}
+
+func loadGraph(t testing.TB, qs graph.QuadStore, sub algo.Subgraph) *algo.Graph {
+	g, err := algo.Load(context.TODO(), qs, sub)
+	require.NoError(t, err)
+	return g
+}
+
+// byNode maps results to node names.
+func byNode(g *algo.Graph, vals interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for i := 0; i < g.Len(); i++ {
+		name := string(g.Node(i).(quad.IRI))
+		switch vals := vals.(type) {
+		case []int:
+			out[name] = vals[i]
+		case []float64:
+			out[name] = vals[i]
+		}
+	}
+	return out
+}
+
+// sameGroups checks that nodes are grouped into the same sets, regardless of group ids.
+func sameGroups(t testing.TB, g *algo.Graph, ids []int, exp ...[]string) {
+	groups := make(map[int][]string)
+	for i, id := range ids {
+		groups[id] = append(groups[id], string(g.Node(i).(quad.IRI)))
+	}
+	var got, want []string
+	for _, s := range groups {
+		sort.Strings(s)
+		got = append(got, strings.Join(s, ","))
+	}
+	for _, s := range exp {
+		sort.Strings(s)
+		want = append(want, strings.Join(s, ","))
+	}
+	require.ElementsMatch(t, want, got)
+}
+
+func TestLoad(t *testing.T) {
+	qs := testStore()
+
+	g := loadGraph(t, qs, algo.Subgraph{})
+	require.Equal(t, 7, g.Len())
+	require.Equal(t, 7, g.Links())
+	require.Equal(t, -1, g.Index(quad.String("A")))
+
+	g = loadGraph(t, qs, algo.Subgraph{Literals: true})
+	require.Equal(t, 8, g.Len())
+
+	g = loadGraph(t, qs, algo.Subgraph{Predicates: []quad.Value{quad.IRI("likes")}})
+	require.Equal(t, 2, g.Len())
+	require.Equal(t, 1, g.Links())
+
+	g = loadGraph(t, qs, algo.Subgraph{Predicates: []quad.Value{quad.IRI("unknown")}})
+	require.Equal(t, 0, g.Len())
+
+	g = loadGraph(t, qs, algo.Subgraph{
+		Nodes: path.StartPath(qs, quad.IRI("a"), quad.IRI("b"), quad.IRI("x"), quad.IRI("z")),
+	})
+	require.Equal(t, 3, g.Len())
+	require.Equal(t, 2, g.Links())
+	a, b, x := g.Index(quad.IRI("a")), g.Index(quad.IRI("b")), g.Index(quad.IRI("x"))
+	require.ElementsMatch(t, []int{b, x}, g.Out(a))
+	require.Equal(t, []int{a}, g.In(b))
+}
+
+func TestComponents(t *testing.T) {
+	ctx := context.TODO()
+	g := loadGraph(t, testStore(), algo.Subgraph{Predicates: []quad.Value{quad.IRI("follows")}})
+
+	wcc, err := algo.WeaklyConnectedComponents(ctx, g)
+	require.NoError(t, err)
+	sameGroups(t, g, wcc, []string{"a", "b", "c", "d", "e"}, []string{"x", "y"})
+
+	scc, err := algo.StronglyConnectedComponents(ctx, g)
+	require.NoError(t, err)
+	sameGroups(t, g, scc, []string{"a", "b", "c"}, []string{"d"}, []string{"e"}, []string{"x"}, []string{"y"})
+	for _, id := range scc {
+		require.True(t, id >= 0 && id < 5)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	ctx := context.TODO()
+	// symmetric cycle: all ranks are equal
+	qs := memstore.New(
+		quad.MakeIRI("a", "p", "b", ""),
+		quad.MakeIRI("b", "p", "c", ""),
+		quad.MakeIRI("c", "p", "a", ""),
+	)
+	g := loadGraph(t, qs, algo.Subgraph{})
+	rank, err := algo.PageRank(ctx, g, 0, 0, 0)
+	require.NoError(t, err)
+	for _, r := range rank {
+		require.InDelta(t, 1.0/3, r, 1e-6)
+	}
+
+	// star: the center has the highest rank
+	qs = memstore.New(
+		quad.MakeIRI("a", "p", "c", ""),
+		quad.MakeIRI("b", "p", "c", ""),
+		quad.MakeIRI("d", "p", "c", ""),
+	)
+	g = loadGraph(t, qs, algo.Subgraph{})
+	rank, err = algo.PageRank(ctx, g, 0.85, 100, 1e-9)
+	require.NoError(t, err)
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	require.InDelta(t, 1.0, sum, 1e-6)
+	c := g.Index(quad.IRI("c"))
+	for i, r := range rank {
+		if i != c {
+			require.True(t, r < rank[c])
+		}
+	}
+}
+
+func TestCentrality(t *testing.T) {
+	ctx := context.TODO()
+	// a -> b -> c, all paths from a to c go through b
+	qs := memstore.New(
+		quad.MakeIRI("a", "p", "b", ""),
+		quad.MakeIRI("b", "p", "c", ""),
+	)
+	g := loadGraph(t, qs, algo.Subgraph{})
+
+	deg, err := algo.DegreeCentrality(ctx, g)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"a": 0.5, "b": 1.0, "c": 0.5,
+	}, byNode(g, deg))
+
+	btw, err := algo.BetweennessCentrality(ctx, g)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"a": 0.0, "b": 0.5, "c": 0.0,
+	}, byNode(g, btw))
+}
+
+func TestTriangles(t *testing.T) {
+	g := loadGraph(t, testStore(), algo.Subgraph{Predicates: []quad.Value{quad.IRI("follows")}})
+	tri, err := algo.Triangles(context.TODO(), g)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"a": 1, "b": 1, "c": 1, "d": 0, "e": 0, "x": 0, "y": 0,
+	}, byNode(g, tri))
+}
+
+func TestLabelPropagation(t *testing.T) {
+	// two cliques connected with a single link
+	var quads []quad.Quad
+	clique := func(nodes ...string) {
+		for i, a := range nodes {
+			for _, b := range nodes[i+1:] {
+				quads = append(quads, quad.MakeIRI(a, "p", b, ""))
+			}
+		}
+	}
+	clique("a1", "a2", "a3", "a4")
+	clique("b1", "b2", "b3", "b4")
+	quads = append(quads, quad.MakeIRI("b4", "p", "a4", ""))
+
+	g := loadGraph(t, memstore.New(quads...), algo.Subgraph{})
+	labels, err := algo.LabelPropagation(context.TODO(), g, 0)
+	require.NoError(t, err)
+	sameGroups(t, g, labels, []string{"a1", "a2", "a3", "a4"}, []string{"b1", "b2", "b3", "b4"})
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.TODO()
+	qs := testStore()
+	qw, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+
+	a, ok := algo.ByName("wcc")
+	require.True(t, ok)
+	g := loadGraph(t, qs, algo.Subgraph{Predicates: []quad.Value{quad.IRI("follows")}})
+	vals, err := a.Run(ctx, g, algo.Options{})
+	require.NoError(t, err)
+	require.NoError(t, algo.Write(ctx, qw, g, vals, quad.IRI("component"), quad.IRI("algo")))
+
+	count := func() int {
+		n, err := path.StartPath(qs).LabelContext(quad.IRI("algo")).Has(quad.IRI("component")).Iterate(ctx).Count()
+		require.NoError(t, err)
+		return int(n)
+	}
+	require.Equal(t, 7, count())
+
+	// results of the second run replace the first
+	g = loadGraph(t, qs, algo.Subgraph{Predicates: []quad.Value{quad.IRI("likes")}})
+	vals, err = a.Run(ctx, g, algo.Options{})
+	require.NoError(t, err)
+	require.NoError(t, algo.Write(ctx, qw, g, vals, quad.IRI("component"), quad.IRI("algo")))
+	require.Equal(t, 2, count())
+}
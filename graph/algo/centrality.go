@@ -0,0 +1,91 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import "context"
+
+// DegreeCentrality returns the number of incoming and outgoing links of each node,
+// normalized by the maximal possible degree.
+func DegreeCentrality(ctx context.Context, g *Graph) ([]float64, error) {
+	n := g.Len()
+	out := make([]float64, n)
+	if n < 2 {
+		return out, nil
+	}
+	norm := 1 / float64(n-1)
+	for i := range out {
+		out[i] = float64(len(g.in[i])+len(g.out[i])) * norm
+	}
+	return out, nil
+}
+
+// BetweennessCentrality returns the fraction of shortest paths between other pairs of nodes
+// that pass through each node. Links are directed.
+func BetweennessCentrality(ctx context.Context, g *Graph) ([]float64, error) {
+	// Brandes' algorithm for unweighted graphs
+	n := g.Len()
+	cb := make([]float64, n)
+	var (
+		stack = make([]int, 0, n)
+		queue = make([]int, 0, n)
+		preds = make([][]int, n)
+		sigma = make([]float64, n)
+		dist  = make([]int, n)
+		delta = make([]float64, n)
+	)
+	for s := 0; s < n; s++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		stack, queue = stack[:0], queue[:0]
+		for i := 0; i < n; i++ {
+			preds[i] = preds[i][:0]
+			sigma[i], dist[i], delta[i] = 0, -1, 0
+		}
+		sigma[s], dist[s] = 1, 0
+		queue = append(queue, s)
+		for len(queue) != 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range g.out[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				cb[w] += delta[w]
+			}
+		}
+	}
+	if n > 2 {
+		norm := 1 / float64((n-1)*(n-2))
+		for i := range cb {
+			cb[i] *= norm
+		}
+	}
+	return cb, nil
+}
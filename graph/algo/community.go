@@ -0,0 +1,104 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import "context"
+
+// Triangles returns the number of triangles each node is part of. Direction of links is ignored.
+func Triangles(ctx context.Context, g *Graph) ([]int, error) {
+	adj := g.undirected()
+	cnt := make([]int, g.Len())
+	mark := make([]bool, g.Len())
+	for u, nu := range adj {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, v := range nu {
+			mark[v] = true
+		}
+		// count each triangle once, for u < v < w
+		for _, v := range nu {
+			if v <= u {
+				continue
+			}
+			for _, w := range adj[v] {
+				if w <= v || !mark[w] {
+					continue
+				}
+				cnt[u]++
+				cnt[v]++
+				cnt[w]++
+			}
+		}
+		for _, v := range nu {
+			mark[v] = false
+		}
+	}
+	return cnt, nil
+}
+
+// LabelPropagation detects communities by repeatedly assigning each node the most frequent
+// label of its neighbors. Direction of links is ignored. On ties, the node keeps its current
+// label if it's one of the most frequent, or takes the smallest one otherwise, which makes
+// the result deterministic.
+//
+// Communities are numbered from 0 in the order of their first node.
+// Iterations is replaced with a default if not positive.
+func LabelPropagation(ctx context.Context, g *Graph, iterations int) ([]int, error) {
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	adj := g.undirected()
+	labels := make([]int, g.Len())
+	for i := range labels {
+		labels[i] = i
+	}
+	freq := make(map[int]int)
+	for iter := 0; iter < iterations; iter++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		changed := false
+		for u, nu := range adj {
+			if len(nu) == 0 {
+				continue
+			}
+			for k := range freq {
+				delete(freq, k)
+			}
+			for _, v := range nu {
+				freq[labels[v]]++
+			}
+			best, bestN := -1, 0
+			for l, c := range freq {
+				if c > bestN || (c == bestN && l < best) {
+					best, bestN = l, c
+				}
+			}
+			if freq[labels[u]] == bestN {
+				// keep the current label on ties to let the iteration converge
+				best = labels[u]
+			}
+			if best != labels[u] {
+				labels[u] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return renumber(labels), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import "context"
+
+// renumber maps arbitrary component ids to 0..k-1 in the order of the first node of each component.
+func renumber(comp []int) []int {
+	ids := make(map[int]int)
+	for i, c := range comp {
+		id, ok := ids[c]
+		if !ok {
+			id = len(ids)
+			ids[c] = id
+		}
+		comp[i] = id
+	}
+	return comp
+}
+
+// WeaklyConnectedComponents returns a component id for each node, ignoring the direction of links.
+// Components are numbered from 0 in the order of their first node.
+func WeaklyConnectedComponents(ctx context.Context, g *Graph) ([]int, error) {
+	parent := make([]int, g.Len())
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for i := range g.out {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		for _, j := range g.out[i] {
+			a, b := find(i), find(j)
+			if a == b {
+				continue
+			} else if a < b {
+				parent[b] = a
+			} else {
+				parent[a] = b
+			}
+		}
+	}
+	comp := make([]int, g.Len())
+	for i := range comp {
+		comp[i] = find(i)
+	}
+	return renumber(comp), nil
+}
+
+// StronglyConnectedComponents returns a component id for each node.
+// Nodes are in the same component if they are reachable from each other.
+// Components are numbered from 0 in the order of their first node.
+func StronglyConnectedComponents(ctx context.Context, g *Graph) ([]int, error) {
+	// iterative version of Tarjan's algorithm
+	n := g.Len()
+	const unvisited = -1
+	var (
+		index   = make([]int, n)
+		low     = make([]int, n)
+		onStack = make([]bool, n)
+		comp    = make([]int, n)
+		stack   []int
+		next    int
+		ncomp   int
+	)
+	for i := range index {
+		index[i] = unvisited
+	}
+	type frame struct {
+		node, edge int
+	}
+	var calls []frame
+	for root := 0; root < n; root++ {
+		if index[root] != unvisited {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		calls = append(calls[:0], frame{node: root})
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		for len(calls) != 0 {
+			f := &calls[len(calls)-1]
+			v := f.node
+			if f.edge < len(g.out[v]) {
+				w := g.out[v][f.edge]
+				f.edge++
+				if index[w] == unvisited {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					calls = append(calls, frame{node: w})
+				} else if onStack[w] && index[w] < low[v] {
+					low[v] = index[w]
+				}
+				continue
+			}
+			calls = calls[:len(calls)-1]
+			if len(calls) != 0 {
+				if p := calls[len(calls)-1].node; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] != index[v] {
+				continue
+			}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp[w] = ncomp
+				if w == v {
+					break
+				}
+			}
+			ncomp++
+		}
+	}
+	return renumber(comp), nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package algo implements graph algorithms that run on any QuadStore.
+//
+// Algorithms don't run on the QuadStore directly. Instead, the subgraph is loaded
+// into memory with Load, and all algorithms work on the compact adjacency lists.
+// Each algorithm returns a value for every node of the loaded graph, which can
+// be written back to the store with Write.
+package algo
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad"
+)
+
+// Subgraph selects a part of the graph that algorithms run on.
+type Subgraph struct {
+	// Predicates restricts links to the given predicates. Links with any predicate are used if empty.
+	Predicates []quad.Value
+	// Nodes restricts the graph to nodes returned by the path, and links between them.
+	Nodes *path.Path
+	// Literals includes links to literal values. By default, only IRIs and blank nodes are included.
+	Literals bool
+}
+
+// Graph is a directed graph loaded into memory. Nodes are indexed from 0 to Len()-1.
+// Parallel links between the same pair of nodes are merged.
+type Graph struct {
+	qs    graph.QuadStore
+	nodes []refs.Ref
+	ids   map[interface{}]int
+	out   [][]int
+	in    [][]int
+	links map[[2]int]struct{}
+}
+
+func newGraph(qs graph.QuadStore) *Graph {
+	return &Graph{
+		qs:    qs,
+		ids:   make(map[interface{}]int),
+		links: make(map[[2]int]struct{}),
+	}
+}
+
+// Len returns the number of nodes in the graph.
+func (g *Graph) Len() int { return len(g.nodes) }
+
+// Ref returns a reference of the node in the QuadStore.
+func (g *Graph) Ref(i int) refs.Ref { return g.nodes[i] }
+
+// Node returns the value of the node.
+func (g *Graph) Node(i int) quad.Value { return g.qs.NameOf(g.nodes[i]) }
+
+// Index returns an index of the node, or -1 if it is not in the graph.
+func (g *Graph) Index(v quad.Value) int {
+	if i, ok := g.ids[refs.ToKey(g.qs.ValueOf(v))]; ok {
+		return i
+	}
+	return -1
+}
+
+// Out returns indexes of nodes that the node links to.
+func (g *Graph) Out(i int) []int { return g.out[i] }
+
+// In returns indexes of nodes that link to the node.
+func (g *Graph) In(i int) []int { return g.in[i] }
+
+// Links returns the number of links in the graph.
+func (g *Graph) Links() int { return len(g.links) }
+
+func (g *Graph) addNode(r refs.Ref) int {
+	k := refs.ToKey(r)
+	if i, ok := g.ids[k]; ok {
+		return i
+	}
+	i := len(g.nodes)
+	g.ids[k] = i
+	g.nodes = append(g.nodes, r)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	return i
+}
+
+// addLink adds a link between two nodes of the graph.
+func (g *Graph) addLink(from, to int) {
+	k := [2]int{from, to}
+	if _, ok := g.links[k]; ok {
+		return
+	}
+	g.links[k] = struct{}{}
+	g.out[from] = append(g.out[from], to)
+	g.in[to] = append(g.in[to], from)
+}
+
+// undirected returns a list of neighbors of each node ignoring the direction of links, without self-loops.
+func (g *Graph) undirected() [][]int {
+	adj := make([][]int, g.Len())
+	for k := range g.links {
+		u, v := k[0], k[1]
+		if u == v {
+			continue
+		}
+		if _, ok := g.links[[2]int{v, u}]; ok && u > v {
+			// reverse link was or will be added
+			continue
+		}
+		adj[u] = append(adj[u], v)
+		adj[v] = append(adj[v], u)
+	}
+	for _, a := range adj {
+		sort.Ints(a)
+	}
+	return adj
+}
+
+// loader accumulates links of the subgraph.
+type loader struct {
+	g        *Graph
+	sub      Subgraph
+	preds    map[interface{}]struct{}
+	allowed  map[interface{}]bool
+	fixedSet bool
+}
+
+// allow checks if the node can be added to the graph.
+func (l *loader) allow(r refs.Ref) bool {
+	k := refs.ToKey(r)
+	if ok, seen := l.allowed[k]; seen || l.fixedSet {
+		return ok
+	}
+	ok := l.sub.Literals
+	if !ok {
+		switch l.g.qs.NameOf(r).(type) {
+		case quad.IRI, quad.BNode:
+			ok = true
+		}
+	}
+	l.allowed[k] = ok
+	return ok
+}
+
+func (l *loader) addQuads(ctx context.Context, it iterator.Shape) error {
+	qs := l.g.qs
+	sc := it.Iterate()
+	defer sc.Close()
+	for sc.Next(ctx) {
+		q := sc.Result()
+		if l.preds != nil {
+			if _, ok := l.preds[refs.ToKey(qs.QuadDirection(q, quad.Predicate))]; !ok {
+				continue
+			}
+		}
+		s, o := qs.QuadDirection(q, quad.Subject), qs.QuadDirection(q, quad.Object)
+		if !l.allow(s) || !l.allow(o) {
+			continue
+		}
+		l.g.addLink(l.g.addNode(s), l.g.addNode(o))
+	}
+	return sc.Err()
+}
+
+// Load reads the subgraph of the QuadStore into memory.
+func Load(ctx context.Context, qs graph.QuadStore, sub Subgraph) (*Graph, error) {
+	l := &loader{
+		g:       newGraph(qs),
+		sub:     sub,
+		allowed: make(map[interface{}]bool),
+	}
+	var predRefs []refs.Ref
+	if len(sub.Predicates) != 0 {
+		l.preds = make(map[interface{}]struct{})
+		for _, p := range sub.Predicates {
+			if r := qs.ValueOf(p); r != nil {
+				l.preds[refs.ToKey(r)] = struct{}{}
+				predRefs = append(predRefs, r)
+			}
+		}
+		if len(predRefs) == 0 {
+			return l.g, nil
+		}
+	}
+	var nodes iterator.Shape
+	if sub.Nodes != nil {
+		// only nodes of the path are allowed
+		l.fixedSet = true
+		var list []refs.Ref
+		sc := sub.Nodes.BuildIterator(ctx).Iterate()
+		for sc.Next(ctx) {
+			r := sc.Result()
+			if k := refs.ToKey(r); !l.allowed[k] {
+				l.allowed[k] = true
+				list = append(list, r)
+				l.g.addNode(r)
+			}
+		}
+		err := sc.Err()
+		sc.Close()
+		if err != nil {
+			return nil, err
+		}
+		nodes = iterator.NewFixed(list...)
+	} else if len(predRefs) != 0 {
+		// links with given predicates can be listed directly
+		for _, p := range predRefs {
+			if err := l.addQuads(ctx, qs.QuadIterator(quad.Predicate, p)); err != nil {
+				return nil, err
+			}
+		}
+		return l.g, nil
+	} else {
+		nodes = qs.NodesAllIterator()
+	}
+	sc := nodes.Iterate()
+	defer sc.Close()
+	for sc.Next(ctx) {
+		if err := l.addQuads(ctx, qs.QuadIterator(quad.Subject, sc.Result())); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return l.g, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	DefaultDamping    = 0.85
+	DefaultIterations = 100
+	DefaultTolerance  = 1e-6
+)
+
+// PageRank computes a rank of each node. Ranks sum to 1.
+//
+// Damping, iterations and tolerance are replaced with defaults if not positive.
+// Iteration stops when the sum of rank changes drops below the tolerance.
+// The rank of nodes without outgoing links is distributed evenly across all nodes.
+func PageRank(ctx context.Context, g *Graph, damping float64, iterations int, tolerance float64) ([]float64, error) {
+	if damping <= 0 || damping >= 1 {
+		damping = DefaultDamping
+	}
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	n := g.Len()
+	if n == 0 {
+		return nil, nil
+	}
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for iter := 0; iter < iterations; iter++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dangling := 0.0
+		for i := range rank {
+			if len(g.out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		diff := 0.0
+		for i := range next {
+			sum := 0.0
+			for _, j := range g.in[i] {
+				sum += rank[j] / float64(len(g.out[j]))
+			}
+			next[i] = base + damping*sum
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < tolerance {
+			break
+		}
+	}
+	return rank, nil
+}
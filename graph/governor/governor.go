@@ -0,0 +1,194 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package governor limits resources that a single query may use.
+//
+// Governor is passed to iterators and query languages in the context. Iterators account each unit of work
+// in it and stop with ErrLimitExceeded once any of the limits is reached.
+package governor
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Resource is a kind of resource tracked by the governor.
+type Resource string
+
+const (
+	// Steps is the number of Next and Contains calls made by iterators.
+	Steps = Resource("steps")
+	// Refs is the number of refs kept in memory by iterators.
+	Refs = Resource("refs")
+	// Results is the number of results returned by the query.
+	Results = Resource("results")
+	// VMTime is the time in milliseconds spent by a query script (Gizmo) in the VM.
+	// Time spent waiting for the client to read results is not accounted.
+	VMTime = Resource("vm_time")
+)
+
+// Limits for a single query. Zero or negative value means no limit.
+type Limits struct {
+	Steps   int64 `json:"steps,omitempty"`
+	Refs    int64 `json:"refs,omitempty"`
+	Results int64 `json:"results,omitempty"`
+	VMTime  int64 `json:"vm_time,omitempty"`
+}
+
+// IsZero checks if no limits are set.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Merge returns limits that are set in l, and sets the rest from def.
+func (l Limits) Merge(def Limits) Limits {
+	if l.Steps == 0 {
+		l.Steps = def.Steps
+	}
+	if l.Refs == 0 {
+		l.Refs = def.Refs
+	}
+	if l.Results == 0 {
+		l.Results = def.Results
+	}
+	if l.VMTime == 0 {
+		l.VMTime = def.VMTime
+	}
+	return l
+}
+
+// Usage is the amount of resources used by a query.
+type Usage struct {
+	Steps   int64 `json:"steps"`
+	Refs    int64 `json:"refs"`
+	Results int64 `json:"results"`
+	VMTime  int64 `json:"vm_time"`
+}
+
+// ErrLimitExceeded is returned when a query reaches one of its limits.
+type ErrLimitExceeded struct {
+	Resource Resource
+	Limit    int64
+	Usage    Usage
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	return fmt.Sprintf("query exceeded the limit of %d %s", e.Limit, e.Resource)
+}
+
+// Governor tracks resources used by a single query. It's safe for concurrent use.
+//
+// All methods can be called on a nil Governor, in which case no limits are checked.
+type Governor struct {
+	limits  Limits
+	steps   int64
+	refs    int64
+	results int64
+	vmTime  int64
+}
+
+// New creates a governor with given limits.
+func New(l Limits) *Governor {
+	return &Governor{limits: l}
+}
+
+// Limits returns limits of the governor.
+func (g *Governor) Limits() Limits {
+	if g == nil {
+		return Limits{}
+	}
+	return g.limits
+}
+
+// Usage returns resources used so far.
+func (g *Governor) Usage() Usage {
+	if g == nil {
+		return Usage{}
+	}
+	return Usage{
+		Steps:   atomic.LoadInt64(&g.steps),
+		Refs:    atomic.LoadInt64(&g.refs),
+		Results: atomic.LoadInt64(&g.results),
+		VMTime:  atomic.LoadInt64(&g.vmTime),
+	}
+}
+
+func (g *Governor) add(r Resource, cnt *int64, limit int64, n int64) error {
+	if v := atomic.AddInt64(cnt, n); limit > 0 && v > limit {
+		return &ErrLimitExceeded{Resource: r, Limit: limit, Usage: g.Usage()}
+	}
+	return nil
+}
+
+// Step accounts n iterator calls.
+func (g *Governor) Step(n int64) error {
+	if g == nil {
+		return nil
+	}
+	return g.add(Steps, &g.steps, g.limits.Steps, n)
+}
+
+// Materialize accounts n refs that are kept in memory.
+func (g *Governor) Materialize(n int64) error {
+	if g == nil {
+		return nil
+	}
+	return g.add(Refs, &g.refs, g.limits.Refs, n)
+}
+
+// Result accounts n query results.
+func (g *Governor) Result(n int64) error {
+	if g == nil {
+		return nil
+	}
+	return g.add(Results, &g.results, g.limits.Results, n)
+}
+
+// Execute accounts n milliseconds spent by a query script in the VM.
+func (g *Governor) Execute(n int64) error {
+	if g == nil {
+		return nil
+	}
+	return g.add(VMTime, &g.vmTime, g.limits.VMTime, n)
+}
+
+type contextKey struct{}
+
+// WithGovernor returns a context that carries a given governor.
+func WithGovernor(ctx context.Context, g *Governor) context.Context {
+	return context.WithValue(ctx, contextKey{}, g)
+}
+
+// FromContext returns a governor from the context, or nil if it's not set.
+func FromContext(ctx context.Context) *Governor {
+	g, _ := ctx.Value(contextKey{}).(*Governor)
+	return g
+}
+
+// Lazy looks up a governor in the context on the first use and remembers it.
+// It's embedded into iterators to avoid a context lookup on each call.
+type Lazy struct {
+	g    *Governor
+	init bool
+}
+
+// From returns a governor from the context passed on the first call.
+func (l *Lazy) From(ctx context.Context) *Governor {
+	if !l.init {
+		l.g = FromContext(ctx)
+		l.init = true
+	}
+	return l.g
+}
@@ -0,0 +1,47 @@
+package governor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph/governor"
+)
+
+func TestGovernor(t *testing.T) {
+	g := governor.New(governor.Limits{Steps: 3, Refs: 1})
+	require.NoError(t, g.Step(2))
+	require.NoError(t, g.Step(1))
+	require.NoError(t, g.Result(100))
+
+	err := g.Step(1)
+	e, ok := err.(*governor.ErrLimitExceeded)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, governor.Steps, e.Resource)
+	require.Equal(t, int64(3), e.Limit)
+	require.Equal(t, governor.Usage{Steps: 4, Results: 100}, e.Usage)
+
+	require.NoError(t, g.Materialize(1))
+	require.Error(t, g.Materialize(1))
+}
+
+func TestGovernorNil(t *testing.T) {
+	ctx := context.Background()
+	var l governor.Lazy
+	g := l.From(ctx)
+	require.Nil(t, g)
+	require.NoError(t, g.Step(1))
+	require.Equal(t, governor.Usage{}, g.Usage())
+
+	g = governor.New(governor.Limits{})
+	require.Nil(t, l.From(governor.WithGovernor(ctx, g)), "expected cached value")
+	require.Equal(t, g, governor.FromContext(governor.WithGovernor(ctx, g)))
+}
+
+func TestLimitsMerge(t *testing.T) {
+	l := governor.Limits{Steps: 10}.Merge(governor.Limits{Steps: 5, Refs: 20})
+	require.Equal(t, governor.Limits{Steps: 10, Refs: 20}, l)
+	require.True(t, governor.Limits{}.IsZero())
+	require.False(t, l.IsZero())
+}
@@ -59,6 +59,8 @@ var graphTests = []struct {
 	{"schema", TestSchema},
 	{"delete reinserted", TestDeleteReinserted},
 	{"delete reinserted dup", TestDeleteReinsertedDup},
+	{"stats", TestStats},
+	{"next batch", TestNextBatch},
 }
 
 func TestAll(t *testing.T, gen testutil.DatabaseFunc, conf *Config) {
@@ -96,6 +98,9 @@ func BenchmarkAll(b *testing.B, gen testutil.DatabaseFunc, conf *Config) {
 	b.Run("integration", func(b *testing.B) {
 		BenchmarkIntegration(b, gen, conf.AlwaysRunIntegration)
 	})
+	b.Run("iterate", func(b *testing.B) {
+		BenchmarkIterate(b, gen)
+	})
 }
 
 // MakeQuadSet makes a simple test graph.
@@ -1260,6 +1265,148 @@ func TestDeleteReinsertedDup(t testing.TB, gen testutil.DatabaseFunc, _ *Config)
 	}
 }
 
+// TestNextBatch checks that iterator.NextBatch returns the same results as Next for iterators of the quad store.
+func TestNextBatch(t testing.TB, gen testutil.DatabaseFunc, _ *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	const size = 1000
+	quads := make([]quad.Quad, 0, size)
+	for i := 0; i < size; i++ {
+		quads = append(quads, quad.Quad{
+			Subject:   irif("n%d", i/5),
+			Predicate: quad.IRI("sub"),
+			Object:    irif("n%d", i/2+i%2),
+		})
+	}
+	w := testutil.MakeWriter(t, qs, opts)
+	require.NoError(t, w.AddQuadSet(quads))
+
+	ctx := context.TODO()
+	shapes := []struct {
+		name  string
+		shape func() iterator.Shape
+	}{
+		{name: "nodes", shape: qs.NodesAllIterator},
+		{name: "quads", shape: qs.QuadsAllIterator},
+		{name: "direction", shape: func() iterator.Shape {
+			return qs.QuadIterator(quad.Predicate, qs.ValueOf(quad.IRI("sub")))
+		}},
+		{name: "out", shape: func() iterator.Shape {
+			// the same node is reached by multiple quads; backends may return it as alternative paths
+			s := shape.Out(shape.AllNodes{}, shape.Lookup{quad.IRI("sub")}, nil)
+			s, _ = shape.Optimize(ctx, s, qs)
+			return shape.BuildIterator(ctx, qs, s)
+		}},
+	}
+	for _, c := range shapes {
+		var exp []interface{}
+		it := c.shape().Iterate()
+		for it.Next(ctx) {
+			exp = append(exp, refs.ToKey(it.Result()))
+		}
+		require.NoError(t, it.Err())
+		it.Close()
+		require.NotEmpty(t, exp, c.name)
+
+		for _, n := range []int{1, 7, 64, size} {
+			var got []interface{}
+			buf := make([]refs.Ref, n)
+			it := c.shape().Iterate()
+			for {
+				k := iterator.NextBatch(ctx, it, buf)
+				if k == 0 {
+					break
+				}
+				for _, r := range buf[:k] {
+					got = append(got, refs.ToKey(r))
+				}
+			}
+			require.NoError(t, it.Err())
+			it.Close()
+			require.Equal(t, exp, got, "%s: batch of %d", c.name, n)
+		}
+	}
+}
+
+func TestStats(t testing.TB, gen testutil.DatabaseFunc, _ *Config) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	sp, ok := qs.(graph.StatsProvider)
+	if !ok {
+		t.SkipNow()
+	}
+	ctx := context.TODO()
+	if _, err := sp.StatsTable(ctx); err == graph.ErrNoStats {
+		t.SkipNow()
+	}
+
+	w := testutil.MakeWriter(t, qs, opts, MakeQuadSet()...)
+
+	expect := func(follows, status, label graph.ValueStats) {
+		st, err := sp.PredicateStats(ctx, qs.ValueOf(quad.String("follows")))
+		require.NoError(t, err)
+		require.Equal(t, follows, st, "follows")
+
+		st, err = sp.PredicateStats(ctx, qs.ValueOf(quad.String("status")))
+		require.NoError(t, err)
+		require.Equal(t, status, st, "status")
+
+		st, err = sp.LabelStats(ctx, qs.ValueOf(quad.String("status_graph")))
+		require.NoError(t, err)
+		require.Equal(t, label, st, "status_graph")
+
+		tbl, err := sp.StatsTable(ctx)
+		require.NoError(t, err)
+		var exp graph.StatsTable
+		if !follows.IsZero() {
+			exp.Predicates = append(exp.Predicates, graph.NamedStats{Value: quad.String("follows"), ValueStats: follows})
+		}
+		if !status.IsZero() {
+			exp.Predicates = append(exp.Predicates, graph.NamedStats{Value: quad.String("status"), ValueStats: status})
+		}
+		if !label.IsZero() {
+			exp.Labels = append(exp.Labels, graph.NamedStats{Value: quad.String("status_graph"), ValueStats: label})
+		}
+		require.Equal(t, exp, *tbl)
+	}
+
+	expect(
+		graph.ValueStats{Quads: 8, Subjects: 6, Objects: 4},
+		graph.ValueStats{Quads: 3, Subjects: 3, Objects: 1},
+		graph.ValueStats{Quads: 3, Subjects: 3, Objects: 1},
+	)
+
+	err := w.RemoveQuad(quad.Make("E", "follows", "F", nil))
+	require.NoError(t, err)
+	err = w.RemoveQuad(quad.Make("B", "status", "cool", "status_graph"))
+	require.NoError(t, err)
+	// duplicates should not affect stats
+	err = qs.ApplyDeltas([]graph.Delta{
+		{Quad: quad.Make("A", "follows", "B", nil), Action: graph.Add},
+	}, graph.IgnoreOpts{IgnoreDup: true})
+	require.NoError(t, err)
+
+	expect(
+		graph.ValueStats{Quads: 7, Subjects: 5, Objects: 4},
+		graph.ValueStats{Quads: 2, Subjects: 2, Objects: 1},
+		graph.ValueStats{Quads: 2, Subjects: 2, Objects: 1},
+	)
+
+	tx := graph.NewTransaction()
+	tx.RemoveQuad(quad.Make("D", "status", "cool", "status_graph"))
+	tx.RemoveQuad(quad.Make("G", "status", "cool", "status_graph"))
+	err = w.ApplyTransaction(tx)
+	require.NoError(t, err)
+
+	expect(
+		graph.ValueStats{Quads: 7, Subjects: 5, Objects: 4},
+		graph.ValueStats{},
+		graph.ValueStats{},
+	)
+}
+
 func irif(format string, args ...interface{}) quad.IRI {
 	return quad.IRI(fmt.Sprintf(format, args...))
 }
@@ -1309,3 +1456,76 @@ func BenchmarkImport(b *testing.B, gen testutil.DatabaseFunc) {
 	}
 	b.StopTimer()
 }
+
+// BenchmarkIterate compares iteration with resolving values one at a time to batched iteration
+// with iterator.NextBatch and graph.ValuesOf.
+func BenchmarkIterate(b *testing.B, gen testutil.DatabaseFunc) {
+	qs, _, closer := gen(b)
+	defer closer()
+
+	const size = 10000
+
+	w, err := qs.NewQuadWriter()
+	require.NoError(b, err)
+	quads := make([]quad.Quad, 0, size)
+	for i := 0; i < size; i++ {
+		quads = append(quads, quad.Quad{
+			Subject:   irif("n%d", i/5),
+			Predicate: quad.IRI("sub"),
+			Object:    irif("n%d", i/2+i%2),
+		})
+	}
+	_, err = w.WriteQuads(quads)
+	require.NoError(b, err)
+	require.NoError(b, w.Close())
+
+	ctx := context.TODO()
+	shapes := []struct {
+		name  string
+		shape func() iterator.Shape
+	}{
+		{name: "nodes", shape: func() iterator.Shape {
+			return qs.NodesAllIterator()
+		}},
+		{name: "limit", shape: func() iterator.Shape {
+			return iterator.NewLimit(iterator.NewSkip(qs.NodesAllIterator(), size/10), size/2)
+		}},
+		{name: "or", shape: func() iterator.Shape {
+			return iterator.NewOr(iterator.NewLimit(qs.NodesAllIterator(), size/10), qs.NodesAllIterator())
+		}},
+		{name: "and", shape: func() iterator.Shape {
+			return iterator.NewAnd(qs.NodesAllIterator(), qs.NodesAllIterator())
+		}},
+	}
+	for _, c := range shapes {
+		c := c
+		b.Run(c.name, func(b *testing.B) {
+			b.Run("next", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					it := c.shape().Iterate()
+					for it.Next(ctx) {
+						_ = qs.NameOf(it.Result())
+					}
+					require.NoError(b, it.Err())
+					it.Close()
+				}
+			})
+			b.Run("batch", func(b *testing.B) {
+				buf := make([]graph.Ref, 100)
+				for i := 0; i < b.N; i++ {
+					it := c.shape().Iterate()
+					for {
+						n := iterator.NextBatch(ctx, it, buf)
+						if n == 0 {
+							break
+						}
+						_, err := graph.ValuesOf(ctx, qs, buf[:n])
+						require.NoError(b, err)
+					}
+					require.NoError(b, it.Err())
+					it.Close()
+				}
+			})
+		})
+	}
+}
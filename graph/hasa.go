@@ -38,6 +38,7 @@ import (
 	"fmt"
 
 	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/graph/refs"
 	"github.com/cayleygraph/quad"
@@ -127,6 +128,8 @@ type hasANext struct {
 	primary iterator.Scanner
 	dir     quad.Direction
 	result  refs.Ref
+	gov     governor.Lazy
+	err     error
 }
 
 // Construct a new HasA iterator, given the quad subiterator, and the quad
@@ -160,7 +163,10 @@ func (it *hasANext) NextPath(ctx context.Context) bool {
 // subiterator we can get a value from, and we can take that resultant quad,
 // pull our direction out of it, and return that.
 func (it *hasANext) Next(ctx context.Context) bool {
-	if !it.primary.Next(ctx) {
+	if it.err != nil || !it.primary.Next(ctx) {
+		return false
+	}
+	if it.err = it.gov.From(ctx).Step(1); it.err != nil {
 		return false
 	}
 	it.result = it.qs.QuadDirection(it.primary.Result(), it.dir)
@@ -168,6 +174,9 @@ func (it *hasANext) Next(ctx context.Context) bool {
 }
 
 func (it *hasANext) Err() error {
+	if it.err != nil {
+		return it.err
+	}
 	return it.primary.Err()
 }
 
@@ -190,6 +199,7 @@ type hasAContains struct {
 	dir     quad.Direction
 	results iterator.Scanner
 	result  refs.Ref
+	gov     governor.Lazy
 	err     error
 }
 
@@ -242,6 +252,9 @@ func (it *hasAContains) nextContains(ctx context.Context) bool {
 		return false
 	}
 	for it.results.Next(ctx) {
+		if it.err = it.gov.From(ctx).Step(1); it.err != nil {
+			return false
+		}
 		link := it.results.Result()
 		if clog.V(4) {
 			clog.Infof("Quad is %v", it.qs.Quad(link))
@@ -0,0 +1,464 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+// AggregateFunc is a function that computes a single value from a group of values.
+type AggregateFunc int
+
+const (
+	// AggregateCount counts results of the group. If a tag is set, only results with this tag are counted.
+	AggregateCount AggregateFunc = iota
+	// AggregateSum sums numeric values of the tag.
+	AggregateSum
+	// AggregateAvg computes an average of numeric values of the tag.
+	AggregateAvg
+	// AggregateMin finds a minimal numeric value of the tag.
+	AggregateMin
+	// AggregateMax finds a maximal numeric value of the tag.
+	AggregateMax
+)
+
+var aggregateNames = []string{
+	AggregateCount: "count",
+	AggregateSum:   "sum",
+	AggregateAvg:   "avg",
+	AggregateMin:   "min",
+	AggregateMax:   "max",
+}
+
+func (f AggregateFunc) String() string {
+	if f >= 0 && int(f) < len(aggregateNames) {
+		return aggregateNames[f]
+	}
+	return fmt.Sprintf("aggregate(%d)", int(f))
+}
+
+// ParseAggregateFunc finds an aggregate function by name.
+func ParseAggregateFunc(name string) (AggregateFunc, error) {
+	name = strings.ToLower(name)
+	for f, s := range aggregateNames {
+		if s == name {
+			return AggregateFunc(f), nil
+		}
+	}
+	if name == "average" {
+		return AggregateAvg, nil
+	}
+	return 0, fmt.Errorf("unknown aggregate function: %q", name)
+}
+
+// Aggregate describes a value computed for each group of results.
+type Aggregate struct {
+	Func AggregateFunc
+	// Tag is a tag with values to aggregate. It can be empty for AggregateCount.
+	Tag string
+	// As is a tag that the computed value will be stored in.
+	As string
+}
+
+// aggNumber returns a numeric value suitable for aggregation and reports if it's an integer.
+// The last return value is false if the value is not a number.
+func aggNumber(v quad.Value) (f float64, isInt, ok bool) {
+	switch v := v.(type) {
+	case quad.Int:
+		return float64(v), true, true
+	case quad.Float:
+		return float64(v), false, true
+	}
+	return 0, false, false
+}
+
+// aggState accumulates values of a single aggregate.
+type aggState struct {
+	cnt    int64
+	n      int64 // number of numeric values
+	isum   int64
+	fsum   float64
+	floats bool // at least one value is not an integer
+	min    quad.Value
+	minV   float64
+	max    quad.Value
+	maxV   float64
+}
+
+func (s *aggState) add(v quad.Value) {
+	s.cnt++
+	f, isInt, ok := aggNumber(v)
+	if !ok {
+		return
+	}
+	s.n++
+	if isInt {
+		s.isum += int64(v.(quad.Int))
+	} else {
+		s.fsum += f
+		s.floats = true
+	}
+	if s.min == nil || f < s.minV {
+		s.min, s.minV = v, f
+	}
+	if s.max == nil || f > s.maxV {
+		s.max, s.maxV = v, f
+	}
+}
+
+func (s *aggState) value(f AggregateFunc) quad.Value {
+	switch f {
+	case AggregateCount:
+		return quad.Int(s.cnt)
+	case AggregateSum:
+		if s.floats {
+			return quad.Float(float64(s.isum) + s.fsum)
+		}
+		return quad.Int(s.isum)
+	case AggregateAvg:
+		if s.n == 0 {
+			return nil
+		}
+		return quad.Float((float64(s.isum) + s.fsum) / float64(s.n))
+	case AggregateMin:
+		return s.min
+	case AggregateMax:
+		return s.max
+	}
+	return nil
+}
+
+// GroupBy iterator groups results of a subiterator by the value of a tag and computes aggregates for each group.
+//
+// Each result of the iterator is a value of the group tag. Results of the subiterator without
+// the group tag are skipped. If the group tag is empty, all results form a single group,
+// and the value of the first aggregate is returned as a result.
+// Computed aggregates are stored in tags. Subiterator tags are not propagated.
+type GroupBy struct {
+	it   Shape
+	qs   refs.Namer
+	tag  string
+	aggs []Aggregate
+}
+
+// NewGroupBy creates a new iterator that groups and aggregates results of a subiterator.
+// qs is used to resolve tagged values to compute aggregates.
+func NewGroupBy(it Shape, qs refs.Namer, tag string, aggs []Aggregate) *GroupBy {
+	return &GroupBy{it: it, qs: qs, tag: tag, aggs: aggs}
+}
+
+func (it *GroupBy) Iterate() Scanner {
+	return newGroupByNext(it)
+}
+
+func (it *GroupBy) Lookup() Index {
+	return newGroupByContains(it)
+}
+
+// SubIterators returns a slice of the sub iterators.
+func (it *GroupBy) SubIterators() []Shape {
+	return []Shape{it.it}
+}
+
+func (it *GroupBy) Optimize(ctx context.Context) (Shape, bool) {
+	sub, optimized := it.it.Optimize(ctx)
+	it.it = sub
+	return it, optimized
+}
+
+func (it *GroupBy) Stats(ctx context.Context) (Costs, error) {
+	sub, err := it.it.Stats(ctx)
+	st := Costs{
+		NextCost:     sub.NextCost * 2,
+		ContainsCost: sub.NextCost * sub.Size.Value,
+		Size: refs.Size{
+			Value: sub.Size.Value,
+			Exact: false,
+		},
+	}
+	if it.tag == "" {
+		st.Size = refs.Size{Value: 1, Exact: true}
+	}
+	return st, err
+}
+
+func (it *GroupBy) String() string {
+	var aggs []string
+	for _, a := range it.aggs {
+		aggs = append(aggs, fmt.Sprintf("%s(%s) as %s", a.Func, a.Tag, a.As))
+	}
+	return fmt.Sprintf("GroupBy(%q, %s)", it.tag, strings.Join(aggs, ", "))
+}
+
+type aggGroup struct {
+	key   refs.Ref
+	state []aggState
+}
+
+func (g *aggGroup) result(it *GroupBy) result {
+	tags := make(map[string]refs.Ref, len(it.aggs)+1)
+	if it.tag != "" {
+		tags[it.tag] = g.key
+	}
+	var first refs.Ref
+	for i, a := range it.aggs {
+		v := g.state[i].value(a.Func)
+		if v == nil {
+			continue
+		}
+		r := refs.PreFetched(v)
+		if i == 0 {
+			first = r
+		}
+		if a.As != "" {
+			tags[a.As] = r
+		}
+	}
+	id := g.key
+	if it.tag == "" {
+		id = first
+	}
+	return result{id: id, tags: tags}
+}
+
+// computeGroups reads all results of the subiterator and returns computed groups in order of their first result.
+func (it *GroupBy) computeGroups(ctx context.Context) ([]result, error) {
+	sc := it.it.Iterate()
+	defer sc.Close()
+	var (
+		groups []*aggGroup
+		byKey  = make(map[interface{}]*aggGroup)
+	)
+	if it.tag == "" {
+		g := &aggGroup{state: make([]aggState, len(it.aggs))}
+		groups = append(groups, g)
+		byKey[nil] = g
+	}
+	// resolve each ref only once
+	names := make(map[interface{}]quad.Value)
+	nameOf := func(r refs.Ref) quad.Value {
+		if v, ok := r.(refs.PreFetchedValue); ok {
+			return v.NameOf()
+		}
+		k := refs.ToKey(r)
+		v, ok := names[k]
+		if !ok && it.qs != nil {
+			v = it.qs.NameOf(r)
+			names[k] = v
+		}
+		return v
+	}
+	add := func() {
+		tags := make(map[string]refs.Ref)
+		sc.TagResults(tags)
+		var key interface{}
+		if it.tag != "" {
+			r, ok := tags[it.tag]
+			if !ok || r == nil {
+				return
+			}
+			key = refs.ToKey(r)
+			if _, ok := byKey[key]; !ok {
+				g := &aggGroup{key: r, state: make([]aggState, len(it.aggs))}
+				groups = append(groups, g)
+				byKey[key] = g
+			}
+		}
+		g := byKey[key]
+		for i, a := range it.aggs {
+			if a.Tag == "" {
+				if a.Func == AggregateCount {
+					g.state[i].cnt++
+				}
+				continue
+			}
+			if r, ok := tags[a.Tag]; ok && r != nil {
+				g.state[i].add(nameOf(r))
+			}
+		}
+	}
+	for sc.Next(ctx) {
+		add()
+		for sc.NextPath(ctx) {
+			add()
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]result, 0, len(groups))
+	for _, g := range groups {
+		r := g.result(it)
+		if r.id == nil {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+type groupByNext struct {
+	it      *GroupBy
+	results []result
+	done    bool
+	index   int
+	err     error
+}
+
+func newGroupByNext(it *GroupBy) *groupByNext {
+	return &groupByNext{it: it}
+}
+
+func (it *groupByNext) TagResults(dst map[string]refs.Ref) {
+	if it.index == 0 || it.index > len(it.results) {
+		return
+	}
+	for tag, v := range it.results[it.index-1].tags {
+		dst[tag] = v
+	}
+}
+
+func (it *groupByNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.done {
+		it.results, it.err = it.it.computeGroups(ctx)
+		it.done = true
+		if it.err != nil {
+			return false
+		}
+	}
+	if it.index >= len(it.results) {
+		return false
+	}
+	it.index++
+	return true
+}
+
+func (it *groupByNext) Err() error {
+	return it.err
+}
+
+func (it *groupByNext) Result() refs.Ref {
+	if it.index == 0 || it.index > len(it.results) {
+		return nil
+	}
+	return it.results[it.index-1].id
+}
+
+func (it *groupByNext) NextPath(ctx context.Context) bool {
+	return false
+}
+
+func (it *groupByNext) Close() error {
+	it.results = nil
+	return nil
+}
+
+func (it *groupByNext) String() string { return "GroupByNext" }
+
+type groupByContains struct {
+	it     *GroupBy
+	done   bool
+	byKey  map[interface{}]int
+	byName map[string]int
+	all    []result
+	cur    int
+	err    error
+}
+
+func newGroupByContains(it *GroupBy) *groupByContains {
+	return &groupByContains{it: it, cur: -1}
+}
+
+func (it *groupByContains) TagResults(dst map[string]refs.Ref) {
+	if it.cur < 0 {
+		return
+	}
+	for tag, v := range it.all[it.cur].tags {
+		dst[tag] = v
+	}
+}
+
+func (it *groupByContains) Err() error {
+	return it.err
+}
+
+func (it *groupByContains) Result() refs.Ref {
+	if it.cur < 0 {
+		return nil
+	}
+	return it.all[it.cur].id
+}
+
+func (it *groupByContains) nameKey(r refs.Ref) (string, bool) {
+	if v, ok := r.(refs.PreFetchedValue); ok {
+		return quad.StringOf(v.NameOf()), true
+	}
+	if it.it.qs == nil {
+		return "", false
+	}
+	return quad.StringOf(it.it.qs.NameOf(r)), true
+}
+
+func (it *groupByContains) Contains(ctx context.Context, val refs.Ref) bool {
+	it.cur = -1
+	if it.err != nil {
+		return false
+	}
+	if !it.done {
+		it.done = true
+		it.all, it.err = it.it.computeGroups(ctx)
+		if it.err != nil {
+			return false
+		}
+		it.byKey = make(map[interface{}]int, len(it.all))
+		it.byName = make(map[string]int, len(it.all))
+		for i, r := range it.all {
+			it.byKey[refs.ToKey(r.id)] = i
+			if s, ok := it.nameKey(r.id); ok {
+				it.byName[s] = i
+			}
+		}
+	}
+	if i, ok := it.byKey[refs.ToKey(val)]; ok {
+		it.cur = i
+		return true
+	}
+	if s, ok := it.nameKey(val); ok {
+		if i, ok := it.byName[s]; ok {
+			it.cur = i
+			return true
+		}
+	}
+	return false
+}
+
+func (it *groupByContains) NextPath(ctx context.Context) bool {
+	return false
+}
+
+func (it *groupByContains) Close() error {
+	it.all = nil
+	return nil
+}
+
+func (it *groupByContains) String() string { return "GroupByContains" }
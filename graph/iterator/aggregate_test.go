@@ -0,0 +1,105 @@
+package iterator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupBy(t *testing.T) {
+	ctx := context.TODO()
+	row := func(city string, age quad.Value) map[string]refs.Ref {
+		tags := map[string]refs.Ref{"city": refs.PreFetched(quad.IRI(city))}
+		if age != nil {
+			tags["age"] = refs.PreFetched(age)
+		}
+		return tags
+	}
+	sub := tagRows{
+		row("ny", quad.Int(30)),
+		row("ny", quad.Float(41.5)),
+		row("sf", quad.Int(20)),
+		row("sf", quad.Int(25)),
+		row("sf", quad.String("unknown")),
+		row("la", nil),
+	}
+	aggs := []Aggregate{
+		{Func: AggregateCount, As: "n"},
+		{Func: AggregateSum, Tag: "age", As: "sum"},
+		{Func: AggregateAvg, Tag: "age", As: "avg"},
+		{Func: AggregateMin, Tag: "age", As: "min"},
+		{Func: AggregateMax, Tag: "age", As: "max"},
+	}
+	it := NewGroupBy(sub, nil, "city", aggs)
+
+	var got []map[string]quad.Value
+	sc := it.Iterate()
+	for sc.Next(ctx) {
+		tags := make(map[string]refs.Ref)
+		sc.TagResults(tags)
+		require.Equal(t, tags["city"], sc.Result())
+		m := make(map[string]quad.Value)
+		for k, v := range tags {
+			m[k] = v.(refs.PreFetchedValue).NameOf()
+		}
+		got = append(got, m)
+	}
+	require.NoError(t, sc.Err())
+	require.NoError(t, sc.Close())
+	require.Equal(t, []map[string]quad.Value{
+		{"city": quad.IRI("ny"), "n": quad.Int(2), "sum": quad.Float(71.5), "avg": quad.Float(35.75), "min": quad.Int(30), "max": quad.Float(41.5)},
+		{"city": quad.IRI("sf"), "n": quad.Int(3), "sum": quad.Int(45), "avg": quad.Float(22.5), "min": quad.Int(20), "max": quad.Int(25)},
+		{"city": quad.IRI("la"), "n": quad.Int(1), "sum": quad.Int(0)},
+	}, got)
+
+	idx := it.Lookup()
+	require.True(t, idx.Contains(ctx, refs.PreFetched(quad.IRI("sf"))))
+	tags := make(map[string]refs.Ref)
+	idx.TagResults(tags)
+	require.Equal(t, refs.PreFetched(quad.Int(3)), tags["n"])
+	require.False(t, idx.Contains(ctx, refs.PreFetched(quad.IRI("nyc"))))
+
+	// no grouping
+	it = NewGroupBy(sub, nil, "", []Aggregate{{Func: AggregateMax, Tag: "age", As: "max"}})
+	sc = it.Iterate()
+	require.True(t, sc.Next(ctx))
+	require.Equal(t, refs.PreFetched(quad.Float(41.5)), sc.Result())
+	require.False(t, sc.Next(ctx))
+}
+
+// tagRows is an iterator that returns results with given tags.
+type tagRows []map[string]refs.Ref
+
+func (it tagRows) Iterate() Scanner {
+	return &tagRowsNext{rows: it, i: -1}
+}
+func (it tagRows) Lookup() Index                            { return nil }
+func (it tagRows) Stats(ctx context.Context) (Costs, error) { return Costs{}, nil }
+func (it tagRows) Optimize(ctx context.Context) (Shape, bool) {
+	return it, false
+}
+func (it tagRows) SubIterators() []Shape { return nil }
+func (it tagRows) String() string        { return "tagRows" }
+
+type tagRowsNext struct {
+	rows tagRows
+	i    int
+}
+
+func (it *tagRowsNext) Next(ctx context.Context) bool {
+	it.i++
+	return it.i < len(it.rows)
+}
+func (it *tagRowsNext) Result() refs.Ref                  { return refs.PreFetched(quad.Int(it.i)) }
+func (it *tagRowsNext) NextPath(ctx context.Context) bool { return false }
+func (it *tagRowsNext) Err() error                        { return nil }
+func (it *tagRowsNext) Close() error                      { return nil }
+func (it *tagRowsNext) String() string                    { return "tagRowsNext" }
+func (it *tagRowsNext) TagResults(dst map[string]refs.Ref) {
+	for k, v := range it.rows[it.i] {
+		dst[k] = v
+	}
+}
@@ -18,6 +18,7 @@ package iterator
 import (
 	"context"
 
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/refs"
 )
 
@@ -45,6 +46,12 @@ func (it *And) Iterate() Scanner {
 	if len(it.sub) == 0 {
 		return NewNull().Iterate()
 	}
+	return newAndNext(it.sub[0].Iterate(), it.lookupSecondary(), it)
+}
+
+// lookupSecondary creates an index for all subiterators except the primary one.
+// It's used by workers in parallel mode.
+func (it *And) lookupSecondary() Index {
 	sub := make([]Index, 0, len(it.sub)-1)
 	for _, s := range it.sub[1:] {
 		sub = append(sub, s.Lookup())
@@ -53,7 +60,7 @@ func (it *And) Iterate() Scanner {
 	for _, s := range it.opt {
 		opt = append(opt, s.Lookup())
 	}
-	return newAndNext(it.sub[0].Iterate(), newAndContains(sub, opt))
+	return newAndContains(sub, opt)
 }
 
 func (it *And) Lookup() Index {
@@ -113,14 +120,21 @@ type andNext struct {
 	primary   Scanner
 	secondary Index
 	result    refs.Ref
+	gov       governor.Lazy
+	err       error
+
+	shape    *And          // used to create more iterators in parallel mode
+	pre      *andPrefilter // set if running in parallel mode
+	preCheck bool
 }
 
 // NewAnd creates an And iterator. `qs` is only required when needing a handle
 // for QuadStore-specific optimizations, otherwise nil is acceptable.
-func newAndNext(pri Scanner, sec Index) Scanner {
+func newAndNext(pri Scanner, sec Index, shape *And) Scanner {
 	return &andNext{
 		primary:   pri,
 		secondary: sec,
+		shape:     shape,
 	}
 }
 
@@ -140,8 +154,33 @@ func (it *andNext) String() string {
 // this value against the subiterators. A productive choice of primary iterator
 // is therefore very important.
 func (it *andNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.preCheck {
+		it.preCheck = true
+		if n := Parallelism(ctx); n > 1 && it.shape != nil && len(it.shape.sub) > 1 {
+			it.pre = newAndPrefilter(ctx, it.shape, n)
+		}
+	}
 	for it.primary.Next(ctx) {
+		if err := it.gov.From(ctx).Step(1); err != nil {
+			it.err = err
+			return false
+		}
 		cur := it.primary.Result()
+		if it.pre != nil {
+			ok, known, err := it.pre.Check(ctx, cur)
+			if err != nil {
+				it.err = err
+				return false
+			} else if !known {
+				it.pre.Close()
+				it.pre = nil
+			} else if !ok {
+				continue
+			}
+		}
 		if it.secondary.Contains(ctx, cur) {
 			it.result = cur
 			return true
@@ -150,7 +189,50 @@ func (it *andNext) Next(ctx context.Context) bool {
 	return false
 }
 
+// NextBatch advances the And iterator by multiple values. It reads a batch of candidates from the primary
+// iterator and checks them against other subiterators.
+func (it *andNext) NextBatch(ctx context.Context, dst []refs.Ref) int {
+	if it.err != nil || len(dst) == 0 {
+		return 0
+	}
+	if !it.preCheck || it.pre != nil {
+		// prefilter expects candidates one by one
+		n := 0
+		for n < len(dst) && it.Next(ctx) {
+			dst[n] = it.result
+			n++
+		}
+		return n
+	}
+	for {
+		n := NextBatch(ctx, it.primary, dst)
+		if n == 0 {
+			return 0
+		}
+		if err := it.gov.From(ctx).Step(int64(n)); err != nil {
+			it.err = err
+			return 0
+		}
+		m := 0
+		for _, cur := range dst[:n] {
+			if it.secondary.Contains(ctx, cur) {
+				dst[m] = cur
+				m++
+			} else if it.secondary.Err() != nil {
+				return 0
+			}
+		}
+		if m > 0 {
+			it.result = dst[m-1]
+			return m
+		}
+	}
+}
+
 func (it *andNext) Err() error {
+	if it.err != nil {
+		return it.err
+	}
 	if err := it.primary.Err(); err != nil {
 		return err
 	}
@@ -186,6 +268,10 @@ func (it *andNext) NextPath(ctx context.Context) bool {
 // follow this contract, the And follows the contract.  It closes all
 // subiterators it can, but returns the first error it encounters.
 func (it *andNext) Close() error {
+	if it.pre != nil {
+		it.pre.Close()
+		it.pre = nil
+	}
 	err := it.primary.Close()
 	if err2 := it.secondary.Close(); err2 != nil && err == nil {
 		err = err2
@@ -202,6 +288,7 @@ type andContains struct {
 	optCheck []bool
 
 	result refs.Ref
+	gov    governor.Lazy
 	err    error
 }
 
@@ -255,6 +342,10 @@ func (it *andContains) Result() refs.Ref {
 
 // Check a value against the entire iterator, in order.
 func (it *andContains) Contains(ctx context.Context, val refs.Ref) bool {
+	if err := it.gov.From(ctx).Step(1); err != nil {
+		it.err = err
+		return false
+	}
 	prev := it.result
 	for i, sub := range it.sub {
 		if !sub.Contains(ctx, val) {
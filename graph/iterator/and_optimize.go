@@ -66,6 +66,12 @@ func (it *And) Optimize(ctx context.Context) (Shape, bool) {
 	// now a permutation of itself, but the contents are unchanged.
 	its = optimizeOrder(ctx, its)
 
+	// Replace expensive Contains() checks with hash joins, if possible.
+	its = optimizeHashJoin(ctx, its)
+	if len(its) == 1 && len(it.opt) == 0 {
+		return its[0], true
+	}
+
 	its, _ = materializeIts(ctx, its)
 
 	// Okay! At this point we have an optimized order.
@@ -125,6 +131,11 @@ func optimizeOrder(ctx context.Context, its []Shape) []Shape {
 		costs = append(costs, st)
 	}
 	for i, root := range its {
+		if _, ok := root.(*Sort); ok {
+			// Sort only works as a primary iterator; Contains will ignore the order
+			best = root
+			break
+		}
 		rootStats := costs[i]
 		cost := rootStats.NextCost
 		for j, f := range its {
@@ -166,6 +177,48 @@ func optimizeOrder(ctx context.Context, its []Shape) []Shape {
 	return out
 }
 
+// optimizeHashJoin(l) takes an ordered list of iterators and joins the primary
+// iterator with each of the others using a hash join, if calling Contains() on
+// the other iterator for each primary value is projected to cost more than
+// reading all its values to a hash set once.
+//
+// Small primary iterators are never joined this way: the number of Contains()
+// calls is low, and cost estimates are too rough to justify the change of plan.
+//
+// The primary iterator is always the one that is streamed. It was chosen as the
+// cheapest one to Next(), and it determines the order of results, the number of
+// times each value is returned and its paths. Thus, the join returns exactly the
+// same results as the And would.
+func optimizeHashJoin(ctx context.Context, its []Shape) []Shape {
+	if len(its) < 2 {
+		return its
+	}
+	primary := its[0]
+	if _, ok := primary.(*Sort); ok {
+		// Sort must remain the primary iterator to preserve the order
+		return its
+	}
+	pst, _ := primary.Stats(ctx)
+	if pst.Size.Value < hashJoinMinSize {
+		return its
+	}
+	out := []Shape{nil}
+	for _, sub := range its[1:] {
+		st, _ := sub.Stats(ctx)
+		if _, ok := sub.(*Sort); ok || st.ContainsCost*pst.Size.Value <= hashJoinBuildCost(st) {
+			out = append(out, sub)
+			continue
+		}
+		if clog.V(3) {
+			clog.Infof("And: hash join of %p and %p", primary, sub)
+		}
+		primary = NewHashJoin(primary, sub)
+		pst, _ = primary.Stats(ctx)
+	}
+	out[0] = primary
+	return out
+}
+
 func sortByContainsCost(ctx context.Context, arr []Shape) error {
 	cost := make([]Costs, 0, len(arr))
 	var last error
@@ -0,0 +1,171 @@
+package iterator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+var batchCases = []struct {
+	name  string
+	shape func() Shape
+}{
+	{
+		name: "fixed",
+		shape: func() Shape {
+			return NewFixed(Int64Node(1), Int64Node(2), Int64Node(3), Int64Node(4), Int64Node(5))
+		},
+	},
+	{
+		name: "limit",
+		shape: func() Shape {
+			return NewLimit(newInt64(1, 100, true), 42)
+		},
+	},
+	{
+		name: "skip",
+		shape: func() Shape {
+			return NewSkip(newInt64(1, 100, true), 17)
+		},
+	},
+	{
+		name: "or",
+		shape: func() Shape {
+			return NewOr(
+				NewFixed(Int64Node(1), Int64Node(2)),
+				NewFixed(),
+				newInt64(10, 50, true),
+			)
+		},
+	},
+	{
+		name: "short circuit",
+		shape: func() Shape {
+			return NewShortCircuitOr(
+				NewFixed(),
+				newInt64(10, 50, true),
+				newInt64(60, 70, true),
+			)
+		},
+	},
+	{
+		name: "and",
+		shape: func() Shape {
+			return NewAnd(
+				newInt64(1, 100, true),
+				NewFixed(Int64Node(3), Int64Node(50), Int64Node(99), Int64Node(200)),
+			)
+		},
+	},
+	{
+		name: "nested",
+		shape: func() Shape {
+			return NewLimit(NewSkip(NewAnd(
+				NewOr(newInt64(1, 30, true), newInt64(20, 60, true)),
+				newInt64(10, 40, true),
+			), 5), 30)
+		},
+	},
+}
+
+func collectNext(t *testing.T, it Scanner) []refs.Ref {
+	ctx := context.TODO()
+	var out []refs.Ref
+	for it.Next(ctx) {
+		out = append(out, it.Result())
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	return out
+}
+
+func collectBatch(t *testing.T, it Scanner, size int) []refs.Ref {
+	ctx := context.TODO()
+	var out []refs.Ref
+	buf := make([]refs.Ref, size)
+	for {
+		n := NextBatch(ctx, it, buf)
+		if n == 0 {
+			break
+		}
+		require.True(t, n <= size)
+		require.Equal(t, buf[n-1], it.Result())
+		out = append(out, buf[:n]...)
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	return out
+}
+
+func TestNextBatch(t *testing.T) {
+	for _, c := range batchCases {
+		t.Run(c.name, func(t *testing.T) {
+			expect := collectNext(t, c.shape().Iterate())
+			require.NotEmpty(t, expect)
+			for _, size := range []int{1, 3, 100} {
+				_, ok := c.shape().Iterate().(BatchScanner)
+				require.True(t, ok)
+				got := collectBatch(t, c.shape().Iterate(), size)
+				require.Equal(t, expect, got, "batch size: %d", size)
+			}
+		})
+	}
+}
+
+// batchNamer records the number of calls to resolve values.
+type batchNamer struct {
+	single int
+	batch  int
+}
+
+func (qs *batchNamer) ValueOf(v quad.Value) refs.Ref {
+	return nil
+}
+
+func (qs *batchNamer) NameOf(v refs.Ref) quad.Value {
+	qs.single++
+	return quad.Int(v.(Int64Node))
+}
+
+func (qs *batchNamer) ValuesOf(ctx context.Context, vals []refs.Ref) ([]quad.Value, error) {
+	qs.batch++
+	out := make([]quad.Value, len(vals))
+	for i, v := range vals {
+		out[i] = quad.Int(v.(Int64Node))
+	}
+	return out, nil
+}
+
+func (qs *batchNamer) RefsOf(ctx context.Context, nodes []quad.Value) ([]refs.Ref, error) {
+	panic("not implemented")
+}
+
+func TestChainBatchValues(t *testing.T) {
+	ctx := context.TODO()
+	for _, paths := range []bool{false, true} {
+		qs := &batchNamer{}
+		vals, err := Iterate(ctx, newInt64(1, 250, true)).Paths(paths).Limit(220).AllValues(qs)
+		require.NoError(t, err)
+		require.Len(t, vals, 220)
+		require.Equal(t, quad.Int(1), vals[0])
+		require.Equal(t, quad.Int(220), vals[219])
+		require.Equal(t, 0, qs.single)
+		require.Equal(t, 3, qs.batch)
+
+		qs = &batchNamer{}
+		var tags []map[string]quad.Value
+		err = Iterate(ctx, NewSave(newInt64(1, 150, true), "a", "b")).Paths(paths).TagValues(qs, func(m map[string]quad.Value) {
+			tags = append(tags, m)
+		})
+		require.NoError(t, err)
+		require.Len(t, tags, 150)
+		require.Equal(t, map[string]quad.Value{"a": quad.Int(150), "b": quad.Int(150)}, tags[149])
+		require.Equal(t, 0, qs.single)
+		require.Equal(t, 3, qs.batch)
+	}
+}
@@ -130,6 +130,16 @@ func (it *fixedNext) Next(ctx context.Context) bool {
 	return true
 }
 
+// NextBatch copies the next values of the iterator to dst.
+func (it *fixedNext) NextBatch(ctx context.Context, dst []refs.Ref) int {
+	n := copy(dst, it.values[it.ind:])
+	if n > 0 {
+		it.ind += n
+		it.result = dst[n-1]
+	}
+	return n
+}
+
 func (it *fixedNext) Err() error {
 	return nil
 }
@@ -0,0 +1,327 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+// Defines the HashJoin iterator, an alternative to And for intersecting two large iterators.
+//
+// And checks each value of its primary iterator against other iterators by calling Contains,
+// which is expensive when other iterators are not backed by an index (for example, regex filters).
+// Instead, HashJoin reads all values of one iterator (build side) to a hash set once,
+// and then scans the other iterator (probe side), returning values that are present in the set.
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/refs"
+)
+
+var _ Shape = (*HashJoin)(nil)
+
+// HashJoin is an intersection of two iterators. It materializes the build iterator into a hash set
+// keyed by refs.ToKey, and streams values of the probe iterator that are in that set.
+//
+// Tags of both iterators are preserved. Paths of a result are listed in the same way as for And:
+// all paths of the probe iterator first, followed by other paths of the build iterator. If the build
+// iterator returns the same value multiple times, paths of all its occurrences are listed.
+type HashJoin struct {
+	probe Shape
+	build Shape
+}
+
+// NewHashJoin creates a HashJoin iterator. The build iterator should be the smaller one,
+// since all its values are kept in memory.
+func NewHashJoin(probe, build Shape) *HashJoin {
+	return &HashJoin{probe: probe, build: build}
+}
+
+func (it *HashJoin) Iterate() Scanner {
+	return newHashJoinNext(it.probe.Iterate(), it.build)
+}
+
+func (it *HashJoin) Lookup() Index {
+	return newHashJoinContains(it.probe.Lookup(), it.build)
+}
+
+// SubIterators returns the probe and the build iterators.
+func (it *HashJoin) SubIterators() []Shape {
+	return []Shape{it.probe, it.build}
+}
+
+func (it *HashJoin) String() string {
+	return "HashJoin"
+}
+
+func (it *HashJoin) Optimize(ctx context.Context) (Shape, bool) {
+	probe, ok1 := it.probe.Optimize(ctx)
+	build, ok2 := it.build.Optimize(ctx)
+	if IsNull(probe) || IsNull(build) {
+		return NewNull(), true
+	}
+	if !ok1 && !ok2 {
+		return it, false
+	}
+	return NewHashJoin(probe, build), true
+}
+
+// Stats returns the costs of the join, including the cost of building the hash set,
+// amortized over all values of the probe iterator.
+func (it *HashJoin) Stats(ctx context.Context) (Costs, error) {
+	probe, err := it.probe.Stats(ctx)
+	build, err2 := it.build.Stats(ctx)
+	if err == nil {
+		err = err2
+	}
+	buildCost := hashJoinBuildCost(build) / (probe.Size.Value + 1)
+	size := probe.Size
+	if build.Size.Value < size.Value {
+		size = build.Size
+	}
+	return Costs{
+		NextCost:     probe.NextCost + hashLookupCost + buildCost,
+		ContainsCost: probe.ContainsCost + hashLookupCost + buildCost,
+		Size: refs.Size{
+			Value: size.Value,
+			Exact: false,
+		},
+	}, err
+}
+
+// hashJoinMinSize is the minimal size of the primary iterator of And to consider a hash join.
+const hashJoinMinSize = 1000
+
+// hashLookupCost is the cost of checking a single value against the hash set.
+const hashLookupCost = 1
+
+// hashJoinBuildCost returns the cost of reading all values of an iterator to the hash set.
+func hashJoinBuildCost(st Costs) int64 {
+	return (st.NextCost + hashLookupCost) * st.Size.Value
+}
+
+// hashSet maps keys of values of the build iterator to tags of all their paths.
+type hashSet map[interface{}][]map[string]refs.Ref
+
+// buildHashSet reads all values and paths of an iterator to the hash set.
+func buildHashSet(ctx context.Context, s Shape) (hashSet, error) {
+	it := s.Iterate()
+	gov := governor.FromContext(ctx)
+	set := make(hashSet)
+	for it.Next(ctx) {
+		key := refs.ToKey(it.Result())
+		n := 0
+		for {
+			tags := make(map[string]refs.Ref)
+			it.TagResults(tags)
+			if len(tags) == 0 {
+				tags = nil
+			}
+			set[key] = append(set[key], tags)
+			n++
+			if !it.NextPath(ctx) {
+				break
+			}
+		}
+		if err := gov.Materialize(int64(n)); err != nil {
+			it.Close()
+			return nil, err
+		}
+	}
+	err := it.Err()
+	if err2 := it.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// hashJoinPaths keeps the state of build iterator paths for the current result.
+type hashJoinPaths struct {
+	paths []map[string]refs.Ref
+	ind   int
+}
+
+func (p *hashJoinPaths) set(paths []map[string]refs.Ref) {
+	p.paths, p.ind = paths, 0
+}
+
+func (p *hashJoinPaths) tagResults(dst map[string]refs.Ref) {
+	if p.ind >= len(p.paths) {
+		return
+	}
+	for k, v := range p.paths[p.ind] {
+		dst[k] = v
+	}
+}
+
+func (p *hashJoinPaths) next() bool {
+	if p.ind+1 >= len(p.paths) {
+		return false
+	}
+	p.ind++
+	return true
+}
+
+type hashJoinNext struct {
+	probe Scanner
+	build Shape
+	set   hashSet
+	cur   hashJoinPaths
+
+	result refs.Ref
+	gov    governor.Lazy
+	err    error
+}
+
+func newHashJoinNext(probe Scanner, build Shape) *hashJoinNext {
+	return &hashJoinNext{
+		probe: probe,
+		build: build,
+	}
+}
+
+func (it *hashJoinNext) TagResults(dst map[string]refs.Ref) {
+	it.probe.TagResults(dst)
+	it.cur.tagResults(dst)
+}
+
+func (it *hashJoinNext) String() string {
+	return "HashJoinNext"
+}
+
+// Next builds the hash set on the first call, and then returns the next value
+// of the probe iterator that is present in the set.
+func (it *hashJoinNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.set == nil {
+		if it.set, it.err = buildHashSet(ctx, it.build); it.err != nil {
+			return false
+		}
+	}
+	if len(it.set) == 0 {
+		return false
+	}
+	for it.probe.Next(ctx) {
+		if it.err = it.gov.From(ctx).Step(1); it.err != nil {
+			return false
+		}
+		cur := it.probe.Result()
+		if paths, ok := it.set[refs.ToKey(cur)]; ok {
+			it.result = cur
+			it.cur.set(paths)
+			return true
+		}
+	}
+	return false
+}
+
+func (it *hashJoinNext) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.probe.Err()
+}
+
+func (it *hashJoinNext) Result() refs.Ref {
+	return it.result
+}
+
+// NextPath lists paths of the probe iterator first, and then the rest of paths of the build iterator.
+func (it *hashJoinNext) NextPath(ctx context.Context) bool {
+	if it.probe.NextPath(ctx) {
+		return true
+	} else if err := it.probe.Err(); err != nil {
+		return false
+	}
+	return it.cur.next()
+}
+
+func (it *hashJoinNext) Close() error {
+	it.set = nil
+	return it.probe.Close()
+}
+
+type hashJoinContains struct {
+	probe Index
+	build Shape
+	set   hashSet
+	cur   hashJoinPaths
+
+	result refs.Ref
+	err    error
+}
+
+func newHashJoinContains(probe Index, build Shape) *hashJoinContains {
+	return &hashJoinContains{
+		probe: probe,
+		build: build,
+	}
+}
+
+func (it *hashJoinContains) TagResults(dst map[string]refs.Ref) {
+	it.probe.TagResults(dst)
+	it.cur.tagResults(dst)
+}
+
+func (it *hashJoinContains) String() string {
+	return "HashJoinContains"
+}
+
+// Contains checks the value against the hash set first, and then against the probe iterator.
+func (it *hashJoinContains) Contains(ctx context.Context, val refs.Ref) bool {
+	if it.err != nil {
+		return false
+	}
+	if it.set == nil {
+		if it.set, it.err = buildHashSet(ctx, it.build); it.err != nil {
+			return false
+		}
+	}
+	paths, ok := it.set[refs.ToKey(val)]
+	if !ok || !it.probe.Contains(ctx, val) {
+		return false
+	}
+	it.result = it.probe.Result()
+	it.cur.set(paths)
+	return true
+}
+
+func (it *hashJoinContains) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.probe.Err()
+}
+
+func (it *hashJoinContains) Result() refs.Ref {
+	return it.result
+}
+
+func (it *hashJoinContains) NextPath(ctx context.Context) bool {
+	if it.probe.NextPath(ctx) {
+		return true
+	} else if err := it.probe.Err(); err != nil {
+		return false
+	}
+	return it.cur.next()
+}
+
+func (it *hashJoinContains) Close() error {
+	it.set = nil
+	return it.probe.Close()
+}
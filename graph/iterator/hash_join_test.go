@@ -0,0 +1,130 @@
+package iterator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+)
+
+// slowLookup is a shape with an expensive Contains, similar to filters that are not backed by an index.
+type slowLookup struct {
+	Shape
+}
+
+func (it slowLookup) Optimize(ctx context.Context) (Shape, bool) {
+	return it, false
+}
+
+func (it slowLookup) Stats(ctx context.Context) (Costs, error) {
+	st, err := it.Shape.Stats(ctx)
+	st.ContainsCost = 100 * st.Size.Value
+	return st, err
+}
+
+var hashJoinCases = []struct {
+	name         string
+	probe, build func() Shape
+}{
+	{
+		name:  "ranges",
+		probe: func() Shape { return newInt64(1, 1000, true) },
+		build: func() Shape { return newInt64(300, 1500, true) },
+	},
+	{
+		name:  "tags",
+		probe: func() Shape { return NewSave(newInt64(1, 100, true), "a") },
+		build: func() Shape { return NewSave(newInt64(50, 150, true), "b") },
+	},
+	{
+		name: "paths",
+		probe: func() Shape {
+			return NewOr(
+				NewSave(newInt64(1, 60, true), "a"),
+				NewSave(newInt64(40, 100, true), "b"),
+			)
+		},
+		build: func() Shape { return NewSave(newInt64(20, 80, true), "c", "d") },
+	},
+	{
+		name:  "empty",
+		probe: func() Shape { return newInt64(1, 100, true) },
+		build: func() Shape { return NewFixed() },
+	},
+}
+
+func TestHashJoin(t *testing.T) {
+	ctx := context.TODO()
+	for _, c := range hashJoinCases {
+		t.Run(c.name, func(t *testing.T) {
+			expect := collectResults(t, ctx, NewAnd(c.probe(), c.build()).Iterate())
+			got := collectResults(t, ctx, NewHashJoin(c.probe(), c.build()).Iterate())
+			require.Equal(t, expect, got)
+
+			and := NewAnd(c.probe(), c.build()).Lookup()
+			join := NewHashJoin(c.probe(), c.build()).Lookup()
+			for _, v := range []int{0, 1, 20, 35, 50, 99, 100, 300, 1000, 2000} {
+				ok := and.Contains(ctx, Int64Node(v))
+				require.Equal(t, ok, join.Contains(ctx, Int64Node(v)), "%d", v)
+				if !ok {
+					continue
+				}
+				require.Equal(t, and.Result(), join.Result())
+				for {
+					tags1, tags2 := make(map[string]refs.Ref), make(map[string]refs.Ref)
+					and.TagResults(tags1)
+					join.TagResults(tags2)
+					require.Equal(t, tags1, tags2, "%d", v)
+					ok := and.NextPath(ctx)
+					require.Equal(t, ok, join.NextPath(ctx), "%d", v)
+					if !ok {
+						break
+					}
+				}
+			}
+			require.NoError(t, and.Close())
+			require.NoError(t, join.Close())
+		})
+	}
+}
+
+func TestHashJoinOptimize(t *testing.T) {
+	ctx := context.TODO()
+
+	// both branches are large and checking values is expensive
+	a := NewAnd(slowLookup{newInt64(1, 1000, true)}, slowLookup{newInt64(500, 2000, true)})
+	it, changed := a.Optimize(ctx)
+	require.True(t, changed)
+	join, ok := it.(*HashJoin)
+	require.True(t, ok, "%T", it)
+	require.Len(t, join.SubIterators(), 2)
+	n, err := Iterate(ctx, it).Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(501), n)
+
+	// checking values is cheap
+	a = NewAnd(newInt64(1, 1000, true), newInt64(500, 2000, true))
+	it, _ = a.Optimize(ctx)
+	_, ok = it.(*And)
+	require.True(t, ok, "%T", it)
+
+	// other branches remain in the And
+	a = NewAnd(
+		slowLookup{newInt64(1, 1000, true)},
+		slowLookup{newInt64(500, 2000, true)},
+		newInt64(1, 5000, true),
+	)
+	it, _ = a.Optimize(ctx)
+	and, ok := it.(*And)
+	require.True(t, ok, "%T", it)
+	subs := and.SubIterators()
+	require.Len(t, subs, 2)
+	_, ok = subs[0].(*HashJoin)
+	require.True(t, ok, "%T", subs[0])
+	n, err = Iterate(ctx, and).Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(501), n)
+}
@@ -60,6 +60,55 @@ func (c *Chain) nextPath() bool {
 	}
 	return ok
 }
+
+// nextBatch reads the next batch of results to buf, which must have a capacity of at least valueBatch.
+// It uses BatchScanner if iteration over sub-paths is disabled.
+func (c *Chain) nextBatch(buf []refs.Ref) []refs.Ref {
+	buf = buf[:0]
+	if c.ctx.Err() != nil {
+		return buf
+	}
+	if !c.paths {
+		n := valueBatch
+		if c.limit >= 0 && c.limit-c.n < n {
+			n = c.limit - c.n
+		}
+		if n <= 0 {
+			return buf
+		}
+		n = NextBatch(c.ctx, c.it, buf[:n])
+		c.n += n
+		return buf[:n]
+	}
+	for len(buf) < valueBatch && c.next() {
+		buf = append(buf, c.it.Result())
+		for c.nextPath() {
+			buf = append(buf, c.it.Result())
+		}
+	}
+	return buf
+}
+
+// eachBatch will run a provided callback for each batch of results of the iterator.
+func (c *Chain) eachBatch(fnc func([]refs.Ref) error) error {
+	c.start()
+	defer c.end()
+	buf := make([]refs.Ref, 0, valueBatch)
+	for {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		buf = c.nextBatch(buf)
+		if len(buf) == 0 {
+			break
+		}
+		if err := fnc(buf); err != nil {
+			return err
+		}
+	}
+	return c.it.Err()
+}
+
 func (c *Chain) start() {
 	if c.optimize {
 		c.s, _ = c.s.Optimize(c.ctx)
@@ -98,27 +147,12 @@ func (c *Chain) UnOptimized() *Chain {
 
 // Each will run a provided callback for each result of the iterator.
 func (c *Chain) Each(fnc func(refs.Ref)) error {
-	c.start()
-	defer c.end()
-	done := c.ctx.Done()
-
-	for c.next() {
-		select {
-		case <-done:
-			return c.ctx.Err()
-		default:
+	return c.eachBatch(func(batch []refs.Ref) error {
+		for _, v := range batch {
+			fnc(v)
 		}
-		fnc(c.it.Result())
-		for c.nextPath() {
-			select {
-			case <-done:
-				return c.ctx.Err()
-			default:
-			}
-			fnc(c.it.Result())
-		}
-	}
-	return c.it.Err()
+		return nil
+	})
 }
 
 // All will return all results of an iterator.
@@ -161,28 +195,16 @@ iteration:
 
 // All will return all results of an iterator.
 func (c *Chain) All() ([]refs.Ref, error) {
-	c.start()
-	defer c.end()
-	done := c.ctx.Done()
 	var out []refs.Ref
-iteration:
-	for c.next() {
-		select {
-		case <-done:
-			break iteration
-		default:
-		}
-		out = append(out, c.it.Result())
-		for c.nextPath() {
-			select {
-			case <-done:
-				break iteration
-			default:
-			}
-			out = append(out, c.it.Result())
-		}
+	err := c.eachBatch(func(batch []refs.Ref) error {
+		out = append(out, batch...)
+		return nil
+	})
+	if err == c.ctx.Err() {
+		// return partial results on cancellation
+		err = c.it.Err()
 	}
-	return out, c.it.Err()
+	return out, err
 }
 
 // First will return a first result of an iterator. It returns nil if iterator is empty.
@@ -257,25 +279,23 @@ func (c *Chain) TagEach(fnc func(map[string]refs.Ref)) error {
 
 var errNoQuadStore = fmt.Errorf("no quad store in Iterate")
 
+// valueBatch is the number of results resolved to values at once.
+const valueBatch = 100
+
 // EachValue is an analog of Each, but it will additionally call NameOf
 // for each graph.Ref before passing it to a callback.
+//
+// Values are resolved in batches, using refs.BatchNamer if the quad store implements it.
 func (c *Chain) EachValue(qs refs.Namer, fnc func(quad.Value)) error {
-	if qs != nil {
-		c.qs = qs
-	}
-	if c.qs == nil {
-		return errNoQuadStore
-	}
-	// TODO(dennwc): batch NameOf?
-	return c.Each(func(v refs.Ref) {
-		if nv := c.qs.NameOf(v); nv != nil {
-			fnc(nv)
-		}
+	return c.EachValuePair(qs, func(_ refs.Ref, v quad.Value) {
+		fnc(v)
 	})
 }
 
 // EachValuePair is an analog of Each, but it will additionally call NameOf
 // for each graph.Ref before passing it to a callback. Original value will be passed as well.
+//
+// Values are resolved in batches, using refs.BatchNamer if the quad store implements it.
 func (c *Chain) EachValuePair(qs refs.Namer, fnc func(refs.Ref, quad.Value)) error {
 	if qs != nil {
 		c.qs = qs
@@ -283,11 +303,17 @@ func (c *Chain) EachValuePair(qs refs.Namer, fnc func(refs.Ref, quad.Value)) err
 	if c.qs == nil {
 		return errNoQuadStore
 	}
-	// TODO(dennwc): batch NameOf?
-	return c.Each(func(v refs.Ref) {
-		if nv := c.qs.NameOf(v); nv != nil {
-			fnc(v, nv)
+	return c.eachBatch(func(batch []refs.Ref) error {
+		vals, err := refs.ValuesOf(c.ctx, c.qs, batch)
+		if err != nil {
+			return err
 		}
+		for i, nv := range vals {
+			if nv != nil {
+				fnc(batch[i], nv)
+			}
+		}
+		return nil
 	})
 }
 
@@ -326,32 +352,24 @@ func (c *Chain) SendValues(qs refs.Namer, out chan<- quad.Value) error {
 	if c.qs == nil {
 		return errNoQuadStore
 	}
-	c.start()
-	defer c.end()
 	done := c.ctx.Done()
-	send := func(v refs.Ref) error {
-		nv := c.qs.NameOf(c.it.Result())
-		if nv == nil {
-			return nil
-		}
-		select {
-		case <-done:
-			return c.ctx.Err()
-		case out <- c.qs.NameOf(c.it.Result()):
-		}
-		return nil
-	}
-	for c.next() {
-		if err := send(c.it.Result()); err != nil {
+	return c.eachBatch(func(batch []refs.Ref) error {
+		vals, err := refs.ValuesOf(c.ctx, c.qs, batch)
+		if err != nil {
 			return err
 		}
-		for c.nextPath() {
-			if err := send(c.it.Result()); err != nil {
-				return err
+		for _, nv := range vals {
+			if nv == nil {
+				continue
+			}
+			select {
+			case <-done:
+				return c.ctx.Err()
+			case out <- nv:
 			}
 		}
-	}
-	return c.it.Err()
+		return nil
+	})
 }
 
 // TagValues is an analog of TagEach, but it will additionally call NameOf
@@ -363,11 +381,60 @@ func (c *Chain) TagValues(qs refs.Namer, fnc func(map[string]quad.Value)) error
 	if c.qs == nil {
 		return errNoQuadStore
 	}
-	return c.TagEach(func(m map[string]refs.Ref) {
-		vm := make(map[string]quad.Value, len(m))
+	c.start()
+	defer c.end()
+	var (
+		maps []map[string]refs.Ref
+		keys []string
+		ids  []refs.Ref
+	)
+	// values of all tags in a batch are resolved at once
+	flush := func() error {
+		if len(maps) == 0 {
+			return nil
+		}
+		vals, err := refs.ValuesOf(c.ctx, c.qs, ids)
+		if err != nil {
+			return err
+		}
+		j := 0
+		for _, m := range maps {
+			vm := make(map[string]quad.Value, len(m))
+			for end := j + len(m); j < end; j++ {
+				vm[keys[j]] = vals[j]
+			}
+			fnc(vm)
+		}
+		maps, keys, ids = maps[:0], keys[:0], ids[:0]
+		return nil
+	}
+	add := func() error {
+		m := make(map[string]refs.Ref)
+		c.it.TagResults(m)
+		maps = append(maps, m)
 		for k, v := range m {
-			vm[k] = c.qs.NameOf(v) // TODO(dennwc): batch NameOf?
+			keys = append(keys, k)
+			ids = append(ids, v)
 		}
-		fnc(vm)
-	})
+		if len(ids) < valueBatch {
+			return nil
+		}
+		return flush()
+	}
+	for c.next() {
+		if err := add(); err != nil {
+			return err
+		}
+		for c.nextPath() {
+			if err := add(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	} else if err = c.it.Err(); err != nil {
+		return err
+	}
+	return flush()
 }
@@ -87,6 +87,36 @@ type Scanner interface {
 	Next(ctx context.Context) bool
 }
 
+// BatchScanner is an optional interface for scanners that can return multiple results at once.
+type BatchScanner interface {
+	Scanner
+
+	// NextBatch advances the iterator by up to len(dst) values and writes them to dst.
+	// It returns the number of values written, or zero if no further advancement is possible,
+	// or if an error was encountered. Err should be consulted to distinguish between the two cases.
+	// Returning less values than requested does not mean that the iterator is exhausted.
+	//
+	// It is equivalent to calling Next repeatedly and collecting each Result, except that NextPath
+	// is not called on returned values. Thus, alternative paths are skipped, and tags are not available.
+	// After the call, Result returns the last value in the batch.
+	NextBatch(ctx context.Context, dst []refs.Ref) int
+}
+
+// NextBatch advances the scanner by up to len(dst) values and writes them to dst.
+// It uses BatchScanner if the scanner implements it, and falls back to calling Next otherwise.
+// See BatchScanner for details.
+func NextBatch(ctx context.Context, it Scanner, dst []refs.Ref) int {
+	if bit, ok := it.(BatchScanner); ok {
+		return bit.NextBatch(ctx, dst)
+	}
+	n := 0
+	for n < len(dst) && it.Next(ctx) {
+		dst[n] = it.Result()
+		n++
+	}
+	return n
+}
+
 // Index is an index lookup iterator. It allows to check if an index contains a specific value.
 type Index interface {
 	Base
@@ -86,6 +86,21 @@ func (it *limitNext) Next(ctx context.Context) bool {
 	return false
 }
 
+// NextBatch advances the Limit iterator by multiple values. It will stop iteration if Limit was reached.
+func (it *limitNext) NextBatch(ctx context.Context, dst []refs.Ref) int {
+	if it.limit > 0 {
+		if it.count >= it.limit {
+			return 0
+		}
+		if rest := it.limit - it.count; int64(len(dst)) > rest {
+			dst = dst[:rest]
+		}
+	}
+	n := NextBatch(ctx, it.it, dst)
+	it.count += int64(n)
+	return n
+}
+
 func (it *limitNext) Err() error {
 	return it.it.Err()
 }
@@ -18,11 +18,16 @@ package iterator
 
 import (
 	"context"
+	"encoding/binary"
+	"io"
 
 	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/refs"
 )
 
+// MaterializeLimit is the maximal number of results kept by Materialize if spilling to disk is disabled.
+// If the limit is exceeded, Materialize iterates the subiterator directly.
 const MaterializeLimit = 1000
 
 type result struct {
@@ -96,7 +101,12 @@ type materializeNext struct {
 	next Scanner
 
 	containsMap map[interface{}]int
-	values      [][]result
+	groups      int
+	values      [][]result // only set if results are kept in memory
+	size        int64
+	spill       *spillFile
+	spilled     []int64 // offsets of the last record for each group, plus one
+	cur         []result
 	index       int
 	subindex    int
 	hasRun      bool
@@ -116,8 +126,23 @@ func newMaterializeNext(sub Shape) *materializeNext {
 func (it *materializeNext) Close() error {
 	it.containsMap = nil
 	it.values = nil
+	it.cur = nil
 	it.hasRun = false
-	return it.next.Close()
+	err := it.closeSpill()
+	if err2 := it.next.Close(); err2 != nil && err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (it *materializeNext) closeSpill() error {
+	if it.spill == nil {
+		return nil
+	}
+	err := it.spill.Close()
+	it.spill = nil
+	it.spilled = nil
+	return err
 }
 
 func (it *materializeNext) TagResults(dst map[string]refs.Ref) {
@@ -131,7 +156,7 @@ func (it *materializeNext) TagResults(dst map[string]refs.Ref) {
 	if it.Result() == nil {
 		return
 	}
-	for tag, value := range it.values[it.index][it.subindex].tags {
+	for tag, value := range it.cur[it.subindex].tags {
 		dst[tag] = value
 	}
 }
@@ -144,16 +169,13 @@ func (it *materializeNext) Result() refs.Ref {
 	if it.aborted {
 		return it.next.Result()
 	}
-	if len(it.values) == 0 {
-		return nil
-	}
-	if it.index == -1 {
+	if it.index == -1 || it.index >= it.groups {
 		return nil
 	}
-	if it.index >= len(it.values) {
+	if it.subindex >= len(it.cur) {
 		return nil
 	}
-	return it.values[it.index][it.subindex].id
+	return it.cur[it.subindex].id
 }
 
 func (it *materializeNext) Next(ctx context.Context) bool {
@@ -171,7 +193,12 @@ func (it *materializeNext) Next(ctx context.Context) bool {
 
 	it.index++
 	it.subindex = 0
-	if it.index >= len(it.values) {
+	if it.index >= it.groups {
+		it.cur = nil
+		return false
+	}
+	if err := it.loadGroup(it.index); err != nil {
+		it.err = err
 		return false
 	}
 	return true
@@ -193,7 +220,7 @@ func (it *materializeNext) NextPath(ctx context.Context) bool {
 	}
 
 	it.subindex++
-	if it.subindex >= len(it.values[it.index]) {
+	if it.subindex >= len(it.cur) {
 		// Don't go off the end of the world
 		it.subindex--
 		return false
@@ -202,52 +229,174 @@ func (it *materializeNext) NextPath(ctx context.Context) bool {
 }
 
 func (it *materializeNext) materializeSet(ctx context.Context) {
+	it.hasRun = true
 	i := 0
 	mn := 0
 	for it.next.Next(ctx) {
-		i++
-		if i > MaterializeLimit {
-			it.aborted = true
-			break
-		}
 		id := it.next.Result()
-		val := refs.ToKey(id)
-		if _, ok := it.containsMap[val]; !ok {
-			it.containsMap[val] = len(it.values)
-			it.values = append(it.values, nil)
-		}
-		index := it.containsMap[val]
 		tags := make(map[string]refs.Ref, mn)
 		it.next.TagResults(tags)
 		if n := len(tags); n > mn {
 			mn = n
 		}
-		it.values[index] = append(it.values[index], result{id: id, tags: tags})
-		for it.next.NextPath(ctx) {
-			i++
-			if i > MaterializeLimit {
-				it.aborted = true
+		group := []result{{id: id, tags: tags}}
+		i++
+		for SpillMemoryLimit > 0 || i <= MaterializeLimit {
+			if !it.next.NextPath(ctx) {
 				break
 			}
+			i++
 			tags := make(map[string]refs.Ref, mn)
 			it.next.TagResults(tags)
 			if n := len(tags); n > mn {
 				mn = n
 			}
-			it.values[index] = append(it.values[index], result{id: id, tags: tags})
+			group = append(group, result{id: id, tags: tags})
+		}
+		if SpillMemoryLimit <= 0 && i > MaterializeLimit {
+			it.aborted = true
+			break
+		}
+		if err := governor.FromContext(ctx).Materialize(int64(len(group))); err != nil {
+			_ = it.closeSpill()
+			it.err = err
+			return
+		}
+		if err := it.add(id, group); err != nil {
+			_ = it.closeSpill()
+			if e, ok := err.(errCannotSpill); ok {
+				if clog.V(2) {
+					clog.Infof("materialize: %v", e)
+				}
+				it.aborted = true
+				break
+			}
+			it.err = err
+			return
 		}
 	}
 	it.err = it.next.Err()
+	if it.err == nil && it.spill != nil {
+		it.err = it.spill.Flush()
+	}
 	if it.err == nil && it.aborted {
 		if clog.V(2) {
 			clog.Infof("Aborting subiterator")
 		}
 		it.values = nil
 		it.containsMap = nil
+		it.groups = 0
 		_ = it.next.Close()
 		it.next = it.sub.Iterate()
 	}
-	it.hasRun = true
+}
+
+// add appends results for a given node. Once the memory budget is exceeded, all results are written to disk.
+func (it *materializeNext) add(id refs.Ref, group []result) error {
+	key := refs.ToKey(id)
+	index, ok := it.containsMap[key]
+	if !ok {
+		index = it.groups
+		it.groups++
+		it.containsMap[key] = index
+		if it.spill != nil {
+			it.spilled = append(it.spilled, 0)
+		} else {
+			it.values = append(it.values, nil)
+		}
+	}
+	if it.spill != nil {
+		return it.spillGroup(index, group)
+	}
+	it.values[index] = append(it.values[index], group...)
+	for _, r := range group {
+		it.size += resultSize(r)
+	}
+	if SpillMemoryLimit <= 0 || it.size <= SpillMemoryLimit {
+		return nil
+	}
+	f, err := newSpillFile()
+	if err != nil {
+		return err
+	}
+	it.spill = f
+	it.spilled = make([]int64, len(it.values))
+	for i, g := range it.values {
+		if err := it.spillGroup(i, g); err != nil {
+			return err
+		}
+	}
+	it.values = nil
+	it.size = 0
+	return nil
+}
+
+// spillGroup writes results for a node to disk. Records for the same node are linked to each other.
+func (it *materializeNext) spillGroup(index int, group []result) error {
+	buf := appendUvarint(nil, uint64(it.spilled[index]))
+	buf = appendUvarint(buf, uint64(len(group)))
+	var err error
+	for _, r := range group {
+		buf, err = appendRef(buf, r.id)
+		if err != nil {
+			return err
+		}
+		buf, err = appendTags(buf, r.tags)
+		if err != nil {
+			return err
+		}
+	}
+	off, err := it.spill.Write(buf)
+	if err != nil {
+		return err
+	}
+	it.spilled[index] = off + 1
+	return nil
+}
+
+// loadGroup sets all results for a node with a given index as current.
+func (it *materializeNext) loadGroup(index int) error {
+	if it.spill == nil {
+		it.cur = it.values[index]
+		return nil
+	}
+	var chunks [][]result
+	for off := it.spilled[index]; off != 0; {
+		buf, err := it.spill.ReadAt(off - 1)
+		if err != nil {
+			return err
+		}
+		prev, i := binary.Uvarint(buf)
+		if i <= 0 {
+			return io.ErrUnexpectedEOF
+		}
+		buf = buf[i:]
+		n, i := binary.Uvarint(buf)
+		if i <= 0 {
+			return io.ErrUnexpectedEOF
+		}
+		buf = buf[i:]
+		group := make([]result, 0, n)
+		for j := uint64(0); j < n; j++ {
+			var r result
+			r.id, buf, err = readRef(buf)
+			if err != nil {
+				return err
+			}
+			r.tags, buf, err = readTags(buf)
+			if err != nil {
+				return err
+			}
+			group = append(group, r)
+		}
+		chunks = append(chunks, group)
+		off = int64(prev)
+	}
+	it.cur = nil
+	for i := len(chunks) - 1; i >= 0; i-- {
+		it.cur = append(it.cur, chunks[i]...)
+	}
+	return nil
 }
 
 type materializeContains struct {
@@ -320,6 +469,10 @@ func (it *materializeContains) Contains(ctx context.Context, v refs.Ref) bool {
 	if i, ok := it.next.containsMap[key]; ok {
 		it.next.index = i
 		it.next.subindex = 0
+		if err := it.next.loadGroup(i); err != nil {
+			it.next.err = err
+			return false
+		}
 		return true
 	}
 	return false
@@ -44,7 +44,10 @@ func TestMaterializeIteratorErrorAbort(t *testing.T) {
 
 	// This tests that we properly return 0 results and the error when the
 	// underlying iterator is larger than our 'abort at' value, and then
-	// returns an error.
+	// returns an error. Materialize only aborts if spilling to disk is disabled.
+	defer func(limit int64) { SpillMemoryLimit = limit }(SpillMemoryLimit)
+	SpillMemoryLimit = 0
+
 	or := NewOr(
 		newInt64(1, int64(MaterializeLimit+1), true),
 		errIt,
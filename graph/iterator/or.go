@@ -141,6 +141,8 @@ type orNext struct {
 	curInd       int
 	result       refs.Ref
 	err          error
+
+	parallel int // number of branches to prefetch; 0 if not yet checked
 }
 
 func newOrNext(sub []Scanner, shortCircuit bool) *orNext {
@@ -168,12 +170,16 @@ func (it *orNext) Next(ctx context.Context) bool {
 	if it.curInd >= len(it.sub) {
 		return false
 	}
+	if it.parallel == 0 {
+		it.startParallel(ctx)
+	}
 	var first bool
 	for {
 		if it.curInd == -1 {
 			it.curInd = 0
 			first = true
 		}
+		it.prefetch(ctx)
 		curIt := it.sub[it.curInd]
 
 		if curIt.Next(ctx) {
@@ -198,6 +204,72 @@ func (it *orNext) Next(ctx context.Context) bool {
 	return false
 }
 
+// NextBatch advances the Or iterator by multiple values. All values in the batch are returned by the same subiterator.
+func (it *orNext) NextBatch(ctx context.Context, dst []refs.Ref) int {
+	if it.curInd >= len(it.sub) || len(dst) == 0 {
+		return 0
+	}
+	if it.parallel == 0 {
+		it.startParallel(ctx)
+	}
+	var first bool
+	for {
+		if it.curInd == -1 {
+			it.curInd = 0
+			first = true
+		}
+		it.prefetch(ctx)
+		curIt := it.sub[it.curInd]
+
+		if n := NextBatch(ctx, curIt, dst); n > 0 {
+			it.result = dst[n-1]
+			return n
+		}
+
+		it.err = curIt.Err()
+		if it.err != nil {
+			return 0
+		}
+
+		if it.shortCircuit && !first {
+			break
+		}
+		it.curInd++
+		if it.curInd >= len(it.sub) {
+			break
+		}
+	}
+
+	return 0
+}
+
+// startParallel wraps branches into prefetching scanners if parallel execution is enabled.
+// Short-circuiting Or only needs the first non-empty branch, thus it's never executed in parallel.
+func (it *orNext) startParallel(ctx context.Context) {
+	it.parallel = Parallelism(ctx)
+	if it.parallel == 1 || it.shortCircuit || len(it.sub) < 2 {
+		it.parallel = 1
+		return
+	}
+	for i, sub := range it.sub {
+		it.sub[i] = newPrefetchNext(sub, true)
+	}
+}
+
+// prefetch starts prefetching the current branch and a few branches after it.
+func (it *orNext) prefetch(ctx context.Context) {
+	if it.parallel <= 1 {
+		return
+	}
+	end := it.curInd + it.parallel
+	if end > len(it.sub) {
+		end = len(it.sub)
+	}
+	for _, sub := range it.sub[it.curInd:end] {
+		sub.(*prefetchNext).start(ctx)
+	}
+}
+
 func (it *orNext) Err() error {
 	return it.err
 }
@@ -0,0 +1,395 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+// Parallel execution of independent iterator branches.
+//
+// It's disabled by default and can be enabled for a query by passing a context created with WithParallelism.
+// In this mode:
+//
+//  - Or runs its branches in separate goroutines, so each of them fetches the next result concurrently;
+//  - And checks candidates from its primary iterator in batches on a pool of workers, and skips ones that won't match;
+//  - Recursive expands each level of the frontier in parallel.
+//
+// Results are always returned in the same order, independent of how goroutines are scheduled. And and Or return
+// results in the same order as in sequential execution, but the order of Recursive results may differ from it.
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/refs"
+)
+
+const (
+	// prefetchBuffer is the number of results buffered for each prefetched branch.
+	prefetchBuffer = 64
+	// parallelBatch is the number of candidates checked by And worker at once.
+	parallelBatch = 32
+)
+
+type parallelKey struct{}
+
+// WithParallelism enables parallel execution of iterators for a given context.
+// The n is the maximal number of branches executed concurrently by a single iterator.
+// Values less than 2 disable parallel execution.
+func WithParallelism(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelKey{}, n)
+}
+
+// Parallelism returns the number of branches that iterators may execute concurrently.
+// It returns 1 if parallel execution is disabled.
+func Parallelism(ctx context.Context) int {
+	n, _ := ctx.Value(parallelKey{}).(int)
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// prefetchResult is a result of the scanner with tags of its current path.
+type prefetchResult struct {
+	id   refs.Ref
+	tags map[string]refs.Ref
+	ok   bool
+}
+
+const (
+	cmdNext = iota
+	cmdNextPath
+)
+
+var _ Scanner = (*prefetchNext)(nil)
+
+// prefetchNext runs a scanner in a separate goroutine.
+//
+// Calling NextPath on a scanner may affect results returned by it later, thus if paths are needed the goroutine
+// only runs ahead to the next result and then waits for the caller to either request the next path or the next result.
+// Without paths, results are buffered and NextPath is never called on the scanner.
+type prefetchNext struct {
+	sub    Scanner
+	paths  bool
+	cmd    chan int
+	res    chan prefetchResult
+	done   chan struct{}
+	cancel func()
+	subErr error // set before res is closed
+
+	cur     prefetchResult
+	fetched bool // goroutine fetches the next result without a command
+	last    bool // goroutine has exited
+	err     error
+}
+
+func newPrefetchNext(sub Scanner, paths bool) *prefetchNext {
+	return &prefetchNext{sub: sub, paths: paths}
+}
+
+// start runs the goroutine, if it's not yet running.
+func (it *prefetchNext) start(ctx context.Context) {
+	if it.res != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	it.cancel = cancel
+	it.fetched = true
+	it.done = make(chan struct{})
+	if it.paths {
+		it.cmd = make(chan int)
+		it.res = make(chan prefetchResult, 1)
+	} else {
+		it.res = make(chan prefetchResult, prefetchBuffer)
+	}
+	go func() {
+		defer close(it.done)
+		defer close(it.res)
+		it.subErr = it.run(ctx)
+	}()
+}
+
+func (it *prefetchNext) result() prefetchResult {
+	r := prefetchResult{id: it.sub.Result(), tags: make(map[string]refs.Ref), ok: true}
+	it.sub.TagResults(r.tags)
+	return r
+}
+
+func (it *prefetchNext) run(ctx context.Context) error {
+	send := func(r prefetchResult) bool {
+		select {
+		case it.res <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	cmd := cmdNext
+	for {
+		switch cmd {
+		case cmdNext:
+			if !it.sub.Next(ctx) {
+				return it.sub.Err()
+			}
+			if !send(it.result()) {
+				return ctx.Err()
+			}
+		case cmdNextPath:
+			r := prefetchResult{}
+			if it.sub.NextPath(ctx) {
+				r = it.result()
+			} else if err := it.sub.Err(); err != nil {
+				return err
+			}
+			if !send(r) {
+				return ctx.Err()
+			}
+		}
+		if !it.paths {
+			continue
+		}
+		select {
+		case cmd = <-it.cmd:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// call sends a command to the goroutine, if necessary, and waits for the reply.
+func (it *prefetchNext) call(ctx context.Context, cmd int, send bool) (prefetchResult, bool) {
+	if it.last {
+		return prefetchResult{}, false
+	}
+	if send {
+		select {
+		case it.cmd <- cmd:
+		case <-it.done:
+		case <-ctx.Done():
+			it.err = ctx.Err()
+			return prefetchResult{}, false
+		}
+	}
+	select {
+	case r, ok := <-it.res:
+		if !ok {
+			it.last = true
+			it.err = it.subErr
+		}
+		return r, ok
+	case <-ctx.Done():
+		it.err = ctx.Err()
+		return prefetchResult{}, false
+	}
+}
+
+func (it *prefetchNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	it.start(ctx)
+	r, ok := it.call(ctx, cmdNext, it.paths && !it.fetched)
+	it.fetched = false
+	it.cur = r
+	return ok
+}
+
+func (it *prefetchNext) Result() refs.Ref {
+	return it.cur.id
+}
+
+func (it *prefetchNext) TagResults(dst map[string]refs.Ref) {
+	for k, v := range it.cur.tags {
+		dst[k] = v
+	}
+}
+
+func (it *prefetchNext) NextPath(ctx context.Context) bool {
+	if !it.paths || it.err != nil || !it.cur.ok {
+		return false
+	}
+	r, ok := it.call(ctx, cmdNextPath, true)
+	if !ok || !r.ok {
+		return false
+	}
+	it.cur = r
+	return true
+}
+
+func (it *prefetchNext) Err() error {
+	return it.err
+}
+
+func (it *prefetchNext) Close() error {
+	if it.res != nil && it.cancel != nil {
+		it.cancel()
+		<-it.done
+		it.cancel = nil
+	}
+	return it.sub.Close()
+}
+
+func (it *prefetchNext) String() string {
+	return "PrefetchNext"
+}
+
+// andCheck is a batch of candidates from the primary iterator of And, and results of checking them.
+type andCheck struct {
+	seq  int
+	ids  []refs.Ref
+	ok   []bool
+	stop bool // no batches will follow
+}
+
+// andPrefilter checks candidates of the primary iterator of And against its other subiterators on a pool of workers.
+//
+// The primary iterator cannot run ahead of And, since And must only call NextPath on it for matching values.
+// Instead, a separate copy of the primary iterator feeds candidates to workers, and And uses their results
+// to skip values that won't match without calling Contains. Values that match are still checked by And itself
+// to position its subiterators, thus the results and their paths are the same as in sequential execution.
+type andPrefilter struct {
+	cancel func()
+	out    chan andCheck
+	done   chan struct{}
+
+	next    int // sequence number of the next batch
+	pending map[int]andCheck
+	cur     andCheck
+	ind     int
+	last    bool
+}
+
+func newAndPrefilter(ctx context.Context, and *And, workers int) *andPrefilter {
+	// iterators used by the prefilter run sequentially, to avoid spawning more goroutines for each nested And
+	ctx, cancel := context.WithCancel(WithParallelism(ctx, 1))
+	it := &andPrefilter{
+		cancel:  cancel,
+		out:     make(chan andCheck, workers),
+		done:    make(chan struct{}),
+		pending: make(map[int]andCheck),
+	}
+	jobs := make(chan andCheck, workers)
+	var wg sync.WaitGroup
+	wg.Add(1 + workers)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		// work of the copy is not accounted, it's bounded by the main primary iterator anyway
+		it.scout(governor.WithGovernor(ctx, nil), and.sub[0].Iterate(), jobs)
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			it.check(ctx, and.lookupSecondary(), jobs)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(it.out)
+		close(it.done)
+	}()
+	return it
+}
+
+func (it *andPrefilter) send(ctx context.Context, ch chan<- andCheck, b andCheck) bool {
+	select {
+	case ch <- b:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// scout reads candidates from a copy of the primary iterator and sends them to workers in batches.
+func (it *andPrefilter) scout(ctx context.Context, sc Scanner, jobs chan<- andCheck) {
+	defer sc.Close()
+	b := andCheck{}
+	for sc.Next(ctx) {
+		b.ids = append(b.ids, sc.Result())
+		if len(b.ids) < parallelBatch {
+			continue
+		}
+		if !it.send(ctx, jobs, b) {
+			return
+		}
+		b = andCheck{seq: b.seq + 1}
+	}
+	b.stop = true
+	it.send(ctx, jobs, b)
+}
+
+// check checks candidates received from jobs against the index.
+func (it *andPrefilter) check(ctx context.Context, sec Index, jobs <-chan andCheck) {
+	defer sec.Close()
+	for b := range jobs {
+		b.ok = make([]bool, 0, len(b.ids))
+		for _, id := range b.ids {
+			ok := sec.Contains(ctx, id)
+			if sec.Err() != nil {
+				break
+			}
+			b.ok = append(b.ok, ok)
+		}
+		if len(b.ok) < len(b.ids) {
+			// return results we have and let And check the rest
+			b.ids, b.stop = b.ids[:len(b.ok)], true
+			it.send(ctx, it.out, b)
+			return
+		}
+		if !it.send(ctx, it.out, b) {
+			return
+		}
+	}
+}
+
+// Check returns the result of checking a given candidate by workers.
+// The known flag is false if the candidate wasn't checked, in which case the prefilter must not be used anymore.
+func (it *andPrefilter) Check(ctx context.Context, id refs.Ref) (ok, known bool, err error) {
+	for it.ind >= len(it.cur.ids) {
+		if it.last {
+			return false, false, nil
+		}
+		if b, ok := it.pending[it.next]; ok {
+			delete(it.pending, it.next)
+			it.next++
+			it.cur, it.ind, it.last = b, 0, b.stop
+			continue
+		}
+		select {
+		case b, ok := <-it.out:
+			if !ok {
+				// workers may stop early because the query was canceled
+				return false, false, ctx.Err()
+			}
+			it.pending[b.seq] = b
+		case <-ctx.Done():
+			return false, false, ctx.Err()
+		}
+	}
+	i := it.ind
+	it.ind++
+	if refs.ToKey(it.cur.ids[i]) != refs.ToKey(id) {
+		// copy of the primary iterator returned a different sequence
+		return false, false, nil
+	}
+	return it.cur.ok[i], true, nil
+}
+
+// Close stops all workers and waits for them to exit.
+func (it *andPrefilter) Close() {
+	it.cancel()
+	for range it.out {
+	}
+	<-it.done
+}
@@ -0,0 +1,169 @@
+package iterator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/graphmock"
+	. "github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+func collectResults(t *testing.T, ctx context.Context, it Scanner) [][]spillResult {
+	var out [][]spillResult
+	for it.Next(ctx) {
+		var group []spillResult
+		for {
+			tags := make(map[string]refs.Ref)
+			it.TagResults(tags)
+			group = append(group, spillResult{ID: it.Result(), Tags: tags})
+			if !it.NextPath(ctx) {
+				break
+			}
+		}
+		out = append(out, group)
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	return out
+}
+
+var parallelTestQs = &graphmock.Store{
+	Data: []quad.Quad{
+		quad.MakeRaw("alice", "follows", "bob", ""),
+		quad.MakeRaw("alice", "parent", "charlie", ""),
+		quad.MakeRaw("bob", "follows", "alice", ""),
+		quad.MakeRaw("bob", "parent", "dani", ""),
+		quad.MakeRaw("bob", "parent", "emily", ""),
+	},
+}
+
+func rawNodes(names ...string) *Fixed {
+	fixed := NewFixed()
+	for _, s := range names {
+		fixed.Add(refs.PreFetched(quad.Raw(s)))
+	}
+	return fixed
+}
+
+var parallelCases = []struct {
+	name  string
+	shape func() Shape
+}{
+	{
+		name: "or",
+		shape: func() Shape {
+			return NewOr(
+				NewSave(newInt64(1, 300, true), "a"),
+				NewSave(newInt64(200, 500, true), "b"),
+				NewSave(newInt64(400, 700, true), "c"),
+			)
+		},
+	},
+	{
+		name: "and",
+		shape: func() Shape {
+			return NewAnd(
+				NewSave(newInt64(1, 1000, true), "a"),
+				NewSave(newInt64(300, 1500, true), "b"),
+			)
+		},
+	},
+	{
+		name: "and paths",
+		shape: func() Shape {
+			return NewAnd(
+				NewOr(
+					NewSave(newInt64(1, 200, true), "a"),
+					NewSave(newInt64(100, 300, true), "b"),
+				),
+				NewOr(
+					NewSave(newInt64(50, 250, true), "c"),
+					NewSave(newInt64(150, 350, true), "d"),
+				),
+			)
+		},
+	},
+	{
+		// LinksTo shares paths of a node between all its quads,
+		// thus And must not read paths of candidates that don't match
+		name: "and shared paths",
+		shape: func() Shape {
+			qs := parallelTestQs
+			return graph.NewHasA(qs, NewAnd(
+				graph.NewLinksTo(qs, NewMaterialize(NewOr(
+					NewSave(rawNodes("alice", "bob"), "a"),
+					NewSave(rawNodes("bob"), "b"),
+				)), quad.Subject),
+				graph.NewLinksTo(qs, rawNodes("parent"), quad.Predicate),
+			), quad.Object)
+		},
+	},
+	{
+		name: "recursive",
+		shape: func() Shape {
+			start := NewFixed(
+				refs.PreFetched(quad.Raw("alice")),
+				refs.PreFetched(quad.Raw("fred")),
+				refs.PreFetched(quad.Raw("greg")),
+			)
+			return NewRecursive(NewSave(start, "start"), singleHop(recTestQs, "parent"), 0)
+		},
+	},
+	{
+		name: "sort",
+		shape: func() Shape {
+			return NewSort(modNamer{}, NewSave(NewOr(
+				newInt64(1, 300, true),
+				newInt64(200, 500, true),
+			), "x"), SortKey{Tag: "x"})
+		},
+	},
+}
+
+func TestParallel(t *testing.T) {
+	for _, c := range parallelCases {
+		t.Run(c.name, func(t *testing.T) {
+			expect := collectResults(t, context.Background(), c.shape().Iterate())
+			require.NotEmpty(t, expect)
+			for _, n := range []int{2, 4, 16} {
+				ctx := WithParallelism(context.Background(), n)
+				got := collectResults(t, ctx, c.shape().Iterate())
+				require.Equal(t, expect, got, "parallelism: %d", n)
+			}
+		})
+	}
+}
+
+func TestParallelCancel(t *testing.T) {
+	shapes := []Shape{
+		NewOr(newInt64(1, 1000000, true), newInt64(1, 1000000, true)),
+		NewAnd(newInt64(1, 1000000, true), newInt64(1, 1000000, true)),
+	}
+	for _, s := range shapes {
+		ctx, cancel := context.WithCancel(WithParallelism(context.Background(), 4))
+		it := s.Iterate()
+		require.True(t, it.Next(ctx), "%v", s)
+		cancel()
+		n := 0
+		for it.Next(ctx) {
+			n++
+		}
+		require.True(t, n < 1000000, "%v", s)
+		require.Equal(t, context.Canceled, it.Err(), "%v", s)
+		require.NoError(t, it.Close())
+	}
+}
+
+func TestParallelClose(t *testing.T) {
+	ctx := WithParallelism(context.Background(), 4)
+	for _, c := range parallelCases {
+		it := c.shape().Iterate()
+		require.True(t, it.Next(ctx), c.name)
+		require.NoError(t, it.Close(), c.name)
+	}
+}
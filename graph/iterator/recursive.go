@@ -4,6 +4,7 @@ import (
 	"context"
 	"math"
 
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/refs"
 	"github.com/cayleygraph/quad"
 )
@@ -61,16 +62,29 @@ func (it *Recursive) Optimize(ctx context.Context) (Shape, bool) {
 	return it, false
 }
 
+// Stats estimates the size of the recursive expansion by applying the morphism to a
+// single-node probe and summing the expected fanout for each step up to the max depth.
 func (it *Recursive) Stats(ctx context.Context) (Costs, error) {
-	base := NewFixed()
-	base.Add(Int64Node(20))
-	fanoutit := it.morphism(base)
+	fanoutit := it.morphism(recursiveProbe{})
 	fanoutStats, err := fanoutit.Stats(ctx)
 	subitStats, err2 := it.subIt.Stats(ctx)
 	if err == nil {
 		err = err2
 	}
-	size := int64(math.Pow(float64(subitStats.Size.Value*fanoutStats.Size.Value), 5))
+	// sub + sub*f + sub*f^2 + ... + sub*f^depth
+	var (
+		fanout = float64(fanoutStats.Size.Value)
+		sum    = 0.0
+		step   = float64(subitStats.Size.Value)
+	)
+	for i := 0; i <= it.maxDepth && step >= 1 && sum < math.MaxInt64/2; i++ {
+		sum += step
+		step *= fanout
+	}
+	size := int64(math.MaxInt64 / 2)
+	if sum < float64(size) {
+		size = int64(sum)
+	}
 	return Costs{
 		NextCost:     subitStats.NextCost + fanoutStats.NextCost,
 		ContainsCost: (subitStats.NextCost+fanoutStats.NextCost)*(size/10) + subitStats.ContainsCost,
@@ -81,6 +95,32 @@ func (it *Recursive) Stats(ctx context.Context) (Costs, error) {
 	}, err
 }
 
+var _ Shape = recursiveProbe{}
+
+// recursiveProbe is a placeholder for a single node that is used to estimate the fanout of a morphism.
+type recursiveProbe struct{}
+
+func (recursiveProbe) Iterate() Scanner { return NewNull().Iterate() }
+
+func (recursiveProbe) Lookup() Index { return NewNull().Lookup() }
+
+func (recursiveProbe) Stats(ctx context.Context) (Costs, error) {
+	return Costs{
+		NextCost:     1,
+		ContainsCost: 1,
+		Size:         refs.Size{Value: 1, Exact: false},
+	}, nil
+}
+
+func (recursiveProbe) Optimize(ctx context.Context) (Shape, bool) {
+	// never replace it, morphism must see the probe as is
+	return recursiveProbe{}, false
+}
+
+func (recursiveProbe) SubIterators() []Shape { return nil }
+
+func (recursiveProbe) String() string { return "RecursiveProbe" }
+
 func (it *Recursive) String() string {
 	return "Recursive"
 }
@@ -89,6 +129,7 @@ func (it *Recursive) String() string {
 type recursiveNext struct {
 	subIt  Scanner
 	result seenAt
+	gov    governor.Lazy
 	err    error
 
 	morphism      Morphism
@@ -138,9 +179,16 @@ func (it *recursiveNext) TagResults(dst map[string]refs.Ref) {
 }
 
 func (it *recursiveNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	gov := it.gov.From(ctx)
 	it.pathIndex = 0
 	if it.depth == 0 {
 		for it.subIt.Next(ctx) {
+			if it.err = gov.Materialize(1); it.err != nil {
+				return false
+			}
 			res := it.subIt.Result()
 			it.depthCache = append(it.depthCache, it.subIt.Result())
 			tags := make(map[string]refs.Ref)
@@ -156,7 +204,13 @@ func (it *recursiveNext) Next(ctx context.Context) bool {
 	}
 
 	for {
+		if it.err = gov.Step(1); it.err != nil {
+			return false
+		}
 		if !it.nextIt.Next(ctx) {
+			if it.err = it.nextIt.Err(); it.err != nil {
+				return false
+			}
 			if it.maxDepth > 0 && it.depth >= it.maxDepth {
 				return false
 			} else if len(it.depthCache) == 0 {
@@ -168,7 +222,7 @@ func (it *recursiveNext) Next(ctx context.Context) bool {
 			if it.nextIt != nil {
 				it.nextIt.Close()
 			}
-			it.nextIt = it.morphism(Tag(it.baseIt, recursiveBaseTag)).Iterate()
+			it.nextIt = it.expand(ctx)
 			continue
 		}
 		val := it.nextIt.Result()
@@ -176,6 +230,9 @@ func (it *recursiveNext) Next(ctx context.Context) bool {
 		it.nextIt.TagResults(results)
 		key := refs.ToKey(val)
 		if _, seen := it.seen[key]; !seen {
+			if it.err = gov.Materialize(1); it.err != nil {
+				return false
+			}
 			base := results[recursiveBaseTag]
 			delete(results, recursiveBaseTag)
 			it.seen[key] = seenAt{
@@ -192,6 +249,34 @@ func (it *recursiveNext) Next(ctx context.Context) bool {
 	}
 }
 
+// expand applies the morphism to the current frontier.
+// In parallel mode the frontier is split into chunks that are expanded concurrently.
+func (it *recursiveNext) expand(ctx context.Context) Scanner {
+	n := Parallelism(ctx)
+	vals := it.baseIt.Values()
+	if n <= 1 || len(vals) < 2 {
+		return it.morphism(Tag(it.baseIt, recursiveBaseTag)).Iterate()
+	}
+	if n > len(vals) {
+		n = len(vals)
+	}
+	size := (len(vals) + n - 1) / n
+	var subs []Scanner
+	for len(vals) > 0 {
+		if size > len(vals) {
+			size = len(vals)
+		}
+		// NextPath is never called on the expansion, so chunks can run ahead freely
+		sub := newPrefetchNext(it.morphism(Tag(NewFixed(vals[:size]...), recursiveBaseTag)).Iterate(), false)
+		sub.start(ctx)
+		subs = append(subs, sub)
+		vals = vals[size:]
+	}
+	or := newOrNext(subs, false)
+	or.parallel = 1 // branches are already running
+	return or
+}
+
 func (it *recursiveNext) Err() error {
 	return it.err
 }
@@ -89,6 +89,17 @@ func (it *skipNext) Next(ctx context.Context) bool {
 	return false
 }
 
+// NextBatch advances the Skip iterator by multiple values. It will skip all initial values
+// before returning actual results.
+func (it *skipNext) NextBatch(ctx context.Context, dst []refs.Ref) int {
+	for ; it.skipped < it.skip; it.skipped++ {
+		if !it.primaryIt.Next(ctx) {
+			return 0
+		}
+	}
+	return NextBatch(ctx, it.primaryIt, dst)
+}
+
 func (it *skipNext) Err() error {
 	return it.primaryIt.Err()
 }
@@ -3,25 +3,49 @@ package iterator
 import (
 	"context"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
 )
 
+// SortKey describes a single key to order results by.
+type SortKey struct {
+	// Tag is a tag to sort by. If empty, results are sorted by the node itself.
+	Tag string
+	// Desc reverses the order of values for this key.
+	Desc bool
+	// Hidden removes the tag from results after sorting. It's used for tags that are
+	// only added to the query to be able to sort by them.
+	Hidden bool
+}
+
 // Sort iterator orders values from it's subiterator.
+//
+// Results are ordered by each key in turn, comparing native types of values.
+// Results without a value for the key are placed last. The order is stable.
 type Sort struct {
 	namer refs.Namer
 	subIt Shape
+	keys  []SortKey
 }
 
-// NewSort creates a new Sort iterator.
-// TODO(dennwc): This iterator must not be used inside And: it may be moved to a Contains branch and won't do anything.
-//               We should make And/Intersect account for this.
-func NewSort(namer refs.Namer, subIt Shape) *Sort {
-	return &Sort{namer, subIt}
+// NewSort creates a new Sort iterator. If no keys are given, results are sorted by node values in ascending order.
+//
+// This iterator must be the primary one (not a Contains branch) in And, otherwise it won't do anything.
+// And optimizer accounts for this automatically.
+func NewSort(namer refs.Namer, subIt Shape, keys ...SortKey) *Sort {
+	if len(keys) == 0 {
+		keys = []SortKey{{}}
+	}
+	return &Sort{namer: namer, subIt: subIt, keys: keys}
 }
 
 func (it *Sort) Iterate() Scanner {
-	return newSortNext(it.namer, it.subIt.Iterate())
+	return newSortNext(it.namer, it.subIt.Iterate(), it.keys)
 }
 
 func (it *Sort) Lookup() Index {
@@ -53,7 +77,21 @@ func (it *Sort) Stats(ctx context.Context) (Costs, error) {
 }
 
 func (it *Sort) String() string {
-	return "Sort"
+	if len(it.keys) == 1 && it.keys[0] == (SortKey{}) {
+		return "Sort"
+	}
+	var keys []string
+	for _, k := range it.keys {
+		s := k.Tag
+		if s == "" {
+			s = "<node>"
+		}
+		if k.Desc {
+			s += " desc"
+		}
+		keys = append(keys, s)
+	}
+	return "Sort(" + strings.Join(keys, ", ") + ")"
 }
 
 // SubIterators returns a slice of the sub iterators.
@@ -63,31 +101,71 @@ func (it *Sort) SubIterators() []Shape {
 
 type sortValue struct {
 	result
-	str   string
+	keys  []quad.Value
 	paths []result
 }
-type sortByString []sortValue
 
-func (v sortByString) Len() int { return len(v) }
-func (v sortByString) Less(i, j int) bool {
-	return v[i].str < v[j].str
+// size returns an approximate size of the value in memory.
+func (v sortValue) size() int64 {
+	n := resultSize(v.result) + 16*int64(len(v.keys))
+	for _, p := range v.paths {
+		n += resultSize(p)
+	}
+	return n
+}
+
+type sortByKeys struct {
+	keys []SortKey
+	vals []sortValue
+}
+
+func (v sortByKeys) Len() int { return len(v.vals) }
+func (v sortByKeys) Less(i, j int) bool {
+	return lessKeys(v.keys, v.vals[i].keys, v.vals[j].keys)
+}
+func (v sortByKeys) Swap(i, j int) { v.vals[i], v.vals[j] = v.vals[j], v.vals[i] }
+
+// lessKeys compares values of sort keys of two results.
+func lessKeys(keys []SortKey, a, b []quad.Value) bool {
+	for k, key := range keys {
+		if a[k] == nil || b[k] == nil {
+			if (a[k] == nil) != (b[k] == nil) {
+				// missing values are always last
+				return b[k] == nil
+			}
+			continue
+		}
+		c := CompareValues(a[k], b[k])
+		if c == 0 {
+			continue
+		}
+		if key.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
 }
-func (v sortByString) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
 
 type sortNext struct {
 	namer     refs.Namer
 	subIt     Scanner
-	ordered   sortByString
+	keys      []SortKey
+	hasRun    bool
+	ordered   []sortValue
+	merge     *sortMerge // set if results were spilled to disk
+	cur       *sortValue
 	result    result
 	err       error
 	index     int
 	pathIndex int
 }
 
-func newSortNext(namer refs.Namer, subIt Scanner) *sortNext {
+func newSortNext(namer refs.Namer, subIt Scanner, keys []SortKey) *sortNext {
 	return &sortNext{
 		namer:     namer,
 		subIt:     subIt,
+		keys:      keys,
 		pathIndex: -1,
 	}
 }
@@ -110,68 +188,320 @@ func (it *sortNext) Next(ctx context.Context) bool {
 	if it.err != nil {
 		return false
 	}
-	if it.ordered == nil {
-		v, err := getSortedValues(ctx, it.namer, it.subIt)
-		it.ordered = v
-		it.err = err
+	if !it.hasRun {
+		it.hasRun = true
+		it.ordered, it.merge, it.err = getSortedValues(ctx, it.namer, it.subIt, it.keys)
 		if it.err != nil {
 			return false
 		}
 	}
-	if it.index >= len(it.ordered) {
-		return false
+	it.cur = nil
+	if it.merge != nil {
+		v, ok := it.merge.Next()
+		if !ok {
+			it.err = it.merge.Err()
+			return false
+		}
+		it.cur = &v
+	} else {
+		if it.index >= len(it.ordered) {
+			return false
+		}
+		it.cur = &it.ordered[it.index]
+		it.index++
 	}
 	it.pathIndex = -1
-	it.result = it.ordered[it.index].result
-	it.index++
+	it.result = it.cur.result
 	return true
 }
 
 func (it *sortNext) NextPath(ctx context.Context) bool {
-	if it.index >= len(it.ordered) {
+	if it.cur == nil {
 		return false
 	}
-	r := it.ordered[it.index]
-	if it.pathIndex+1 >= len(r.paths) {
+	if it.pathIndex+1 >= len(it.cur.paths) {
 		return false
 	}
 	it.pathIndex++
-	it.result = r.paths[it.pathIndex]
+	it.result = it.cur.paths[it.pathIndex]
 	return true
 }
 
 func (it *sortNext) Close() error {
 	it.ordered = nil
-	return it.subIt.Close()
+	it.cur = nil
+	var err error
+	if it.merge != nil {
+		err = it.merge.Close()
+		it.merge = nil
+	}
+	if err2 := it.subIt.Close(); err2 != nil && err == nil {
+		err = err2
+	}
+	return err
 }
 
 func (it *sortNext) String() string {
 	return "SortNext"
 }
 
-func getSortedValues(ctx context.Context, namer refs.Namer, it Scanner) (sortByString, error) {
-	var v sortByString
-	for it.Next(ctx) {
-		id := it.Result()
-		// TODO(dennwc): batch and use refs.ValuesOf
-		name := namer.NameOf(id)
-		str := name.String()
-		tags := make(map[string]refs.Ref)
+// sortKeyValue returns a value of the sort key for a given result.
+func sortKeyValue(namer refs.Namer, key SortKey, r result) quad.Value {
+	if key.Tag == "" {
+		return namer.NameOf(r.id)
+	}
+	ref, ok := r.tags[key.Tag]
+	if !ok || ref == nil {
+		return nil
+	}
+	return namer.NameOf(ref)
+}
+
+// hideTags removes hidden sort tags from the result.
+func hideTags(keys []SortKey, tags map[string]refs.Ref) {
+	for _, k := range keys {
+		if k.Hidden {
+			delete(tags, k.Tag)
+		}
+	}
+}
+
+func sameTags(a, b map[string]refs.Ref) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		v2, ok := b[k]
+		if !ok || refs.ToKey(v) != refs.ToKey(v2) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextSortValue reads the current result of the iterator with all its paths and computes values of sort keys.
+func nextSortValue(ctx context.Context, namer refs.Namer, it Scanner, keys []SortKey) sortValue {
+	id := it.Result()
+	tags := make(map[string]refs.Ref)
+	it.TagResults(tags)
+	// TODO(dennwc): batch and use refs.ValuesOf
+	val := sortValue{
+		result: result{id, tags},
+		keys:   make([]quad.Value, len(keys)),
+	}
+	for i, k := range keys {
+		val.keys[i] = sortKeyValue(namer, k, val.result)
+	}
+	for it.NextPath(ctx) {
+		tags = make(map[string]refs.Ref)
 		it.TagResults(tags)
-		val := sortValue{
-			result: result{id, tags},
-			str:    str,
+		r := result{id, tags}
+		// a node may have multiple values for a key on different paths;
+		// use the one that is sorted first
+		for i, k := range keys {
+			kv := sortKeyValue(namer, k, r)
+			if kv == nil {
+				continue
+			}
+			if cur := val.keys[i]; cur == nil {
+				val.keys[i] = kv
+			} else if c := CompareValues(kv, cur); (c < 0 && !k.Desc) || (c > 0 && k.Desc) {
+				val.keys[i] = kv
+			}
 		}
-		for it.NextPath(ctx) {
-			tags = make(map[string]refs.Ref)
-			it.TagResults(tags)
-			val.paths = append(val.paths, result{id, tags})
+		val.paths = append(val.paths, r)
+	}
+	hideTags(keys, val.tags)
+	paths := val.paths[:0]
+nextPath:
+	for _, p := range val.paths {
+		hideTags(keys, p.tags)
+		// paths may become the same after removing hidden tags
+		if sameTags(p.tags, val.tags) {
+			continue
+		}
+		for _, p2 := range paths {
+			if sameTags(p.tags, p2.tags) {
+				continue nextPath
+			}
+		}
+		paths = append(paths, p)
+	}
+	val.paths = paths
+	return val
+}
+
+// getSortedValues reads all results of the iterator and sorts them.
+//
+// Results are kept in memory until SpillMemoryLimit is reached. After this, sorted runs of results
+// are written to disk, and the merge of runs is returned instead.
+func getSortedValues(ctx context.Context, namer refs.Namer, it Scanner, keys []SortKey) ([]sortValue, *sortMerge, error) {
+	var (
+		v       []sortValue
+		size    int64
+		spill   *spillFile
+		runs    []spillRun
+		noSpill = SpillMemoryLimit <= 0
+	)
+	closeSpill := func() {
+		if spill != nil {
+			_ = spill.Close()
+		}
+	}
+	gov := governor.FromContext(ctx)
+	for it.Next(ctx) {
+		val := nextSortValue(ctx, namer, it, keys)
+		if err := gov.Materialize(int64(1 + len(val.paths))); err != nil {
+			closeSpill()
+			return nil, nil, err
 		}
 		v = append(v, val)
+		size += val.size()
+		if noSpill || size <= SpillMemoryLimit {
+			continue
+		}
+		sort.Stable(sortByKeys{keys: keys, vals: v})
+		if spill == nil {
+			var err error
+			spill, err = newSpillFile()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		run, n, err := writeSortRun(spill, v)
+		if n != 0 {
+			runs = append(runs, run)
+		}
+		if _, ok := err.(errCannotSpill); ok {
+			// keep the rest in memory, but merge it with runs that are already on disk
+			clog.Warningf("sort: %v; keeping results in memory", err)
+			noSpill = true
+		} else if err != nil {
+			closeSpill()
+			return nil, nil, err
+		}
+		v = append(v[:0:0], v[n:]...)
+		size = 0
+		for _, val := range v {
+			size += val.size()
+		}
 	}
 	if err := it.Err(); err != nil {
-		return v, err
+		closeSpill()
+		return nil, nil, err
+	}
+	sort.Stable(sortByKeys{keys: keys, vals: v})
+	if len(runs) == 0 {
+		closeSpill()
+		return v, nil, nil
+	}
+	m, err := newSortMerge(keys, spill, runs, v)
+	if err != nil {
+		closeSpill()
+		return nil, nil, err
+	}
+	return nil, m, nil
+}
+
+// valueKind returns a rank of the value type, used to order values of different types.
+func valueKind(v quad.Value) int {
+	switch v.(type) {
+	case quad.Int, quad.Float:
+		return 0
+	case quad.Time:
+		return 1
+	case quad.Bool:
+		return 2
+	case quad.IRI:
+		return 3
+	case quad.BNode:
+		return 4
+	case quad.String, quad.TypedString, quad.LangString:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// CompareValuesNullsLast compares values in the same way as CompareValues, but orders nil values after all other values.
+func CompareValuesNullsLast(a, b quad.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return CompareValues(a, b)
+}
+
+// CompareValues compares two values using their native types.
+// Values of different types are ordered by type: numbers, times, booleans, IRIs, blank nodes, strings and other values.
+// It returns -1 if a < b, 1 if a > b and 0 if values are equal.
+func CompareValues(a, b quad.Value) int {
+	if ka, kb := valueKind(a), valueKind(b); ka != kb {
+		if ka < kb {
+			return -1
+		}
+		return 1
+	}
+	switch a := a.(type) {
+	case quad.Int:
+		switch b := b.(type) {
+		case quad.Int:
+			return compareInt(int64(a), int64(b))
+		case quad.Float:
+			return compareFloat(float64(a), float64(b))
+		}
+	case quad.Float:
+		switch b := b.(type) {
+		case quad.Int:
+			return compareFloat(float64(a), float64(b))
+		case quad.Float:
+			return compareFloat(float64(a), float64(b))
+		}
+	case quad.Time:
+		ta, tb := time.Time(a), time.Time(b.(quad.Time))
+		if ta.Before(tb) {
+			return -1
+		} else if ta.After(tb) {
+			return 1
+		}
+		return 0
+	case quad.Bool:
+		if a == b.(quad.Bool) {
+			return 0
+		} else if !a {
+			return -1
+		}
+		return 1
+	case quad.IRI:
+		return strings.Compare(string(a), string(b.(quad.IRI)))
+	case quad.BNode:
+		return strings.Compare(string(a), string(b.(quad.BNode)))
+	case quad.String, quad.TypedString, quad.LangString:
+		if c := strings.Compare(quad.StringOf(a), quad.StringOf(b)); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
 	}
-	sort.Sort(v)
-	return v, nil
+	return 0
 }
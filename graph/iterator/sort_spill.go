@@ -0,0 +1,238 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"io"
+
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/pquads"
+)
+
+// spillRun is a range of the spill file with sorted results.
+type spillRun struct {
+	start, end int64
+}
+
+// writeSortRun writes sorted values to the spill file as a single run.
+// It returns the number of values written. If an error occurs, values before it are still part of the run.
+func writeSortRun(f *spillFile, vals []sortValue) (spillRun, int, error) {
+	run := spillRun{start: f.off, end: f.off}
+	var buf []byte
+	for i, v := range vals {
+		var err error
+		buf, err = appendSortValue(buf[:0], v)
+		if err != nil {
+			return run, i, err
+		}
+		if _, err = f.Write(buf); err != nil {
+			return run, i, err
+		}
+		run.end = f.off
+	}
+	return run, len(vals), nil
+}
+
+func appendSortValue(buf []byte, v sortValue) ([]byte, error) {
+	buf, err := appendRef(buf, v.id)
+	if err != nil {
+		return nil, err
+	}
+	buf = appendUvarint(buf, uint64(len(v.keys)))
+	for _, k := range v.keys {
+		var p []byte
+		if k != nil {
+			p, err = marshalValue(k)
+			if err != nil {
+				return nil, err
+			}
+		}
+		buf = appendBytes(buf, p)
+	}
+	buf, err = appendTags(buf, v.tags)
+	if err != nil {
+		return nil, err
+	}
+	buf = appendUvarint(buf, uint64(len(v.paths)))
+	for _, p := range v.paths {
+		buf, err = appendTags(buf, p.tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func readSortValue(buf []byte) (sortValue, error) {
+	var v sortValue
+	id, buf, err := readRef(buf)
+	if err != nil {
+		return v, err
+	}
+	v.id = id
+	n, i := binary.Uvarint(buf)
+	if i <= 0 {
+		return v, io.ErrUnexpectedEOF
+	}
+	buf = buf[i:]
+	v.keys = make([]quad.Value, n)
+	for j := range v.keys {
+		var p []byte
+		p, buf, err = readBytes(buf)
+		if err != nil {
+			return v, err
+		}
+		if len(p) == 0 {
+			continue
+		}
+		v.keys[j], err = pquads.UnmarshalValue(p)
+		if err != nil {
+			return v, err
+		}
+	}
+	v.tags, buf, err = readTags(buf)
+	if err != nil {
+		return v, err
+	}
+	n, i = binary.Uvarint(buf)
+	if i <= 0 {
+		return v, io.ErrUnexpectedEOF
+	}
+	buf = buf[i:]
+	if n != 0 {
+		v.paths = make([]result, n)
+	}
+	for j := range v.paths {
+		var tags map[string]refs.Ref
+		tags, buf, err = readTags(buf)
+		if err != nil {
+			return v, err
+		}
+		v.paths[j] = result{id: v.id, tags: tags}
+	}
+	return v, nil
+}
+
+// sortSource is a single sorted sequence of values for the merge.
+type sortSource struct {
+	index int          // index of the source, used to keep the sort stable
+	run   *spillReader // nil for values kept in memory
+	mem   []sortValue
+	cur   sortValue
+}
+
+func (s *sortSource) next() (bool, error) {
+	if s.run == nil {
+		if len(s.mem) == 0 {
+			return false, nil
+		}
+		s.cur = s.mem[0]
+		s.mem = s.mem[1:]
+		return true, nil
+	}
+	rec, err := s.run.Next()
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	s.cur, err = readSortValue(rec)
+	return err == nil, err
+}
+
+// sortMerge merges sorted runs from the spill file and values kept in memory.
+type sortMerge struct {
+	keys []SortKey
+	file *spillFile
+	srcs []*sortSource
+	err  error
+}
+
+func newSortMerge(keys []SortKey, f *spillFile, runs []spillRun, mem []sortValue) (*sortMerge, error) {
+	if err := f.Flush(); err != nil {
+		return nil, err
+	}
+	m := &sortMerge{keys: keys, file: f}
+	for i, r := range runs {
+		m.srcs = append(m.srcs, &sortSource{index: i, run: newSpillReader(f, r.start, r.end)})
+	}
+	// values in memory were read last
+	m.srcs = append(m.srcs, &sortSource{index: len(runs), mem: mem})
+	all := m.srcs
+	m.srcs = m.srcs[:0]
+	for _, s := range all {
+		ok, err := s.next()
+		if err != nil {
+			return nil, err
+		} else if ok {
+			m.srcs = append(m.srcs, s)
+		}
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+func (m *sortMerge) Len() int { return len(m.srcs) }
+func (m *sortMerge) Less(i, j int) bool {
+	a, b := m.srcs[i], m.srcs[j]
+	if lessKeys(m.keys, a.cur.keys, b.cur.keys) {
+		return true
+	} else if lessKeys(m.keys, b.cur.keys, a.cur.keys) {
+		return false
+	}
+	return a.index < b.index
+}
+func (m *sortMerge) Swap(i, j int) { m.srcs[i], m.srcs[j] = m.srcs[j], m.srcs[i] }
+func (m *sortMerge) Push(x interface{}) {
+	m.srcs = append(m.srcs, x.(*sortSource))
+}
+func (m *sortMerge) Pop() interface{} {
+	n := len(m.srcs) - 1
+	s := m.srcs[n]
+	m.srcs = m.srcs[:n]
+	return s
+}
+
+// Next returns the next value in sorted order.
+func (m *sortMerge) Next() (sortValue, bool) {
+	if m.err != nil || len(m.srcs) == 0 {
+		return sortValue{}, false
+	}
+	s := m.srcs[0]
+	v := s.cur
+	ok, err := s.next()
+	if err != nil {
+		m.err = err
+		return sortValue{}, false
+	} else if ok {
+		heap.Fix(m, 0)
+	} else {
+		heap.Pop(m)
+	}
+	return v, true
+}
+
+func (m *sortMerge) Err() error {
+	return m.err
+}
+
+// Close removes the spill file.
+func (m *sortMerge) Close() error {
+	m.srcs = nil
+	return m.file.Close()
+}
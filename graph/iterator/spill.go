@@ -0,0 +1,333 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+// Sort and Materialize need to keep all results of a subiterator. To prevent running out of memory,
+// both spill results to a temporary file once the memory budget is exceeded.
+//
+// Results are stored as a sequence of records, each prefixed with its length as uvarint.
+// Refs are encoded with a codec registered for their type, and values are encoded in pquads format.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/pquads"
+)
+
+var (
+	// SpillDir is a directory for temporary files created by Sort and Materialize.
+	// If empty, the default directory for temporary files is used.
+	SpillDir string
+	// SpillMemoryLimit is an approximate memory budget (in bytes) for results kept in memory by
+	// a single Sort or Materialize iterator. Once exceeded, results are spilled to a temporary file.
+	// Zero or negative value disables spilling.
+	SpillMemoryLimit int64 = 64 << 20
+)
+
+// errCannotSpill is returned when results contain refs or values that cannot be written to disk.
+type errCannotSpill struct {
+	Reason string
+}
+
+func (e errCannotSpill) Error() string {
+	return "cannot spill results to disk: " + e.Reason
+}
+
+type refCodec struct {
+	marshal   func(refs.Ref) ([]byte, error)
+	unmarshal func([]byte) (refs.Ref, error)
+}
+
+var refCodecs struct {
+	sync.RWMutex
+	list   []refCodec
+	byType map[reflect.Type]int
+}
+
+// RegisterRefType registers functions to encode and decode refs of the same type as ref.
+// Only results with registered ref types can be spilled to disk by Sort and Materialize.
+func RegisterRefType(ref refs.Ref, marshal func(refs.Ref) ([]byte, error), unmarshal func([]byte) (refs.Ref, error)) {
+	t := reflect.TypeOf(ref)
+	refCodecs.Lock()
+	defer refCodecs.Unlock()
+	if refCodecs.byType == nil {
+		refCodecs.byType = make(map[reflect.Type]int)
+	}
+	if _, ok := refCodecs.byType[t]; ok {
+		panic(fmt.Errorf("ref type %v is already registered", t))
+	}
+	refCodecs.list = append(refCodecs.list, refCodec{marshal: marshal, unmarshal: unmarshal})
+	// zero is reserved for nil refs
+	refCodecs.byType[t] = len(refCodecs.list)
+}
+
+func init() {
+	RegisterRefType(refs.ValueHash{}, func(r refs.Ref) ([]byte, error) {
+		h := r.(refs.ValueHash)
+		return h[:], nil
+	}, func(p []byte) (refs.Ref, error) {
+		var h refs.ValueHash
+		if len(p) != len(h) {
+			return nil, fmt.Errorf("unexpected hash size: %d", len(p))
+		}
+		copy(h[:], p)
+		return h, nil
+	})
+	RegisterRefType(refs.QuadHash{}, func(r refs.Ref) ([]byte, error) {
+		q := r.(refs.QuadHash)
+		p := make([]byte, 0, 4*quad.HashSize)
+		for _, h := range q.Dirs() {
+			p = append(p, h[:]...)
+		}
+		return p, nil
+	}, func(p []byte) (refs.Ref, error) {
+		var q refs.QuadHash
+		if len(p) != 4*quad.HashSize {
+			return nil, fmt.Errorf("unexpected quad hash size: %d", len(p))
+		}
+		for i, d := range quad.Directions {
+			var h refs.ValueHash
+			copy(h[:], p[i*quad.HashSize:])
+			q.Set(d, h)
+		}
+		return q, nil
+	})
+	RegisterRefType(refs.PreFetched(nil), func(r refs.Ref) ([]byte, error) {
+		return marshalValue(r.(refs.PreFetchedValue).NameOf())
+	}, func(p []byte) (refs.Ref, error) {
+		v, err := pquads.UnmarshalValue(p)
+		if err != nil {
+			return nil, err
+		}
+		return refs.PreFetched(v), nil
+	})
+	RegisterRefType(Int64Node(0), func(r refs.Ref) ([]byte, error) {
+		p := make([]byte, binary.MaxVarintLen64)
+		return p[:binary.PutVarint(p, int64(r.(Int64Node)))], nil
+	}, func(p []byte) (refs.Ref, error) {
+		v, n := binary.Varint(p)
+		if n <= 0 {
+			return nil, errors.New("invalid int64 ref")
+		}
+		return Int64Node(v), nil
+	})
+}
+
+// marshalValue encodes a value in pquads format, returning an error for unsupported value types.
+func marshalValue(v quad.Value) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errCannotSpill{Reason: fmt.Sprint(r)}
+		}
+	}()
+	return pquads.MarshalValue(v)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var p [binary.MaxVarintLen64]byte
+	return append(buf, p[:binary.PutUvarint(p[:], v)]...)
+}
+
+func appendBytes(buf, p []byte) []byte {
+	buf = appendUvarint(buf, uint64(len(p)))
+	return append(buf, p...)
+}
+
+func readBytes(buf []byte) ([]byte, []byte, error) {
+	n, i := binary.Uvarint(buf)
+	if i <= 0 || uint64(len(buf)-i) < n {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	buf = buf[i:]
+	return buf[:n], buf[n:], nil
+}
+
+func appendRef(buf []byte, r refs.Ref) ([]byte, error) {
+	if r == nil {
+		return appendUvarint(buf, 0), nil
+	}
+	refCodecs.RLock()
+	i, ok := refCodecs.byType[reflect.TypeOf(r)]
+	var c refCodec
+	if ok {
+		c = refCodecs.list[i-1]
+	}
+	refCodecs.RUnlock()
+	if !ok {
+		return nil, errCannotSpill{Reason: fmt.Sprintf("unregistered ref type %T", r)}
+	}
+	p, err := c.marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	buf = appendUvarint(buf, uint64(i))
+	return appendBytes(buf, p), nil
+}
+
+func readRef(buf []byte) (refs.Ref, []byte, error) {
+	i, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	buf = buf[n:]
+	if i == 0 {
+		return nil, buf, nil
+	}
+	refCodecs.RLock()
+	var c refCodec
+	if int(i) <= len(refCodecs.list) {
+		c = refCodecs.list[i-1]
+	}
+	refCodecs.RUnlock()
+	if c.unmarshal == nil {
+		return nil, nil, fmt.Errorf("unknown ref type: %d", i)
+	}
+	p, buf, err := readBytes(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := c.unmarshal(p)
+	return r, buf, err
+}
+
+func appendTags(buf []byte, tags map[string]refs.Ref) ([]byte, error) {
+	buf = appendUvarint(buf, uint64(len(tags)))
+	var err error
+	for k, v := range tags {
+		buf = appendBytes(buf, []byte(k))
+		buf, err = appendRef(buf, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func readTags(buf []byte) (map[string]refs.Ref, []byte, error) {
+	n, i := binary.Uvarint(buf)
+	if i <= 0 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	buf = buf[i:]
+	tags := make(map[string]refs.Ref, n)
+	for j := uint64(0); j < n; j++ {
+		k, rest, err := readBytes(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		v, rest, err := readRef(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		tags[string(k)] = v
+		buf = rest
+	}
+	return tags, buf, nil
+}
+
+// resultSize returns an approximate size of the result in memory.
+func resultSize(r result) int64 {
+	n := int64(64)
+	for k := range r.tags {
+		n += int64(len(k)) + 64
+	}
+	return n
+}
+
+// spillFile is a temporary file with a sequence of records.
+type spillFile struct {
+	f   *os.File
+	w   *bufio.Writer
+	off int64
+	buf []byte
+}
+
+func newSpillFile() (*spillFile, error) {
+	f, err := ioutil.TempFile(SpillDir, "cayley-spill-")
+	if err != nil {
+		return nil, err
+	}
+	if clog.V(2) {
+		clog.Infof("spilling results to %s", f.Name())
+	}
+	return &spillFile{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Write appends a record to the file and returns its offset.
+func (s *spillFile) Write(rec []byte) (int64, error) {
+	off := s.off
+	s.buf = appendUvarint(s.buf[:0], uint64(len(rec)))
+	if _, err := s.w.Write(s.buf); err != nil {
+		return 0, err
+	}
+	if _, err := s.w.Write(rec); err != nil {
+		return 0, err
+	}
+	s.off += int64(len(s.buf) + len(rec))
+	return off, nil
+}
+
+// Flush must be called after writing records, before reading them.
+func (s *spillFile) Flush() error {
+	return s.w.Flush()
+}
+
+// ReadAt reads a single record at a given offset.
+func (s *spillFile) ReadAt(off int64) ([]byte, error) {
+	r := newSpillReader(s, off, s.off)
+	return r.Next()
+}
+
+// Close closes and removes the file.
+func (s *spillFile) Close() error {
+	err := s.f.Close()
+	if err2 := os.Remove(s.f.Name()); err2 != nil && err == nil {
+		err = err2
+	}
+	return err
+}
+
+// spillReader reads records sequentially from a range of the file.
+type spillReader struct {
+	r *bufio.Reader
+}
+
+func newSpillReader(s *spillFile, start, end int64) *spillReader {
+	return &spillReader{r: bufio.NewReader(io.NewSectionReader(s.f, start, end-start))}
+}
+
+// Next reads the next record. It returns io.EOF if there are no records left.
+func (r *spillReader) Next() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	rec := make([]byte, n)
+	if _, err = io.ReadFull(r.r, rec); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return rec, err
+}
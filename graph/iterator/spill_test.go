@@ -0,0 +1,124 @@
+package iterator_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+// modNamer names Int64 nodes by their value modulo 10 to produce lots of equal sort keys.
+type modNamer struct{}
+
+func (modNamer) ValueOf(quad.Value) refs.Ref { return nil }
+func (modNamer) NameOf(r refs.Ref) quad.Value {
+	return quad.Int(int64(r.(Int64Node)) % 10)
+}
+
+type spillResult struct {
+	ID   refs.Ref
+	Tags map[string]refs.Ref
+}
+
+func allResults(t *testing.T, it Scanner) [][]spillResult {
+	ctx := context.TODO()
+	var out [][]spillResult
+	for it.Next(ctx) {
+		var group []spillResult
+		for {
+			tags := make(map[string]refs.Ref)
+			it.TagResults(tags)
+			group = append(group, spillResult{ID: it.Result(), Tags: tags})
+			if !it.NextPath(ctx) {
+				break
+			}
+		}
+		out = append(out, group)
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	return out
+}
+
+func withSpillLimit(limit int64, fnc func()) {
+	defer func(old int64) { SpillMemoryLimit = old }(SpillMemoryLimit)
+	SpillMemoryLimit = limit
+	fnc()
+}
+
+func TestSortSpill(t *testing.T) {
+	var (
+		vals   []refs.Ref
+		expect []int64
+	)
+	for i := int64(0); i < 500; i++ {
+		v := (i * 37) % 500
+		vals = append(vals, Int64Node(v))
+		expect = append(expect, v)
+	}
+	sort.SliceStable(expect, func(i, j int) bool {
+		return expect[i]%10 < expect[j]%10
+	})
+	newIt := func() Shape {
+		return NewSort(modNamer{}, NewSave(NewFixed(vals...), "x"), SortKey{Tag: "x"})
+	}
+	for _, limit := range []int64{0, 1, 10000} {
+		withSpillLimit(limit, func() {
+			res := allResults(t, newIt().Iterate())
+			require.Len(t, res, len(expect), "limit: %d", limit)
+			for i, v := range expect {
+				require.Equal(t, []spillResult{{
+					ID:   Int64Node(v),
+					Tags: map[string]refs.Ref{"x": Int64Node(v)},
+				}}, res[i], "limit: %d", limit)
+			}
+		})
+	}
+}
+
+func TestMaterializeSpill(t *testing.T) {
+	ctx := context.TODO()
+	newIt := func() Shape {
+		return NewMaterialize(NewOr(
+			NewSave(newInt64(1, 3000, true), "a"),
+			NewSave(newInt64(2000, 2500, true), "b"),
+		))
+	}
+	var expect [][]spillResult
+	for i := int64(1); i <= 3000; i++ {
+		group := []spillResult{{ID: Int64Node(i), Tags: map[string]refs.Ref{"a": Int64Node(i)}}}
+		if i >= 2000 && i <= 2500 {
+			group = append(group, spillResult{ID: Int64Node(i), Tags: map[string]refs.Ref{"b": Int64Node(i)}})
+		}
+		expect = append(expect, group)
+	}
+	for _, limit := range []int64{1, 10000} {
+		withSpillLimit(limit, func() {
+			require.Equal(t, expect, allResults(t, newIt().Iterate()), "limit: %d", limit)
+
+			it := newIt().Lookup()
+			require.True(t, it.Contains(ctx, Int64Node(2100)))
+			var tags []map[string]refs.Ref
+			for {
+				m := make(map[string]refs.Ref)
+				it.TagResults(m)
+				tags = append(tags, m)
+				if !it.NextPath(ctx) {
+					break
+				}
+			}
+			require.Equal(t, []map[string]refs.Ref{
+				{"a": Int64Node(2100)},
+				{"b": Int64Node(2100)},
+			}, tags)
+			require.False(t, it.Contains(ctx, Int64Node(5000)))
+			require.NoError(t, it.Err())
+			require.NoError(t, it.Close())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package iterator
 import (
 	"context"
 
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/refs"
 )
 
@@ -61,6 +62,7 @@ func (it *Unique) String() string {
 type uniqueNext struct {
 	subIt  Scanner
 	result refs.Ref
+	gov    governor.Lazy
 	err    error
 	seen   map[interface{}]bool
 }
@@ -81,10 +83,16 @@ func (it *uniqueNext) TagResults(dst map[string]refs.Ref) {
 // Next advances the subiterator, continuing until it returns a value which it
 // has not previously seen.
 func (it *uniqueNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
 	for it.subIt.Next(ctx) {
 		curr := it.subIt.Result()
 		key := refs.ToKey(curr)
 		if ok := it.seen[key]; !ok {
+			if it.err = it.gov.From(ctx).Materialize(1); it.err != nil {
+				return false
+			}
 			it.result = curr
 			it.seen[key] = true
 			return true
@@ -113,6 +113,24 @@ func (it *allIteratorNext) Err() error {
 	return it.err
 }
 
+// NextBatch returns multiple values at once. Next is only called to refill the buffer of primitives,
+// the rest of the buffer is copied directly.
+func (it *allIteratorNext) NextBatch(ctx context.Context, dst []graph.Ref) int {
+	n := 0
+	for n < len(dst) && it.Next(ctx) {
+		dst[n] = it.Result()
+		n++
+		for n < len(dst) && len(it.buf) > 1 {
+			it.buf = it.buf[1:]
+			if it.accept(it.buf[0]) {
+				dst[n] = it.Result()
+				n++
+			}
+		}
+	}
+	return n
+}
+
 func (it *allIteratorNext) Result() graph.Ref {
 	if it.id > uint64(it.horizon) {
 		return nil
@@ -156,27 +174,34 @@ func (it *allIteratorNext) Next(ctx context.Context) bool {
 			it.buf = it.buf[1:]
 		}
 		for ; len(it.buf) > 0; it.buf = it.buf[1:] {
-			p := it.buf[0]
-			it.prim = p
-			if p == nil || p.Deleted {
-				continue
-			}
-			it.id = it.prim.ID
-			if p.IsNode() && it.nodes {
+			if it.accept(it.buf[0]) {
 				return true
 			}
-			if !p.IsNode() && !it.nodes {
-				if it.cons == nil {
-					return true
-				}
-				if Int64Value(p.GetDirection(it.cons.dir)) == it.cons.val {
-					return true
-				}
-			}
 		}
 	}
 }
 
+// accept moves the iterator to a primitive from the buffer and checks if it should be returned.
+func (it *allIteratorNext) accept(p *proto.Primitive) bool {
+	it.prim = p
+	if p == nil || p.Deleted {
+		return false
+	}
+	it.id = p.ID
+	if p.IsNode() && it.nodes {
+		return true
+	}
+	if !p.IsNode() && !it.nodes {
+		if it.cons == nil {
+			return true
+		}
+		if Int64Value(p.GetDirection(it.cons.dir)) == it.cons.val {
+			return true
+		}
+	}
+	return false
+}
+
 func (it *allIteratorNext) NextPath(ctx context.Context) bool {
 	return false
 }
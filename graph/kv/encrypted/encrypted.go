@@ -0,0 +1,688 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encrypted implements a wrapper for hidalgo KV databases that encrypts data at rest.
+//
+// All values are encrypted with AES-GCM. Keys selected by a user-defined function
+// (for example, keys derived from node values) are replaced with a keyed HMAC, so equality
+// lookups still work, but the original key cannot be recovered without the secret key.
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hidal-go/hidalgo/kv"
+)
+
+const (
+	// KeySize is the size of the secret key in bytes.
+	KeySize = 32
+
+	version   = 1
+	keyIDSize = 4
+
+	flagPlain  = 0 // payload contains only the value
+	flagHashed = 1 // payload contains the original key and the value
+)
+
+var (
+	ErrNoKeys     = errors.New("encrypted: no keys provided")
+	ErrUnknownKey = errors.New("encrypted: value is encrypted with an unknown key")
+	ErrMalformed  = errors.New("encrypted: malformed value")
+)
+
+// Key is a single secret key used for encryption and for hashing the keys.
+type Key struct {
+	id   [keyIDSize]byte
+	aead cipher.AEAD
+	mac  []byte
+}
+
+func derive(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("cayley encrypted kv: " + purpose))
+	return h.Sum(nil)
+}
+
+// NewKey creates a new key from a secret. The secret must be KeySize bytes long.
+func NewKey(secret []byte) (*Key, error) {
+	if len(secret) != KeySize {
+		return nil, fmt.Errorf("encrypted: key must be %d bytes, got %d", KeySize, len(secret))
+	}
+	block, err := aes.NewCipher(derive(secret, "value"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{aead: aead, mac: derive(secret, "key")}
+	copy(k.id[:], derive(secret, "id"))
+	return k, nil
+}
+
+// GenerateKey generates a new random secret in a format accepted by ParseKeyFile.
+func GenerateKey() (string, error) {
+	secret := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// KeyRing is a list of keys. The first key is used to encrypt and hash the data,
+// while all keys can be used to decrypt it.
+type KeyRing struct {
+	keys []*Key
+}
+
+// NewKeyRing creates a new key ring. The first key is used for writes.
+func NewKeyRing(keys ...*Key) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return &KeyRing{keys: keys}, nil
+}
+
+// ParseKeyFile reads a list of base64-encoded secrets, one per line.
+// Empty lines and lines starting with '#' are ignored. The first key is used for writes.
+func ParseKeyFile(data []byte) (*KeyRing, error) {
+	var keys []*Key
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		secret, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("encrypted: cannot decode key on line %d: %v", i+1, err)
+		}
+		k, err := NewKey(secret)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		keys = append(keys, k)
+	}
+	return NewKeyRing(keys...)
+}
+
+// LoadKeyFile reads a key ring from a file. See ParseKeyFile for the format.
+func LoadKeyFile(path string) (*KeyRing, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyFile(data)
+}
+
+func (r *KeyRing) active() *Key {
+	return r.keys[0]
+}
+
+func (r *KeyRing) byID(id []byte) *Key {
+	for _, k := range r.keys {
+		if bytes.Equal(k.id[:], id) {
+			return k
+		}
+	}
+	return nil
+}
+
+// HashFunc reports if a given key must be replaced with a keyed hash.
+type HashFunc func(k kv.Key) bool
+
+// New wraps the database to encrypt all values with a given key ring.
+// If hashed function is set, it selects keys that will be replaced with a keyed hash.
+//
+// The hashed key preserves the number and the length of key parts, as well as the first
+// byte of the key, so it is written to the same kind of bucket. Thus, only prefix scans that
+// cover the whole range of hashed keys (i.e. at most the first byte of it) return complete results.
+func New(db kv.KV, keys *KeyRing, hashed HashFunc) *DB {
+	return &DB{db: db, keys: keys, hashed: hashed}
+}
+
+var _ kv.KV = (*DB)(nil)
+
+// DB is a KV database that encrypts the data.
+type DB struct {
+	db     kv.KV
+	keys   *KeyRing
+	hashed HashFunc
+}
+
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+func (db *DB) Tx(rw bool) (kv.Tx, error) {
+	tx, err := db.db.Tx(rw)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx, keys: db.keys, hashed: db.hashed}, nil
+}
+
+// isBucket checks if a put operation only creates a bucket. See kv.CreateBucket.
+func isBucket(k kv.Key, v kv.Value) bool {
+	return len(k) != 0 && len(k[len(k)-1]) == 0 && len(v) == 0
+}
+
+func encodeKey(k kv.Key) []byte {
+	var (
+		buf = make([]byte, 0, 16)
+		tmp [binary.MaxVarintLen64]byte
+	)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(k)))]...)
+	for _, p := range k {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(p)))]...)
+		buf = append(buf, p...)
+	}
+	return buf
+}
+
+func decodeKey(b []byte) (kv.Key, []byte, error) {
+	n, sz := binary.Uvarint(b)
+	if sz <= 0 || n > uint64(len(b)) {
+		return nil, nil, ErrMalformed
+	}
+	b = b[sz:]
+	k := make(kv.Key, 0, int(n))
+	for i := 0; i < int(n); i++ {
+		l, sz := binary.Uvarint(b)
+		if sz <= 0 || l > uint64(len(b)-sz) {
+			return nil, nil, ErrMalformed
+		}
+		b = b[sz:]
+		k = append(k, append([]byte{}, b[:l]...))
+		b = b[l:]
+	}
+	return k, b, nil
+}
+
+// hashKey replaces the key with a keyed hash of the same shape.
+func (k *Key) hashKey(key kv.Key) kv.Key {
+	total := 0
+	for _, p := range key {
+		total += len(p)
+	}
+	// expand HMAC output to cover the whole key
+	data := encodeKey(key)
+	stream := make([]byte, 0, total+sha256.Size)
+	for ctr := uint32(0); len(stream) < total; ctr++ {
+		h := hmac.New(sha256.New, k.mac)
+		var c [4]byte
+		binary.BigEndian.PutUint32(c[:], ctr)
+		h.Write(c[:])
+		h.Write(data)
+		stream = h.Sum(stream)
+	}
+	out := make(kv.Key, len(key))
+	for i, p := range key {
+		out[i] = stream[:len(p):len(p)]
+		stream = stream[len(p):]
+	}
+	if len(out) != 0 && len(out[0]) != 0 {
+		// keep the same kind of bucket
+		out[0][0] = key[0][0]
+	}
+	return out
+}
+
+func (k *Key) seal(raw kv.Key, orig kv.Key, v kv.Value) (kv.Value, error) {
+	var payload []byte
+	if orig != nil {
+		ek := encodeKey(orig)
+		payload = make([]byte, 0, 1+len(ek)+len(v))
+		payload = append(payload, flagHashed)
+		payload = append(payload, ek...)
+	} else {
+		payload = make([]byte, 0, 1+len(v))
+		payload = append(payload, flagPlain)
+	}
+	payload = append(payload, v...)
+
+	ns := k.aead.NonceSize()
+	out := make([]byte, 1+keyIDSize+ns, 1+keyIDSize+ns+len(payload)+k.aead.Overhead())
+	out[0] = version
+	copy(out[1:], k.id[:])
+	nonce := out[1+keyIDSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(out, nonce, payload, encodeKey(raw)), nil
+}
+
+func (r *KeyRing) keyOf(v kv.Value) *Key {
+	if len(v) < 1+keyIDSize || v[0] != version {
+		return nil
+	}
+	return r.byID(v[1 : 1+keyIDSize])
+}
+
+// open decrypts the value. It returns the original key, if it was hashed.
+func (r *KeyRing) open(raw kv.Key, v kv.Value) (kv.Key, kv.Value, error) {
+	if len(v) == 0 {
+		// bucket marker
+		return nil, v, nil
+	} else if len(v) < 1+keyIDSize || v[0] != version {
+		return nil, nil, ErrMalformed
+	}
+	k := r.byID(v[1 : 1+keyIDSize])
+	if k == nil {
+		return nil, nil, ErrUnknownKey
+	}
+	v = v[1+keyIDSize:]
+	ns := k.aead.NonceSize()
+	if len(v) < ns {
+		return nil, nil, ErrMalformed
+	}
+	payload, err := k.aead.Open(nil, v[:ns], v[ns:], encodeKey(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypted: cannot decrypt value: %v", err)
+	} else if len(payload) == 0 {
+		return nil, nil, ErrMalformed
+	}
+	switch payload[0] {
+	case flagPlain:
+		return nil, payload[1:], nil
+	case flagHashed:
+		orig, rest, err := decodeKey(payload[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return orig, rest, nil
+	}
+	return nil, nil, ErrMalformed
+}
+
+// Tx is an encrypted transaction.
+type Tx struct {
+	tx     kv.Tx
+	keys   *KeyRing
+	hashed HashFunc
+}
+
+func (tx *Tx) isHashed(k kv.Key) bool {
+	return tx.hashed != nil && tx.hashed(k)
+}
+
+// rawKey returns a key that is used to store the value in the underlying database.
+func (tx *Tx) rawKey(k kv.Key) kv.Key {
+	if !tx.isHashed(k) {
+		return k
+	}
+	return tx.keys.active().hashKey(k)
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	return tx.tx.Commit(ctx)
+}
+
+func (tx *Tx) Close() error {
+	return tx.tx.Close()
+}
+
+func (tx *Tx) decrypt(k, raw kv.Key, v kv.Value) (kv.Value, error) {
+	orig, v, err := tx.keys.open(raw, v)
+	if err != nil {
+		return nil, err
+	}
+	if orig != nil && orig.Compare(k) != 0 {
+		// hash collision
+		return nil, kv.ErrNotFound
+	}
+	return v, nil
+}
+
+func (tx *Tx) Get(ctx context.Context, k kv.Key) (kv.Value, error) {
+	raw := tx.rawKey(k)
+	v, err := tx.tx.Get(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return tx.decrypt(k, raw, v)
+}
+
+func (tx *Tx) GetBatch(ctx context.Context, keys []kv.Key) ([]kv.Value, error) {
+	raw := make([]kv.Key, len(keys))
+	for i, k := range keys {
+		raw[i] = tx.rawKey(k)
+	}
+	vals, err := tx.tx.GetBatch(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if v == nil {
+			continue
+		}
+		v, err = tx.decrypt(keys[i], raw[i], v)
+		if err == kv.ErrNotFound {
+			v = nil
+		} else if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func (tx *Tx) Put(k kv.Key, v kv.Value) error {
+	if isBucket(k, v) {
+		return tx.tx.Put(k, v)
+	}
+	key := tx.keys.active()
+	var (
+		raw  = k
+		orig kv.Key
+	)
+	if tx.isHashed(k) {
+		raw, orig = key.hashKey(k), k
+	}
+	ev, err := key.seal(raw, orig, v)
+	if err != nil {
+		return err
+	}
+	return tx.tx.Put(raw, ev)
+}
+
+func (tx *Tx) Del(k kv.Key) error {
+	return tx.tx.Del(tx.rawKey(k))
+}
+
+func (tx *Tx) Scan(pref kv.Key) kv.Iterator {
+	return &Iterator{it: tx.tx.Scan(pref), keys: tx.keys, pref: pref}
+}
+
+// Iterator decrypts values from the underlying iterator.
+// It returns original keys for the ones that were hashed.
+type Iterator struct {
+	it   kv.Iterator
+	keys *KeyRing
+	pref kv.Key
+
+	key kv.Key
+	val kv.Value
+	err error
+}
+
+func hasPrefix(k, pref kv.Key) bool {
+	if len(pref) > len(k) {
+		return false
+	}
+	for i, p := range pref {
+		if i == len(pref)-1 {
+			return bytes.HasPrefix(k[i], p)
+		} else if !bytes.Equal(k[i], p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	for it.it.Next(ctx) {
+		raw := it.it.Key()
+		orig, v, err := it.keys.open(raw, it.it.Val())
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if orig != nil {
+			if !hasPrefix(orig, it.pref) {
+				continue
+			}
+			it.key = orig
+		} else {
+			it.key = raw
+		}
+		it.val = v
+		return true
+	}
+	return false
+}
+
+func (it *Iterator) Key() kv.Key {
+	return it.key
+}
+
+func (it *Iterator) Val() kv.Value {
+	return it.val
+}
+
+func (it *Iterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
+
+func (it *Iterator) Close() error {
+	return it.it.Close()
+}
+
+// Rotate re-encrypts all the data in the underlying database with the active key from a new key ring.
+// Old key ring must be able to decrypt all existing values. It is safe to resume the process if it was interrupted.
+//
+// Changes are committed in batches of a given size, and each batch continues the scan after the last key of the previous one.
+func Rotate(ctx context.Context, db kv.KV, old, keys *KeyRing, hashed HashFunc, batch int) (int, error) {
+	if batch <= 0 {
+		batch = 10000
+	}
+	// values encrypted with either key ring can be read during the rotation
+	all := &KeyRing{keys: append(append([]*Key{}, keys.keys...), old.keys...)}
+	key := keys.active()
+	total := 0
+	var last kv.Key
+	for {
+		n, next, err := rotateBatch(ctx, db, all, key, hashed, batch, last)
+		total += n
+		if err != nil {
+			return total, err
+		} else if next == nil {
+			return total, nil
+		}
+		last = next
+	}
+}
+
+// rotateBatch re-encrypts up to batch values that go after the last key and returns the last scanned key,
+// or nil if there are no more keys.
+func rotateBatch(ctx context.Context, db kv.KV, all *KeyRing, key *Key, hashed HashFunc, batch int, last kv.Key) (int, kv.Key, error) {
+	tx, err := db.Tx(true)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Close()
+
+	type pair struct {
+		raw  kv.Key
+		orig kv.Key
+		val  kv.Value
+	}
+	var (
+		list []pair
+		it   kv.Iterator
+		next kv.Key // nil if the scan has ended
+	)
+	if last == nil {
+		it = tx.Scan(nil)
+	} else {
+		it = scanAfter(tx, last)
+	}
+	for len(list) < batch && it.Next(ctx) {
+		raw := it.Key()
+		v := it.Val()
+		if len(v) == 0 || all.keyOf(v) == key {
+			continue // bucket marker or already rotated
+		}
+		orig, val, err := all.open(raw, v)
+		if err != nil {
+			it.Close()
+			return 0, nil, fmt.Errorf("cannot decrypt %q: %v", raw, err)
+		}
+		list = append(list, pair{raw: raw.Clone(), orig: orig, val: val})
+	}
+	if len(list) == batch {
+		next = list[len(list)-1].raw
+	}
+	err = it.Err()
+	it.Close()
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, p := range list {
+		raw, orig := p.raw, kv.Key(nil)
+		if p.orig != nil || (hashed != nil && hashed(p.raw)) {
+			if p.orig != nil {
+				orig = p.orig
+			} else {
+				// value was not hashed previously
+				orig = p.raw
+			}
+			raw = key.hashKey(orig)
+		}
+		ev, err := key.seal(raw, orig, p.val)
+		if err != nil {
+			return 0, nil, err
+		}
+		if raw.Compare(p.raw) != 0 {
+			if err = tx.Del(p.raw); err != nil {
+				return 0, nil, err
+			}
+		}
+		if err = tx.Put(raw, ev); err != nil {
+			return 0, nil, err
+		}
+	}
+	if len(list) == 0 {
+		return 0, next, nil
+	}
+	return len(list), next, tx.Commit(ctx)
+}
+
+// scanAfter returns an iterator over all keys that go after a given key.
+//
+// Databases only support prefix scans, thus the rest of the key space is split into a sequence of prefixes:
+// the key itself, its extensions and keys that differ from it in a single byte at each position.
+// Each of the prefixes is a single seek in the database, so the scan doesn't need to skip already visited keys.
+func scanAfter(tx kv.Tx, last kv.Key) kv.Iterator {
+	m := len(last) - 1
+	it := &afterIterator{tx: tx, last: last, it: tx.Scan(last), first: true, lvl: m, pos: len(last[m])}
+	it.nextPos()
+	return it
+}
+
+type afterIterator struct {
+	tx    kv.Tx
+	last  kv.Key
+	it    kv.Iterator
+	first bool // scanning the prefix that includes the last key
+	err   error
+
+	lvl, pos int // part of the key and the byte position of the next prefix
+	b        int // next byte value at this position
+}
+
+// nextPos moves to the previous byte position of the key, or to the previous part of the key.
+// Parts of the key above the last one are first extended with any byte, since such keys are also greater.
+func (it *afterIterator) nextPos() {
+	it.pos--
+	if it.pos >= 0 {
+		it.b = int(it.last[it.lvl][it.pos]) + 1
+		return
+	}
+	it.lvl--
+	if it.lvl >= 0 {
+		it.pos = len(it.last[it.lvl])
+		it.b = 0
+	}
+}
+
+func (it *afterIterator) nextPrefix() kv.Key {
+	for it.lvl >= 0 {
+		if it.b > 0xff {
+			it.nextPos()
+			continue
+		}
+		part := make([]byte, it.pos+1)
+		copy(part, it.last[it.lvl][:it.pos])
+		part[it.pos] = byte(it.b)
+		it.b++
+		pref := make(kv.Key, it.lvl+1)
+		copy(pref, it.last[:it.lvl])
+		pref[it.lvl] = part
+		return pref
+	}
+	return nil
+}
+
+func (it *afterIterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if it.it != nil {
+			if it.it.Next(ctx) {
+				if it.first && it.it.Key().Compare(it.last) == 0 {
+					continue
+				}
+				return true
+			}
+			it.err = it.it.Err()
+			it.it.Close()
+			it.it, it.first = nil, false
+			continue
+		}
+		pref := it.nextPrefix()
+		if pref == nil {
+			return false
+		}
+		it.it = it.tx.Scan(pref)
+	}
+	return false
+}
+
+func (it *afterIterator) Key() kv.Key {
+	return it.it.Key()
+}
+
+func (it *afterIterator) Val() kv.Value {
+	return it.it.Val()
+}
+
+func (it *afterIterator) Err() error {
+	return it.err
+}
+
+func (it *afterIterator) Close() error {
+	if it.it != nil {
+		return it.it.Close()
+	}
+	return nil
+}
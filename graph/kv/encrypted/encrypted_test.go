@@ -0,0 +1,148 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+
+	hkv "github.com/hidal-go/hidalgo/kv"
+	hkvtest "github.com/hidal-go/hidalgo/kv/kvtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/graphtest"
+	"github.com/cayleygraph/cayley/graph/kv"
+	"github.com/cayleygraph/cayley/graph/kv/bolt"
+	"github.com/cayleygraph/cayley/graph/kv/btree"
+	"github.com/cayleygraph/cayley/graph/kv/encrypted"
+	"github.com/cayleygraph/cayley/graph/kv/kvtest"
+	"github.com/cayleygraph/quad"
+)
+
+func newKeyRing(t testing.TB, n int) *encrypted.KeyRing {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		k, err := encrypted.GenerateKey()
+		require.NoError(t, err)
+		buf.WriteString(k + "\n")
+	}
+	keys, err := encrypted.ParseKeyFile(buf.Bytes())
+	require.NoError(t, err)
+	return keys
+}
+
+func TestEncryptedKV(t *testing.T) {
+	keys := newKeyRing(t, 1)
+	hkvtest.RunTest(t, func(t testing.TB) (hkv.KV, func()) {
+		return encrypted.New(btree.New(), keys, kv.IsValueKey), func() {}
+	})
+}
+
+func TestEncrypted(t *testing.T) {
+	keys := newKeyRing(t, 1)
+	kvtest.TestAll(t, func(t testing.TB) (hkv.KV, graph.Options, func()) {
+		return kv.NewEncrypted(btree.New(), keys), nil, func() {}
+	}, nil)
+}
+
+// rawPairs returns all key-value pairs from the underlying database.
+func rawPairs(t testing.TB, db hkv.KV) []hkv.Pair {
+	var out []hkv.Pair
+	err := hkv.View(db, func(tx hkv.Tx) error {
+		return hkv.Each(context.TODO(), tx, nil, func(k hkv.Key, v hkv.Value) error {
+			out = append(out, hkv.Pair{Key: k.Clone(), Val: v.Clone()})
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	return out
+}
+
+func sortedQuads(t testing.TB, qs graph.QuadStore) []quad.Quad {
+	quads := graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator())
+	sort.Sort(quad.ByQuadString(quads))
+	return quads
+}
+
+func TestEncryptedRotate(t *testing.T) {
+	for _, batch := range []int{1, 7, 1000} {
+		t.Run("btree/"+strconv.Itoa(batch), func(t *testing.T) {
+			testRotate(t, btree.New(), batch)
+		})
+		t.Run("bolt/"+strconv.Itoa(batch), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cayley_test_encrypted")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			raw, err := bolt.Create(dir, nil)
+			require.NoError(t, err)
+			defer raw.Close()
+			testRotate(t, raw, batch)
+		})
+	}
+}
+
+func testRotate(t *testing.T, raw hkv.KV, batch int) {
+	ctx := context.TODO()
+	old := newKeyRing(t, 1)
+
+	db := kv.NewEncrypted(raw, old)
+	require.NoError(t, kv.Init(db, nil))
+	qs, err := kv.New(db, nil)
+	require.NoError(t, err)
+	w, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuadSet(graphtest.MakeQuadSet()))
+	exp := sortedQuads(t, qs)
+
+	// no plaintext values should be stored
+	before := rawPairs(t, raw)
+	for _, p := range before {
+		require.False(t, bytes.Contains(p.Val, []byte("follows")), "%q", p.Key)
+	}
+
+	keys := newKeyRing(t, 1)
+	n, err := encrypted.Rotate(ctx, raw, old, keys, kv.IsValueKey, batch)
+	require.NoError(t, err)
+	values := 0
+	for _, p := range before {
+		if len(p.Val) != 0 {
+			values++
+		}
+	}
+	require.Equal(t, values, n)
+
+	// rotation is idempotent
+	n, err = encrypted.Rotate(ctx, raw, old, keys, kv.IsValueKey, batch)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	after := rawPairs(t, raw)
+	require.Equal(t, len(before), len(after))
+
+	// old key cannot read the data anymore
+	_, err = kv.New(kv.NewEncrypted(raw, old), nil)
+	require.Error(t, err)
+
+	qs, err = kv.New(kv.NewEncrypted(raw, keys), nil)
+	require.NoError(t, err)
+	require.Equal(t, exp, sortedQuads(t, qs))
+	require.NotNil(t, qs.ValueOf(quad.String("follows")))
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hidal-go/hidalgo/kv"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/kv/encrypted"
+)
+
+// OptEncryptionKeyFile is a path to the key file used to encrypt the database.
+// See encrypted.ParseKeyFile for the file format.
+const OptEncryptionKeyFile = "encryption_key_file"
+
+// IsValueKey checks if the key refers to the bucket indexed by the hash of the node value.
+// Those keys are hashed with a secret key when the encryption is enabled.
+func IsValueKey(k kv.Key) bool {
+	if len(k) != 2 || len(k[0]) != 3 || len(k[1]) == 0 {
+		return false
+	}
+	switch k[0][0] {
+	case 'v', 'n':
+		return true
+	}
+	return false
+}
+
+// NewEncrypted wraps the database with a given key ring, so all values are encrypted
+// and node hashes are replaced with keyed hashes.
+func NewEncrypted(db kv.KV, keys *encrypted.KeyRing) kv.KV {
+	return encrypted.New(db, keys, IsValueKey)
+}
+
+// wrapEncrypted wraps the database if the encryption key file is specified in the options.
+func wrapEncrypted(db kv.KV, opt graph.Options) (kv.KV, error) {
+	path, err := opt.StringKey(OptEncryptionKeyFile, "")
+	if err != nil {
+		return nil, err
+	} else if path == "" {
+		return db, nil
+	}
+	keys, err := encrypted.LoadKeyFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load encryption key: %v", err)
+	}
+	return NewEncrypted(db, keys), nil
+}
+
+// Reencrypt re-encrypts the database with a key file that contains a new key.
+// Options must contain the current key file.
+func Reencrypt(ctx context.Context, name, addr string, opt graph.Options, keyFile string) (int, error) {
+	r, ok := registry[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported key-value backend: %q", name)
+	} else if !r.IsPersistent {
+		return 0, graph.ErrQuadStoreNotPersistent
+	}
+	path, err := opt.StringKey(OptEncryptionKeyFile, "")
+	if err != nil {
+		return 0, err
+	} else if path == "" {
+		return 0, fmt.Errorf("database is not encrypted: %q option is not set", OptEncryptionKeyFile)
+	}
+	old, err := encrypted.LoadKeyFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot load current encryption key: %v", err)
+	}
+	keys, err := encrypted.LoadKeyFile(keyFile)
+	if err != nil {
+		return 0, fmt.Errorf("cannot load new encryption key: %v", err)
+	}
+	db, err := r.NewFunc(addr, opt)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	n, err := encrypted.Rotate(ctx, db, old, keys, IsValueKey, 0)
+	if err != nil {
+		return n, err
+	}
+	clog.Infof("re-encrypted %d values", n)
+	return n, db.Close()
+}
@@ -426,6 +426,10 @@ func (w *quadWriter) flush() error {
 		w.err = err
 		return err
 	}
+	if err := w.qs.flushStats(ctx, w.tx); err != nil {
+		w.err = err
+		return err
+	}
 	if err := w.tx.Commit(ctx); err != nil {
 		w.qs.writer.Unlock()
 		w.tx = nil
@@ -455,6 +459,7 @@ func (w *quadWriter) WriteQuads(buf []quad.Quad) (int, error) {
 			return 0, err
 		}
 		w.tx = wrapTx(tx)
+		w.qs.stats.buf.reset()
 	}
 	deltas := graphlog.InsertQuads(buf)
 	if _, err := w.qs.applyAddDeltas(w.tx, nil, deltas, graph.IgnoreOpts{IgnoreDup: true}); err != nil {
@@ -485,6 +490,9 @@ func (w *quadWriter) Close() error {
 	ctx := context.TODO()
 	// flush quad indexes and commit
 	err := w.qs.flushMapBucket(ctx, w.tx)
+	if err == nil {
+		err = w.qs.flushStats(ctx, w.tx)
+	}
 	if err != nil {
 		_ = w.tx.Close()
 		w.tx = nil
@@ -572,6 +580,8 @@ func (qs *QuadStore) ApplyDeltas(in []graph.Delta, ignoreOpts graph.IgnoreOpts)
 	}
 	defer tx.Close()
 	tx = wrapTx(tx)
+	// drop stats changes left from failed transactions
+	qs.stats.buf.reset()
 
 	deltas := graphlog.SplitDeltas(in)
 	if len(deltas.QuadDel) != 0 || len(deltas.DecNode) != 0 {
@@ -666,6 +676,9 @@ func (qs *QuadStore) ApplyDeltas(in []graph.Delta, ignoreOpts graph.IgnoreOpts)
 	if err != nil {
 		return err
 	}
+	if err = qs.flushStats(ctx, tx); err != nil {
+		return err
+	}
 	return tx.Commit(ctx)
 }
 
@@ -711,6 +724,7 @@ func (qs *QuadStore) indexLink(tx kv.Tx, p *proto.Primitive) error {
 		}
 	}
 	qs.bloomAdd(p)
+	qs.updateStats(p, +1)
 	err = qs.indexSchema(tx, p)
 	if err != nil {
 		return err
@@ -722,6 +736,7 @@ func (qs *QuadStore) markAsDead(tx kv.Tx, p *proto.Primitive) error {
 	p.Deleted = true
 	//TODO(barakmich): Add tombstone?
 	qs.bloomRemove(p)
+	qs.updateStats(p, -1)
 	return qs.addToLog(tx, p)
 }
 
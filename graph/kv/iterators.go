@@ -62,6 +62,13 @@ func (qs *QuadStore) QuadIteratorSize(ctx context.Context, d quad.Direction, v g
 			return qs.indexSize(ctx, ind, []uint64{uint64(vi)})
 		}
 	}
+	if qs.stats.enabled && (d == quad.Predicate || d == quad.Label) {
+		vs, err := qs.valueStats(ctx, d, vi)
+		if err != nil {
+			return refs.Size{}, err
+		}
+		return refs.Size{Value: vs.Quads, Exact: true}, nil
+	}
 	st, err := qs.Stats(ctx, false)
 	if err != nil {
 		return refs.Size{}, err
@@ -34,6 +34,17 @@ func newQuadStoreFunc(gen DatabaseFunc, bloom bool) testutil.DatabaseFunc {
 	}
 }
 
+func newQuadStoreStatsFunc(gen DatabaseFunc) testutil.DatabaseFunc {
+	return newQuadStoreFunc(func(t testing.TB) (hkv.KV, graph.Options, func()) {
+		db, opt, closer := gen(t)
+		if opt == nil {
+			opt = make(graph.Options)
+		}
+		opt[kv.OptStats] = true
+		return db, opt, closer
+	}, true)
+}
+
 func NewQuadStoreFunc(gen DatabaseFunc) testutil.DatabaseFunc {
 	return newQuadStoreFunc(gen, true)
 }
@@ -83,6 +94,22 @@ func TestAll(t *testing.T, gen DatabaseFunc, conf *Config) {
 	t.Run("optimize", func(t *testing.T) {
 		testOptimize(t, gen, conf)
 	})
+	qsgenStats := newQuadStoreStatsFunc(gen)
+	t.Run("qs-stats", func(t *testing.T) {
+		qconf := conf.quadStore()
+		t.Run("stats", func(t *testing.T) {
+			graphtest.TestStats(t, qsgenStats, qconf)
+		})
+		t.Run("add and remove", func(t *testing.T) {
+			graphtest.TestAddRemove(t, qsgenStats, qconf)
+		})
+		t.Run("delete reinserted dup", func(t *testing.T) {
+			graphtest.TestDeleteReinsertedDup(t, qsgenStats, qconf)
+		})
+		t.Run("writers", func(t *testing.T) {
+			graphtest.TestWriters(t, qsgenStats, qconf)
+		})
+	})
 }
 
 func testOptimize(t *testing.T, gen DatabaseFunc, _ *Config) {
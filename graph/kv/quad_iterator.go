@@ -212,6 +212,25 @@ func (it *quadIteratorNext) Next(ctx context.Context) bool {
 	}
 }
 
+// NextBatch returns multiple quads at once. Next is only called to refill the buffer of primitives,
+// the rest of the buffer is copied directly.
+func (it *quadIteratorNext) NextBatch(ctx context.Context, dst []graph.Ref) int {
+	n := 0
+	for n < len(dst) && it.Next(ctx) {
+		dst[n] = it.prim
+		n++
+		for n < len(dst) && len(it.buf) > 1 {
+			it.buf, it.off = it.buf[1:], it.off+1
+			if p := it.buf[0]; p != nil && !p.Deleted {
+				it.prim = p
+				dst[n] = p
+				n++
+			}
+		}
+	}
+	return n
+}
+
 func (it *quadIteratorNext) NextPath(ctx context.Context) bool {
 	return false
 }
@@ -27,6 +27,7 @@ import (
 
 	"github.com/cayleygraph/cayley/clog"
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/graph/proto"
 	"github.com/cayleygraph/cayley/graph/refs"
 	"github.com/cayleygraph/cayley/internal/lru"
@@ -50,7 +51,11 @@ type Registration struct {
 type InitFunc func(string, graph.Options) (kv.KV, error)
 type NewFunc func(string, graph.Options) (kv.KV, error)
 
+// registry keeps all KV registrations, so the raw database can be opened.
+var registry = make(map[string]Registration)
+
 func Register(name string, r Registration) {
+	registry[name] = r
 	graph.RegisterQuadStore(name, graph.QuadStoreRegistration{
 		InitFunc: func(addr string, opt graph.Options) error {
 			if !r.IsPersistent {
@@ -61,6 +66,9 @@ func Register(name string, r Registration) {
 				return err
 			}
 			defer kv.Close()
+			if kv, err = wrapEncrypted(kv, opt); err != nil {
+				return err
+			}
 			if err = Init(kv, opt); err != nil {
 				return err
 			}
@@ -71,6 +79,12 @@ func Register(name string, r Registration) {
 			if err != nil {
 				return nil, err
 			}
+			ekv, err := wrapEncrypted(kv, opt)
+			if err != nil {
+				kv.Close()
+				return nil, err
+			}
+			kv = ekv
 			if !r.IsPersistent {
 				if err = Init(kv, opt); err != nil {
 					kv.Close()
@@ -110,6 +124,11 @@ type QuadStore struct {
 	mapBloom  map[string]*boom.BloomFilter
 	mapNodes  *boom.BloomFilter
 
+	stats struct {
+		enabled bool
+		buf     statsBuffer
+	}
+
 	exists struct {
 		disabled bool
 		sync.Mutex
@@ -152,6 +171,13 @@ func Init(kv kv.KV, opt graph.Options) error {
 	if err := qs.writeIndexesMeta(ctx); err != nil {
 		return err
 	}
+	if stats, err := opt.BoolKey(OptStats, false); err != nil {
+		return err
+	} else if stats {
+		if err := initStats(ctx, qs.db); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -174,6 +200,10 @@ func New(kv kv.KV, opt graph.Options) (graph.QuadStore, error) {
 		return nil, err
 	}
 	qs.indexes.all = list
+	qs.stats.enabled, err = qs.readStatsMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
 	qs.valueLRU = lru.New(2000)
 	qs.exists.disabled, _ = opt.BoolKey(OptNoBloom, false)
 	if err := qs.initBloomFilter(ctx); err != nil {
@@ -320,6 +350,13 @@ func (qs *QuadStore) ValuesOf(ctx context.Context, vals []graph.Ref) ([]quad.Val
 			}
 			inds = append(inds, i)
 			irefs = append(irefs, uint64(v))
+		case *proto.Primitive:
+			// quads have no value, same as in NameOf
+			if !v.IsNode() {
+				continue
+			}
+			inds = append(inds, i)
+			irefs = append(irefs, v.ID)
 		default:
 			return out, fmt.Errorf("unknown type of graph.Ref; not meant for this quadstore. apparently a %#v", v)
 		}
@@ -468,3 +505,17 @@ func (qs *QuadStore) getPrimitives(ctx context.Context, vals []uint64) ([]*proto
 type Int64Value uint64
 
 func (v Int64Value) Key() interface{} { return v }
+
+func init() {
+	// allow Sort and Materialize to spill results to disk
+	iterator.RegisterRefType(Int64Value(0), func(r refs.Ref) ([]byte, error) {
+		p := make([]byte, binary.MaxVarintLen64)
+		return p[:binary.PutUvarint(p, uint64(r.(Int64Value)))], nil
+	}, func(p []byte) (refs.Ref, error) {
+		v, n := binary.Uvarint(p)
+		if n <= 0 {
+			return nil, errors.New("invalid node id")
+		}
+		return Int64Value(v), nil
+	})
+}
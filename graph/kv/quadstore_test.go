@@ -73,6 +73,8 @@ var (
 	vAuto = []byte("auto")
 
 	kIndexes = []byte("indexes")
+
+	kStats = []byte("stats")
 )
 
 type Ops []kvOp
@@ -145,6 +147,7 @@ func TestApplyDeltas(t *testing.T) {
 	expect(Ops{
 		{opGet, key(bMeta, kVers), vVers, nil},
 		{opGet, key(bMeta, kIndexes), []byte(`[{"dirs":"AQI=","unique":false},{"dirs":"AwIB","unique":false}]`), nil},
+		{opGet, key(bMeta, kStats), nil, hkv.ErrNotFound},
 		{opGet, key(bMeta, []byte("size")), nil, hkv.ErrNotFound},
 	})
 
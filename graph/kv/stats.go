@@ -0,0 +1,325 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/hidal-go/hidalgo/kv"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/proto"
+	"github.com/cayleygraph/quad"
+)
+
+// OptStats enables collection of per-predicate and per-label statistics.
+// It can only be set when the database is initialized.
+const OptStats = "stats"
+
+var (
+	_ graph.StatsProvider = (*QuadStore)(nil)
+
+	// statsBucket stores counters for each predicate and label.
+	// Key is a direction prefix followed by the node id, value is encoded valueStats.
+	statsBucket = kv.Key{[]byte("stats")}
+	// statsRefsBucket stores the number of quads for each (predicate or label, subject or object) pair.
+	// It is used to maintain the number of distinct subjects and objects.
+	statsRefsBucket = kv.Key{[]byte("stats_refs")}
+
+	keyMetaStats = metaBucket.AppendBytes([]byte("stats"))
+
+	// statsDirs is a list of directions stats are collected for.
+	statsDirs = []quad.Direction{quad.Predicate, quad.Label}
+)
+
+func statsKey(d quad.Direction, id uint64) []byte {
+	k := make([]byte, 9)
+	k[0] = d.Prefix()
+	binary.BigEndian.PutUint64(k[1:], id)
+	return k
+}
+
+func statsRefKey(d quad.Direction, id uint64, side quad.Direction, node uint64) []byte {
+	k := make([]byte, 18)
+	k[0] = d.Prefix()
+	binary.BigEndian.PutUint64(k[1:], id)
+	k[9] = side.Prefix()
+	binary.BigEndian.PutUint64(k[10:], node)
+	return k
+}
+
+func decodeValueStats(b []byte) (graph.ValueStats, error) {
+	if len(b) == 0 {
+		return graph.ValueStats{}, nil
+	} else if len(b) != 24 {
+		return graph.ValueStats{}, fmt.Errorf("unexpected stats size: %d", len(b))
+	}
+	return graph.ValueStats{
+		Quads:    int64(binary.LittleEndian.Uint64(b[0:])),
+		Subjects: int64(binary.LittleEndian.Uint64(b[8:])),
+		Objects:  int64(binary.LittleEndian.Uint64(b[16:])),
+	}, nil
+}
+
+func encodeValueStats(st graph.ValueStats) []byte {
+	b := make([]byte, 24)
+	binary.LittleEndian.PutUint64(b[0:], uint64(st.Quads))
+	binary.LittleEndian.PutUint64(b[8:], uint64(st.Subjects))
+	binary.LittleEndian.PutUint64(b[16:], uint64(st.Objects))
+	return b
+}
+
+// statsBuffer accumulates stats changes until the transaction is flushed.
+type statsBuffer struct {
+	quads map[string]int64 // stats key -> quads delta
+	refs  map[string]int64 // stats ref key -> quads delta
+}
+
+func (b *statsBuffer) reset() {
+	b.quads, b.refs = nil, nil
+}
+
+func (b *statsBuffer) add(p *proto.Primitive, delta int64) {
+	if b.quads == nil {
+		b.quads = make(map[string]int64)
+		b.refs = make(map[string]int64)
+	}
+	for _, d := range statsDirs {
+		id := p.GetDirection(d)
+		if id == 0 {
+			continue
+		}
+		b.quads[string(statsKey(d, id))] += delta
+		for _, side := range []quad.Direction{quad.Subject, quad.Object} {
+			b.refs[string(statsRefKey(d, id, side, p.GetDirection(side)))] += delta
+		}
+	}
+}
+
+func initStats(ctx context.Context, db kv.KV) error {
+	return kv.Update(ctx, db, func(tx kv.Tx) error {
+		for _, b := range []kv.Key{statsBucket, statsRefsBucket} {
+			_ = kv.CreateBucket(ctx, tx, b)
+		}
+		return tx.Put(keyMetaStats, []byte{1})
+	})
+}
+
+func (qs *QuadStore) readStatsMeta(ctx context.Context) (bool, error) {
+	var ok bool
+	err := kv.View(qs.db, func(tx kv.Tx) error {
+		_, err := tx.Get(ctx, keyMetaStats)
+		if err == kv.ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+	return ok, err
+}
+
+func (qs *QuadStore) updateStats(p *proto.Primitive, delta int64) {
+	if !qs.stats.enabled {
+		return
+	}
+	qs.stats.buf.add(p, delta)
+}
+
+// flushStats writes all buffered stats changes to the database.
+func (qs *QuadStore) flushStats(ctx context.Context, tx kv.Tx) error {
+	if !qs.stats.enabled {
+		return nil
+	}
+	buf := qs.stats.buf
+	qs.stats.buf.reset()
+	if len(buf.quads) == 0 {
+		return nil
+	}
+	// first, update quad counters for each pair and find the ones that appeared or disappeared
+	keys := make([]kv.Key, 0, len(buf.refs))
+	for k, n := range buf.refs {
+		if n != 0 {
+			keys = append(keys, statsRefsBucket.AppendBytes([]byte(k)))
+		}
+	}
+	sort.Sort(kv.ByKey(keys))
+	vals, err := tx.GetBatch(ctx, keys)
+	if err != nil {
+		return err
+	}
+	type distinct struct{ subj, obj int64 }
+	dist := make(map[string]distinct)
+	for i, k := range keys {
+		rk := k[1]
+		old, err := asInt64(vals[i], 0)
+		if err != nil {
+			return err
+		}
+		cnt := old + buf.refs[string(rk)]
+		if cnt > 0 {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], uint64(cnt))
+			err = tx.Put(k, b[:])
+		} else {
+			err = tx.Del(k)
+		}
+		if err != nil {
+			return err
+		}
+		var dn int64
+		if old <= 0 && cnt > 0 {
+			dn = +1
+		} else if old > 0 && cnt <= 0 {
+			dn = -1
+		} else {
+			continue
+		}
+		sk := string(rk[:9])
+		ds := dist[sk]
+		if rk[9] == quad.Subject.Prefix() {
+			ds.subj += dn
+		} else {
+			ds.obj += dn
+		}
+		dist[sk] = ds
+	}
+	// then update counters of each predicate and label
+	keys = keys[:0]
+	for k := range buf.quads {
+		keys = append(keys, statsBucket.AppendBytes([]byte(k)))
+	}
+	sort.Sort(kv.ByKey(keys))
+	vals, err = tx.GetBatch(ctx, keys)
+	if err != nil {
+		return err
+	}
+	for i, k := range keys {
+		st, err := decodeValueStats(vals[i])
+		if err != nil {
+			return err
+		}
+		sk := string(k[1])
+		ds := dist[sk]
+		st.Quads += buf.quads[sk]
+		st.Subjects += ds.subj
+		st.Objects += ds.obj
+		if st.Quads > 0 {
+			err = tx.Put(k, encodeValueStats(st))
+		} else {
+			err = tx.Del(k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (qs *QuadStore) valueStats(ctx context.Context, d quad.Direction, v graph.Ref) (graph.ValueStats, error) {
+	if !qs.stats.enabled {
+		return graph.ValueStats{}, graph.ErrNoStats
+	}
+	vi, ok := v.(Int64Value)
+	if !ok {
+		return graph.ValueStats{}, nil
+	}
+	var st graph.ValueStats
+	err := kv.View(qs.db, func(tx kv.Tx) error {
+		val, err := tx.Get(ctx, statsBucket.AppendBytes(statsKey(d, uint64(vi))))
+		if err == kv.ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		st, err = decodeValueStats(val)
+		return err
+	})
+	return st, err
+}
+
+// PredicateStats implements graph.StatsProvider.
+func (qs *QuadStore) PredicateStats(ctx context.Context, pred graph.Ref) (graph.ValueStats, error) {
+	return qs.valueStats(ctx, quad.Predicate, pred)
+}
+
+// LabelStats implements graph.StatsProvider.
+func (qs *QuadStore) LabelStats(ctx context.Context, label graph.Ref) (graph.ValueStats, error) {
+	return qs.valueStats(ctx, quad.Label, label)
+}
+
+// StatsTable implements graph.StatsProvider.
+func (qs *QuadStore) StatsTable(ctx context.Context) (*graph.StatsTable, error) {
+	if !qs.stats.enabled {
+		return nil, graph.ErrNoStats
+	}
+	type entry struct {
+		dir quad.Direction
+		id  uint64
+		st  graph.ValueStats
+	}
+	var list []entry
+	err := kv.View(qs.db, func(tx kv.Tx) error {
+		it := tx.Scan(statsBucket)
+		defer it.Close()
+		for it.Next(ctx) {
+			k := it.Key()
+			if len(k) != 2 || len(k[1]) != 9 {
+				continue
+			}
+			var dir quad.Direction
+			switch k[1][0] {
+			case quad.Predicate.Prefix():
+				dir = quad.Predicate
+			case quad.Label.Prefix():
+				dir = quad.Label
+			default:
+				continue
+			}
+			st, err := decodeValueStats(it.Val())
+			if err != nil {
+				return err
+			}
+			list = append(list, entry{
+				dir: dir,
+				id:  binary.BigEndian.Uint64(k[1][1:]),
+				st:  st,
+			})
+		}
+		return it.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := &graph.StatsTable{}
+	for _, e := range list {
+		ns := graph.NamedStats{
+			Value:      qs.NameOf(Int64Value(e.id)),
+			ValueStats: e.st,
+		}
+		switch e.dir {
+		case quad.Predicate:
+			t.Predicates = append(t.Predicates, ns)
+		case quad.Label:
+			t.Labels = append(t.Labels, ns)
+		}
+	}
+	t.Sort()
+	return t, nil
+}
@@ -32,7 +32,9 @@ package graph
 import (
 	"context"
 	"fmt"
+	"math"
 
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/graph/refs"
 	"github.com/cayleygraph/quad"
@@ -46,6 +48,7 @@ type LinksTo struct {
 	primary iterator.Shape
 	dir     quad.Direction
 	size    refs.Size
+	pred    Ref // predicate hint, see SetPredicateHint
 }
 
 // NewLinksTo construct a new LinksTo iterator around a direction and a subiterator of
@@ -58,6 +61,15 @@ func NewLinksTo(qs QuadIndexer, it iterator.Shape, d quad.Direction) *LinksTo {
 	}
 }
 
+// SetPredicateHint tells the iterator that its results will be intersected with
+// quads that have a given predicate. If the quad store implements StatsProvider,
+// predicate statistics will be used to estimate the size of the intersection
+// instead of a fixed fanout factor.
+func (it *LinksTo) SetPredicateHint(pred Ref) {
+	it.pred = pred
+	it.size = refs.Size{}
+}
+
 // Direction returns the direction under consideration.
 func (it *LinksTo) Direction() quad.Direction { return it.dir }
 
@@ -122,11 +134,21 @@ func (it *LinksTo) getSize(ctx context.Context) refs.Size {
 		it.size.Value, it.size.Exact = sz, exact
 		return it.size
 	}
+	st, _ := it.primary.Stats(ctx)
+	if ps, ok := PredicateStats(ctx, it.qs, it.pred); ok {
+		if deg, ok := ps.Degree(it.dir); ok {
+			value := int64(math.Ceil(float64(st.Size.Value) * deg))
+			if value > ps.Quads {
+				value = ps.Quads
+			}
+			it.size.Value, it.size.Exact = value, false
+			return it.size
+		}
+	}
 	stats, _ := it.qs.Stats(ctx, false)
 	maxSize := stats.Quads.Value/2 + 1
 	// TODO(barakmich): It should really come from the quadstore itself
 	const fanoutFactor = 20
-	st, _ := it.primary.Stats(ctx)
 	value := st.Size.Value * fanoutFactor
 	if value > maxSize {
 		value = maxSize
@@ -144,6 +166,7 @@ type linksToNext struct {
 	dir     quad.Direction
 	nextIt  iterator.Scanner
 	result  refs.Ref
+	gov     governor.Lazy
 	err     error
 }
 
@@ -172,7 +195,13 @@ func (it *linksToNext) String() string {
 
 // Next()ing a LinksTo operates as described above.
 func (it *linksToNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
 	for {
+		if it.err = it.gov.From(ctx).Step(1); it.err != nil {
+			return false
+		}
 		if it.nextIt.Next(ctx) {
 			it.result = it.nextIt.Result()
 			return true
@@ -237,6 +266,8 @@ type linksToContains struct {
 	primary iterator.Index
 	dir     quad.Direction
 	result  refs.Ref
+	gov     governor.Lazy
+	err     error
 }
 
 // Construct a new LinksTo iterator around a direction and a subiterator of
@@ -264,6 +295,9 @@ func (it *linksToContains) String() string {
 // If it checks in the right direction for the subiterator, it is a valid link
 // for the LinksTo.
 func (it *linksToContains) Contains(ctx context.Context, val refs.Ref) bool {
+	if it.err = it.gov.From(ctx).Step(1); it.err != nil {
+		return false
+	}
 	node := it.qs.QuadDirection(val, it.dir)
 	if it.primary.Contains(ctx, node) {
 		it.result = val
@@ -273,6 +307,9 @@ func (it *linksToContains) Contains(ctx context.Context, val refs.Ref) bool {
 }
 
 func (it *linksToContains) Err() error {
+	if it.err != nil {
+		return it.err
+	}
 	return it.primary.Err()
 }
 
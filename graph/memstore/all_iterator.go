@@ -125,6 +125,36 @@ func (it *allIteratorNext) Next(ctx context.Context) bool {
 	return false
 }
 
+// NextBatch returns multiple values at once by scanning the slice of primitives directly.
+func (it *allIteratorNext) NextBatch(ctx context.Context, dst []graph.Ref) int {
+	n := 0
+	if len(dst) == 0 || !it.Next(ctx) {
+		return 0
+	}
+	dst[n] = it.Result()
+	n++
+	all := it.all
+	for it.i++; n < len(dst) && it.i < len(all); it.i++ {
+		p := all[it.i]
+		if p.ID > it.maxid {
+			it.done = true
+			return n
+		}
+		if it.ok(p) {
+			it.cur = p
+			dst[n] = it.Result()
+			n++
+		}
+	}
+	if it.i >= len(all) {
+		it.done = true
+	} else {
+		// Next increments the index before reading
+		it.i--
+	}
+	return n
+}
+
 func (it *allIteratorNext) Result() graph.Ref {
 	if it.cur == nil {
 		return nil
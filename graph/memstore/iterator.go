@@ -125,6 +125,36 @@ func (it *iteratorNext) Next(ctx context.Context) bool {
 	}
 }
 
+// NextBatch returns multiple values at once by copying them from data pages of the tree.
+// Next is only used to move between pages or to resume the enumeration when the tree was changed.
+func (it *iteratorNext) NextBatch(ctx context.Context, dst []graph.Ref) int {
+	n := 0
+	for n < len(dst) {
+		e := it.iter
+		if e == nil || e.err != nil || e.ver != e.t.ver || e.q == nil || e.i >= e.q.c {
+			if !it.Next(ctx) {
+				break
+			}
+			dst[n] = qprim{p: it.cur}
+			n++
+			continue
+		}
+		page := e.q.d[e.i:e.q.c]
+		if len(page) > len(dst)-n {
+			page = page[:len(dst)-n]
+		}
+		for _, de := range page {
+			dst[n] = qprim{p: de.v}
+			n++
+		}
+		last := page[len(page)-1]
+		e.i += len(page)
+		e.k, e.hit = last.k, false
+		it.cur = last.v
+	}
+	return n
+}
+
 func (it *iteratorNext) Err() error {
 	return it.err
 }
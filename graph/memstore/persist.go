@@ -0,0 +1,532 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/proto"
+	"github.com/cayleygraph/quad/pquads"
+)
+
+const (
+	walFile      = "memstore.wal"
+	snapshotFile = "memstore.pq"
+)
+
+const (
+	// OptSync sets the fsync policy of the write-ahead log.
+	// Possible values are "always" (default), "interval" and "never".
+	OptSync = "sync"
+	// OptSyncInterval sets the period of background syncs for "interval" sync policy.
+	OptSyncInterval = "sync_interval"
+	// OptSnapshotEvery sets the number of logged deltas after which a new snapshot is written.
+	OptSnapshotEvery = "snapshot_every"
+)
+
+const (
+	defaultSyncInterval  = time.Second
+	defaultSnapshotEvery = 100000
+
+	walVersion = 1
+	walMaxSize = 64 * 1024 * 1024
+)
+
+var walMagic = [4]byte{0, 'm', 'w', 0}
+
+var errWALCorrupted = errors.New("memstore: corrupted write-ahead log")
+
+// SyncPolicy controls when the write-ahead log is flushed to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs the log after each applied batch of deltas.
+	SyncAlways = SyncPolicy(iota)
+	// SyncInterval syncs the log periodically in background.
+	SyncInterval
+	// SyncNever leaves syncing to the operating system.
+	SyncNever
+)
+
+func parseSyncPolicy(s string) (SyncPolicy, error) {
+	switch s {
+	case "", "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	}
+	return 0, fmt.Errorf("memstore: unknown sync policy: %q", s)
+}
+
+// persistOptions is a set of persistence options parsed from graph.Options.
+type persistOptions struct {
+	sync          SyncPolicy
+	syncInterval  time.Duration
+	snapshotEvery int
+}
+
+func parsePersistOptions(opt graph.Options) (*persistOptions, error) {
+	po := &persistOptions{}
+	s, err := opt.StringKey(OptSync, "")
+	if err != nil {
+		return nil, err
+	}
+	po.sync, err = parseSyncPolicy(s)
+	if err != nil {
+		return nil, err
+	}
+	s, err = opt.StringKey(OptSyncInterval, "")
+	if err != nil {
+		return nil, err
+	}
+	po.syncInterval = defaultSyncInterval
+	if s != "" {
+		po.syncInterval, err = time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		} else if po.syncInterval <= 0 {
+			return nil, fmt.Errorf("memstore: invalid sync interval: %v", po.syncInterval)
+		}
+	}
+	po.snapshotEvery, err = opt.IntKey(OptSnapshotEvery, defaultSnapshotEvery)
+	if err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// Init creates an empty persistent memstore in the directory at a given path.
+func Init(path string, opt graph.Options) error {
+	if path == "" {
+		return errors.New("memstore: path to the database must be specified")
+	}
+	if _, err := parsePersistOptions(opt); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return err
+	}
+	for _, name := range []string{walFile, snapshotFile} {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			return graph.ErrDatabaseExists
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(path, walFile), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err = writeWALHeader(f); err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Open loads a persistent memstore from the directory at a given path.
+// It reads the last snapshot and replays the write-ahead log on top of it.
+//
+// For compatibility with older versions, graph.ErrQuadStoreNotPersistent is
+// returned if the path points to a regular file, so it can be loaded as a quad file instead.
+func Open(path string, opt graph.Options) (*QuadStore, error) {
+	po, err := parsePersistOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, graph.ErrNotInitialized
+	} else if err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, graph.ErrQuadStoreNotPersistent
+	}
+	walPath := filepath.Join(path, walFile)
+	if _, err = os.Stat(walPath); os.IsNotExist(err) {
+		return nil, graph.ErrNotInitialized
+	} else if err != nil {
+		return nil, err
+	}
+	qs := newQuadStore()
+	start := time.Now()
+	if err = qs.loadSnapshot(filepath.Join(path, snapshotFile)); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(walPath, os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	n, off, err := qs.replayWAL(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// drop incomplete batches at the end of the log
+	if err = f.Truncate(off); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(off, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if clog.V(1) {
+		clog.Infof("memstore: loaded %d quads (%d deltas replayed) in %v", len(qs.quads), n, time.Since(start))
+	}
+	qs.wal = newWAL(path, f, po, n)
+	return qs, nil
+}
+
+// loadSnapshot loads all quads from pquads snapshot file, if it exists.
+func (qs *QuadStore) loadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := pquads.NewReader(bufio.NewReader(f), walMaxSize)
+	for {
+		q, err := r.ReadQuad()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("memstore: cannot read snapshot: %v", err)
+		}
+		qs.AddQuad(q)
+	}
+}
+
+// replayWAL applies all complete batches from the write-ahead log.
+// It returns the number of deltas applied and an offset of the end of the last complete batch.
+func (qs *QuadStore) replayWAL(f *os.File) (int, int64, error) {
+	r := &walReader{r: bufio.NewReader(f)}
+	if err := r.readHeader(); err != nil {
+		return 0, 0, err
+	}
+	var (
+		n     int
+		last  = r.off
+		batch []graph.Delta
+		d     proto.LogDelta
+	)
+	for {
+		err := r.readMsg(&d)
+		if err == io.EOF || err == io.ErrUnexpectedEOF || err == errWALCorrupted {
+			if err != io.EOF || len(batch) != 0 {
+				clog.Warningf("memstore: dropping incomplete batch at the end of the log")
+			}
+			return n, last, nil
+		} else if err != nil {
+			return n, last, err
+		}
+		if d.Quad == nil {
+			// commit marker
+			if err = qs.applyDeltas(batch, graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true}); err != nil {
+				return n, last, err
+			}
+			n += len(batch)
+			last = r.off
+			batch = batch[:0]
+			continue
+		}
+		batch = append(batch, graph.Delta{
+			Quad:   d.Quad.ToNative(),
+			Action: graph.Procedure(d.Action),
+		})
+	}
+}
+
+func writeWALHeader(w io.Writer) error {
+	var buf [8]byte
+	copy(buf[:4], walMagic[:])
+	binary.LittleEndian.PutUint32(buf[4:], walVersion)
+	_, err := w.Write(buf[:])
+	return err
+}
+
+// walReader reads length-prefixed log records and tracks the offset of consumed bytes.
+type walReader struct {
+	r   *bufio.Reader
+	off int64
+	buf []byte
+}
+
+func (r *walReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.off++
+	}
+	return b, err
+}
+
+func (r *walReader) readHeader() error {
+	var buf [8]byte
+	n, err := io.ReadFull(r.r, buf[:])
+	r.off += int64(n)
+	if err != nil {
+		return fmt.Errorf("memstore: cannot read log header: %v", err)
+	} else if !bytes.Equal(buf[:4], walMagic[:]) {
+		return fmt.Errorf("memstore: not a write-ahead log file")
+	} else if vers := binary.LittleEndian.Uint32(buf[4:]); vers != walVersion {
+		return fmt.Errorf("memstore: unsupported log version: %d", vers)
+	}
+	return nil
+}
+
+func (r *walReader) readMsg(d *proto.LogDelta) error {
+	sz, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return io.EOF
+	} else if err != nil {
+		return errWALCorrupted
+	}
+	if sz > walMaxSize {
+		return errWALCorrupted
+	}
+	if uint64(cap(r.buf)) < sz {
+		r.buf = make([]byte, sz)
+	}
+	buf := r.buf[:sz]
+	n, err := io.ReadFull(r.r, buf)
+	r.off += int64(n)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	d.Reset()
+	if err = d.Unmarshal(buf); err != nil {
+		return errWALCorrupted
+	}
+	return nil
+}
+
+// wal is a write-ahead log of a persistent memstore.
+type wal struct {
+	mu     sync.Mutex
+	dir    string
+	f      *os.File
+	w      *bufio.Writer
+	opt    persistOptions
+	seq    uint64
+	logged int // number of deltas logged since the last snapshot
+	dirty  bool
+	buf    []byte
+	err    error
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newWAL(dir string, f *os.File, opt *persistOptions, logged int) *wal {
+	l := &wal{
+		dir: dir, f: f,
+		w:      bufio.NewWriter(f),
+		opt:    *opt,
+		logged: logged,
+	}
+	if l.opt.sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop()
+	}
+	return l
+}
+
+func (l *wal) syncLoop() {
+	defer close(l.done)
+	t := time.NewTicker(l.opt.syncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+		}
+		l.mu.Lock()
+		if l.dirty && l.err == nil {
+			if err := l.f.Sync(); err != nil {
+				clog.Errorf("memstore: cannot sync the log: %v", err)
+			}
+			l.dirty = false
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (l *wal) writeMsg(d *proto.LogDelta) error {
+	sz := d.ProtoSize()
+	if cap(l.buf) < sz+binary.MaxVarintLen64 {
+		l.buf = make([]byte, sz+binary.MaxVarintLen64)
+	}
+	buf := l.buf[:cap(l.buf)]
+	n := binary.PutUvarint(buf, uint64(sz))
+	m, err := d.MarshalTo(buf[n:])
+	if err != nil {
+		return err
+	}
+	_, err = l.w.Write(buf[:n+m])
+	return err
+}
+
+// Append writes a batch of deltas to the log followed by a commit marker.
+// The batch is flushed to the file and synced according to the sync policy.
+func (l *wal) Append(deltas []graph.Delta) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.seq++
+	ts := time.Now().UnixNano()
+	for _, d := range deltas {
+		if err := l.writeMsg(&proto.LogDelta{
+			ID:        l.seq,
+			Quad:      pquads.MakeQuad(d.Quad),
+			Action:    int32(d.Action),
+			Timestamp: ts,
+		}); err != nil {
+			return l.fail(err)
+		}
+	}
+	if err := l.writeMsg(&proto.LogDelta{ID: l.seq, Timestamp: ts}); err != nil {
+		return l.fail(err)
+	}
+	if err := l.w.Flush(); err != nil {
+		return l.fail(err)
+	}
+	l.logged += len(deltas)
+	switch l.opt.sync {
+	case SyncAlways:
+		if err := l.f.Sync(); err != nil {
+			return l.fail(err)
+		}
+	case SyncInterval:
+		l.dirty = true
+	}
+	return nil
+}
+
+// fail marks the log as broken. A partially written batch will be dropped on the next open,
+// but we cannot guarantee it for any subsequent writes.
+func (l *wal) fail(err error) error {
+	l.err = fmt.Errorf("memstore: cannot write the log: %v", err)
+	return l.err
+}
+
+// NeedSnapshot checks if the log grew enough to write a new snapshot.
+func (l *wal) NeedSnapshot() bool {
+	return l.opt.snapshotEvery > 0 && l.logged >= l.opt.snapshotEvery
+}
+
+// Snapshot writes all quads from the store to a new snapshot file and truncates the log.
+func (l *wal) Snapshot(qs *QuadStore) error {
+	if l.err != nil {
+		return l.err
+	}
+	tmp := filepath.Join(l.dir, snapshotFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	w := pquads.NewWriter(bw, nil)
+	for _, p := range qs.cloneAll() {
+		if p.Quad.Zero() {
+			continue
+		}
+		if err = w.WriteQuad(qs.lookupQuadDirs(p.Quad)); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(l.dir, snapshotFile))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(l.dir)
+	// Log entries are now part of the snapshot. Even if we crash before truncating the log,
+	// replaying it again on top of the new snapshot will lead to the same state.
+	if err = l.f.Truncate(0); err != nil {
+		return l.fail(err)
+	} else if _, err = l.f.Seek(0, io.SeekStart); err != nil {
+		return l.fail(err)
+	}
+	l.w.Reset(l.f)
+	if err = writeWALHeader(l.w); err != nil {
+		return l.fail(err)
+	} else if err = l.w.Flush(); err != nil {
+		return l.fail(err)
+	} else if err = l.f.Sync(); err != nil {
+		return l.fail(err)
+	}
+	l.logged = 0
+	l.dirty = false
+	return nil
+}
+
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// Close flushes and syncs the log and closes the file.
+func (l *wal) Close() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.w.Flush()
+	if err2 := l.f.Sync(); err == nil {
+		err = err2
+	}
+	if err2 := l.f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/graphtest"
+	"github.com/cayleygraph/quad"
+)
+
+func makePersistent(t testing.TB, opt graph.Options) (string, func()) {
+	dir, err := ioutil.TempDir("", "cayley_test_memstore")
+	require.NoError(t, err)
+	err = Init(dir, opt)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMemstorePersistent(t *testing.T) {
+	graphtest.TestAll(t, func(t testing.TB) (graph.QuadStore, graph.Options, func()) {
+		opt := graph.Options{OptSync: "never"}
+		dir, closer := makePersistent(t, opt)
+		qs, err := Open(dir, opt)
+		if err != nil {
+			closer()
+			t.Fatal(err)
+		}
+		return qs, opt, func() {
+			qs.Close()
+			closer()
+		}
+	}, &graphtest.Config{
+		AlwaysRunIntegration: true,
+	})
+}
+
+func sortedQuads(t testing.TB, qs graph.QuadStore) []quad.Quad {
+	quads := graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator())
+	sort.Sort(quad.ByQuadString(quads))
+	return quads
+}
+
+func TestMemstoreReopen(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opt  graph.Options
+	}{
+		{name: "wal", opt: graph.Options{}},
+		{name: "snapshot", opt: graph.Options{OptSnapshotEvery: 3}},
+		{name: "interval", opt: graph.Options{OptSync: "interval", OptSyncInterval: "10ms"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir, closer := makePersistent(t, c.opt)
+			defer closer()
+
+			err := Init(dir, c.opt)
+			require.Equal(t, graph.ErrDatabaseExists, err)
+
+			qs, err := Open(dir, c.opt)
+			require.NoError(t, err)
+			w, err := graph.NewQuadWriter("single", qs, nil)
+			require.NoError(t, err)
+			require.NoError(t, w.AddQuadSet(simpleGraph))
+			require.NoError(t, w.RemoveQuad(simpleGraph[0]))
+			require.NoError(t, w.AddQuad(quad.MakeRaw("A", "follows", "E", "")))
+			exp := sortedQuads(t, qs)
+			require.NoError(t, qs.Close())
+
+			qs, err = Open(dir, c.opt)
+			require.NoError(t, err)
+			defer qs.Close()
+			require.Equal(t, exp, sortedQuads(t, qs))
+		})
+	}
+}
+
+func TestMemstoreTruncatedLog(t *testing.T) {
+	dir, closer := makePersistent(t, nil)
+	defer closer()
+
+	qs, err := Open(dir, nil)
+	require.NoError(t, err)
+	w, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuadSet(simpleGraph[:4]))
+	exp := sortedQuads(t, qs)
+	require.NoError(t, w.AddQuadSet(simpleGraph[4:]))
+	require.NoError(t, qs.Close())
+
+	// simulate a crash in the middle of the last batch
+	path := filepath.Join(dir, walFile)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, fi.Size()-5))
+
+	qs, err = Open(dir, nil)
+	require.NoError(t, err)
+	require.Equal(t, exp, sortedQuads(t, qs))
+
+	// log must accept new batches after the recovery
+	w, err = graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuad(simpleGraph[5]))
+	exp = sortedQuads(t, qs)
+	require.NoError(t, qs.Close())
+
+	qs, err = Open(dir, nil)
+	require.NoError(t, err)
+	defer qs.Close()
+	require.Equal(t, exp, sortedQuads(t, qs))
+}
+
+func TestMemstoreOpenFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "cayley_test_memstore")
+	require.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = graph.NewQuadStore(QuadStoreType, f.Name(), nil)
+	require.Equal(t, graph.ErrQuadStoreNotPersistent, err)
+}
@@ -16,10 +16,13 @@ package memstore
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/cayleygraph/cayley/clog"
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/graph/refs"
@@ -30,12 +33,15 @@ const QuadStoreType = "memstore"
 
 func init() {
 	graph.RegisterQuadStore(QuadStoreType, graph.QuadStoreRegistration{
-		NewFunc: func(string, graph.Options) (graph.QuadStore, error) {
-			return newQuadStore(), nil
+		NewFunc: func(path string, opt graph.Options) (graph.QuadStore, error) {
+			if path == "" {
+				return newQuadStore(), nil
+			}
+			return Open(path, opt)
 		},
 		UpgradeFunc:  nil,
-		InitFunc:     nil,
-		IsPersistent: false,
+		InitFunc:     Init,
+		IsPersistent: true,
 	})
 }
 
@@ -43,6 +49,21 @@ type bnode int64
 
 func (n bnode) Key() interface{} { return n }
 
+func init() {
+	// allow Sort and Materialize to spill results to disk; quads are not registered,
+	// since they point to primitives in memory
+	iterator.RegisterRefType(bnode(0), func(r refs.Ref) ([]byte, error) {
+		p := make([]byte, binary.MaxVarintLen64)
+		return p[:binary.PutVarint(p, int64(r.(bnode)))], nil
+	}, func(p []byte) (refs.Ref, error) {
+		v, n := binary.Varint(p)
+		if n <= 0 {
+			return nil, errors.New("invalid node id")
+		}
+		return bnode(v), nil
+	})
+}
+
 type qprim struct {
 	p *Primitive
 }
@@ -141,11 +162,16 @@ type QuadStore struct {
 	all     []*Primitive // might not be sorted by id
 	reading bool         // someone else might be reading "all" slice - next insert/delete should clone it
 	index   QuadDirectionIndex
-	horizon int64 // used only to assign ids to tx
+	horizon int64     // used only to assign ids to tx
+	wal     *wal      // write-ahead log; set only for persistent stores
+	pstats  quadStats // per-predicate stats
+	lstats  quadStats // per-label stats
 	// vip_index map[string]map[int64]map[string]map[int64]*b.Tree
 }
 
 // New creates a new in-memory quad store and loads provided quads.
+//
+// Use Open to create a quad store that persists changes on disk.
 func New(quads ...quad.Quad) *QuadStore {
 	qs := newQuadStore()
 	for _, q := range quads {
@@ -160,6 +186,9 @@ func newQuadStore() *QuadStore {
 		quads: make(map[internalQuad]int64),
 		prim:  make(map[int64]*Primitive),
 		index: NewQuadDirectionIndex(),
+
+		pstats: make(quadStats),
+		lstats: make(quadStats),
 	}
 }
 
@@ -205,6 +234,10 @@ func (qs *QuadStore) resolveVal(v quad.Value, add bool) (int64, bool) {
 				return id, ok
 			}
 			qs.appendPrimitive(&Primitive{ID: id, refs: 1})
+			if id > qs.last {
+				// make sure new primitives won't reuse this id
+				qs.last = id
+			}
 			return id, true
 		}
 	}
@@ -295,6 +328,7 @@ func (qs *QuadStore) AddQuad(q quad.Quad) (int64, bool) {
 	for _, t := range qs.indexesForQuad(p) {
 		t.Set(id, pr)
 	}
+	qs.updateStats(p, +1)
 	// TODO(barakmich): Add VIP indexing
 	return id, true
 }
@@ -309,12 +343,27 @@ func (qs *QuadStore) WriteQuad(q quad.Quad) error {
 
 // WriteQuads implements quad.Writer.
 func (qs *QuadStore) WriteQuads(buf []quad.Quad) (int, error) {
+	if qs.wal != nil {
+		return qs.writeQuadsLogged(buf)
+	}
 	for _, q := range buf {
 		qs.AddQuad(q)
 	}
 	return len(buf), nil
 }
 
+// writeQuadsLogged adds quads the same way as AddQuad does, but writes them to the log first.
+func (qs *QuadStore) writeQuadsLogged(buf []quad.Quad) (int, error) {
+	deltas := make([]graph.Delta, 0, len(buf))
+	for _, q := range buf {
+		deltas = append(deltas, graph.Delta{Quad: q, Action: graph.Add})
+	}
+	if err := qs.ApplyDeltas(deltas, graph.IgnoreOpts{IgnoreDup: true}); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
 func (qs *QuadStore) NewQuadWriter() (quad.WriteCloser, error) {
 	return &quadWriter{qs: qs}, nil
 }
@@ -324,15 +373,12 @@ type quadWriter struct {
 }
 
 func (w *quadWriter) WriteQuad(q quad.Quad) error {
-	w.qs.AddQuad(q)
-	return nil
+	_, err := w.qs.WriteQuads([]quad.Quad{q})
+	return err
 }
 
 func (w *quadWriter) WriteQuads(buf []quad.Quad) (int, error) {
-	for _, q := range buf {
-		w.qs.AddQuad(q)
-	}
-	return len(buf), nil
+	return w.qs.WriteQuads(buf)
 }
 
 func (w *quadWriter) Close() error {
@@ -369,6 +415,9 @@ func (qs *QuadStore) Delete(id int64) bool {
 		t.Delete(id)
 	}
 	delete(qs.quads, p.Quad)
+	if !p.Quad.Zero() {
+		qs.updateStats(p.Quad, -1)
+	}
 	// remove primitive
 	delete(qs.prim, id)
 	di := -1
@@ -403,6 +452,51 @@ func (qs *QuadStore) findQuad(q quad.Quad) (int64, internalQuad, bool) {
 }
 
 func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
+	if qs.wal == nil {
+		return qs.applyDeltas(deltas, ignoreOpts)
+	}
+	qs.wal.mu.Lock()
+	defer qs.wal.mu.Unlock()
+	if err := qs.precheckDeltas(deltas, ignoreOpts); err != nil {
+		return err
+	}
+	if err := qs.wal.Append(deltas); err != nil {
+		return err
+	}
+	// transaction was already checked, skip it
+	if err := qs.applyDeltas(deltas, graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true}); err != nil {
+		return err
+	}
+	if qs.wal.NeedSnapshot() {
+		if err := qs.wal.Snapshot(qs); err != nil {
+			clog.Errorf("memstore: cannot write snapshot: %v", err)
+		}
+	}
+	return nil
+}
+
+func (qs *QuadStore) applyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
+	if err := qs.precheckDeltas(deltas, ignoreOpts); err != nil {
+		return err
+	}
+	for _, d := range deltas {
+		switch d.Action {
+		case graph.Add:
+			qs.AddQuad(d.Quad)
+		case graph.Delete:
+			if id, _, ok := qs.findQuad(d.Quad); ok {
+				qs.Delete(id)
+			}
+		default:
+			// TODO: ideally we should rollback it
+			return &graph.DeltaError{Delta: d, Err: graph.ErrInvalidAction}
+		}
+	}
+	qs.horizon++
+	return nil
+}
+
+func (qs *QuadStore) precheckDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
 	// Precheck the whole transaction (if required)
 	if !ignoreOpts.IgnoreDup || !ignoreOpts.IgnoreMissing {
 		for _, d := range deltas {
@@ -424,21 +518,6 @@ func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOp
 			}
 		}
 	}
-
-	for _, d := range deltas {
-		switch d.Action {
-		case graph.Add:
-			qs.AddQuad(d.Quad)
-		case graph.Delete:
-			if id, _, ok := qs.findQuad(d.Quad); ok {
-				qs.Delete(id)
-			}
-		default:
-			// TODO: ideally we should rollback it
-			return &graph.DeltaError{Delta: d, Err: graph.ErrInvalidAction}
-		}
-	}
-	qs.horizon++
 	return nil
 }
 
@@ -561,4 +640,9 @@ func (qs *QuadStore) NodesAllIterator() iterator.Shape {
 	return qs.newAllIterator(true, qs.last)
 }
 
-func (qs *QuadStore) Close() error { return nil }
+func (qs *QuadStore) Close() error {
+	if qs.wal == nil {
+		return nil
+	}
+	return qs.wal.Close()
+}
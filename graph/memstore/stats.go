@@ -0,0 +1,121 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memstore
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley/graph"
+)
+
+var _ graph.StatsProvider = (*QuadStore)(nil)
+
+// valueStats tracks the number of quads and distinct subjects and objects
+// for a single predicate or label.
+type valueStats struct {
+	quads int64
+	subj  map[int64]int
+	obj   map[int64]int
+}
+
+func (s *valueStats) update(q internalQuad, delta int) {
+	s.quads += int64(delta)
+	incCount(s.subj, q.S, delta)
+	incCount(s.obj, q.O, delta)
+}
+
+func (s *valueStats) stats() graph.ValueStats {
+	if s == nil {
+		return graph.ValueStats{}
+	}
+	return graph.ValueStats{
+		Quads:    s.quads,
+		Subjects: int64(len(s.subj)),
+		Objects:  int64(len(s.obj)),
+	}
+}
+
+func incCount(m map[int64]int, id int64, delta int) {
+	if n := m[id] + delta; n > 0 {
+		m[id] = n
+	} else {
+		delete(m, id)
+	}
+}
+
+// quadStats maintains per-predicate and per-label statistics.
+type quadStats map[int64]*valueStats
+
+func (m quadStats) update(id int64, q internalQuad, delta int) {
+	if id == 0 {
+		return
+	}
+	s := m[id]
+	if s == nil {
+		if delta < 0 {
+			return
+		}
+		s = &valueStats{subj: make(map[int64]int), obj: make(map[int64]int)}
+		m[id] = s
+	}
+	s.update(q, delta)
+	if s.quads <= 0 {
+		delete(m, id)
+	}
+}
+
+func (qs *QuadStore) updateStats(q internalQuad, delta int) {
+	qs.pstats.update(q.P, q, delta)
+	qs.lstats.update(q.L, q, delta)
+}
+
+// PredicateStats implements graph.StatsProvider.
+func (qs *QuadStore) PredicateStats(ctx context.Context, pred graph.Ref) (graph.ValueStats, error) {
+	id, ok := asID(pred)
+	if !ok {
+		return graph.ValueStats{}, nil
+	}
+	return qs.pstats[id].stats(), nil
+}
+
+// LabelStats implements graph.StatsProvider.
+func (qs *QuadStore) LabelStats(ctx context.Context, label graph.Ref) (graph.ValueStats, error) {
+	id, ok := asID(label)
+	if !ok {
+		return graph.ValueStats{}, nil
+	}
+	return qs.lstats[id].stats(), nil
+}
+
+func (qs *QuadStore) namedStats(m quadStats) []graph.NamedStats {
+	var out []graph.NamedStats
+	for id, s := range m {
+		out = append(out, graph.NamedStats{
+			Value:      qs.lookupVal(id),
+			ValueStats: s.stats(),
+		})
+	}
+	return out
+}
+
+// StatsTable implements graph.StatsProvider.
+func (qs *QuadStore) StatsTable(ctx context.Context) (*graph.StatsTable, error) {
+	t := &graph.StatsTable{
+		Predicates: qs.namedStats(qs.pstats),
+		Labels:     qs.namedStats(qs.lstats),
+	}
+	t.Sort()
+	return t, nil
+}
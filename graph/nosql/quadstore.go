@@ -17,6 +17,7 @@ package nosql
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -127,6 +128,29 @@ type QuadHash [4]string
 func (QuadHash) IsNode() bool       { return false }
 func (v QuadHash) Key() interface{} { return v }
 
+func init() {
+	// allow Sort and Materialize to spill results to disk
+	iterator.RegisterRefType(NodeHash(""), func(r refs.Ref) ([]byte, error) {
+		return []byte(r.(NodeHash)), nil
+	}, func(p []byte) (refs.Ref, error) {
+		return NodeHash(p), nil
+	})
+	iterator.RegisterRefType(QuadHash{}, func(r refs.Ref) ([]byte, error) {
+		q := r.(QuadHash)
+		return json.Marshal(q[:])
+	}, func(p []byte) (refs.Ref, error) {
+		var q QuadHash
+		var arr []string
+		if err := json.Unmarshal(p, &arr); err != nil {
+			return nil, err
+		} else if len(arr) != len(q) {
+			return nil, fmt.Errorf("unexpected quad hash length: %d", len(arr))
+		}
+		copy(q[:], arr)
+		return q, nil
+	})
+}
+
 func (v QuadHash) Get(d quad.Direction) string {
 	var ind int
 	switch d {
@@ -0,0 +1,663 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+// Defines the PathSearch iterator. It takes a subiterator of source nodes, a
+// subiterator of target nodes and an optional subiterator of predicates, and
+// searches for chains of links connecting each source with the targets.
+//
+// Unlike Recursive, it returns the actual chain of nodes and predicates that
+// was found. The chain is attached to each result as a PathValue tag.
+//
+// Shortest path search is done with a bidirectional breadth-first search,
+// expanding the smaller of the two frontiers on each step. All paths search
+// first calculates distances to the targets, and then enumerates simple paths
+// from the source, pruning branches that cannot reach a target in time.
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+// Hop is a single step of a path. Pred is the predicate that links the Node
+// with the next hop of the path. It is nil for the last hop.
+type Hop struct {
+	Node quad.Value
+	Pred quad.Value
+}
+
+var _ quad.Value = PathValue(nil)
+
+// PathValue is an ordered list of hops from the source node to the target node.
+type PathValue []Hop
+
+// String returns a human-readable representation of the path.
+func (p PathValue) String() string {
+	var sb strings.Builder
+	for i, h := range p {
+		if i != 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(quad.StringOf(h.Node))
+		if h.Pred != nil {
+			sb.WriteString(" -")
+			sb.WriteString(quad.StringOf(h.Pred))
+			sb.WriteString("->")
+		}
+	}
+	return sb.String()
+}
+
+// Native returns the path itself.
+func (p PathValue) Native() interface{} { return p }
+
+// Nodes returns all nodes of the path, including the source and the target.
+func (p PathValue) Nodes() []quad.Value {
+	out := make([]quad.Value, 0, len(p))
+	for _, h := range p {
+		out = append(out, h.Node)
+	}
+	return out
+}
+
+// PathSearch iterator returns nodes from the target iterator that can be reached from
+// the nodes of the source iterator by following links with predicates from the
+// predicate iterator.
+type PathSearch struct {
+	qs       QuadStore
+	from     iterator.Shape
+	to       iterator.Shape
+	preds    iterator.Shape
+	maxDepth int
+	all      bool
+	reverse  bool
+	pathTags []string
+}
+
+// NewShortestPath creates an iterator that returns a single shortest path from each
+// source node to the closest target node.
+//
+// If preds is nil, links with any predicate are followed. Paths are limited to maxDepth
+// links. If 0 is passed, it will use the default value of 50 links; if -1 is passed, the
+// length of the path is not limited.
+func NewShortestPath(qs QuadStore, from, to, preds iterator.Shape, maxDepth int) *PathSearch {
+	return newPathSearch(qs, from, to, preds, maxDepth, false)
+}
+
+// NewAllPaths creates an iterator that returns all simple paths (without loops) from each
+// source node to any of the target nodes. Each path is returned as a separate result.
+//
+// Arguments are the same as for NewShortestPath. Note that the number of paths grows
+// exponentially with the maximal depth.
+func NewAllPaths(qs QuadStore, from, to, preds iterator.Shape, maxDepth int) *PathSearch {
+	return newPathSearch(qs, from, to, preds, maxDepth, true)
+}
+
+func newPathSearch(qs QuadStore, from, to, preds iterator.Shape, maxDepth int, all bool) *PathSearch {
+	if maxDepth == 0 {
+		maxDepth = iterator.DefaultMaxRecursiveSteps
+	}
+	return &PathSearch{
+		qs:       qs,
+		from:     from,
+		to:       to,
+		preds:    preds,
+		maxDepth: maxDepth,
+		all:      all,
+	}
+}
+
+// SetReverse makes the iterator follow links from the object to the subject.
+func (it *PathSearch) SetReverse(reverse bool) {
+	it.reverse = reverse
+}
+
+// AddPathTag adds a tag that will contain the PathValue of each result.
+func (it *PathSearch) AddPathTag(tag string) {
+	it.pathTags = append(it.pathTags, tag)
+}
+
+func (it *PathSearch) Iterate() iterator.Scanner {
+	return newPathSearchNext(it)
+}
+
+func (it *PathSearch) Lookup() iterator.Index {
+	return newCachedContains(newPathSearchNext(it))
+}
+
+// SubIterators returns the source, target and predicate iterators.
+func (it *PathSearch) SubIterators() []iterator.Shape {
+	if it.preds == nil {
+		return []iterator.Shape{it.from, it.to}
+	}
+	return []iterator.Shape{it.from, it.to, it.preds}
+}
+
+// Optimize optimizes all subiterators. If there are no sources or targets,
+// the iterator becomes Null.
+func (it *PathSearch) Optimize(ctx context.Context) (iterator.Shape, bool) {
+	if sub, ok := it.from.Optimize(ctx); ok {
+		it.from = sub
+	}
+	if sub, ok := it.to.Optimize(ctx); ok {
+		it.to = sub
+	}
+	if it.preds != nil {
+		if sub, ok := it.preds.Optimize(ctx); ok {
+			it.preds = sub
+		}
+	}
+	if iterator.IsNull(it.from) || iterator.IsNull(it.to) {
+		return iterator.NewNull(), true
+	}
+	return it, false
+}
+
+// Stats returns a rough estimation of the search cost. Each source node may require
+// a traversal of a large portion of the graph. The number of links per node is taken
+// from predicate statistics, if they are available.
+func (it *PathSearch) Stats(ctx context.Context) (iterator.Costs, error) {
+	fromStats, err := it.from.Stats(ctx)
+	toStats, err2 := it.to.Stats(ctx)
+	if err == nil {
+		err = err2
+	}
+	dir := quad.Subject
+	if it.reverse {
+		dir = quad.Object
+	}
+	fanout := int64(math.Ceil(predicateFanout(ctx, it.qs, it.preds, dir)))
+	size := fromStats.Size.Value
+	if it.all {
+		size *= fanout
+	}
+	nextCost := fromStats.NextCost + toStats.NextCost + fanout*int64(it.depthLimit())
+	return iterator.Costs{
+		NextCost:     nextCost,
+		ContainsCost: nextCost * (size/10 + 1),
+		Size: refs.Size{
+			Value: size,
+			Exact: false,
+		},
+	}, err
+}
+
+// depthLimit returns the maximal depth, or a guess for unlimited searches.
+func (it *PathSearch) depthLimit() int {
+	if it.maxDepth < 0 {
+		return iterator.DefaultMaxRecursiveSteps
+	}
+	return it.maxDepth
+}
+
+func (it *PathSearch) String() string {
+	if it.all {
+		return fmt.Sprintf("AllPaths(%d)", it.maxDepth)
+	}
+	return fmt.Sprintf("ShortestPath(%d)", it.maxDepth)
+}
+
+// refPath is a path in a form of refs. It has one predicate less than nodes.
+type refPath struct {
+	nodes []refs.Ref
+	preds []refs.Ref
+}
+
+func (p refPath) end() refs.Ref {
+	return p.nodes[len(p.nodes)-1]
+}
+
+// visit is a parent pointer left by the breadth-first search.
+type visit struct {
+	next refs.Ref // previous node for the forward search, next node for the backward search
+	pred refs.Ref
+	dist int
+}
+
+type pathSearchNext struct {
+	it   *PathSearch
+	from iterator.Scanner
+
+	started bool
+	// targets is a set of target nodes; targetList preserves their order
+	targets    map[interface{}]struct{}
+	targetList []refs.Ref
+	// preds is a set of allowed predicates, nil means any predicate
+	preds map[interface{}]struct{}
+
+	tags   map[string]refs.Ref
+	paths  []refPath
+	cur    refPath
+	result refs.Ref
+	err    error
+}
+
+func newPathSearchNext(it *PathSearch) *pathSearchNext {
+	return &pathSearchNext{
+		it:   it,
+		from: it.from.Iterate(),
+	}
+}
+
+func (it *pathSearchNext) TagResults(dst map[string]refs.Ref) {
+	for k, v := range it.tags {
+		dst[k] = v
+	}
+	if len(it.it.pathTags) == 0 {
+		return
+	}
+	p := refs.PreFetched(pathValue(it.it.qs, it.cur))
+	for _, tag := range it.it.pathTags {
+		dst[tag] = p
+	}
+}
+
+// pathValue converts a path to a PathValue.
+func pathValue(qs refs.Namer, p refPath) PathValue {
+	out := make(PathValue, len(p.nodes))
+	for i, n := range p.nodes {
+		out[i].Node = qs.NameOf(n)
+		if i < len(p.preds) {
+			out[i].Pred = qs.NameOf(p.preds[i])
+		}
+	}
+	return out
+}
+
+// collect reads all results of the shape into a set.
+func collect(ctx context.Context, s iterator.Shape) (map[interface{}]struct{}, []refs.Ref, error) {
+	sc := s.Iterate()
+	defer sc.Close()
+	set := make(map[interface{}]struct{})
+	var list []refs.Ref
+	for sc.Next(ctx) {
+		v := sc.Result()
+		k := refs.ToKey(v)
+		if _, ok := set[k]; ok {
+			continue
+		}
+		set[k] = struct{}{}
+		list = append(list, v)
+	}
+	return set, list, sc.Err()
+}
+
+func (it *pathSearchNext) start(ctx context.Context) error {
+	it.started = true
+	var err error
+	it.targets, it.targetList, err = collect(ctx, it.it.to)
+	if err != nil {
+		return err
+	}
+	if it.it.preds != nil {
+		it.preds, _, err = collect(ctx, it.it.preds)
+	}
+	return err
+}
+
+// links calls fn for each link from the node n in the search direction, or against it
+// if back is set. It stops when fn returns false.
+func (it *pathSearchNext) links(ctx context.Context, n refs.Ref, back bool, fn func(m, pred refs.Ref) bool) error {
+	return eachLink(ctx, it.it.qs, n, back != it.it.reverse, it.preds, func(_, m, pred refs.Ref) bool {
+		return fn(m, pred)
+	})
+}
+
+// eachLink calls fn for each quad that links the node n with other nodes, from the subject
+// to the object, or in the opposite direction if back is set. If preds is not nil, only
+// links with predicates from the set are followed. It stops when fn returns false.
+func eachLink(ctx context.Context, qs QuadIndexer, n refs.Ref, back bool, preds map[interface{}]struct{}, fn func(q, m, pred refs.Ref) bool) error {
+	from, to := quad.Subject, quad.Object
+	if back {
+		from, to = to, from
+	}
+	sc := qs.QuadIterator(from, n).Iterate()
+	defer sc.Close()
+	for sc.Next(ctx) {
+		q := sc.Result()
+		pred := qs.QuadDirection(q, quad.Predicate)
+		if preds != nil {
+			if _, ok := preds[refs.ToKey(pred)]; !ok {
+				continue
+			}
+		}
+		if !fn(q, qs.QuadDirection(q, to), pred) {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+func (it *pathSearchNext) withinDepth(n int) bool {
+	return it.it.maxDepth < 0 || n <= it.it.maxDepth
+}
+
+// shortest runs a bidirectional breadth-first search from the source to the targets.
+func (it *pathSearchNext) shortest(ctx context.Context, src refs.Ref) (refPath, bool, error) {
+	skey := refs.ToKey(src)
+	if _, ok := it.targets[skey]; ok {
+		return refPath{nodes: []refs.Ref{src}}, true, nil
+	}
+	fwd := map[interface{}]visit{skey: {}}
+	bwd := make(map[interface{}]visit, len(it.targetList))
+	for _, t := range it.targetList {
+		bwd[refs.ToKey(t)] = visit{}
+	}
+	ffront, bfront := []refs.Ref{src}, it.targetList
+	fdepth, bdepth := 0, 0
+	var (
+		meet     refs.Ref
+		meetDist = -1
+	)
+	for len(ffront) != 0 && len(bfront) != 0 && it.withinDepth(fdepth+bdepth+1) {
+		if err := ctx.Err(); err != nil {
+			return refPath{}, false, err
+		}
+		back := len(bfront) < len(ffront)
+		front, seen, other := ffront, fwd, bwd
+		if back {
+			front, seen, other = bfront, bwd, fwd
+		}
+		var next []refs.Ref
+		for _, n := range front {
+			d := seen[refs.ToKey(n)].dist + 1
+			err := it.links(ctx, n, back, func(m, pred refs.Ref) bool {
+				k := refs.ToKey(m)
+				if _, ok := seen[k]; ok {
+					return true
+				}
+				seen[k] = visit{next: n, pred: pred, dist: d}
+				next = append(next, m)
+				// finish the current level to find the closest meeting point
+				if o, ok := other[k]; ok && (meetDist < 0 || d+o.dist < meetDist) {
+					meet, meetDist = m, d+o.dist
+				}
+				return true
+			})
+			if err != nil {
+				return refPath{}, false, err
+			}
+		}
+		if back {
+			bfront, bdepth = next, bdepth+1
+		} else {
+			ffront, fdepth = next, fdepth+1
+		}
+		if meet != nil {
+			break
+		}
+	}
+	if meet == nil {
+		return refPath{}, false, nil
+	}
+	// walk back to the source, and then forward to the target
+	var p refPath
+	for n := meet; n != nil; {
+		v := fwd[refs.ToKey(n)]
+		p.nodes = append(p.nodes, n)
+		if v.next != nil {
+			p.preds = append(p.preds, v.pred)
+		}
+		n = v.next
+	}
+	reverseRefs(p.nodes)
+	reverseRefs(p.preds)
+	for n := meet; ; {
+		v := bwd[refs.ToKey(n)]
+		if v.next == nil {
+			break
+		}
+		p.nodes = append(p.nodes, v.next)
+		p.preds = append(p.preds, v.pred)
+		n = v.next
+	}
+	return p, true, nil
+}
+
+func reverseRefs(s []refs.Ref) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// allPaths enumerates all simple paths from the source to the targets.
+func (it *pathSearchNext) allPaths(ctx context.Context, src refs.Ref) ([]refPath, error) {
+	// distances from each node to the closest target
+	dist := make(map[interface{}]int, len(it.targetList))
+	for _, t := range it.targetList {
+		dist[refs.ToKey(t)] = 0
+	}
+	front := it.targetList
+	for d := 1; len(front) != 0 && it.withinDepth(d); d++ {
+		var next []refs.Ref
+		for _, n := range front {
+			err := it.links(ctx, n, true, func(m, _ refs.Ref) bool {
+				if k := refs.ToKey(m); !hasKey(dist, k) {
+					dist[k] = d
+					next = append(next, m)
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		front = next
+	}
+	if !hasKey(dist, refs.ToKey(src)) {
+		return nil, nil
+	}
+	var (
+		out    []refPath
+		cur    refPath
+		onPath = make(map[interface{}]struct{})
+	)
+	var walk func(n refs.Ref) error
+	walk = func(n refs.Ref) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		k := refs.ToKey(n)
+		onPath[k] = struct{}{}
+		cur.nodes = append(cur.nodes, n)
+		defer func() {
+			delete(onPath, k)
+			cur.nodes = cur.nodes[:len(cur.nodes)-1]
+		}()
+		if _, ok := it.targets[k]; ok {
+			out = append(out, refPath{
+				nodes: append([]refs.Ref{}, cur.nodes...),
+				preds: append([]refs.Ref{}, cur.preds...),
+			})
+		}
+		depth := len(cur.preds) + 1
+		var links []refPath
+		err := it.links(ctx, n, false, func(m, pred refs.Ref) bool {
+			mk := refs.ToKey(m)
+			if _, ok := onPath[mk]; ok {
+				return true
+			}
+			if d, ok := dist[mk]; !ok || !it.withinDepth(depth+d) {
+				return true
+			}
+			links = append(links, refPath{nodes: []refs.Ref{m}, preds: []refs.Ref{pred}})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		// links are collected first to avoid keeping quad iterators open during the recursion
+		for _, l := range links {
+			cur.preds = append(cur.preds, l.preds[0])
+			err := walk(l.nodes[0])
+			cur.preds = cur.preds[:len(cur.preds)-1]
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(src); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func hasKey(m map[interface{}]int, k interface{}) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func (it *pathSearchNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		if it.err = it.start(ctx); it.err != nil {
+			return false
+		}
+	}
+	for len(it.paths) == 0 {
+		if !it.from.Next(ctx) {
+			it.err = it.from.Err()
+			return false
+		}
+		src := it.from.Result()
+		if it.it.all {
+			it.paths, it.err = it.allPaths(ctx, src)
+		} else {
+			var p refPath
+			var ok bool
+			p, ok, it.err = it.shortest(ctx, src)
+			if ok {
+				it.paths = []refPath{p}
+			}
+		}
+		if it.err != nil {
+			return false
+		}
+		it.tags = make(map[string]refs.Ref)
+		it.from.TagResults(it.tags)
+	}
+	it.cur, it.paths = it.paths[0], it.paths[1:]
+	it.result = it.cur.end()
+	return true
+}
+
+func (it *pathSearchNext) Err() error {
+	return it.err
+}
+
+func (it *pathSearchNext) Result() refs.Ref {
+	return it.result
+}
+
+// NextPath returns other tag combinations of the source node.
+func (it *pathSearchNext) NextPath(ctx context.Context) bool {
+	if !it.from.NextPath(ctx) {
+		it.err = it.from.Err()
+		return false
+	}
+	it.tags = make(map[string]refs.Ref)
+	it.from.TagResults(it.tags)
+	return true
+}
+
+func (it *pathSearchNext) Close() error {
+	return it.from.Close()
+}
+
+func (it *pathSearchNext) String() string {
+	return "PathSearchNext"
+}
+
+// cachedContains reads all results of the scanner on the first Contains call and
+// caches them by the result value, together with all their tags.
+type cachedContains struct {
+	next    iterator.Scanner
+	done    bool
+	results map[interface{}][]map[string]refs.Ref
+	cur     []map[string]refs.Ref
+	result  refs.Ref
+}
+
+func newCachedContains(next iterator.Scanner) *cachedContains {
+	return &cachedContains{
+		next:    next,
+		results: make(map[interface{}][]map[string]refs.Ref),
+	}
+}
+
+func (it *cachedContains) TagResults(dst map[string]refs.Ref) {
+	if len(it.cur) == 0 {
+		return
+	}
+	for k, v := range it.cur[0] {
+		dst[k] = v
+	}
+}
+
+func (it *cachedContains) Err() error {
+	return it.next.Err()
+}
+
+func (it *cachedContains) Result() refs.Ref {
+	return it.result
+}
+
+func (it *cachedContains) Contains(ctx context.Context, val refs.Ref) bool {
+	if !it.done {
+		for it.next.Next(ctx) {
+			k := refs.ToKey(it.next.Result())
+			for {
+				tags := make(map[string]refs.Ref)
+				it.next.TagResults(tags)
+				it.results[k] = append(it.results[k], tags)
+				if !it.next.NextPath(ctx) {
+					break
+				}
+			}
+		}
+		it.done = true
+	}
+	it.cur = it.results[refs.ToKey(val)]
+	if len(it.cur) == 0 {
+		return false
+	}
+	it.result = val
+	return true
+}
+
+func (it *cachedContains) NextPath(ctx context.Context) bool {
+	if len(it.cur) <= 1 {
+		return false
+	}
+	it.cur = it.cur[1:]
+	return true
+}
+
+func (it *cachedContains) Close() error {
+	return it.next.Close()
+}
+
+func (it *cachedContains) String() string {
+	return "CachedContains(" + it.next.String() + ")"
+}
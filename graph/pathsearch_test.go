@@ -0,0 +1,129 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+func pathStore() *memstore.QuadStore {
+	// a -> b -> c -> d
+	// a -> x -> d
+	// d -> a (loop)
+	return memstore.New(
+		quad.MakeIRI("a", "p", "b", ""),
+		quad.MakeIRI("b", "p", "c", ""),
+		quad.MakeIRI("c", "p", "d", ""),
+		quad.MakeIRI("a", "q", "x", ""),
+		quad.MakeIRI("x", "p", "d", ""),
+		quad.MakeIRI("d", "p", "a", ""),
+	)
+}
+
+func fixedOf(qs graph.QuadStore, vals ...string) *iterator.Fixed {
+	it := iterator.NewFixed()
+	for _, v := range vals {
+		it.Add(qs.ValueOf(quad.IRI(v)))
+	}
+	return it
+}
+
+func collectPaths(t testing.TB, qs graph.QuadStore, it *graph.PathSearch) []string {
+	ctx := context.TODO()
+	it.AddPathTag("path")
+	sc := it.Iterate()
+	defer sc.Close()
+	var out []string
+	for sc.Next(ctx) {
+		tags := make(map[string]refs.Ref)
+		sc.TagResults(tags)
+		p := qs.NameOf(tags["path"]).(graph.PathValue)
+		require.Equal(t, qs.NameOf(sc.Result()), p[len(p)-1].Node)
+		out = append(out, p.String())
+	}
+	require.NoError(t, sc.Err())
+	return out
+}
+
+func TestShortestPath(t *testing.T) {
+	qs := pathStore()
+	got := collectPaths(t, qs, graph.NewShortestPath(qs, fixedOf(qs, "a", "b"), fixedOf(qs, "d"), nil, 0))
+	require.Equal(t, []string{
+		"<a> -<q>-> <x> -<p>-> <d>",
+		"<b> -<p>-> <c> -<p>-> <d>",
+	}, got)
+
+	// only follow "p"
+	got = collectPaths(t, qs, graph.NewShortestPath(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), fixedOf(qs, "p"), 0))
+	require.Equal(t, []string{"<a> -<p>-> <b> -<p>-> <c> -<p>-> <d>"}, got)
+
+	// depth limit
+	got = collectPaths(t, qs, graph.NewShortestPath(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), fixedOf(qs, "p"), 2))
+	require.Empty(t, got)
+
+	// the source is the target
+	got = collectPaths(t, qs, graph.NewShortestPath(qs, fixedOf(qs, "a"), fixedOf(qs, "a", "d"), nil, 0))
+	require.Equal(t, []string{"<a>"}, got)
+
+	it := graph.NewShortestPath(qs, fixedOf(qs, "d"), fixedOf(qs, "a"), nil, 0)
+	it.SetReverse(true)
+	got = collectPaths(t, qs, it)
+	require.Equal(t, []string{"<d> -<p>-> <x> -<q>-> <a>"}, got)
+}
+
+func TestAllPaths(t *testing.T) {
+	qs := pathStore()
+	got := collectPaths(t, qs, graph.NewAllPaths(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), nil, 0))
+	require.ElementsMatch(t, []string{
+		"<a> -<p>-> <b> -<p>-> <c> -<p>-> <d>",
+		"<a> -<q>-> <x> -<p>-> <d>",
+	}, got)
+
+	got = collectPaths(t, qs, graph.NewAllPaths(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), nil, 2))
+	require.Equal(t, []string{"<a> -<q>-> <x> -<p>-> <d>"}, got)
+
+	// contains
+	ctx := context.TODO()
+	it := graph.NewAllPaths(qs, fixedOf(qs, "a"), fixedOf(qs, "c", "d"), nil, 0).Lookup()
+	require.True(t, it.Contains(ctx, qs.ValueOf(quad.IRI("d"))))
+	require.True(t, it.NextPath(ctx))
+	require.False(t, it.NextPath(ctx))
+	require.False(t, it.Contains(ctx, qs.ValueOf(quad.IRI("b"))))
+}
+
+func TestPathSearchStats(t *testing.T) {
+	ctx := context.TODO()
+	qs := pathStore()
+
+	// each node has a single "p" link
+	st, err := graph.NewAllPaths(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), fixedOf(qs, "p"), 3).Stats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), st.Size.Value)
+
+	// no statistics for an arbitrary set of predicates
+	def, err := graph.NewAllPaths(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), nil, 3).Stats(ctx)
+	require.NoError(t, err)
+	require.True(t, def.Size.Value > st.Size.Value)
+	require.True(t, def.NextCost > st.NextCost)
+}
@@ -156,3 +156,33 @@ var (
 	ErrDatabaseExists = errors.New("quadstore: cannot init; database already exists")
 	ErrNotInitialized = errors.New("quadstore: not initialized")
 )
+
+// HasQuad checks if a quad is stored in the quad store.
+func HasQuad(ctx context.Context, qs QuadStore, q quad.Quad) (bool, error) {
+	var ids [4]Ref
+	for i, d := range quad.Directions {
+		v := q.Get(d)
+		if v == nil {
+			continue
+		}
+		if ids[i] = qs.ValueOf(v); ids[i] == nil {
+			return false, nil
+		}
+	}
+	it := qs.QuadIterator(quad.Subject, ids[0]).Iterate()
+	defer it.Close()
+	for it.Next(ctx) {
+		ref := it.Result()
+		found := true
+		for i, d := range quad.Directions[1:] {
+			if refs.ToKey(qs.QuadDirection(ref, d)) != refs.ToKey(ids[i+1]) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, it.Err()
+}
@@ -0,0 +1,302 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rdfpatch implements reading and writing of RDF Patch documents
+// (https://afs.github.io/rdf-patch/), which describe a sequence of changes to a graph.
+//
+// Each change is either an addition (A) or a deletion (D) of a single quad.
+// Changes are grouped into transactions with TX and TC (commit) or TA (abort) rows.
+// Quads are written in N-Quads syntax.
+package rdfpatch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/nquads"
+)
+
+const (
+	// ContentType is a MIME type of RDF Patch documents.
+	ContentType = "application/rdf-patch"
+	// Ext is a file extension of RDF Patch documents.
+	Ext = ".rdfp"
+)
+
+// Reader reads transactions from an RDF Patch document.
+type Reader struct {
+	r      *bufio.Reader
+	line   int
+	inTx   bool
+	header map[string]quad.Value
+}
+
+// NewReader returns an RDF Patch decoder that takes its input from the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), header: make(map[string]quad.Value)}
+}
+
+// Header returns all header rows that were read so far.
+func (r *Reader) Header() map[string]quad.Value {
+	return r.header
+}
+
+func (r *Reader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("rdfpatch: line %d: "+format, append([]interface{}{r.line}, args...)...)
+}
+
+// readRow returns the code and the rest of the next row, skipping empty lines and comments.
+func (r *Reader) readRow() (string, string, error) {
+	for {
+		line, err := r.r.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		} else if err != nil {
+			return "", "", err
+		}
+		r.line++
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			return line, "", nil
+		}
+		return line[:i], strings.TrimSpace(line[i:]), nil
+	}
+}
+
+// parseValue parses a single value written in N-Quads syntax.
+func parseValue(s string) (quad.Value, error) {
+	q, err := nquads.Parse("<_> <_> " + s + " .")
+	if err != nil {
+		return nil, err
+	}
+	return q.Object, nil
+}
+
+// ReadTransaction returns the next transaction from the patch, or io.EOF if there are no more transactions.
+//
+// Changes that are not enclosed in TX and TC rows are grouped into a single transaction
+// that ends at the next transaction boundary. Aborted transactions are skipped.
+func (r *Reader) ReadTransaction() (*graph.Transaction, error) {
+	var tx *graph.Transaction
+	for {
+		code, rest, err := r.readRow()
+		if err == io.EOF {
+			if r.inTx {
+				return nil, r.errorf("unexpected end of patch in transaction")
+			} else if tx != nil {
+				return tx, nil
+			}
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+		if rest != "" && rest != "." && strings.HasPrefix(code, "T") {
+			return nil, r.errorf("unexpected data after %s", code)
+		}
+		switch code {
+		case "H":
+			i := strings.IndexAny(rest, " \t")
+			if i < 0 {
+				return nil, r.errorf("invalid header")
+			}
+			v, err := parseValue(strings.TrimSuffix(strings.TrimSpace(rest[i:]), "."))
+			if err != nil {
+				return nil, r.errorf("invalid header value: %v", err)
+			}
+			r.header[rest[:i]] = v
+		case "TX":
+			if r.inTx {
+				return nil, r.errorf("nested transaction")
+			}
+			r.inTx = true
+			if tx != nil {
+				// return changes made before the transaction
+				return tx, nil
+			}
+		case "TC":
+			if !r.inTx {
+				return nil, r.errorf("commit outside of transaction")
+			}
+			r.inTx = false
+			if tx != nil {
+				return tx, nil
+			}
+		case "TA":
+			if !r.inTx {
+				return nil, r.errorf("abort outside of transaction")
+			}
+			r.inTx = false
+			tx = nil
+		case "A", "D":
+			q, err := nquads.Parse(rest)
+			if err != nil {
+				return nil, r.errorf("%v", err)
+			} else if !q.IsValid() {
+				return nil, r.errorf("invalid quad: %q", rest)
+			}
+			if tx == nil {
+				tx = graph.NewTransaction()
+			}
+			if code == "A" {
+				tx.AddQuad(q)
+			} else {
+				tx.RemoveQuad(q)
+			}
+		case "PA", "PD":
+			// prefix changes are not tracked by the graph
+		default:
+			return nil, r.errorf("unknown row: %q", code)
+		}
+	}
+}
+
+// Apply reads all transactions from the patch and applies them to the quad writer one by one.
+// It returns the number of changes that were applied.
+func Apply(qw graph.QuadWriter, r *Reader) (int, error) {
+	n := 0
+	for {
+		tx, err := r.ReadTransaction()
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+		if len(tx.Deltas) == 0 {
+			continue
+		}
+		if err = qw.ApplyTransaction(tx); err != nil {
+			return n, err
+		}
+		n += len(tx.Deltas)
+	}
+}
+
+// Writer writes transactions to an RDF Patch document.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewWriter returns an RDF Patch encoder that writes its output to the provided io.Writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) write(s ...string) {
+	for _, s := range s {
+		if w.err != nil {
+			return
+		}
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+// WriteHeader writes a header row. Headers must be written before any transactions.
+func (w *Writer) WriteHeader(key string, v quad.Value) error {
+	w.write("H ", key, " ", v.String(), " .\n")
+	return w.err
+}
+
+// WriteDeltas writes a set of changes as a single transaction.
+func (w *Writer) WriteDeltas(deltas []graph.Delta) error {
+	if len(deltas) == 0 {
+		return w.err
+	}
+	w.write("TX .\n")
+	for _, d := range deltas {
+		switch d.Action {
+		case graph.Add:
+			w.write("A ")
+		case graph.Delete:
+			w.write("D ")
+		default:
+			return fmt.Errorf("rdfpatch: unknown action: %v", d.Action)
+		}
+		w.write(d.Quad.NQuad(), "\n")
+	}
+	w.write("TC .\n")
+	return w.err
+}
+
+// WriteTransaction writes all changes of the transaction.
+func (w *Writer) WriteTransaction(tx *graph.Transaction) error {
+	return w.WriteDeltas(tx.Deltas)
+}
+
+// Close flushes all the data to the underlying writer. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// Diff returns a transaction that changes the old set of quads to the new one.
+// Deletions are ordered as in the old set and go first, followed by additions ordered as in the new set.
+//
+// Both sets of quads are loaded into memory.
+func Diff(old, new quad.Reader) (*graph.Transaction, error) {
+	var oldList []quad.Quad
+	oldSet := make(map[string]struct{})
+	for {
+		q, err := old.ReadQuad()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		k := q.NQuad()
+		if _, ok := oldSet[k]; ok {
+			continue
+		}
+		oldSet[k] = struct{}{}
+		oldList = append(oldList, q)
+	}
+	var added []quad.Quad
+	newSet := make(map[string]struct{})
+	for {
+		q, err := new.ReadQuad()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		k := q.NQuad()
+		if _, ok := newSet[k]; ok {
+			continue
+		}
+		newSet[k] = struct{}{}
+		if _, ok := oldSet[k]; !ok {
+			added = append(added, q)
+		}
+	}
+	tx := graph.NewTransaction()
+	for _, q := range oldList {
+		if _, ok := newSet[q.NQuad()]; !ok {
+			tx.RemoveQuad(q)
+		}
+	}
+	for _, q := range added {
+		tx.AddQuad(q)
+	}
+	return tx, nil
+}
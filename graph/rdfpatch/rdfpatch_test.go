@@ -0,0 +1,155 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdfpatch_test
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/graphtest"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/graph/rdfpatch"
+	"github.com/cayleygraph/cayley/writer"
+	"github.com/cayleygraph/quad"
+)
+
+var (
+	qA = quad.MakeIRI("a", "follows", "b", "")
+	qB = quad.MakeIRI("b", "follows", "c", "")
+	qC = quad.Make(quad.IRI("c"), quad.IRI("name"), "C", quad.IRI("g"))
+)
+
+func readAll(t testing.TB, r *rdfpatch.Reader) [][]graph.Delta {
+	var out [][]graph.Delta
+	for {
+		tx, err := r.ReadTransaction()
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+		out = append(out, tx.Deltas)
+	}
+}
+
+func TestReader(t *testing.T) {
+	const patch = `H id <uuid:0686c69d-8f89-4496-acb5-744f0157a8db> .
+# comment
+PA "ex" "http://example.org/" .
+A <a> <follows> <b> .
+TX .
+A <b> <follows> <c> .
+D <a> <follows> <b> .
+TC .
+TX .
+A <x> <y> <z> .
+TA .
+TX
+A <c> <name> "C" <g> .
+TC
+`
+	r := rdfpatch.NewReader(strings.NewReader(patch))
+	got := readAll(t, r)
+	require.Equal(t, [][]graph.Delta{
+		{{Quad: qA, Action: graph.Add}},
+		{{Quad: qB, Action: graph.Add}, {Quad: qA, Action: graph.Delete}},
+		{{Quad: qC, Action: graph.Add}},
+	}, got)
+	require.Equal(t, quad.IRI("uuid:0686c69d-8f89-4496-acb5-744f0157a8db"), r.Header()["id"])
+
+	for _, bad := range []string{
+		"TX .\nA <a> <b> <c> .\n",
+		"TX .\nTX .\n",
+		"TC .\n",
+		"A <a> <b> .\n",
+		"X <a> <b> <c> .\n",
+	} {
+		_, err := rdfpatch.NewReader(strings.NewReader(bad)).ReadTransaction()
+		require.Error(t, err, "%q", bad)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := rdfpatch.NewWriter(&buf)
+	require.NoError(t, w.WriteHeader("id", quad.IRI("uuid:1")))
+	tx := graph.NewTransaction()
+	tx.AddQuad(qB)
+	tx.RemoveQuad(qA)
+	require.NoError(t, w.WriteTransaction(tx))
+	tx = graph.NewTransaction()
+	tx.AddQuad(qC)
+	require.NoError(t, w.WriteTransaction(tx))
+	require.NoError(t, w.WriteTransaction(graph.NewTransaction()))
+	require.NoError(t, w.Close())
+
+	require.Equal(t, `H id <uuid:1> .
+TX .
+A <b> <follows> <c> .
+D <a> <follows> <b> .
+TC .
+TX .
+A <c> <name> "C" <g> .
+TC .
+`, buf.String())
+
+	got := readAll(t, rdfpatch.NewReader(&buf))
+	require.Equal(t, [][]graph.Delta{
+		{{Quad: qB, Action: graph.Add}, {Quad: qA, Action: graph.Delete}},
+		{{Quad: qC, Action: graph.Add}},
+	}, got)
+}
+
+func TestDiffApply(t *testing.T) {
+	old := []quad.Quad{qA, qB, qA}
+	cur := []quad.Quad{qB, qC}
+	tx, err := rdfpatch.Diff(quad.NewReader(old), quad.NewReader(cur))
+	require.NoError(t, err)
+	require.Equal(t, []graph.Delta{
+		{Quad: qA, Action: graph.Delete},
+		{Quad: qC, Action: graph.Add},
+	}, tx.Deltas)
+
+	var buf bytes.Buffer
+	w := rdfpatch.NewWriter(&buf)
+	require.NoError(t, w.WriteTransaction(tx))
+	require.NoError(t, w.Close())
+
+	qs := memstore.New(qA, qB)
+	qw, err := writer.NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	n, err := rdfpatch.Apply(qw, rdfpatch.NewReader(&buf))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	got := graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator())
+	sort.Sort(quad.ByQuadString(got))
+	exp := append([]quad.Quad{}, cur...)
+	sort.Sort(quad.ByQuadString(exp))
+	require.Equal(t, exp, got)
+
+	// the transaction is applied atomically
+	n, err = rdfpatch.Apply(qw, rdfpatch.NewReader(strings.NewReader(
+		"TX .\nA <x> <y> <z> .\nD <a> <follows> <b> .\nTC .\n",
+	)))
+	require.Error(t, err)
+	require.Equal(t, 0, n)
+	require.Nil(t, qs.ValueOf(quad.IRI("x")))
+}
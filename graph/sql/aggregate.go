@@ -0,0 +1,211 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/cayley/query/shape"
+	"github.com/cayleygraph/quad"
+)
+
+const tagAgg = tagPref + "agg"
+
+// aggColumns is a set of query columns used to compute a single aggregate.
+//
+// Integer and float values are stored in separate columns, thus each aggregate is computed
+// for both columns and the results are merged when reading the row.
+type aggColumns struct {
+	Func iterator.AggregateFunc
+	As   string
+
+	Count      string // number of rows
+	Int        string // aggregate of integer values
+	Float      string // aggregate of float values
+	CountInt   string // number of integer values
+	CountFloat string // number of float values
+}
+
+func (a aggColumns) isFloat(col string) bool {
+	return col == a.Float || (col == a.Int && a.Func == iterator.AggregateAvg)
+}
+
+// value computes the aggregate from scanned columns.
+func (a aggColumns) value(ints map[string]sql.NullInt64, floats map[string]sql.NullFloat64) quad.Value {
+	vi, vf := ints[a.Int], floats[a.Float]
+	switch a.Func {
+	case iterator.AggregateCount:
+		return quad.Int(ints[a.Count].Int64)
+	case iterator.AggregateSum:
+		if vf.Valid {
+			return quad.Float(float64(vi.Int64) + vf.Float64)
+		}
+		return quad.Int(vi.Int64)
+	case iterator.AggregateAvg:
+		n := ints[a.CountInt].Int64 + ints[a.CountFloat].Int64
+		if n == 0 {
+			return nil
+		}
+		// integer sum is read as float to avoid overflows in the query
+		return quad.Float((floats[a.Int].Float64 + vf.Float64) / float64(n))
+	case iterator.AggregateMin, iterator.AggregateMax:
+		if !vi.Valid && !vf.Valid {
+			return nil
+		} else if !vf.Valid {
+			return quad.Int(vi.Int64)
+		} else if !vi.Valid {
+			return quad.Float(vf.Float64)
+		}
+		fi := float64(vi.Int64)
+		if (a.Func == iterator.AggregateMin && vf.Float64 < fi) || (a.Func == iterator.AggregateMax && vf.Float64 > fi) {
+			return quad.Float(vf.Float64)
+		}
+		return quad.Int(vi.Int64)
+	}
+	return nil
+}
+
+// aggPointers replaces scan destinations for aggregated columns and returns them.
+func aggPointers(cols []string, aggs []aggColumns, pointers []interface{}) []interface{} {
+	vals := make([]interface{}, len(cols))
+	for _, a := range aggs {
+		for _, c := range []string{a.Count, a.Int, a.Float, a.CountInt, a.CountFloat} {
+			if c == "" {
+				continue
+			}
+			for i, name := range cols {
+				if name != c {
+					continue
+				}
+				if a.isFloat(c) {
+					vals[i] = new(sql.NullFloat64)
+				} else {
+					vals[i] = new(sql.NullInt64)
+				}
+				pointers[i] = vals[i]
+			}
+		}
+	}
+	return vals
+}
+
+// setAggTags computes aggregates from scanned columns and stores them in tags.
+func setAggTags(tags map[string]graph.Ref, cols []string, aggs []aggColumns, vals []interface{}) {
+	ints := make(map[string]sql.NullInt64)
+	floats := make(map[string]sql.NullFloat64)
+	for i, v := range vals {
+		switch v := v.(type) {
+		case *sql.NullInt64:
+			ints[cols[i]] = *v
+		case *sql.NullFloat64:
+			floats[cols[i]] = *v
+		}
+	}
+	for _, a := range aggs {
+		if v := a.value(ints, floats); v != nil && a.As != "" {
+			tags[a.As] = refs.PreFetched(v)
+		}
+	}
+}
+
+func (opt *Optimizer) optimizeGroupBy(s shape.GroupBy) (shape.Shape, bool) {
+	from, ok := s.From.(Select)
+	if !ok || s.Tag == "" {
+		return s, false
+	}
+	hasField := func(name string) bool {
+		for _, f := range from.Fields {
+			if f.NameOrAlias() == name {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasField(s.Tag) {
+		return s, false
+	}
+	for _, a := range s.Aggregates {
+		if a.Tag == "" && a.Func != iterator.AggregateCount {
+			return s, false
+		} else if a.Tag != "" && !hasField(a.Tag) {
+			return s, false
+		}
+	}
+	sub := opt.nextTable()
+	sel := Select{
+		Fields: []Field{
+			{Table: sub, Name: s.Tag, Alias: tagNode},
+			{Table: sub, Name: s.Tag, Alias: s.Tag},
+		},
+		From: []Source{
+			Subquery{Query: from, Alias: sub},
+		},
+		GroupBy: []FieldName{
+			{Table: sub, Name: s.Tag},
+		},
+	}
+	// values of each tag are joined with nodes table only once
+	nodes := make(map[string]string)
+	for i, a := range s.Aggregates {
+		col := func(kind, expr string) string {
+			name := fmt.Sprintf("%s%d_%s", tagAgg, i, kind)
+			sel.Fields = append(sel.Fields, Field{Name: expr, Raw: true, Alias: name})
+			return name
+		}
+		ac := aggColumns{Func: a.Func, As: a.As}
+		if a.Tag == "" {
+			ac.Count = col("n", "COUNT(*)")
+			sel.aggs = append(sel.aggs, ac)
+			continue
+		}
+		n, ok := nodes[a.Tag]
+		if !ok {
+			n = opt.nextTable()
+			nodes[a.Tag] = n
+			sel.From = append(sel.From, Table{Name: "nodes", Alias: n})
+			sel.Where = append(sel.Where, Where{
+				Table: n, Field: "hash", Op: OpEqual,
+				Value: FieldName{Table: sub, Name: a.Tag},
+			})
+		}
+		vi, vf := n+".value_int", n+".value_float"
+		switch a.Func {
+		case iterator.AggregateCount:
+			ac.Count = col("n", "COUNT("+n+".hash)")
+		case iterator.AggregateSum:
+			ac.Int = col("i", "SUM("+vi+")")
+			ac.Float = col("f", "SUM("+vf+")")
+		case iterator.AggregateAvg:
+			ac.Int = col("i", "SUM("+vi+")")
+			ac.Float = col("f", "SUM("+vf+")")
+			ac.CountInt = col("ni", "COUNT("+vi+")")
+			ac.CountFloat = col("nf", "COUNT("+vf+")")
+		case iterator.AggregateMin:
+			ac.Int = col("i", "MIN("+vi+")")
+			ac.Float = col("f", "MIN("+vf+")")
+		case iterator.AggregateMax:
+			ac.Int = col("i", "MAX("+vi+")")
+			ac.Float = col("f", "MAX("+vf+")")
+		default:
+			return s, false
+		}
+		sel.aggs = append(sel.aggs, ac)
+	}
+	return sel, true
+}
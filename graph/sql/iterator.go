@@ -180,6 +180,11 @@ func (it *iteratorBase) scanValue(r *sql.Rows) bool {
 	for i := range pointers {
 		pointers[i] = &nodes[i]
 	}
+	var aggs []interface{}
+	if len(it.query.aggs) != 0 {
+		// aggregated columns contain values instead of hashes
+		aggs = aggPointers(it.cols, it.query.aggs, pointers)
+	}
 	if err := r.Scan(pointers...); err != nil {
 		it.err = err
 		return false
@@ -190,6 +195,9 @@ func (it *iteratorBase) scanValue(r *sql.Rows) bool {
 			it.tags[name] = nodes[i].ValueHash
 		}
 	}
+	if aggs != nil {
+		setAggTags(it.tags, it.cols, it.query.aggs, aggs)
+	}
 	if len(it.cind) > 1 {
 		var q QuadHashes
 		for _, d := range quad.Directions {
@@ -274,11 +282,53 @@ func (it *iteratorNext) Next(ctx context.Context) bool {
 	}
 }
 
+// NextBatch reads up to len(dst) rows from the cursor at once. In nextPath mode, rows with the same main key are skipped.
+func (it *iteratorNext) NextBatch(ctx context.Context, dst []graph.Ref) int {
+	if it.err != nil || len(dst) == 0 {
+		return 0
+	}
+	if it.cursor == nil {
+		it.cursor, it.err = it.qs.Query(ctx, it.query)
+		if it.err != nil {
+			return 0
+		}
+	}
+	n := 0
+	if it.nextPathRes != nil {
+		// result was already read by NextPath
+		it.res, it.tags = it.nextPathRes, it.nextPathTags
+		it.nextPathRes, it.nextPathTags = nil, nil
+		dst[n] = it.res
+		n++
+	}
+	for n < len(dst) {
+		if !it.cursor.Next() {
+			it.err = it.cursor.Err()
+			it.cursor.Close()
+			break
+		}
+		prev := it.res
+		if !it.scanValue(it.cursor) {
+			break
+		}
+		if it.query.nextPath && prev != nil && prev.Key() == it.res.Key() {
+			continue
+		}
+		dst[n] = it.res
+		n++
+	}
+	if it.err != nil {
+		return 0
+	}
+	return n
+}
+
 func (it *iteratorNext) NextPath(ctx context.Context) bool {
 	if it.err != nil {
 		return false
 	}
-	if !it.query.nextPath {
+	if !it.query.nextPath || it.res == nil || it.nextPathRes != nil {
+		// no current result, or the next one was already read
 		return false
 	}
 	if !it.cursor.Next() {
@@ -286,7 +336,7 @@ func (it *iteratorNext) NextPath(ctx context.Context) bool {
 		it.cursor.Close()
 		return false
 	}
-	prev := it.res
+	prev, prevTags := it.res, it.tags
 	if !it.scanValue(it.cursor) {
 		return false
 	}
@@ -294,10 +344,11 @@ func (it *iteratorNext) NextPath(ctx context.Context) bool {
 		return true
 	}
 	// different main keys - return false, but keep this results for the Next
+	// current result and tags are restored, since other iterators may still use them for their paths
 	it.nextPathRes = it.res
 	it.nextPathTags = it.tags
-	it.res = nil
-	it.tags = nil
+	it.res = prev
+	it.tags = prevTags
 	return false
 }
 
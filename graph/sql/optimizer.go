@@ -97,6 +97,8 @@ func (opt *Optimizer) OptimizeShape(ctx context.Context, s shape.Shape) (shape.S
 		return opt.optimizeSave(s)
 	case shape.Page:
 		return opt.optimizePage(s)
+	case shape.GroupBy:
+		return opt.optimizeGroupBy(s)
 	default:
 		return s, false
 	}
@@ -101,6 +101,40 @@ type QuadHashes struct {
 	refs.QuadHash
 }
 
+func init() {
+	// allow Sort and Materialize to spill results to disk
+	iterator.RegisterRefType(NodeHash{}, func(r refs.Ref) ([]byte, error) {
+		h := r.(NodeHash)
+		return h.ValueHash[:], nil
+	}, func(p []byte) (refs.Ref, error) {
+		var h NodeHash
+		if len(p) != quad.HashSize {
+			return nil, fmt.Errorf("unexpected hash length: %d", len(p))
+		}
+		copy(h.ValueHash[:], p)
+		return h, nil
+	})
+	iterator.RegisterRefType(QuadHashes{}, func(r refs.Ref) ([]byte, error) {
+		q := r.(QuadHashes)
+		p := make([]byte, 0, 4*quad.HashSize)
+		for _, h := range q.Dirs() {
+			p = append(p, h[:]...)
+		}
+		return p, nil
+	}, func(p []byte) (refs.Ref, error) {
+		var q QuadHashes
+		if len(p) != 4*quad.HashSize {
+			return nil, fmt.Errorf("unexpected quad hash length: %d", len(p))
+		}
+		for i, d := range quad.Directions {
+			var h refs.ValueHash
+			copy(h[:], p[i*quad.HashSize:])
+			q.Set(d, h)
+		}
+		return q, nil
+	})
+}
+
 type QuadStore struct {
 	db      *sql.DB
 	opt     *Optimizer
@@ -108,6 +142,7 @@ type QuadStore struct {
 	ids     *lru.Cache
 	sizes   *lru.Cache
 	noSizes bool
+	stats   bool // collect per-predicate statistics
 
 	mu    sync.RWMutex
 	nodes int64
@@ -211,6 +246,11 @@ func Init(typ string, addr string, options graph.Options) error {
 	nodesSQL := fl.nodesTable()
 	quadsSQL := fl.quadsTable()
 	indexes := fl.quadIndexes(options)
+	if stats, err := options.BoolKey(OptStats, false); err != nil {
+		return err
+	} else if stats {
+		indexes = append(indexes, fl.statsTable())
+	}
 
 	if fl.NoSchemaChangesInTx {
 		_, err = conn.Exec(nodesSQL)
@@ -227,7 +267,7 @@ func Init(typ string, addr string, options graph.Options) error {
 		}
 		for _, index := range indexes {
 			if _, err = conn.Exec(index); err != nil {
-				clog.Errorf("Cannot create index: %v", err)
+				clog.Errorf("Cannot create index or table: %v", err)
 				return err
 			}
 		}
@@ -254,7 +294,7 @@ func Init(typ string, addr string, options graph.Options) error {
 		}
 		for _, index := range indexes {
 			if _, err = tx.Exec(index); err != nil {
-				clog.Errorf("Cannot create index: %v", err)
+				clog.Errorf("Cannot create index or table: %v", err)
 				tx.Rollback()
 				return err
 			}
@@ -282,6 +322,7 @@ func New(typ string, addr string, options graph.Options) (graph.QuadStore, error
 		sizes:   lru.New(1024),
 		ids:     lru.New(1024),
 		noSizes: true, // Skip size checking by default.
+		stats:   hasStatsTable(conn),
 	}
 	qs.opt.SetRegexpOp(qs.flavor.RegexpOp)
 	if qs.flavor.NoOffsetWithoutLimit {
@@ -416,6 +457,10 @@ func (qs *QuadStore) ApplyDeltas(in []graph.Delta, opts graph.IgnoreOpts) error
 	}
 
 	err = retry(tx, func() error {
+		stats, err := qs.newStatsTracker(tx, deltas)
+		if err != nil {
+			return err
+		}
 		err = qs.flavor.RunTx(tx, deltas.IncNode, deltas.QuadAdd, opts)
 		if err != nil {
 			return err
@@ -469,6 +514,9 @@ func (qs *QuadStore) ApplyDeltas(in []graph.Delta, opts graph.IgnoreOpts) error
 				}
 			}
 		}
+		if err = stats.Flush(); err != nil {
+			return err
+		}
 		if len(deltas.DecNode) == 0 {
 			return nil
 		}
@@ -530,6 +578,12 @@ func (qs *QuadStore) QuadIterator(d quad.Direction, val graph.Ref) iterator.Shap
 }
 
 func (qs *QuadStore) querySize(ctx context.Context, sel Select) (refs.Size, error) {
+	if len(sel.GroupBy) != 0 {
+		// count groups instead of rows in each group
+		sel = Select{
+			From: []Source{Subquery{Query: sel, Alias: "g"}},
+		}
+	}
 	sel.Fields = []Field{
 		{Name: "COUNT(*)", Raw: true}, // TODO: proper support for expressions
 	}
@@ -549,6 +603,13 @@ func (qs *QuadStore) QuadIteratorSize(ctx context.Context, d quad.Direction, val
 	if !ok {
 		return refs.Size{Value: 0, Exact: true}, nil
 	}
+	if qs.stats && (d == quad.Predicate || d == quad.Label) {
+		st, err := qs.valueStats(ctx, d, val)
+		if err != nil {
+			return refs.Size{}, err
+		}
+		return refs.Size{Value: st.Quads, Exact: true}, nil
+	}
 	sel := AllQuads("")
 	sel.WhereEq("", dirField(d), v)
 	return qs.querySize(ctx, sel)
@@ -762,6 +823,11 @@ func (qs *QuadStore) QuadDirection(in graph.Ref, d quad.Direction) graph.Ref {
 
 func (qs *QuadStore) sizeForIterator(dir quad.Direction, hash NodeHash) int64 {
 	var err error
+	if qs.stats && (dir == quad.Predicate || dir == quad.Label) {
+		if st, err := qs.valueStats(context.TODO(), dir, hash); err == nil {
+			return st.Quads
+		}
+	}
 	if qs.noSizes {
 		st, _ := qs.Stats(context.TODO(), false)
 		if dir == quad.Predicate {
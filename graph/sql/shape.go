@@ -261,16 +261,19 @@ var _ Shape = Select{}
 
 // Select is a simplified representation of SQL SELECT query.
 type Select struct {
-	Fields []Field
-	From   []Source
-	Where  []Where
-	Params []Value
-	Limit  int64
-	Offset int64
+	Fields  []Field
+	From    []Source
+	Where   []Where
+	Params  []Value
+	GroupBy []FieldName
+	Limit   int64
+	Offset  int64
 
 	// TODO(dennwc): this field in unexported because we don't want it to a be a part of the API
 	//               however, it's necessary to make NodesFrom optimizations to work with SQL
 	nextPath bool
+	// aggs describes columns that contain aggregated values instead of node hashes.
+	aggs []aggColumns
 }
 
 func (s Select) Clone() Select {
@@ -278,6 +281,7 @@ func (s Select) Clone() Select {
 	s.From = append([]Source{}, s.From...)
 	s.Where = append([]Where{}, s.Where...)
 	s.Params = append([]Value{}, s.Params...)
+	s.GroupBy = append([]FieldName{}, s.GroupBy...)
 	return s
 }
 
@@ -288,7 +292,7 @@ func (s Select) isAll() bool {
 // onlyAsSubquery indicates that query cannot be merged into existing SELECT because of some specific properties of query.
 // An example of such properties might be LIMIT, DISTINCT, etc.
 func (s Select) onlyAsSubquery() bool {
-	return s.Limit > 0 || s.Offset > 0
+	return s.Limit > 0 || s.Offset > 0 || len(s.GroupBy) != 0
 }
 
 func (s Select) Columns() []string {
@@ -352,6 +356,13 @@ func (s Select) SQL(b *Builder) string {
 		}
 		parts = append(parts, "WHERE "+strings.Join(wheres, " AND "))
 	}
+	if len(s.GroupBy) != 0 {
+		var groups []string
+		for _, f := range s.GroupBy {
+			groups = append(groups, f.SQL(b))
+		}
+		parts = append(parts, "GROUP BY "+strings.Join(groups, ", "))
+	}
 	if s.Limit > 0 {
 		parts = append(parts, "LIMIT "+strconv.FormatInt(s.Limit, 10))
 	}
@@ -355,6 +355,33 @@ var shapeCases = []struct {
 		qu:   `SELECT t_5.object_hash AS __node FROM quads AS t_5, (SELECT t_3.subject_hash AS __node FROM quads AS t_3, (SELECT t_1.subject_hash AS __node FROM quads AS t_1, (SELECT subject_hash AS __node FROM quads WHERE predicate_hash = $1 AND object_hash = $2) AS t_2 WHERE t_1.predicate_hash = $3 AND t_1.object_hash = t_2.__node) AS t_4 WHERE t_3.predicate_hash = $4 AND t_3.object_hash = t_4.__node) AS t_6 WHERE t_5.predicate_hash = $5 AND t_5.subject_hash = t_6.__node`,
 		args: sVals("n", "k", "a", "s", "s"),
 	},
+	{
+		name: "group by",
+		s: shape.GroupBy{
+			From: shape.QuadsAction{
+				Result: quad.Subject,
+				Save: map[quad.Direction][]string{
+					quad.Subject: {"person"},
+					quad.Object:  {"age"},
+				},
+				Filter: map[quad.Direction]graph.Ref{
+					quad.Predicate: sVal("age"),
+				},
+			},
+			Tag: "person",
+			Aggregates: []iterator.Aggregate{
+				{Func: iterator.AggregateCount, As: "n"},
+				{Func: iterator.AggregateMax, Tag: "age", As: "max"},
+			},
+		},
+		qu: `SELECT t_1.person AS __node, t_1.person AS person, COUNT(*) AS __agg0_n, MAX(t_2.value_int) AS __agg1_i, MAX(t_2.value_float) AS __agg1_f
+	FROM (SELECT subject_hash AS __node, subject_hash AS person, object_hash AS age
+	FROM quads
+	WHERE predicate_hash = $1) AS t_1, nodes AS t_2
+	WHERE t_2.hash = t_1.age
+	GROUP BY t_1.person`,
+		args: sVals("age"),
+	},
 }
 
 func TestSQLShapes(t *testing.T) {
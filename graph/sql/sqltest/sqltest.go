@@ -39,6 +39,19 @@ func TestAll(t *testing.T, typ string, fnc DatabaseFunc, c *Config) {
 		t.Parallel()
 		testZeroRune(t, create)
 	})
+	createStats := makeDatabaseFunc(typ, func(t testing.TB) (string, graph.Options, func()) {
+		addr, opts, closer := fnc(t)
+		if opts == nil {
+			opts = make(graph.Options)
+		}
+		opts[sql.OptStats] = true
+		return addr, opts, closer
+	})
+	t.Run("stats", func(t *testing.T) {
+		t.Parallel()
+		graphtest.TestStats(t, createStats, c.quadStore())
+		graphtest.TestAddRemove(t, createStats, c.quadStore())
+	})
 }
 
 func BenchmarkAll(t *testing.B, typ string, fnc DatabaseFunc, c *Config) {
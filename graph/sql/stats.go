@@ -0,0 +1,257 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph"
+	graphlog "github.com/cayleygraph/cayley/graph/log"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+// OptStats enables collection of per-predicate and per-label statistics.
+// It can only be set when the database is initialized.
+const OptStats = "stats"
+
+var _ graph.StatsProvider = (*QuadStore)(nil)
+
+// statsDirs is a list of directions stats are collected for.
+var statsDirs = []quad.Direction{quad.Predicate, quad.Label}
+
+func (r Registration) statsTable() string {
+	htyp := r.HashType
+	if htyp == "" {
+		htyp = "BYTEA"
+	}
+	return `CREATE TABLE stats (
+	direction SMALLINT NOT NULL,
+	hash ` + htyp + ` NOT NULL,
+	quads BIGINT NOT NULL,
+	subjects BIGINT NOT NULL,
+	objects BIGINT NOT NULL,
+	PRIMARY KEY (direction, hash)
+);`
+}
+
+// hasStatsTable checks if the database was initialized with statistics enabled.
+func hasStatsTable(conn *sql.DB) bool {
+	var n int64
+	return conn.QueryRow(`SELECT COUNT(*) FROM stats;`).Scan(&n) == nil
+}
+
+// statsPair is a pair of predicate (or label) and a subject (or an object) affected by the transaction.
+type statsPair struct {
+	dir  quad.Direction
+	val  refs.ValueHash
+	side quad.Direction
+	node refs.ValueHash
+}
+
+// statsTracker counts quads for each pair affected by a transaction before and after the changes.
+// The difference is used to update the stats incrementally.
+type statsTracker struct {
+	qs     *QuadStore
+	tx     *sql.Tx
+	before map[statsPair]int64
+	stmts  map[[2]quad.Direction]*sql.Stmt
+}
+
+func (qs *QuadStore) newStatsTracker(tx *sql.Tx, deltas *graphlog.Deltas) (*statsTracker, error) {
+	if !qs.stats {
+		return nil, nil
+	}
+	t := &statsTracker{
+		qs: qs, tx: tx,
+		before: make(map[statsPair]int64),
+		stmts:  make(map[[2]quad.Direction]*sql.Stmt),
+	}
+	for _, list := range [][]graphlog.QuadUpdate{deltas.QuadAdd, deltas.QuadDel} {
+		for _, d := range list {
+			for _, dir := range statsDirs {
+				v := d.Quad.Get(dir)
+				if !v.Valid() {
+					continue
+				}
+				for _, side := range []quad.Direction{quad.Subject, quad.Object} {
+					t.before[statsPair{dir: dir, val: v, side: side, node: d.Quad.Get(side)}] = 0
+				}
+			}
+		}
+	}
+	for p := range t.before {
+		n, err := t.count(p)
+		if err != nil {
+			return nil, err
+		}
+		t.before[p] = n
+	}
+	return t, nil
+}
+
+func (t *statsTracker) count(p statsPair) (int64, error) {
+	k := [2]quad.Direction{p.dir, p.side}
+	stmt := t.stmts[k]
+	if stmt == nil {
+		var err error
+		stmt, err = t.tx.Prepare(fmt.Sprintf(`SELECT COUNT(*) FROM quads WHERE %s_hash = %s AND %s_hash = %s;`,
+			p.side, t.qs.flavor.Placeholder(1), p.dir, t.qs.flavor.Placeholder(2)))
+		if err != nil {
+			return 0, err
+		}
+		t.stmts[k] = stmt
+	}
+	var n int64
+	err := stmt.QueryRow(NodeHash{p.node}.SQLValue(), NodeHash{p.val}.SQLValue()).Scan(&n)
+	return n, err
+}
+
+// Flush recounts all affected pairs and updates the stats table accordingly.
+// It must be called after all changes were applied in the transaction.
+func (t *statsTracker) Flush() error {
+	if t == nil {
+		return nil
+	}
+	type statsKey struct {
+		dir quad.Direction
+		val refs.ValueHash
+	}
+	diff := make(map[statsKey]graph.ValueStats)
+	for p, before := range t.before {
+		after, err := t.count(p)
+		if err != nil {
+			return err
+		}
+		if after == before {
+			continue
+		}
+		k := statsKey{dir: p.dir, val: p.val}
+		st := diff[k]
+		var dn int64
+		if before == 0 && after > 0 {
+			dn = +1
+		} else if before > 0 && after == 0 {
+			dn = -1
+		}
+		if p.side == quad.Subject {
+			// each quad is counted exactly once on the subject side
+			st.Quads += after - before
+			st.Subjects += dn
+		} else {
+			st.Objects += dn
+		}
+		diff[k] = st
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	p := make([]string, 5)
+	for i := range p {
+		p[i] = t.qs.flavor.Placeholder(i + 1)
+	}
+	update, err := t.tx.Prepare(`UPDATE stats SET quads = quads + ` + p[0] + `, subjects = subjects + ` + p[1] + `, objects = objects + ` + p[2] +
+		` WHERE direction = ` + p[3] + ` AND hash = ` + p[4] + `;`)
+	if err != nil {
+		return err
+	}
+	insert, err := t.tx.Prepare(`INSERT INTO stats (quads, subjects, objects, direction, hash) VALUES (` +
+		p[0] + `, ` + p[1] + `, ` + p[2] + `, ` + p[3] + `, ` + p[4] + `);`)
+	if err != nil {
+		return err
+	}
+	for k, st := range diff {
+		if st.IsZero() {
+			continue
+		}
+		args := []interface{}{st.Quads, st.Subjects, st.Objects, int(k.dir), NodeHash{k.val}.SQLValue()}
+		res, err := update.Exec(args...)
+		if err != nil {
+			clog.Errorf("couldn't exec UPDATE stats statement: %v", err)
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n != 0 {
+			continue
+		}
+		if _, err = insert.Exec(args...); err != nil {
+			clog.Errorf("couldn't exec INSERT stats statement: %v", err)
+			return err
+		}
+	}
+	_, err = t.tx.Exec(`DELETE FROM stats WHERE quads <= 0;`)
+	return err
+}
+
+func (qs *QuadStore) valueStats(ctx context.Context, d quad.Direction, v graph.Ref) (graph.ValueStats, error) {
+	if !qs.stats {
+		return graph.ValueStats{}, graph.ErrNoStats
+	}
+	h, ok := v.(NodeHash)
+	if !ok || !h.Valid() {
+		return graph.ValueStats{}, nil
+	}
+	var st graph.ValueStats
+	err := qs.db.QueryRowContext(ctx,
+		`SELECT quads, subjects, objects FROM stats WHERE direction = `+qs.flavor.Placeholder(1)+
+			` AND hash = `+qs.flavor.Placeholder(2)+`;`,
+		int(d), h.SQLValue(),
+	).Scan(&st.Quads, &st.Subjects, &st.Objects)
+	if err == sql.ErrNoRows {
+		return graph.ValueStats{}, nil
+	}
+	return st, err
+}
+
+// PredicateStats implements graph.StatsProvider.
+func (qs *QuadStore) PredicateStats(ctx context.Context, pred graph.Ref) (graph.ValueStats, error) {
+	return qs.valueStats(ctx, quad.Predicate, pred)
+}
+
+// LabelStats implements graph.StatsProvider.
+func (qs *QuadStore) LabelStats(ctx context.Context, label graph.Ref) (graph.ValueStats, error) {
+	return qs.valueStats(ctx, quad.Label, label)
+}
+
+// StatsTable implements graph.StatsProvider.
+func (qs *QuadStore) StatsTable(ctx context.Context) (*graph.StatsTable, error) {
+	if !qs.stats {
+		return nil, graph.ErrNoStats
+	}
+	rows, err := qs.db.QueryContext(ctx, `SELECT direction, hash, quads, subjects, objects FROM stats;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type entry struct {
+		dir int
+		h   NodeHash
+		st  graph.ValueStats
+	}
+	var list []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.dir, &e.h, &e.st.Quads, &e.st.Subjects, &e.st.Objects); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	t := &graph.StatsTable{}
+	for _, e := range list {
+		ns := graph.NamedStats{Value: qs.NameOf(e.h), ValueStats: e.st}
+		switch quad.Direction(e.dir) {
+		case quad.Predicate:
+			t.Predicates = append(t.Predicates, ns)
+		case quad.Label:
+			t.Labels = append(t.Labels, ns)
+		}
+	}
+	t.Sort()
+	return t, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/quad"
+)
+
+// ErrNoStats is returned by StatsProvider if the quad store was not configured to collect statistics.
+var ErrNoStats = errors.New("quadstore: statistics are not collected")
+
+// ValueStats contains statistics about quads that share the same predicate or label.
+type ValueStats struct {
+	Quads    int64 // number of quads
+	Subjects int64 // number of distinct subjects
+	Objects  int64 // number of distinct objects
+}
+
+// IsZero checks if stats are empty.
+func (s ValueStats) IsZero() bool {
+	return s == ValueStats{}
+}
+
+// OutDegree returns an average number of quads per distinct subject.
+func (s ValueStats) OutDegree() float64 {
+	if s.Subjects == 0 {
+		return 0
+	}
+	return float64(s.Quads) / float64(s.Subjects)
+}
+
+// InDegree returns an average number of quads per distinct object.
+func (s ValueStats) InDegree() float64 {
+	if s.Objects == 0 {
+		return 0
+	}
+	return float64(s.Quads) / float64(s.Objects)
+}
+
+// Degree returns an average number of quads per distinct node on a given direction.
+// Only Subject and Object directions are supported.
+func (s ValueStats) Degree(d quad.Direction) (float64, bool) {
+	switch d {
+	case quad.Subject:
+		return s.OutDegree(), s.Subjects != 0
+	case quad.Object:
+		return s.InDegree(), s.Objects != 0
+	}
+	return 0, false
+}
+
+// Distinct returns the number of distinct nodes on a given direction.
+// Only Subject and Object directions are supported.
+func (s ValueStats) Distinct(d quad.Direction) (int64, bool) {
+	switch d {
+	case quad.Subject:
+		return s.Subjects, true
+	case quad.Object:
+		return s.Objects, true
+	}
+	return 0, false
+}
+
+// NamedStats is a ValueStats for a specific predicate or label value.
+type NamedStats struct {
+	Value quad.Value
+	ValueStats
+}
+
+// StatsTable contains statistics for all predicates and labels in the graph.
+type StatsTable struct {
+	Predicates []NamedStats
+	Labels     []NamedStats
+}
+
+// Sort orders all entries by the number of quads (descending) and then by value.
+func (t *StatsTable) Sort() {
+	for _, arr := range [][]NamedStats{t.Predicates, t.Labels} {
+		sort.Slice(arr, func(i, j int) bool {
+			a, b := arr[i], arr[j]
+			if a.Quads != b.Quads {
+				return a.Quads > b.Quads
+			}
+			return quad.StringOf(a.Value) < quad.StringOf(b.Value)
+		})
+	}
+}
+
+// StatsProvider is an optional interface for quad stores that maintain statistics
+// about each predicate and label. Iterators and query optimizers use it to improve
+// cost estimates.
+//
+// Implementations should return ErrNoStats if statistics are not collected.
+type StatsProvider interface {
+	// PredicateStats returns statistics for quads with a given predicate.
+	PredicateStats(ctx context.Context, pred Ref) (ValueStats, error)
+	// LabelStats returns statistics for quads with a given label.
+	LabelStats(ctx context.Context, label Ref) (ValueStats, error)
+	// StatsTable returns statistics for all predicates and labels.
+	StatsTable(ctx context.Context) (*StatsTable, error)
+}
+
+func asStatsProvider(qs QuadIndexer) (StatsProvider, bool) {
+	if h, ok := qs.(*Handle); ok {
+		qs = h.QuadStore
+	}
+	sp, ok := qs.(StatsProvider)
+	return sp, ok
+}
+
+// PredicateStats returns statistics for a given predicate, if quad store implements StatsProvider
+// and collects statistics.
+func PredicateStats(ctx context.Context, qs QuadIndexer, pred Ref) (ValueStats, bool) {
+	if pred == nil {
+		return ValueStats{}, false
+	}
+	sp, ok := asStatsProvider(qs)
+	if !ok {
+		return ValueStats{}, false
+	}
+	st, err := sp.PredicateStats(ctx, pred)
+	if err != nil {
+		return ValueStats{}, false
+	}
+	return st, true
+}
+
+// LabelStats returns statistics for a given label, if quad store implements StatsProvider
+// and collects statistics.
+func LabelStats(ctx context.Context, qs QuadIndexer, label Ref) (ValueStats, bool) {
+	if label == nil {
+		return ValueStats{}, false
+	}
+	sp, ok := asStatsProvider(qs)
+	if !ok {
+		return ValueStats{}, false
+	}
+	st, err := sp.LabelStats(ctx, label)
+	if err != nil {
+		return ValueStats{}, false
+	}
+	return st, true
+}
+
+// defaultFanout is the number of links per node assumed by cost estimates when there are no statistics.
+const defaultFanout = 20
+
+// predicateFanout estimates the number of links with given predicates that each node has in a given direction.
+// Statistics are only used for a fixed set of predicates and only if quad store collects them.
+func predicateFanout(ctx context.Context, qs QuadIndexer, preds iterator.Shape, dir quad.Direction) float64 {
+	fixed, ok := preds.(*iterator.Fixed)
+	if !ok {
+		return defaultFanout
+	}
+	var sum float64
+	for _, p := range fixed.Values() {
+		st, ok := PredicateStats(ctx, qs, p)
+		if !ok {
+			return defaultFanout
+		}
+		deg, _ := st.Degree(dir)
+		sum += deg
+	}
+	return sum
+}
@@ -0,0 +1,528 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+// Defines the WeightedPath iterator. It is similar to PathSearch, but instead of
+// the number of links it minimizes the total cost of the path, where the cost
+// of each link is a numeric property of that link.
+//
+// Links can be weighted in two ways. Either the quad that links two nodes has
+// a label, and the label has a weight property, or the link is reified: the node
+// links to an edge node, the edge node has a weight property and links to the
+// next node.
+//
+// The search is a Dijkstra's algorithm that runs for all source nodes at once,
+// thus results are returned in the order of increasing cost. If a heuristic
+// predicate is set, each node may have a lower bound estimate of the remaining
+// cost to the targets, which turns the search into A*.
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+// EdgeWeights describes how WeightedPath finds links between nodes and their costs.
+type EdgeWeights struct {
+	// Via is a set of predicates that link nodes. If nil, links with any predicate are followed.
+	// For reified links, Via links the node with the edge node.
+	Via iterator.Shape
+	// Next is a set of predicates that link the edge node with the next node.
+	// If set, links are reified and the weight is a property of the edge node.
+	// Otherwise, the weight is a property of the quad label.
+	Next iterator.Shape
+	// Weight is a predicate of the link cost. It must be a non-negative number.
+	// Links without the weight are not followed.
+	Weight Ref
+	// Heuristic is an optional predicate of the lower bound estimate of the remaining cost
+	// from the node to the closest target. Nodes without the estimate assume zero.
+	Heuristic Ref
+}
+
+// WeightedPath iterator returns the cheapest path from each source node to each of the
+// target nodes that can be reached. Results are ordered by the cost of the path.
+type WeightedPath struct {
+	qs       QuadStore
+	from     iterator.Shape
+	to       iterator.Shape
+	weights  EdgeWeights
+	reverse  bool
+	pathTags []string
+	costTags []string
+}
+
+// NewWeightedPath creates a new WeightedPath iterator.
+func NewWeightedPath(qs QuadStore, from, to iterator.Shape, weights EdgeWeights) *WeightedPath {
+	return &WeightedPath{
+		qs:      qs,
+		from:    from,
+		to:      to,
+		weights: weights,
+	}
+}
+
+// SetReverse makes the iterator follow links from the object to the subject.
+func (it *WeightedPath) SetReverse(reverse bool) {
+	it.reverse = reverse
+}
+
+// AddPathTag adds a tag that will contain the PathValue of each result.
+func (it *WeightedPath) AddPathTag(tag string) {
+	it.pathTags = append(it.pathTags, tag)
+}
+
+// AddCostTag adds a tag that will contain the total cost of each path as a quad.Float.
+func (it *WeightedPath) AddCostTag(tag string) {
+	it.costTags = append(it.costTags, tag)
+}
+
+func (it *WeightedPath) Iterate() iterator.Scanner {
+	return newWeightedPathNext(it)
+}
+
+func (it *WeightedPath) Lookup() iterator.Index {
+	return newCachedContains(newWeightedPathNext(it))
+}
+
+// SubIterators returns the source, target and predicate iterators.
+func (it *WeightedPath) SubIterators() []iterator.Shape {
+	out := []iterator.Shape{it.from, it.to}
+	if it.weights.Via != nil {
+		out = append(out, it.weights.Via)
+	}
+	if it.weights.Next != nil {
+		out = append(out, it.weights.Next)
+	}
+	return out
+}
+
+// Optimize optimizes all subiterators. If there are no sources or targets,
+// the iterator becomes Null.
+func (it *WeightedPath) Optimize(ctx context.Context) (iterator.Shape, bool) {
+	for _, sub := range []*iterator.Shape{&it.from, &it.to, &it.weights.Via, &it.weights.Next} {
+		if *sub == nil {
+			continue
+		}
+		if nsub, ok := (*sub).Optimize(ctx); ok {
+			*sub = nsub
+		}
+	}
+	if iterator.IsNull(it.from) || iterator.IsNull(it.to) || it.weights.Weight == nil {
+		return iterator.NewNull(), true
+	}
+	return it, false
+}
+
+// Stats returns a rough estimation of the search cost. Each source node may require
+// a traversal of a large portion of the graph. The number of links per node is taken
+// from predicate statistics, if they are available.
+func (it *WeightedPath) Stats(ctx context.Context) (iterator.Costs, error) {
+	fromStats, err := it.from.Stats(ctx)
+	toStats, err2 := it.to.Stats(ctx)
+	if err == nil {
+		err = err2
+	}
+	dir := quad.Subject
+	if it.reverse {
+		dir = quad.Object
+	}
+	fanout := predicateFanout(ctx, it.qs, it.weights.Via, dir)
+	if it.weights.Next != nil {
+		fanout *= predicateFanout(ctx, it.qs, it.weights.Next, dir)
+	}
+	size := fromStats.Size.Value * toStats.Size.Value
+	nextCost := fromStats.NextCost + toStats.NextCost + int64(math.Ceil(fanout))*int64(iterator.DefaultMaxRecursiveSteps)
+	return iterator.Costs{
+		NextCost:     nextCost,
+		ContainsCost: nextCost * (size/10 + 1),
+		Size: refs.Size{
+			Value: size,
+			Exact: false,
+		},
+	}, err
+}
+
+func (it *WeightedPath) String() string {
+	return "WeightedPath"
+}
+
+// numericValue converts the value to a number, if possible.
+func numericValue(v quad.Value) (float64, bool) {
+	switch v := v.(type) {
+	case quad.Int:
+		return float64(v), true
+	case quad.Float:
+		return float64(v), true
+	case quad.TypedString:
+		if pv, err := v.ParseValue(); err == nil {
+			if _, ok := pv.(quad.TypedString); !ok {
+				return numericValue(pv)
+			}
+		}
+		// types like xsd:decimal have no native representation
+		f, err := strconv.ParseFloat(string(v.Value), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// weightedItem is a state of the search: a node reached from a specific source with a given cost.
+type weightedItem struct {
+	src    int
+	node   refs.Ref
+	cost   float64
+	prio   float64
+	seq    int
+	parent *weightedItem
+	// preds are predicates of the link from the parent; mid is the edge node for reified links
+	preds []refs.Ref
+	mid   refs.Ref
+}
+
+type weightedHeap []*weightedItem
+
+func (h weightedHeap) Len() int { return len(h) }
+func (h weightedHeap) Less(i, j int) bool {
+	if h[i].prio != h[j].prio {
+		return h[i].prio < h[j].prio
+	}
+	return h[i].seq < h[j].seq
+}
+func (h weightedHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *weightedHeap) Push(x interface{}) { *h = append(*h, x.(*weightedItem)) }
+func (h *weightedHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type weightedKey struct {
+	src  int
+	node interface{}
+}
+
+// weightedSource is a source node with all its tag combinations.
+type weightedSource struct {
+	tags []map[string]refs.Ref
+}
+
+type weightedPathNext struct {
+	it   *WeightedPath
+	from iterator.Scanner
+
+	started bool
+	targets map[interface{}]struct{}
+	via     map[interface{}]struct{}
+	next    map[interface{}]struct{}
+	sources []weightedSource
+
+	queue   weightedHeap
+	seq     int
+	best    map[weightedKey]float64
+	settled map[weightedKey]struct{}
+	// weights and estimates are caches of numeric properties, NaN means no value
+	weights   map[interface{}]float64
+	estimates map[interface{}]float64
+
+	cur     *weightedItem
+	pathIdx int
+	err     error
+}
+
+func newWeightedPathNext(it *WeightedPath) *weightedPathNext {
+	return &weightedPathNext{
+		it:        it,
+		from:      it.from.Iterate(),
+		best:      make(map[weightedKey]float64),
+		settled:   make(map[weightedKey]struct{}),
+		weights:   make(map[interface{}]float64),
+		estimates: make(map[interface{}]float64),
+	}
+}
+
+func (it *weightedPathNext) TagResults(dst map[string]refs.Ref) {
+	if it.cur == nil {
+		return
+	}
+	for k, v := range it.sources[it.cur.src].tags[it.pathIdx] {
+		dst[k] = v
+	}
+	if len(it.it.pathTags) != 0 {
+		p := refs.PreFetched(pathValue(it.it.qs, it.refPath(it.cur)))
+		for _, tag := range it.it.pathTags {
+			dst[tag] = p
+		}
+	}
+	for _, tag := range it.it.costTags {
+		dst[tag] = refs.PreFetched(quad.Float(it.cur.cost))
+	}
+}
+
+// refPath reconstructs the path to the given search state.
+func (it *weightedPathNext) refPath(x *weightedItem) refPath {
+	var p refPath
+	for ; x != nil; x = x.parent {
+		p.nodes = append(p.nodes, x.node)
+		if x.mid != nil {
+			p.nodes = append(p.nodes, x.mid)
+		}
+		for i := len(x.preds) - 1; i >= 0; i-- {
+			p.preds = append(p.preds, x.preds[i])
+		}
+	}
+	reverseRefs(p.nodes)
+	reverseRefs(p.preds)
+	return p
+}
+
+func (it *weightedPathNext) start(ctx context.Context) error {
+	it.started = true
+	var err error
+	if it.targets, _, err = collect(ctx, it.it.to); err != nil {
+		return err
+	}
+	if w := it.it.weights.Via; w != nil {
+		if it.via, _, err = collect(ctx, w); err != nil {
+			return err
+		}
+	}
+	if w := it.it.weights.Next; w != nil {
+		if it.next, _, err = collect(ctx, w); err != nil {
+			return err
+		}
+	}
+	for it.from.Next(ctx) {
+		src := weightedSource{}
+		for {
+			tags := make(map[string]refs.Ref)
+			it.from.TagResults(tags)
+			src.tags = append(src.tags, tags)
+			if !it.from.NextPath(ctx) {
+				break
+			}
+		}
+		it.sources = append(it.sources, src)
+		n := it.from.Result()
+		h, err := it.estimate(ctx, n)
+		if err != nil {
+			return err
+		}
+		it.push(&weightedItem{src: len(it.sources) - 1, node: n, prio: h})
+	}
+	return it.from.Err()
+}
+
+func (it *weightedPathNext) push(x *weightedItem) {
+	x.seq = it.seq
+	it.seq++
+	it.best[weightedKey{src: x.src, node: refs.ToKey(x.node)}] = x.cost
+	heap.Push(&it.queue, x)
+}
+
+// property returns a numeric value of the property of the node, or NaN if there is none.
+func (it *weightedPathNext) property(ctx context.Context, n, pred refs.Ref) (float64, error) {
+	qs := it.it.qs
+	v := math.NaN()
+	preds := map[interface{}]struct{}{refs.ToKey(pred): {}}
+	err := eachLink(ctx, qs, n, false, preds, func(_, m, _ refs.Ref) bool {
+		if f, ok := numericValue(qs.NameOf(m)); ok {
+			v = f
+			return false
+		}
+		return true
+	})
+	return v, err
+}
+
+// weight returns the cost of the link stored on the label or the edge node n.
+func (it *weightedPathNext) weight(ctx context.Context, n refs.Ref) (float64, error) {
+	k := refs.ToKey(n)
+	if w, ok := it.weights[k]; ok {
+		return w, nil
+	}
+	w, err := it.property(ctx, n, it.it.weights.Weight)
+	if err != nil {
+		return 0, err
+	} else if w < 0 {
+		return 0, fmt.Errorf("negative link weight: %v", w)
+	}
+	it.weights[k] = w
+	return w, nil
+}
+
+// estimate returns the lower bound of the remaining cost from the node.
+func (it *weightedPathNext) estimate(ctx context.Context, n refs.Ref) (float64, error) {
+	if it.it.weights.Heuristic == nil {
+		return 0, nil
+	}
+	k := refs.ToKey(n)
+	if h, ok := it.estimates[k]; ok {
+		return h, nil
+	}
+	h, err := it.property(ctx, n, it.it.weights.Heuristic)
+	if err != nil {
+		return 0, err
+	} else if math.IsNaN(h) {
+		h = 0
+	}
+	it.estimates[k] = h
+	return h, nil
+}
+
+// relax adds the node m to the queue, if it was reached with a lower cost.
+func (it *weightedPathNext) relax(ctx context.Context, x *weightedItem, m refs.Ref, w float64, preds []refs.Ref, mid refs.Ref) error {
+	if math.IsNaN(w) {
+		return nil
+	}
+	k := weightedKey{src: x.src, node: refs.ToKey(m)}
+	if _, ok := it.settled[k]; ok {
+		return nil
+	}
+	cost := x.cost + w
+	if c, ok := it.best[k]; ok && c <= cost {
+		return nil
+	}
+	h, err := it.estimate(ctx, m)
+	if err != nil {
+		return err
+	}
+	it.push(&weightedItem{
+		src: x.src, node: m, cost: cost, prio: cost + h,
+		parent: x, preds: preds, mid: mid,
+	})
+	return nil
+}
+
+// expand adds all neighbors of the search state to the queue.
+func (it *weightedPathNext) expand(ctx context.Context, x *weightedItem) error {
+	qs := it.it.qs
+	var err error
+	if it.it.weights.Next == nil {
+		// weights are stored on quad labels
+		lerr := eachLink(ctx, qs, x.node, it.it.reverse, it.via, func(q, m, pred refs.Ref) bool {
+			label := qs.QuadDirection(q, quad.Label)
+			if label == nil {
+				return true
+			}
+			var w float64
+			if w, err = it.weight(ctx, label); err == nil {
+				err = it.relax(ctx, x, m, w, []refs.Ref{pred}, nil)
+			}
+			return err == nil
+		})
+		if err == nil {
+			err = lerr
+		}
+		return err
+	}
+	// reified links: node -via-> edge -next-> node, or in the opposite order if reversed
+	first, second := it.via, it.next
+	if it.it.reverse {
+		first, second = second, first
+	}
+	var edges, epreds []refs.Ref
+	if err = eachLink(ctx, qs, x.node, it.it.reverse, first, func(_, e, pred refs.Ref) bool {
+		edges, epreds = append(edges, e), append(epreds, pred)
+		return true
+	}); err != nil {
+		return err
+	}
+	for i, e := range edges {
+		w, err := it.weight(ctx, e)
+		if err != nil {
+			return err
+		} else if math.IsNaN(w) {
+			continue
+		}
+		lerr := eachLink(ctx, qs, e, it.it.reverse, second, func(_, m, pred refs.Ref) bool {
+			err = it.relax(ctx, x, m, w, []refs.Ref{epreds[i], pred}, e)
+			return err == nil
+		})
+		if err == nil {
+			err = lerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (it *weightedPathNext) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		if it.err = it.start(ctx); it.err != nil {
+			return false
+		}
+	}
+	it.pathIdx = 0
+	for it.queue.Len() != 0 {
+		if it.err = ctx.Err(); it.err != nil {
+			return false
+		}
+		x := heap.Pop(&it.queue).(*weightedItem)
+		k := weightedKey{src: x.src, node: refs.ToKey(x.node)}
+		if _, ok := it.settled[k]; ok {
+			continue
+		}
+		it.settled[k] = struct{}{}
+		if it.err = it.expand(ctx, x); it.err != nil {
+			return false
+		}
+		if _, ok := it.targets[k.node]; ok {
+			it.cur = x
+			return true
+		}
+	}
+	it.cur = nil
+	return false
+}
+
+func (it *weightedPathNext) Err() error {
+	return it.err
+}
+
+func (it *weightedPathNext) Result() refs.Ref {
+	if it.cur == nil {
+		return nil
+	}
+	return it.cur.node
+}
+
+// NextPath returns other tag combinations of the source node.
+func (it *weightedPathNext) NextPath(ctx context.Context) bool {
+	if it.cur == nil || it.pathIdx+1 >= len(it.sources[it.cur.src].tags) {
+		return false
+	}
+	it.pathIdx++
+	return true
+}
+
+func (it *weightedPathNext) Close() error {
+	return it.from.Close()
+}
+
+func (it *weightedPathNext) String() string {
+	return "WeightedPathNext"
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/quad"
+)
+
+type weightedResult struct {
+	path string
+	cost quad.Value
+}
+
+func collectWeighted(t testing.TB, qs graph.QuadStore, it *graph.WeightedPath) []weightedResult {
+	ctx := context.TODO()
+	it.AddPathTag("path")
+	it.AddCostTag("cost")
+	sc := it.Iterate()
+	defer sc.Close()
+	var out []weightedResult
+	for sc.Next(ctx) {
+		tags := make(map[string]refs.Ref)
+		sc.TagResults(tags)
+		out = append(out, weightedResult{
+			path: qs.NameOf(tags["path"]).String(),
+			cost: qs.NameOf(tags["cost"]),
+		})
+	}
+	require.NoError(t, sc.Err())
+	return out
+}
+
+func TestWeightedPathLabels(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("a", "road", "b", "r1"),
+		quad.MakeIRI("b", "road", "c", "r2"),
+		quad.MakeIRI("a", "road", "c", "r3"),
+		quad.MakeIRI("c", "road", "d", "r4"),
+		quad.MakeIRI("a", "rail", "d", "r5"),
+		quad.MakeIRI("a", "road", "d", ""), // no weight
+		quad.Make(quad.IRI("r1"), quad.IRI("km"), quad.Int(1), nil),
+		quad.Make(quad.IRI("r2"), quad.IRI("km"), quad.Float(1.5), nil),
+		quad.Make(quad.IRI("r3"), quad.IRI("km"), quad.Int(5), nil),
+		quad.Make(quad.IRI("r4"), quad.IRI("km"), quad.TypedString{Value: "0.5", Type: "xsd:decimal"}, nil),
+		quad.Make(quad.IRI("r5"), quad.IRI("km"), quad.Int(1), nil),
+	)
+	w := graph.EdgeWeights{
+		Via:    fixedOf(qs, "road"),
+		Weight: qs.ValueOf(quad.IRI("km")),
+	}
+	got := collectWeighted(t, qs, graph.NewWeightedPath(qs, fixedOf(qs, "a"), fixedOf(qs, "a", "c", "d"), w))
+	require.Equal(t, []weightedResult{
+		{path: "<a>", cost: quad.Float(0)},
+		{path: "<a> -<road>-> <b> -<road>-> <c>", cost: quad.Float(2.5)},
+		{path: "<a> -<road>-> <b> -<road>-> <c> -<road>-> <d>", cost: quad.Float(3)},
+	}, got)
+
+	// any predicate
+	w.Via = nil
+	got = collectWeighted(t, qs, graph.NewWeightedPath(qs, fixedOf(qs, "a"), fixedOf(qs, "d"), w))
+	require.Equal(t, []weightedResult{
+		{path: "<a> -<rail>-> <d>", cost: quad.Float(1)},
+	}, got)
+
+	// results of different sources are ordered by cost
+	got = collectWeighted(t, qs, graph.NewWeightedPath(qs, fixedOf(qs, "a", "c"), fixedOf(qs, "d"), w))
+	require.Equal(t, []weightedResult{
+		{path: "<c> -<road>-> <d>", cost: quad.Float(0.5)},
+		{path: "<a> -<rail>-> <d>", cost: quad.Float(1)},
+	}, got)
+
+	it := graph.NewWeightedPath(qs, fixedOf(qs, "d"), fixedOf(qs, "a"), w)
+	it.SetReverse(true)
+	got = collectWeighted(t, qs, it)
+	require.Equal(t, []weightedResult{
+		{path: "<d> -<rail>-> <a>", cost: quad.Float(1)},
+	}, got)
+}
+
+func TestWeightedPathReified(t *testing.T) {
+	// a -> b costs 10 directly, or 2+3 through c; heuristic makes it A*
+	qs := memstore.New(
+		quad.MakeIRI("a", "from", "e1", ""),
+		quad.MakeIRI("e1", "to", "b", ""),
+		quad.MakeIRI("a", "from", "e2", ""),
+		quad.MakeIRI("e2", "to", "c", ""),
+		quad.MakeIRI("c", "from", "e3", ""),
+		quad.MakeIRI("e3", "to", "b", ""),
+		quad.Make(quad.IRI("e1"), quad.IRI("cost"), quad.Int(10), nil),
+		quad.Make(quad.IRI("e2"), quad.IRI("cost"), quad.Int(2), nil),
+		quad.Make(quad.IRI("e3"), quad.IRI("cost"), quad.Int(3), nil),
+		quad.Make(quad.IRI("c"), quad.IRI("estimate"), quad.Int(3), nil),
+	)
+	w := graph.EdgeWeights{
+		Via:       fixedOf(qs, "from"),
+		Next:      fixedOf(qs, "to"),
+		Weight:    qs.ValueOf(quad.IRI("cost")),
+		Heuristic: qs.ValueOf(quad.IRI("estimate")),
+	}
+	exp := []weightedResult{
+		{path: "<a> -<from>-> <e2> -<to>-> <c> -<from>-> <e3> -<to>-> <b>", cost: quad.Float(5)},
+	}
+	got := collectWeighted(t, qs, graph.NewWeightedPath(qs, fixedOf(qs, "a"), fixedOf(qs, "b"), w))
+	require.Equal(t, exp, got)
+
+	it := graph.NewWeightedPath(qs, fixedOf(qs, "b"), fixedOf(qs, "a"), w)
+	it.SetReverse(true)
+	got = collectWeighted(t, qs, it)
+	require.Equal(t, []weightedResult{
+		{path: "<b> -<to>-> <e3> -<from>-> <c> -<to>-> <e2> -<from>-> <a>", cost: quad.Float(5)},
+	}, got)
+
+	// contains
+	ctx := context.TODO()
+	idx := graph.NewWeightedPath(qs, fixedOf(qs, "a"), fixedOf(qs, "b", "c"), w).Lookup()
+	require.True(t, idx.Contains(ctx, qs.ValueOf(quad.IRI("c"))))
+	require.False(t, idx.Contains(ctx, qs.ValueOf(quad.IRI("a"))))
+}
+
+func TestWeightedPathStats(t *testing.T) {
+	ctx := context.TODO()
+	qs := memstore.New(
+		quad.MakeIRI("a", "from", "e1", ""),
+		quad.MakeIRI("e1", "to", "b", ""),
+		quad.Make(quad.IRI("e1"), quad.IRI("cost"), quad.Int(1), nil),
+	)
+	w := graph.EdgeWeights{
+		Via:    fixedOf(qs, "from"),
+		Next:   fixedOf(qs, "to"),
+		Weight: qs.ValueOf(quad.IRI("cost")),
+	}
+	st, err := graph.NewWeightedPath(qs, fixedOf(qs, "a"), fixedOf(qs, "b"), w).Stats(ctx)
+	require.NoError(t, err)
+
+	// no statistics for an arbitrary set of predicates
+	w.Via, w.Next = nil, nil
+	def, err := graph.NewWeightedPath(qs, fixedOf(qs, "a"), fixedOf(qs, "b"), w).Stats(ctx)
+	require.NoError(t, err)
+	require.True(t, def.NextCost > st.NextCost)
+}
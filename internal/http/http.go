@@ -24,6 +24,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/internal/gephi"
 	cayleyhttp "github.com/cayleygraph/cayley/server/http"
 )
@@ -44,6 +45,12 @@ type Config struct {
 	ReadOnly bool
 	Timeout  time.Duration
 	Batch    int
+	// Limits are default resource limits for each query.
+	Limits governor.Limits
+	// TokenLimits are resource limits for queries made with a given API token.
+	TokenLimits map[string]governor.Limits
+	// Parallelism is the number of branches each iterator may execute concurrently.
+	Parallelism int
 }
 
 func SetupRoutes(handle *graph.Handle, cfg *Config) error {
@@ -68,6 +75,11 @@ func SetupRoutes(handle *graph.Handle, cfg *Config) error {
 	api2.SetReadOnly(cfg.ReadOnly)
 	api2.SetBatchSize(cfg.Batch)
 	api2.SetQueryTimeout(cfg.Timeout)
+	api2.SetQueryLimits(cfg.Limits)
+	api2.SetQueryParallelism(cfg.Parallelism)
+	for token, l := range cfg.TokenLimits {
+		api2.SetTokenLimits(token, l)
+	}
 
 	// For non API requests serve the UI
 	r.NotFound = http.FileServer(ui)
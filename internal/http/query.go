@@ -109,6 +109,9 @@ func (api *API) ServeV1Query(w http.ResponseWriter, r *http.Request, params http
 	}
 
 	ses := l.Session(h.QuadStore)
+	if ws, ok := ses.(query.WriterSession); ok && !api.config.ReadOnly {
+		ws.SetWriter(h.QuadWriter)
+	}
 	bodyBytes, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		errFunc(w, err)
@@ -25,6 +25,8 @@ const (
 var (
 	pathStep         = reflect.TypeOf((*linkedql.PathStep)(nil)).Elem()
 	iteratorStep     = reflect.TypeOf((*linkedql.IteratorStep)(nil)).Elem()
+	mutationStep     = reflect.TypeOf((*linkedql.MutationStep)(nil)).Elem()
+	expression       = reflect.TypeOf((*linkedql.Expression)(nil)).Elem()
 	entityIdentifier = reflect.TypeOf((*linkedql.EntityIdentifier)(nil)).Elem()
 	value            = reflect.TypeOf((*quad.Value)(nil)).Elem()
 	propertyPath     = reflect.TypeOf((*linkedql.PropertyPath)(nil))
@@ -63,6 +65,9 @@ func typeToRange(t reflect.Type) string {
 	if t == propertyPath {
 		return linkedql.Prefix + "PropertyPath"
 	}
+	if t == expression {
+		return linkedql.Prefix + "Expression"
+	}
 	panic("Unexpected type " + t.String())
 }
 
@@ -181,6 +186,12 @@ func getStepTypeClasses(t reflect.Type) []string {
 	if t.Implements(iteratorStep) {
 		typeClasses = append(typeClasses, linkedql.Prefix+"IteratorStep")
 	}
+	if t.Implements(mutationStep) {
+		typeClasses = append(typeClasses, linkedql.Prefix+"MutationStep")
+	}
+	if t.Implements(expression) {
+		typeClasses = append(typeClasses, linkedql.Prefix+"Expression")
+	}
 	return typeClasses
 }
 
@@ -343,6 +354,15 @@ func (g *generator) Generate() []byte {
 			"@type":         owl.Class,
 			rdfs.SubClassOf: identified{ID: linkedql.Prefix + "Step"},
 		},
+		map[string]interface{}{
+			"@id":           linkedql.Prefix + "MutationStep",
+			"@type":         owl.Class,
+			rdfs.SubClassOf: identified{ID: linkedql.Prefix + "Step"},
+		},
+		map[string]string{
+			"@id":   linkedql.Prefix + "Expression",
+			"@type": owl.Class,
+		},
 	}
 	graph = append(graph, g.out...)
 	data, err := json.Marshal(map[string]interface{}{
@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/rdfpatch"
 	"github.com/cayleygraph/cayley/internal/decompressor"
 	"github.com/cayleygraph/quad"
 	"github.com/cayleygraph/quad/nquads"
@@ -40,7 +42,9 @@ type nopCloser struct {
 
 func (r nopCloser) Close() error { return nil }
 
-func QuadReaderFor(path, typ string) (quad.ReadCloser, error) {
+// openPath opens a file, a URL or stdin ("-") and decompresses the content, if necessary.
+// It returns io.EOF if the content is empty.
+func openPath(path string) (io.Reader, io.Closer, error) {
 	var (
 		r io.Reader
 		c io.Closer
@@ -55,15 +59,15 @@ func QuadReaderFor(path, typ string) (quad.ReadCloser, error) {
 		}
 		f, err := os.Open(path)
 		if os.IsNotExist(err) {
-			return nil, err
+			return nil, nil, err
 		} else if err != nil {
-			return nil, fmt.Errorf("could not open file %q: %v", path, err)
+			return nil, nil, fmt.Errorf("could not open file %q: %v", path, err)
 		}
 		r, c = f, f
 	} else {
 		res, err := http.Get(path)
 		if err != nil {
-			return nil, fmt.Errorf("could not get resource <%s>: %v", u, err)
+			return nil, nil, fmt.Errorf("could not get resource <%s>: %v", u, err)
 		}
 		// TODO(dennwc): save content type for format auto-detection
 		r, c = res.Body, res.Body
@@ -74,9 +78,16 @@ func QuadReaderFor(path, typ string) (quad.ReadCloser, error) {
 		if c != nil {
 			c.Close()
 		}
-		if err == io.EOF {
-			return nopCloser{quad.NewReader(nil)}, nil
-		}
+		return nil, nil, err
+	}
+	return r, c, nil
+}
+
+func QuadReaderFor(path, typ string) (quad.ReadCloser, error) {
+	r, c, err := openPath(path)
+	if err == io.EOF {
+		return nopCloser{quad.NewReader(nil)}, nil
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -150,3 +161,25 @@ func (w *batchLogger) WriteQuads(quads []quad.Quad) (int, error) {
 	}
 	return n, err
 }
+
+// LoadPatch reads an RDF Patch from the given path and applies it to qw transaction by transaction.
+// It returns the number of changes that were applied.
+func LoadPatch(qw graph.QuadWriter, path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+	r, c, err := openPath(path)
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if c != nil {
+		defer c.Close()
+	}
+	n, err := rdfpatch.Apply(qw, rdfpatch.NewReader(r))
+	if err != nil {
+		return n, fmt.Errorf("db: failed to apply patch: %v", err)
+	}
+	return n, nil
+}
@@ -96,6 +96,9 @@ func Repl(ctx context.Context, h *graph.Handle, queryLanguage string, timeout ti
 		return fmt.Errorf("unsupported query language: %q", queryLanguage)
 	}
 	ses := l.Session(h.QuadStore)
+	if ws, ok := ses.(query.WriterSession); ok && h.QuadWriter != nil {
+		ws.SetWriter(h.QuadWriter)
+	}
 
 	term, err := terminal(history)
 	if os.IsNotExist(err) {
@@ -0,0 +1,559 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package turtle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokEOF     tokenType = iota
+	tokIRI               // <iri>
+	tokPName             // prefix:local
+	tokBNode             // _:label
+	tokString            // "string", 'string', """long string""" or '''long string'''
+	tokLang              // @en, only directly after a string
+	tokInteger           // 1
+	tokDecimal           // 1.0
+	tokDouble            // 1e0
+	tokKeyword           // a, true, false, @prefix, @base, PREFIX, BASE, GRAPH
+	tokPunct             // . ; , [ ] ( ) { } ^^
+)
+
+func (t tokenType) String() string {
+	switch t {
+	case tokEOF:
+		return "EOF"
+	case tokIRI:
+		return "IRI"
+	case tokPName:
+		return "prefixed name"
+	case tokBNode:
+		return "blank node"
+	case tokString:
+		return "string"
+	case tokLang:
+		return "language tag"
+	case tokInteger, tokDecimal, tokDouble:
+		return "number"
+	case tokKeyword:
+		return "keyword"
+	case tokPunct:
+		return "punctuation"
+	}
+	return fmt.Sprintf("token(%d)", int(t))
+}
+
+type token struct {
+	typ tokenType
+	// val is a value of the token. For prefixed names it contains the prefix.
+	val string
+	// local is a local part of a prefixed name.
+	local string
+	line  int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "EOF"
+	case tokIRI:
+		return "<" + t.val + ">"
+	case tokPName:
+		return t.val + ":" + t.local
+	case tokBNode:
+		return "_:" + t.val
+	case tokString:
+		return strconv.Quote(t.val)
+	case tokLang:
+		return "@" + t.val
+	}
+	return t.val
+}
+
+func (t token) is(typ tokenType, val string) bool {
+	return t.typ == typ && t.val == val
+}
+
+func (t token) isPunct(val string) bool {
+	return t.is(tokPunct, val)
+}
+
+// isKeyword checks if token is a given keyword. SPARQL-style keywords are case-insensitive.
+func (t token) isKeyword(val string) bool {
+	if t.typ != tokKeyword {
+		return false
+	}
+	switch val {
+	case "PREFIX", "BASE", "GRAPH":
+		return strings.EqualFold(t.val, val)
+	}
+	return t.val == val
+}
+
+// lexer splits Turtle and TriG documents into tokens. It reads the input incrementally,
+// thus documents of any size can be parsed with a constant memory.
+type lexer struct {
+	r      *bufio.Reader
+	line   int
+	last   tokenType
+	peeked *token
+	buf    strings.Builder
+}
+
+func newLexer(r io.Reader) *lexer {
+	return &lexer{r: bufio.NewReader(r), line: 1}
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: "+format, append([]interface{}{l.line}, args...)...)
+}
+
+// peek returns the next token without consuming it.
+func (l *lexer) peek() (token, error) {
+	if l.peeked != nil {
+		return *l.peeked, nil
+	}
+	t, err := l.scan()
+	if err != nil {
+		return t, err
+	}
+	l.peeked = &t
+	return t, nil
+}
+
+// next consumes and returns the next token.
+func (l *lexer) next() (token, error) {
+	if t := l.peeked; t != nil {
+		l.peeked = nil
+		return *t, nil
+	}
+	return l.scan()
+}
+
+func (l *lexer) readRune() (rune, error) {
+	r, _, err := l.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r == '\n' {
+		l.line++
+	}
+	return r, nil
+}
+
+func (l *lexer) unreadRune(r rune) {
+	if r == '\n' {
+		l.line--
+	}
+	_ = l.r.UnreadRune()
+}
+
+// peekRune returns a rune at the current position without consuming it or -1 at the end of input.
+func (l *lexer) peekRune() rune {
+	b, _ := l.r.Peek(utf8.UTFMax)
+	if len(b) == 0 {
+		return -1
+	}
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+// peekByte returns a byte at a given offset from the current position, or -1 if there is no such byte.
+func (l *lexer) peekByte(i int) int {
+	b, _ := l.r.Peek(i + 1)
+	if len(b) <= i {
+		return -1
+	}
+	return int(b[i])
+}
+
+// skipSpace skips whitespaces and comments.
+func (l *lexer) skipSpace() error {
+	for {
+		r, err := l.readRune()
+		if err != nil {
+			return err
+		}
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '#':
+			for r != '\n' {
+				if r, err = l.readRune(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		l.unreadRune(r)
+		return nil
+	}
+}
+
+func (l *lexer) scan() (token, error) {
+	t, err := l.scanToken()
+	if err == nil {
+		l.last = t.typ
+	}
+	return t, err
+}
+
+func (l *lexer) scanToken() (token, error) {
+	if err := l.skipSpace(); err == io.EOF {
+		return token{typ: tokEOF, line: l.line}, nil
+	} else if err != nil {
+		return token{}, err
+	}
+	line := l.line
+	tok := func(typ tokenType, val string) (token, error) {
+		return token{typ: typ, val: val, line: line}, nil
+	}
+	r, err := l.readRune()
+	if err != nil {
+		return token{}, err
+	}
+	switch r {
+	case '<':
+		s, err := l.readIRI()
+		if err != nil {
+			return token{}, err
+		}
+		return tok(tokIRI, s)
+	case '"', '\'':
+		s, err := l.readString(r)
+		if err != nil {
+			return token{}, err
+		}
+		return tok(tokString, s)
+	case '@':
+		name := l.readWhile(func(r rune) bool {
+			return r == '-' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+		})
+		if name == "" {
+			return token{}, l.errorf("unexpected '@'")
+		}
+		if l.last == tokString {
+			return tok(tokLang, name)
+		}
+		switch name {
+		case "prefix", "base":
+			return tok(tokKeyword, "@"+name)
+		}
+		return token{}, l.errorf("unexpected directive: @%s", name)
+	case '^':
+		if r, err := l.readRune(); err != nil || r != '^' {
+			return token{}, l.errorf("expected '^^'")
+		}
+		return tok(tokPunct, "^^")
+	case '.':
+		if c := l.peekByte(0); c >= '0' && c <= '9' {
+			return l.readNumber(line, true)
+		}
+		return tok(tokPunct, ".")
+	case ';', ',', '[', ']', '(', ')', '{', '}':
+		return tok(tokPunct, string(r))
+	case '+', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		l.unreadRune(r)
+		return l.readNumber(line, false)
+	case '_':
+		if l.peekRune() == ':' {
+			_, _ = l.readRune()
+			label, err := l.readLocal()
+			if err != nil {
+				return token{}, err
+			} else if label == "" {
+				return token{}, l.errorf("empty blank node label")
+			}
+			return tok(tokBNode, label)
+		}
+	}
+	if r != ':' && !isNameStart(r) {
+		return token{}, l.errorf("unexpected character: %q", r)
+	}
+	l.unreadRune(r)
+	prefix := l.readName(isNameChar)
+	if l.peekRune() != ':' {
+		switch {
+		case prefix == "a", prefix == "true", prefix == "false",
+			strings.EqualFold(prefix, "PREFIX"), strings.EqualFold(prefix, "BASE"), strings.EqualFold(prefix, "GRAPH"):
+			return tok(tokKeyword, prefix)
+		}
+		return token{}, l.errorf("unexpected word: %q", prefix)
+	}
+	_, _ = l.readRune()
+	local, err := l.readLocal()
+	if err != nil {
+		return token{}, err
+	}
+	return token{typ: tokPName, val: prefix, local: local, line: line}, nil
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || r == '-' || r == 0xB7 || unicode.IsLetter(r) || unicode.IsDigit(r) ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Pc, r)
+}
+
+func isLocalChar(r rune) bool {
+	return r == ':' || r == '%' || r == '\\' || isNameChar(r)
+}
+
+// readWhile reads all runes matching the function.
+func (l *lexer) readWhile(fnc func(r rune) bool) string {
+	l.buf.Reset()
+	for {
+		r := l.peekRune()
+		if r < 0 || !fnc(r) {
+			return l.buf.String()
+		}
+		_, _ = l.readRune()
+		l.buf.WriteRune(r)
+	}
+}
+
+// readName reads the name, allowing dots in the middle of it.
+func (l *lexer) readName(fnc func(r rune) bool) string {
+	l.buf.Reset()
+	for {
+		r := l.peekRune()
+		if r == '.' {
+			// dot cannot be the last character of the name
+			next := l.peekByte(1)
+			if next < 0 || (next < utf8.RuneSelf && !fnc(rune(next))) {
+				return l.buf.String()
+			}
+		} else if r < 0 || !fnc(r) {
+			return l.buf.String()
+		}
+		_, _ = l.readRune()
+		l.buf.WriteRune(r)
+	}
+}
+
+// readLocal reads a local part of a prefixed name, decoding escape sequences.
+func (l *lexer) readLocal() (string, error) {
+	s := l.readName(isLocalChar)
+	if !strings.ContainsAny(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			// escape cannot be consumed by readName, since the name ends with it
+			r, err := l.readRune()
+			if err != nil {
+				return "", l.errorf("unexpected end of name")
+			}
+			if !strings.ContainsRune(localEscapes, r) {
+				return "", l.errorf("invalid escape in a name: %q", r)
+			}
+			b.WriteRune(r)
+			rest, err := l.readLocal()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(rest)
+			break
+		}
+		if !strings.ContainsRune(localEscapes, rune(s[i])) {
+			return "", l.errorf("invalid escape in a name: %q", s[i])
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), nil
+}
+
+// localEscapes is a set of characters that can be escaped in a local part of prefixed names.
+const localEscapes = `_~.-!$&'()*+,;=/?#@%`
+
+func (l *lexer) readIRI() (string, error) {
+	l.buf.Reset()
+	for {
+		r, err := l.readRune()
+		if err == io.EOF {
+			return "", l.errorf("unexpected end of IRI")
+		} else if err != nil {
+			return "", err
+		}
+		switch r {
+		case '>':
+			return l.buf.String(), nil
+		case ' ', '\t', '\r', '\n', '<', '"', '{', '}', '|', '^', '`':
+			return "", l.errorf("invalid character in IRI: %q", r)
+		case '\\':
+			r, err = l.readRune()
+			if err != nil {
+				return "", l.errorf("unexpected end of IRI")
+			}
+			if r != 'u' && r != 'U' {
+				return "", l.errorf("invalid escape in IRI: %q", r)
+			}
+			if r, err = l.readUnicode(r); err != nil {
+				return "", err
+			}
+		}
+		l.buf.WriteRune(r)
+	}
+}
+
+func (l *lexer) readUnicode(c rune) (rune, error) {
+	n := 4
+	if c == 'U' {
+		n = 8
+	}
+	var b [8]byte
+	for i := 0; i < n; i++ {
+		r, err := l.readRune()
+		if err != nil || r >= utf8.RuneSelf {
+			return 0, l.errorf("invalid unicode escape")
+		}
+		b[i] = byte(r)
+	}
+	v, err := strconv.ParseUint(string(b[:n]), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		return 0, l.errorf("invalid unicode escape: \\%c%s", c, b[:n])
+	}
+	return rune(v), nil
+}
+
+// readString reads a string literal. The first quote must be already consumed.
+func (l *lexer) readString(q rune) (string, error) {
+	long := false
+	if l.peekRune() == q {
+		_, _ = l.readRune()
+		if l.peekRune() != q {
+			return "", nil // empty string
+		}
+		_, _ = l.readRune()
+		long = true
+	}
+	l.buf.Reset()
+	for {
+		r, err := l.readRune()
+		if err == io.EOF {
+			return "", l.errorf("unexpected end of string")
+		} else if err != nil {
+			return "", err
+		}
+		switch {
+		case r == q && !long:
+			return l.buf.String(), nil
+		case r == q:
+			if l.peekByte(0) == int(q) && l.peekByte(1) == int(q) {
+				_, _ = l.readRune()
+				_, _ = l.readRune()
+				// the long string may end with quotes
+				for l.peekByte(0) == int(q) {
+					_, _ = l.readRune()
+					l.buf.WriteRune(q)
+				}
+				return l.buf.String(), nil
+			}
+		case (r == '\n' || r == '\r') && !long:
+			return "", l.errorf("unexpected end of line in string")
+		case r == '\\':
+			if r, err = l.readRune(); err != nil {
+				return "", l.errorf("unexpected end of string")
+			}
+			switch r {
+			case 't':
+				r = '\t'
+			case 'b':
+				r = '\b'
+			case 'n':
+				r = '\n'
+			case 'r':
+				r = '\r'
+			case 'f':
+				r = '\f'
+			case '"', '\'', '\\':
+			case 'u', 'U':
+				if r, err = l.readUnicode(r); err != nil {
+					return "", err
+				}
+			default:
+				return "", l.errorf("invalid escape in string: %q", r)
+			}
+		}
+		l.buf.WriteRune(r)
+	}
+}
+
+func (l *lexer) readDigits() {
+	for c := l.peekByte(0); c >= '0' && c <= '9'; c = l.peekByte(0) {
+		_, _ = l.readRune()
+		l.buf.WriteByte(byte(c))
+	}
+}
+
+// readNumber reads a numeric literal. If dot is set, the leading '.' of a decimal was already consumed.
+func (l *lexer) readNumber(line int, dot bool) (token, error) {
+	l.buf.Reset()
+	typ := tokInteger
+	digits := false
+	if !dot {
+		if c := l.peekByte(0); c == '+' || c == '-' {
+			_, _ = l.readRune()
+			l.buf.WriteByte(byte(c))
+		}
+		l.readDigits()
+		digits = l.buf.Len() > 0 && l.buf.String() != "+" && l.buf.String() != "-"
+		if c := l.peekByte(1); l.peekByte(0) == '.' && c >= '0' && c <= '9' {
+			_, _ = l.readRune()
+			dot = true
+		}
+	}
+	if dot {
+		l.buf.WriteByte('.')
+		l.readDigits()
+		typ = tokDecimal
+		digits = true
+	}
+	if c := l.peekByte(0); digits && (c == 'e' || c == 'E') {
+		_, _ = l.readRune()
+		l.buf.WriteByte(byte(c))
+		if c := l.peekByte(0); c == '+' || c == '-' {
+			_, _ = l.readRune()
+			l.buf.WriteByte(byte(c))
+		}
+		n := l.buf.Len()
+		l.readDigits()
+		if l.buf.Len() == n {
+			return token{}, l.errorf("invalid number: %q", l.buf.String())
+		}
+		typ = tokDouble
+	}
+	if !digits {
+		return token{}, l.errorf("invalid number: %q", l.buf.String())
+	}
+	return token{typ: typ, val: l.buf.String(), line: line}, nil
+}
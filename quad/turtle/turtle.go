@@ -0,0 +1,576 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package turtle implements parsing and serialization of RDF 1.1 Turtle
+// (https://www.w3.org/TR/turtle/) and TriG (https://www.w3.org/TR/trig/) documents.
+//
+// Both formats are registered in the quad package as "turtle" and "trig".
+package turtle
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+	"github.com/cayleygraph/quad/voc/xsd"
+)
+
+// AutoConvertTypedString allows to convert TypedString values to native
+// equivalents directly while parsing. It will call ParseValue on all TypedString values.
+//
+// If conversion error occurs, it will preserve original TypedString value.
+var AutoConvertTypedString = true
+
+func init() {
+	quad.RegisterFormat(quad.Format{
+		Name: "turtle",
+		Ext:  []string{".ttl"},
+		Mime: []string{"text/turtle"},
+		Reader: func(r io.Reader) quad.ReadCloser {
+			return NewReader(r)
+		},
+		Writer:         func(w io.Writer) quad.WriteCloser { return NewWriter(w) },
+		MarshalValue:   marshalValue,
+		UnmarshalValue: unmarshalValue,
+	})
+	quad.RegisterFormat(quad.Format{
+		Name: "trig",
+		Ext:  []string{".trig"},
+		Mime: []string{"application/trig"},
+		Reader: func(r io.Reader) quad.ReadCloser {
+			return NewTriGReader(r)
+		},
+		Writer:         func(w io.Writer) quad.WriteCloser { return NewTriGWriter(w) },
+		MarshalValue:   marshalValue,
+		UnmarshalValue: unmarshalValue,
+	})
+}
+
+func marshalValue(v quad.Value) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return []byte(formatValue(v, nil)), nil
+}
+
+func unmarshalValue(b []byte) (quad.Value, error) {
+	r := NewReader(bytes.NewReader(bytes.Join([][]byte{
+		[]byte("<s> <p> "),
+		b,
+		[]byte(" .\n"),
+	}, nil)))
+	q, err := r.ReadQuad()
+	if err == io.EOF {
+		return nil, quad.ErrInvalid
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err = r.ReadQuad(); err != io.EOF {
+		return nil, quad.ErrInvalid
+	}
+	return q.Object, nil
+}
+
+var (
+	iriType  = quad.IRI(rdf.Type).Full()
+	iriFirst = quad.IRI(rdf.First).Full()
+	iriRest  = quad.IRI(rdf.Rest).Full()
+	iriNil   = quad.IRI(rdf.Nil).Full()
+
+	iriInteger = quad.IRI(xsd.Integer).Full()
+	iriDecimal = quad.IRI(xsd.Prefix + "decimal").Full()
+	iriDouble  = quad.IRI(xsd.Double).Full()
+	iriBoolean = quad.IRI(xsd.Boolean).Full()
+)
+
+// Reader implements Turtle and TriG document parsing.
+//
+// Documents are parsed incrementally, one statement at a time.
+// Relative IRIs are resolved against the base IRI, if it was set by the document.
+// Otherwise they are returned unchanged.
+type Reader struct {
+	lex  *lexer
+	trig bool
+
+	base     *url.URL
+	prefixes map[string]string
+
+	// graph is a label of the current graph block
+	graph   quad.Value
+	inGraph bool
+
+	bnodes int
+	labels map[string]quad.BNode // blank node labels of the document that are renamed
+	queue  []quad.Quad
+	err    error
+}
+
+// NewReader returns a Turtle decoder that takes its input from the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{lex: newLexer(r), prefixes: make(map[string]string)}
+}
+
+// NewTriGReader returns a TriG decoder that takes its input from the provided io.Reader.
+func NewTriGReader(r io.Reader) *Reader {
+	d := NewReader(r)
+	d.trig = true
+	return d
+}
+
+// SetBase sets the base IRI used to resolve relative IRIs.
+// Base IRI can be later changed by the document.
+func (d *Reader) SetBase(base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	d.base = u
+	return nil
+}
+
+// ReadQuad returns the next quad from the document, or an error.
+func (d *Reader) ReadQuad() (quad.Quad, error) {
+	for len(d.queue) == 0 {
+		if d.err != nil {
+			return quad.Quad{}, d.err
+		}
+		d.err = d.statement()
+	}
+	q := d.queue[0]
+	d.queue = d.queue[1:]
+	return q, nil
+}
+
+// Close implements quad.ReadCloser.
+func (d *Reader) Close() error { return nil }
+
+func (d *Reader) emit(s, p, o quad.Value) {
+	d.queue = append(d.queue, quad.Quad{Subject: s, Predicate: p, Object: o, Label: d.graph})
+}
+
+// genidPrefix is a prefix of generated blank node labels.
+const genidPrefix = "genid"
+
+func (d *Reader) newBNode() quad.BNode {
+	d.bnodes++
+	return quad.BNode(fmt.Sprintf(genidPrefix+"%d", d.bnodes))
+}
+
+// bnode returns a blank node for a label written in the document.
+// Labels that may collide with generated ones are replaced with new generated labels.
+func (d *Reader) bnode(label string) quad.BNode {
+	if !strings.HasPrefix(label, genidPrefix) {
+		return quad.BNode(label)
+	}
+	if b, ok := d.labels[label]; ok {
+		return b
+	}
+	if d.labels == nil {
+		d.labels = make(map[string]quad.BNode)
+	}
+	b := d.newBNode()
+	d.labels[label] = b
+	return b
+}
+
+func (d *Reader) next() (token, error) {
+	return d.lex.next()
+}
+
+func (d *Reader) peek() (token, error) {
+	return d.lex.peek()
+}
+
+func (d *Reader) unexpected(t token) error {
+	if t.typ == tokEOF {
+		return fmt.Errorf("line %d: unexpected end of document", t.line)
+	}
+	return fmt.Errorf("line %d: unexpected %s: %v", t.line, t.typ, t)
+}
+
+func (d *Reader) expectPunct(p string) error {
+	t, err := d.next()
+	if err != nil {
+		return err
+	} else if !t.isPunct(p) {
+		return fmt.Errorf("line %d: expected '%s', got %v", t.line, p, t)
+	}
+	return nil
+}
+
+// statement reads the next directive or a set of triples, and adds all resulting quads to the queue.
+func (d *Reader) statement() error {
+	t, err := d.next()
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.typ == tokEOF:
+		if d.inGraph {
+			return d.unexpected(t)
+		}
+		return io.EOF
+	case t.isKeyword("@prefix"), t.isKeyword("PREFIX"):
+		if d.inGraph {
+			return d.unexpected(t)
+		}
+		return d.prefixDirective(t.val[0] == '@')
+	case t.isKeyword("@base"), t.isKeyword("BASE"):
+		if d.inGraph {
+			return d.unexpected(t)
+		}
+		return d.baseDirective(t.val[0] == '@')
+	}
+	if d.trig {
+		switch {
+		case t.isPunct("}") && d.inGraph:
+			d.inGraph, d.graph = false, nil
+			return nil
+		case t.isPunct("{") && !d.inGraph:
+			d.inGraph, d.graph = true, nil
+			return nil
+		case t.isKeyword("GRAPH") && !d.inGraph:
+			t, err = d.next()
+			if err != nil {
+				return err
+			}
+			label, err := d.graphLabel(t)
+			if err != nil {
+				return err
+			}
+			if err = d.expectPunct("{"); err != nil {
+				return err
+			}
+			d.inGraph, d.graph = true, label
+			return nil
+		}
+	}
+	return d.triples(t)
+}
+
+func (d *Reader) prefixDirective(dot bool) error {
+	t, err := d.next()
+	if err != nil {
+		return err
+	} else if t.typ != tokPName || t.local != "" {
+		return fmt.Errorf("line %d: expected prefix name, got %v", t.line, t)
+	}
+	prefix := t.val
+	t, err = d.next()
+	if err != nil {
+		return err
+	} else if t.typ != tokIRI {
+		return fmt.Errorf("line %d: expected IRI, got %v", t.line, t)
+	}
+	d.prefixes[prefix] = d.resolve(t.val)
+	if dot {
+		return d.expectPunct(".")
+	}
+	return nil
+}
+
+func (d *Reader) baseDirective(dot bool) error {
+	t, err := d.next()
+	if err != nil {
+		return err
+	} else if t.typ != tokIRI {
+		return fmt.Errorf("line %d: expected IRI, got %v", t.line, t)
+	}
+	if err = d.SetBase(d.resolve(t.val)); err != nil {
+		return fmt.Errorf("line %d: invalid base IRI: %v", t.line, err)
+	}
+	if dot {
+		return d.expectPunct(".")
+	}
+	return nil
+}
+
+// resolve resolves an IRI against the base IRI.
+func (d *Reader) resolve(iri string) string {
+	if d.base == nil {
+		return iri
+	}
+	u, err := url.Parse(iri)
+	if err != nil || u.IsAbs() {
+		return iri
+	}
+	return d.base.ResolveReference(u).String()
+}
+
+func (d *Reader) iri(t token) (quad.IRI, error) {
+	switch t.typ {
+	case tokIRI:
+		return quad.IRI(d.resolve(t.val)), nil
+	case tokPName:
+		ns, ok := d.prefixes[t.val]
+		if !ok {
+			return "", fmt.Errorf("line %d: undefined prefix: %q", t.line, t.val)
+		}
+		return quad.IRI(ns + t.local), nil
+	}
+	return "", d.unexpected(t)
+}
+
+// graphLabel reads a label of the graph block in TriG documents.
+func (d *Reader) graphLabel(t token) (quad.Value, error) {
+	switch {
+	case t.typ == tokBNode:
+		return d.bnode(t.val), nil
+	case t.isPunct("["):
+		if err := d.expectPunct("]"); err != nil {
+			return nil, err
+		}
+		return d.newBNode(), nil
+	}
+	return d.iri(t)
+}
+
+// triples reads a set of triples starting with a given token. In TriG documents
+// it also handles graph blocks that start with a graph label.
+func (d *Reader) triples(t token) error {
+	var (
+		subj quad.Value
+		err  error
+		// the subject was a blank node property list, thus predicates are optional
+		list bool
+	)
+	switch {
+	case t.isPunct("["):
+		nt, err := d.peek()
+		if err != nil {
+			return err
+		}
+		if nt.isPunct("]") {
+			_, _ = d.next()
+			subj = d.newBNode()
+		} else {
+			if subj, err = d.blankNodePropertyList(); err != nil {
+				return err
+			}
+			list = true
+		}
+	case t.isPunct("("):
+		if subj, err = d.collection(); err != nil {
+			return err
+		}
+	case t.typ == tokBNode:
+		subj = d.bnode(t.val)
+	default:
+		iri, err := d.iri(t)
+		if err != nil {
+			return err
+		}
+		subj = iri
+	}
+	nt, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if d.trig && !d.inGraph && !list && !t.isPunct("(") && nt.isPunct("{") {
+		_, _ = d.next()
+		d.inGraph, d.graph = true, subj
+		return nil
+	}
+	if !list || !(nt.isPunct(".") || nt.isPunct("}")) {
+		if err = d.predicateObjectList(subj); err != nil {
+			return err
+		}
+	}
+	t, err = d.next()
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.isPunct("."):
+		return nil
+	case t.isPunct("}") && d.inGraph:
+		// the last statement in the graph block doesn't need a dot
+		d.inGraph, d.graph = false, nil
+		return nil
+	}
+	return fmt.Errorf("line %d: expected '.', got %v", t.line, t)
+}
+
+func (d *Reader) predicateObjectList(subj quad.Value) error {
+	for {
+		t, err := d.next()
+		if err != nil {
+			return err
+		}
+		var pred quad.Value = iriType
+		if !t.isKeyword("a") {
+			iri, err := d.iri(t)
+			if err != nil {
+				return err
+			}
+			pred = iri
+		}
+		if err = d.objectList(subj, pred); err != nil {
+			return err
+		}
+		t, err = d.peek()
+		if err != nil {
+			return err
+		} else if !t.isPunct(";") {
+			return nil
+		}
+		for t.isPunct(";") {
+			_, _ = d.next()
+			if t, err = d.peek(); err != nil {
+				return err
+			}
+		}
+		if t.typ != tokIRI && t.typ != tokPName && !t.isKeyword("a") {
+			return nil
+		}
+	}
+}
+
+func (d *Reader) objectList(subj, pred quad.Value) error {
+	for {
+		t, err := d.next()
+		if err != nil {
+			return err
+		}
+		obj, err := d.object(t)
+		if err != nil {
+			return err
+		}
+		d.emit(subj, pred, obj)
+		if t, err = d.peek(); err != nil {
+			return err
+		} else if !t.isPunct(",") {
+			return nil
+		}
+		_, _ = d.next()
+	}
+}
+
+func (d *Reader) object(t token) (quad.Value, error) {
+	switch t.typ {
+	case tokIRI, tokPName:
+		return d.iri(t)
+	case tokBNode:
+		return d.bnode(t.val), nil
+	case tokString:
+		return d.literal(t)
+	case tokInteger:
+		return d.typed(t.val, iriInteger), nil
+	case tokDecimal:
+		return d.typed(t.val, iriDecimal), nil
+	case tokDouble:
+		return d.typed(t.val, iriDouble), nil
+	case tokKeyword:
+		if t.val == "true" || t.val == "false" {
+			return d.typed(t.val, iriBoolean), nil
+		}
+	case tokPunct:
+		switch t.val {
+		case "[":
+			return d.blankNodePropertyList()
+		case "(":
+			return d.collection()
+		}
+	}
+	return nil, d.unexpected(t)
+}
+
+func (d *Reader) typed(val string, typ quad.IRI) quad.Value {
+	v := quad.TypedString{Value: quad.String(val), Type: typ}
+	if AutoConvertTypedString {
+		if nv, err := v.ParseValue(); err == nil {
+			return nv
+		}
+	}
+	return v
+}
+
+func (d *Reader) literal(t token) (quad.Value, error) {
+	nt, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case nt.typ == tokLang:
+		_, _ = d.next()
+		return quad.LangString{Value: quad.String(t.val), Lang: nt.val}, nil
+	case nt.isPunct("^^"):
+		_, _ = d.next()
+		if nt, err = d.next(); err != nil {
+			return nil, err
+		}
+		typ, err := d.iri(nt)
+		if err != nil {
+			return nil, err
+		}
+		return d.typed(t.val, typ), nil
+	}
+	return quad.String(t.val), nil
+}
+
+// blankNodePropertyList reads a list of properties of a new blank node. The opening bracket must be consumed.
+func (d *Reader) blankNodePropertyList() (quad.Value, error) {
+	node := d.newBNode()
+	t, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	if !t.isPunct("]") {
+		if err = d.predicateObjectList(node); err != nil {
+			return nil, err
+		}
+	}
+	if err = d.expectPunct("]"); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// collection reads an RDF collection. The opening parenthesis must be consumed.
+func (d *Reader) collection() (quad.Value, error) {
+	var items []quad.Value
+	for {
+		t, err := d.next()
+		if err != nil {
+			return nil, err
+		} else if t.isPunct(")") {
+			break
+		}
+		v, err := d.object(t)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	if len(items) == 0 {
+		return iriNil, nil
+	}
+	head := d.newBNode()
+	node := head
+	for i, v := range items {
+		d.emit(node, iriFirst, v)
+		if i == len(items)-1 {
+			d.emit(node, iriRest, iriNil)
+			break
+		}
+		next := d.newBNode()
+		d.emit(node, iriRest, next)
+		node = next
+	}
+	return head, nil
+}
@@ -0,0 +1,304 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package turtle
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cayleygraph/quad"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ex    = "http://example.org/"
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+func iri(s string) quad.IRI { return quad.IRI(ex + s) }
+
+func readAll(t testing.TB, r quad.Reader) []quad.Quad {
+	var out []quad.Quad
+	for {
+		q, err := r.ReadQuad()
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+		out = append(out, q)
+	}
+}
+
+var readerTests = []struct {
+	name string
+	trig bool
+	data string
+	exp  []quad.Quad
+	err  bool
+}{
+	{
+		name: "prefixes and lists",
+		data: `
+@prefix ex: <http://example.org/> .
+PREFIX : <http://example.org/>
+# comment
+ex:alice a ex:Person ; # comment
+	ex:name "Alice", 'Al'@en ;
+	:knows ex:bob, :carol ;
+	.
+`,
+		exp: []quad.Quad{
+			{Subject: iri("alice"), Predicate: quad.IRI(rdfNS + "type"), Object: iri("Person")},
+			{Subject: iri("alice"), Predicate: iri("name"), Object: quad.String("Alice")},
+			{Subject: iri("alice"), Predicate: iri("name"), Object: quad.LangString{Value: "Al", Lang: "en"}},
+			{Subject: iri("alice"), Predicate: iri("knows"), Object: iri("bob")},
+			{Subject: iri("alice"), Predicate: iri("knows"), Object: iri("carol")},
+		},
+	},
+	{
+		name: "base",
+		data: `
+@base <http://example.org/a/> .
+<b> <p> <../c> .
+BASE <http://example.net/>
+<d> <#p> <e> .
+`,
+		exp: []quad.Quad{
+			{Subject: quad.IRI(ex + "a/b"), Predicate: quad.IRI(ex + "a/p"), Object: iri("c")},
+			{Subject: quad.IRI("http://example.net/d"), Predicate: quad.IRI("http://example.net/#p"), Object: quad.IRI("http://example.net/e")},
+		},
+	},
+	{
+		name: "literals",
+		data: `
+@prefix ex: <http://example.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+ex:s ex:p 1, -2.5, 1e3, true, "5"^^xsd:integer, "x"^^ex:t, """long
+"string\"""", "A\t" .
+`,
+		exp: []quad.Quad{
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.Int(1)},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.TypedString{Value: "-2.5", Type: iriDecimal}},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.Float(1000)},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.Bool(true)},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.Int(5)},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.TypedString{Value: "x", Type: iri("t")}},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.String("long\n\"string\"")},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.String("A\t")},
+		},
+	},
+	{
+		name: "blank nodes",
+		data: `
+@prefix ex: <http://example.org/> .
+_:a ex:p [ ex:q [] ; ex:r _:b ] .
+[ ex:s ex:o ] .
+[] ex:t 1.
+`,
+		exp: []quad.Quad{
+			{Subject: quad.BNode("genid1"), Predicate: iri("q"), Object: quad.BNode("genid2")},
+			{Subject: quad.BNode("genid1"), Predicate: iri("r"), Object: quad.BNode("b")},
+			{Subject: quad.BNode("a"), Predicate: iri("p"), Object: quad.BNode("genid1")},
+			{Subject: quad.BNode("genid3"), Predicate: iri("s"), Object: iri("o")},
+			{Subject: quad.BNode("genid4"), Predicate: iri("t"), Object: quad.Int(1)},
+		},
+	},
+	{
+		name: "blank node labels like generated ones",
+		data: `
+@prefix ex: <http://example.org/> .
+_:genid1 ex:p [ ex:q ex:r ] .
+_:genid1 ex:s _:genid2 .
+`,
+		exp: []quad.Quad{
+			{Subject: quad.BNode("genid2"), Predicate: iri("q"), Object: iri("r")},
+			{Subject: quad.BNode("genid1"), Predicate: iri("p"), Object: quad.BNode("genid2")},
+			{Subject: quad.BNode("genid1"), Predicate: iri("s"), Object: quad.BNode("genid3")},
+		},
+	},
+	{
+		name: "decimals with a leading dot",
+		data: `
+@prefix ex: <http://example.org/> .
+ex:s ex:p .5, -.25 .
+<http://example.org/a> <http://example.org/b> .5 .
+`,
+		exp: []quad.Quad{
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.TypedString{Value: ".5", Type: iriDecimal}},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.TypedString{Value: "-.25", Type: iriDecimal}},
+			{Subject: iri("a"), Predicate: iri("b"), Object: quad.TypedString{Value: ".5", Type: iriDecimal}},
+		},
+	},
+	{
+		name: "collections",
+		data: `
+@prefix ex: <http://example.org/> .
+ex:s ex:p ( ex:a "b" ), () .
+`,
+		exp: []quad.Quad{
+			{Subject: quad.BNode("genid1"), Predicate: quad.IRI(rdfNS + "first"), Object: iri("a")},
+			{Subject: quad.BNode("genid1"), Predicate: quad.IRI(rdfNS + "rest"), Object: quad.BNode("genid2")},
+			{Subject: quad.BNode("genid2"), Predicate: quad.IRI(rdfNS + "first"), Object: quad.String("b")},
+			{Subject: quad.BNode("genid2"), Predicate: quad.IRI(rdfNS + "rest"), Object: quad.IRI(rdfNS + "nil")},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.BNode("genid1")},
+			{Subject: iri("s"), Predicate: iri("p"), Object: quad.IRI(rdfNS + "nil")},
+		},
+	},
+	{
+		name: "escaped names",
+		data: `
+@prefix ex: <http://example.org/> .
+ex:a.b ex:c\,d ex:e.
+`,
+		exp: []quad.Quad{
+			{Subject: iri("a.b"), Predicate: iri("c,d"), Object: iri("e")},
+		},
+	},
+	{
+		name: "undefined prefix",
+		data: `ex:a ex:b ex:c .`,
+		err:  true,
+	},
+	{
+		name: "graph in turtle",
+		data: `<g> { <a> <b> <c> }`,
+		err:  true,
+	},
+	{
+		name: "trig",
+		trig: true,
+		data: `
+@prefix ex: <http://example.org/> .
+ex:a ex:b ex:c .
+ex:g1 { ex:a ex:b ex:d . ex:a ex:b ex:e }
+GRAPH ex:g2 { ex:a ex:b ex:f . }
+{ ex:a ex:b ex:g }
+`,
+		exp: []quad.Quad{
+			{Subject: iri("a"), Predicate: iri("b"), Object: iri("c")},
+			{Subject: iri("a"), Predicate: iri("b"), Object: iri("d"), Label: iri("g1")},
+			{Subject: iri("a"), Predicate: iri("b"), Object: iri("e"), Label: iri("g1")},
+			{Subject: iri("a"), Predicate: iri("b"), Object: iri("f"), Label: iri("g2")},
+			{Subject: iri("a"), Predicate: iri("b"), Object: iri("g")},
+		},
+	},
+	{
+		name: "unterminated graph",
+		trig: true,
+		data: `<g> { <a> <b> <c> .`,
+		err:  true,
+	},
+}
+
+func TestReader(t *testing.T) {
+	for _, c := range readerTests {
+		t.Run(c.name, func(t *testing.T) {
+			var r *Reader
+			if c.trig {
+				r = NewTriGReader(strings.NewReader(c.data))
+			} else {
+				r = NewReader(strings.NewReader(c.data))
+			}
+			var got []quad.Quad
+			for {
+				q, err := r.ReadQuad()
+				if err == io.EOF {
+					break
+				} else if c.err && err != nil {
+					return
+				}
+				require.NoError(t, err)
+				got = append(got, q)
+			}
+			require.False(t, c.err, "expected an error")
+			require.Equal(t, c.exp, got)
+		})
+	}
+}
+
+var writerQuads = []quad.Quad{
+	{Subject: iri("alice"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI("http://schema.org/Person")},
+	{Subject: iri("alice"), Predicate: quad.IRI("http://schema.org/name"), Object: quad.String("Alice \"A\"\n")},
+	{Subject: iri("alice"), Predicate: quad.IRI("http://schema.org/name"), Object: quad.LangString{Value: "Al", Lang: "en"}},
+	{Subject: iri("alice"), Predicate: iri("age"), Object: quad.Int(30)},
+	{Subject: iri("alice"), Predicate: iri("knows"), Object: quad.BNode("b1")},
+	{Subject: quad.BNode("b1"), Predicate: iri("odd name"), Object: quad.Bool(false)},
+	{Subject: quad.BNode("b1"), Predicate: iri("p"), Object: quad.TypedString{Value: "x", Type: iri("t")}},
+}
+
+func TestWriter(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	_, err := w.WriteQuads(writerQuads)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	out := buf.String()
+	require.Contains(t, out, "@prefix schema: <http://schema.org/> .\n")
+	require.Contains(t, out, `<http://example.org/alice> a schema:Person ;
+    schema:name "Alice \"A\"\n" ,
+        "Al"@en ;
+    <http://example.org/age> 30 ;`)
+	require.Contains(t, out, `_:b1 <http://example.org/odd\u0020name> false ;`)
+
+	got := readAll(t, NewReader(strings.NewReader(out)))
+	require.Equal(t, writerQuads, got)
+
+	err = NewWriter(buf).WriteQuad(quad.MakeIRI("a", "b", "c", "d"))
+	require.Equal(t, ErrLabel, err)
+}
+
+func TestTriGWriter(t *testing.T) {
+	quads := []quad.Quad{
+		{Subject: iri("a"), Predicate: iri("b"), Object: iri("c")},
+		{Subject: iri("a"), Predicate: iri("b"), Object: iri("d"), Label: iri("g")},
+		{Subject: iri("a"), Predicate: iri("c"), Object: iri("d"), Label: iri("g")},
+		{Subject: iri("e"), Predicate: iri("b"), Object: iri("d"), Label: iri("g")},
+		{Subject: iri("a"), Predicate: iri("b"), Object: iri("e"), Label: quad.BNode("g")},
+		{Subject: iri("a"), Predicate: iri("b"), Object: iri("f")},
+	}
+	buf := bytes.NewBuffer(nil)
+	w := NewTriGWriter(buf)
+	_, err := w.WriteQuads(quads)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	got := readAll(t, NewTriGReader(strings.NewReader(buf.String())))
+	require.Equal(t, quads, got)
+}
+
+func TestFormats(t *testing.T) {
+	require.Equal(t, "turtle", quad.FormatByMime("text/turtle").Name)
+	require.Equal(t, "trig", quad.FormatByMime("application/trig").Name)
+	require.Equal(t, "turtle", quad.FormatByExt(".ttl").Name)
+	require.Equal(t, "trig", quad.FormatByExt(".trig").Name)
+
+	f := quad.FormatByName("turtle")
+	for _, v := range []quad.Value{
+		iri("a"),
+		quad.BNode("b"),
+		quad.String("a\"b"),
+		quad.LangString{Value: "a", Lang: "en"},
+		quad.Int(-3),
+	} {
+		b, err := f.MarshalValue(v)
+		require.NoError(t, err)
+		v2, err := f.UnmarshalValue(b)
+		require.NoError(t, err)
+		require.Equal(t, v, v2, "%s", b)
+	}
+}
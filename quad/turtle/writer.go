@@ -0,0 +1,343 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package turtle
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc"
+)
+
+// ErrLabel is returned when the quad with a label is written to the Turtle document.
+var ErrLabel = errors.New("turtle: cannot write quads with labels, use trig format instead")
+
+// NewWriter returns a Turtle encoder that writes its output to the provided io.Writer.
+//
+// Quads with a label cannot be written to Turtle documents, use NewTriGWriter for them.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// NewTriGWriter returns a TriG encoder that writes its output to the provided io.Writer.
+func NewTriGWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), trig: true}
+}
+
+// Writer implements Turtle and TriG document generation.
+//
+// All namespaces registered in voc package are written as prefixes at the beginning
+// of the document and are used to shorten IRIs. Consecutive quads with the same subject
+// are grouped into a single statement.
+type Writer struct {
+	w    *bufio.Writer
+	trig bool
+	ns   namespaces
+	err  error
+
+	started bool
+	// sep is set when an empty line should be written before the next statement
+	sep bool
+	// block is set when the graph block is open
+	block bool
+	graph string
+	// subj and pred are the last subject and predicate written in the current statement
+	subj, pred string
+}
+
+func (w *Writer) write(s ...string) {
+	for _, s := range s {
+		if w.err != nil {
+			return
+		}
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+func (w *Writer) indent() string {
+	if w.block {
+		return "    "
+	}
+	return ""
+}
+
+func (w *Writer) writePrefixes() {
+	w.ns = newNamespaces(voc.List())
+	list := make([]voc.Namespace, len(w.ns))
+	copy(list, w.ns)
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Prefix < list[j].Prefix
+	})
+	for _, ns := range list {
+		w.write("@prefix ", ns.Prefix, " <", escapeIRI(ns.Full), "> .\n")
+	}
+	w.sep = len(list) != 0
+}
+
+// separate writes an empty line between statements and graph blocks.
+func (w *Writer) separate() {
+	if w.sep {
+		w.write("\n")
+	}
+	w.sep = true
+}
+
+// endStatement closes the current statement, if any.
+func (w *Writer) endStatement() {
+	if w.subj == "" {
+		return
+	}
+	w.write(" .\n")
+	w.subj, w.pred = "", ""
+}
+
+// endBlock closes the current graph block, if any.
+func (w *Writer) endBlock() {
+	w.endStatement()
+	if w.block {
+		w.write("}\n")
+		w.block = false
+	}
+	w.graph = ""
+}
+
+// WriteQuad implements quad.Writer.
+func (w *Writer) WriteQuad(q quad.Quad) error {
+	if !q.IsValid() {
+		return quad.ErrInvalid
+	} else if w.err != nil {
+		return w.err
+	}
+	if !w.started {
+		w.started = true
+		w.writePrefixes()
+	}
+	if q.Label != nil {
+		if !w.trig {
+			return ErrLabel
+		}
+		if g := formatValue(q.Label, w.ns); !w.block || g != w.graph {
+			w.endBlock()
+			w.separate()
+			w.write(g, " {\n")
+			w.sep = false
+			w.block, w.graph = true, g
+		}
+	} else if w.block {
+		w.endBlock()
+	}
+	s := formatValue(q.Subject, w.ns)
+	p := formatPredicate(q.Predicate, w.ns)
+	o := formatValue(q.Object, w.ns)
+	switch {
+	case s != w.subj:
+		w.endStatement()
+		w.separate()
+		w.write(w.indent(), s, " ", p, " ", o)
+	case p != w.pred:
+		w.write(" ;\n", w.indent(), "    ", p, " ", o)
+	default:
+		w.write(" ,\n", w.indent(), "        ", o)
+	}
+	w.subj, w.pred = s, p
+	return w.err
+}
+
+// WriteQuads implements quad.BatchWriter.
+func (w *Writer) WriteQuads(buf []quad.Quad) (int, error) {
+	for i, q := range buf {
+		if err := w.WriteQuad(q); err != nil {
+			return i, err
+		}
+	}
+	return len(buf), nil
+}
+
+// Close finishes the document and flushes all the data to the underlying writer.
+// It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.endBlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// namespaces is a list of namespaces used to shorten IRIs.
+// It is sorted by the length of full IRI in descending order, thus the most specific namespace is used first.
+type namespaces []voc.Namespace
+
+func newNamespaces(list []voc.Namespace) namespaces {
+	var ns namespaces
+	for _, n := range list {
+		p := strings.TrimSuffix(n.Prefix, ":")
+		if p == n.Prefix || !validPrefix(p) {
+			continue
+		}
+		ns = append(ns, n)
+	}
+	sort.SliceStable(ns, func(i, j int) bool {
+		return len(ns[i].Full) > len(ns[j].Full)
+	})
+	return ns
+}
+
+// shorten returns a prefixed name for the IRI, if possible.
+func (ns namespaces) shorten(iri string) (string, bool) {
+	for _, n := range ns {
+		if !strings.HasPrefix(iri, n.Full) {
+			continue
+		}
+		if local := iri[len(n.Full):]; validLocal(local) {
+			return n.Prefix + local, true
+		}
+	}
+	return "", false
+}
+
+func validPrefix(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i, r := range s {
+		if i == 0 && !unicode.IsLetter(r) {
+			return false
+		} else if !isNameChar(r) && r != '.' {
+			return false
+		}
+	}
+	return s[len(s)-1] != '.'
+}
+
+// validLocal checks if the string can be written as a local part of a prefixed name without escaping.
+func validLocal(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i, r := range s {
+		if i == 0 && (r == '-' || r == '.') {
+			return false
+		} else if !isNameChar(r) && r != '.' && r != ':' {
+			return false
+		}
+	}
+	return s[len(s)-1] != '.'
+}
+
+var iriEscaper = strings.NewReplacer(
+	"\\", `\u005C`,
+	">", `\u003E`,
+	"<", `\u003C`,
+	"\"", `\u0022`,
+	" ", `\u0020`,
+	"\n", `\u000A`,
+	"\r", `\u000D`,
+	"\t", `\u0009`,
+	"{", `\u007B`,
+	"}", `\u007D`,
+	"|", `\u007C`,
+	"^", `\u005E`,
+	"`", `\u0060`,
+)
+
+func escapeIRI(s string) string {
+	return iriEscaper.Replace(s)
+}
+
+var labelEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\"", "\\\"",
+	"\n", "\\n",
+	"\r", "\\r",
+	"\t", "\\t",
+)
+
+func formatString(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+func formatIRI(iri quad.IRI, ns namespaces) string {
+	s := string(iri.Full())
+	if pn, ok := ns.shorten(s); ok {
+		return pn
+	}
+	return "<" + escapeIRI(s) + ">"
+}
+
+func formatPredicate(v quad.Value, ns namespaces) string {
+	if iri, ok := v.(quad.IRI); ok && iri.Full() == iriType {
+		return "a"
+	}
+	return formatValue(v, ns)
+}
+
+var (
+	reInteger = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	reDecimal = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+	reDouble  = regexp.MustCompile(`^[+-]?([0-9]+\.[0-9]+|\.[0-9]+|[0-9]+)[eE][+-]?[0-9]+$`)
+)
+
+func formatTyped(v quad.TypedString, ns namespaces) string {
+	val := string(v.Value)
+	switch v.Type.Full() {
+	case iriInteger:
+		if reInteger.MatchString(val) {
+			return val
+		}
+	case iriDecimal:
+		if reDecimal.MatchString(val) {
+			return val
+		}
+	case iriDouble:
+		if reDouble.MatchString(val) {
+			return val
+		}
+	case iriBoolean:
+		if val == "true" || val == "false" {
+			return val
+		}
+	}
+	return formatString(val) + "^^" + formatIRI(v.Type, ns)
+}
+
+// formatValue formats the value according to Turtle syntax. IRIs are shortened using a given list of namespaces.
+func formatValue(v quad.Value, ns namespaces) string {
+	switch v := v.(type) {
+	case quad.IRI:
+		return formatIRI(v, ns)
+	case quad.BNode:
+		return "_:" + string(v)
+	case quad.String:
+		return formatString(string(v))
+	case quad.LangString:
+		return formatString(string(v.Value)) + "@" + v.Lang
+	case quad.TypedString:
+		return formatTyped(v, ns)
+	case quad.Bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case quad.TypedStringer:
+		return formatTyped(v.TypedString(), ns)
+	}
+	return v.String()
+}
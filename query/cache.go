@@ -0,0 +1,60 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import "sync"
+
+// PlanCache is a bounded cache of parsed queries, keyed by the query text.
+//
+// Cached plans are shared between sessions, thus they must not be modified after being added,
+// and parameters of the query must be bound only when the query is executed.
+// It is safe for concurrent use.
+type PlanCache struct {
+	mu  sync.Mutex
+	max int
+	m   map[string]interface{}
+}
+
+// NewPlanCache creates a cache that holds up to max plans.
+func NewPlanCache(max int) *PlanCache {
+	return &PlanCache{max: max}
+}
+
+// Get returns a plan for a given query text, if it was cached.
+func (c *PlanCache) Get(text string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.m[text]
+	return p, ok
+}
+
+// Put adds a plan for a given query text to the cache. If the cache is full, an arbitrary plan is evicted.
+func (c *PlanCache) Put(text string, plan interface{}) {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]interface{})
+	}
+	if _, ok := c.m[text]; !ok && len(c.m) >= c.max {
+		for k := range c.m {
+			delete(c.m, k)
+			break
+		}
+	}
+	c.m[text] = plan
+}
@@ -0,0 +1,938 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cypher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/cayley/query/shape"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+)
+
+// Plan is a compiled Cypher query.
+type Plan struct {
+	// Path matches all patterns of the query. Each result path of it is a single row.
+	Path *path.Path
+	// Columns of the result.
+	Columns []Column
+	// Order lists sort keys for rows.
+	Order []OrderKey
+	// Tags lists all tags that must be read for each row. Columns and sort keys refer to them by index.
+	Tags     []string
+	Distinct bool
+	Skip     int64
+	Limit    int64 // negative if not set
+}
+
+// Column is a single column of the result.
+type Column struct {
+	Name string
+	Tag  int // index in Plan.Tags
+}
+
+// OrderKey is a sort key for rows.
+type OrderKey struct {
+	Tag  int // index in Plan.Tags
+	Desc bool
+}
+
+// Compile converts a Cypher query to a path on the quad store.
+//
+// Every node variable is a tag on the path, node labels are matched via rdf:type, and relationship
+// types and property names are converted to predicate IRIs. Registered namespaces are expanded.
+func Compile(qs graph.QuadStore, q *Query) (*Plan, error) {
+	c := &compiler{
+		nodes: make(map[string]*patternNode),
+		rels:  make(map[string]*patternEdge),
+		saves: make(map[string]int),
+	}
+	return c.compile(qs, q)
+}
+
+// patternNode is a node of the pattern graph. Nodes are connected with edges from all MATCH clauses.
+type patternNode struct {
+	name  string
+	named bool
+	group int // zero for MATCH, or index of OPTIONAL MATCH clause
+	edges []*patternEdge
+	// conditions for each clause; each condition is a path that matches all nodes that satisfy it
+	conds map[int][]*path.Path
+	// conditions on property values from the clause of the node; each condition is a path that matches all values that satisfy it
+	bound map[quad.IRI]*path.Path
+	saves []propSave
+}
+
+type propSave struct {
+	pred quad.IRI
+	tag  string
+}
+
+// patternEdge is a relationship between two nodes of the pattern graph.
+type patternEdge struct {
+	from, to *patternNode
+	rel      *RelPattern
+	group    int
+}
+
+func (e *patternEdge) other(n *patternNode) *patternNode {
+	if e.from == n {
+		return e.to
+	}
+	return e.from
+}
+
+type compiler struct {
+	nodes  map[string]*patternNode
+	order  []*patternNode
+	rels   map[string]*patternEdge
+	relOrd []string
+	anon   int
+	ncond  int // number of conditions that are not yet applied
+
+	plan  Plan
+	saves map[string]int // tag index for properties and variables
+}
+
+func (c *compiler) compile(qs graph.QuadStore, q *Query) (*Plan, error) {
+	if len(q.Match) == 0 || q.Match[0].Optional {
+		return nil, fmt.Errorf("query must start with MATCH")
+	}
+	for i, m := range q.Match {
+		group := 0
+		if m.Optional {
+			group = i
+		}
+		if err := c.addMatch(m, group); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkTree(); err != nil {
+		return nil, err
+	}
+	if err := c.addReturn(q.Return); err != nil {
+		return nil, err
+	}
+	root := c.order[0]
+	p, err := c.compileNode(path.StartPath(qs), root, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.ncond != 0 {
+		return nil, fmt.Errorf("conditions of WHERE can only refer to variables of the same MATCH clause")
+	}
+	if c.plan.Limit > 0 && len(c.plan.Order) == 0 && !c.plan.Distinct {
+		// each row is a separate path of the iterator, and the limit counts them as well;
+		// zero limit means no limit for the iterator, thus it's only checked by results
+		p = p.Limit(c.plan.Skip + c.plan.Limit)
+	}
+	c.plan.Path = p
+	return &c.plan, nil
+}
+
+// node returns a pattern node for a variable, or creates a new one.
+func (c *compiler) node(name string, group int) (*patternNode, error) {
+	if name != "" {
+		if n := c.nodes[name]; n != nil {
+			if n.group != 0 && n.group != group {
+				return nil, fmt.Errorf("variable %q is bound by OPTIONAL MATCH and can't be used in other clauses", name)
+			}
+			return n, nil
+		}
+		if _, ok := c.rels[name]; ok {
+			return nil, fmt.Errorf("variable %q is already used for a relationship", name)
+		}
+	}
+	n := &patternNode{
+		name: name, named: name != "", group: group,
+		conds: make(map[int][]*path.Path),
+		bound: make(map[quad.IRI]*path.Path),
+	}
+	if !n.named {
+		c.anon++
+		n.name = fmt.Sprintf("_n%d", c.anon)
+	}
+	c.nodes[n.name] = n
+	c.order = append(c.order, n)
+	return n, nil
+}
+
+func (c *compiler) addCond(n *patternNode, group int, cond *path.Path) {
+	n.conds[group] = append(n.conds[group], cond)
+	c.ncond++
+}
+
+func (c *compiler) addMatch(m *Match, group int) error {
+	for _, pt := range m.Patterns {
+		var prev *patternNode
+		for i, np := range pt.Nodes {
+			n, err := c.node(np.Var, group)
+			if err != nil {
+				return err
+			}
+			for _, l := range np.Labels {
+				c.addCond(n, group, path.StartMorphism().Has(rdfType, iri(l)))
+			}
+			for _, pr := range np.Props {
+				cond, err := c.compareProp(iri(pr.Key), "=", pr.Value)
+				if err != nil {
+					return err
+				}
+				c.addCond(n, group, cond)
+				if err = c.bindProp(n, group, &Binary{Op: "=", Left: &PropertyAccess{Var: n.name, Key: pr.Key}, Right: pr.Value}); err != nil {
+					return err
+				}
+			}
+			if i > 0 {
+				r := pt.Rels[i-1]
+				if len(r.Props) != 0 {
+					return fmt.Errorf("relationship properties are not supported")
+				}
+				e := &patternEdge{from: prev, to: n, rel: r, group: group}
+				if r.Var != "" {
+					if _, ok := c.rels[r.Var]; ok {
+						return fmt.Errorf("relationship variable %q is used more than once", r.Var)
+					} else if _, ok := c.nodes[r.Var]; ok {
+						return fmt.Errorf("variable %q is already used for a node", r.Var)
+					} else if r.VarLength {
+						return fmt.Errorf("variables for variable length relationships are not supported")
+					}
+					c.rels[r.Var] = e
+					c.relOrd = append(c.relOrd, r.Var)
+				}
+				if r.VarLength {
+					if r.Min < 1 {
+						return fmt.Errorf("minimal length of a relationship must be at least 1")
+					} else if r.Max >= 0 && r.Max < r.Min {
+						return fmt.Errorf("invalid length of a relationship: %d..%d", r.Min, r.Max)
+					}
+				}
+				prev.edges = append(prev.edges, e)
+				n.edges = append(n.edges, e)
+			}
+			prev = n
+		}
+	}
+	if m.Where == nil {
+		return nil
+	}
+	for _, e := range splitAnd(m.Where, nil) {
+		vars := make(map[string]struct{})
+		exprVars(e, vars)
+		if len(vars) != 1 {
+			return fmt.Errorf("each condition in WHERE must refer to exactly one variable: %v", e)
+		}
+		var name string
+		for name = range vars {
+		}
+		n := c.nodes[name]
+		if n == nil {
+			if _, ok := c.rels[name]; ok {
+				return fmt.Errorf("conditions on relationships are not supported: %v", e)
+			}
+			return fmt.Errorf("unknown variable: %q", name)
+		}
+		cond, err := c.compileCond(e)
+		if err != nil {
+			return err
+		}
+		c.addCond(n, group, cond)
+		if err = c.bindProp(n, group, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindProp remembers a comparison of a node property with a constant. Properties may have multiple values,
+// thus the values returned for the property are restricted to the ones that satisfy the comparison.
+// Only the conditions from the clause that binds the node are used.
+func (c *compiler) bindProp(n *patternNode, group int, e Expr) error {
+	b, ok := e.(*Binary)
+	if !ok || n.group != group {
+		return nil
+	}
+	op, left, right := b.Op, b.Left, b.Right
+	if isConst(left) {
+		fop, ok := flipOps[op]
+		if !ok {
+			return nil
+		}
+		op, left, right = fop, right, left
+	}
+	pa, ok := left.(*PropertyAccess)
+	if !ok || !isConst(right) {
+		return nil
+	}
+	vc, err := valueCond(op, right)
+	if err != nil || vc == nil {
+		return err
+	}
+	pred := iri(pa.Key)
+	if prev, ok := n.bound[pred]; ok {
+		vc = prev.Follow(vc)
+	}
+	n.bound[pred] = vc
+	return nil
+}
+
+// checkTree checks that the pattern graph is connected and has no cycles.
+// Nodes of MATCH clauses must be connected without relationships from OPTIONAL MATCH.
+func (c *compiler) checkTree() error {
+	parent := make(map[*patternNode]*patternNode)
+	var find func(n *patternNode) *patternNode
+	find = func(n *patternNode) *patternNode {
+		if p, ok := parent[n]; ok && p != n {
+			r := find(p)
+			parent[n] = r
+			return r
+		}
+		return n
+	}
+	union := func(e *patternEdge) error {
+		a, b := find(e.from), find(e.to)
+		if a == b {
+			return fmt.Errorf("cyclic patterns are not supported")
+		}
+		parent[a] = b
+		return nil
+	}
+	var opt []*patternEdge
+	seen := make(map[*patternEdge]bool)
+	for _, n := range c.order {
+		for _, e := range n.edges {
+			if seen[e] {
+				continue
+			}
+			seen[e] = true
+			if e.group != 0 {
+				if g := e.from.group; g != 0 && g != e.group {
+					return fmt.Errorf("variable %q is bound by OPTIONAL MATCH and can't be used in other clauses", e.from.name)
+				} else if g = e.to.group; g != 0 && g != e.group {
+					return fmt.Errorf("variable %q is bound by OPTIONAL MATCH and can't be used in other clauses", e.to.name)
+				}
+				opt = append(opt, e)
+			} else if err := union(e); err != nil {
+				return err
+			}
+		}
+	}
+	root := find(c.order[0])
+	for _, n := range c.order {
+		if n.group == 0 && find(n) != root {
+			return fmt.Errorf("disconnected patterns are not supported")
+		}
+	}
+	for _, e := range opt {
+		if err := union(e); err != nil {
+			return err
+		}
+	}
+	for _, n := range c.order {
+		if find(n) != find(c.order[0]) {
+			return fmt.Errorf("disconnected patterns are not supported")
+		}
+	}
+	return nil
+}
+
+// tag returns an index of a tag in the plan, adding it if necessary.
+func (c *compiler) tag(name string) int {
+	if i, ok := c.saves[name]; ok {
+		return i
+	}
+	i := len(c.plan.Tags)
+	c.plan.Tags = append(c.plan.Tags, name)
+	c.saves[name] = i
+	return i
+}
+
+// resolve returns a tag index for a RETURN or ORDER BY expression.
+func (c *compiler) resolve(e Expr) (int, error) {
+	switch e := e.(type) {
+	case *Variable:
+		if _, ok := c.nodes[e.Name]; ok {
+			return c.tag(e.Name), nil
+		} else if _, ok := c.rels[e.Name]; ok {
+			return c.tag(e.Name), nil
+		}
+		return 0, fmt.Errorf("unknown variable: %q", e.Name)
+	case *PropertyAccess:
+		n := c.nodes[e.Var]
+		if n == nil {
+			if _, ok := c.rels[e.Var]; ok {
+				return 0, fmt.Errorf("relationship properties are not supported")
+			}
+			return 0, fmt.Errorf("unknown variable: %q", e.Var)
+		}
+		name := e.String()
+		if _, ok := c.saves[name]; !ok {
+			n.saves = append(n.saves, propSave{pred: iri(e.Key), tag: name})
+		}
+		return c.tag(name), nil
+	case *Call:
+		if len(e.Args) != 1 {
+			break
+		}
+		v, ok := e.Args[0].(*Variable)
+		if !ok {
+			break
+		}
+		switch e.Func {
+		case "id":
+			if _, ok := c.nodes[v.Name]; ok {
+				return c.tag(v.Name), nil
+			}
+		case "type":
+			if _, ok := c.rels[v.Name]; ok {
+				return c.tag(v.Name), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unsupported expression: %v", e)
+}
+
+func (c *compiler) addReturn(r *Return) error {
+	c.plan.Distinct = r.Distinct
+	c.plan.Skip, c.plan.Limit = r.Skip, r.Limit
+	items := r.Items
+	if r.All {
+		items = nil
+		for _, n := range c.order {
+			if n.named {
+				items = append(items, ReturnItem{Expr: &Variable{Name: n.name}})
+			}
+		}
+		for _, name := range c.relOrd {
+			items = append(items, ReturnItem{Expr: &Variable{Name: name}})
+		}
+	}
+	names := make(map[string]int)
+	for _, it := range items {
+		tag, err := c.resolve(it.Expr)
+		if err != nil {
+			return err
+		}
+		name := it.Alias
+		if name == "" {
+			name = it.Expr.String()
+		}
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("duplicate column name: %q", name)
+		}
+		names[name] = tag
+		c.plan.Columns = append(c.plan.Columns, Column{Name: name, Tag: tag})
+	}
+	for _, s := range r.Order {
+		tag, ok := -1, false
+		if v, isVar := s.Expr.(*Variable); isVar {
+			// reference to a column alias
+			tag, ok = names[v.Name]
+		}
+		if !ok {
+			var err error
+			if tag, err = c.resolve(s.Expr); err != nil {
+				return err
+			}
+		}
+		c.plan.Order = append(c.plan.Order, OrderKey{Tag: tag, Desc: s.Desc})
+	}
+	return nil
+}
+
+// compileNode applies conditions and saves of a node and follows all its edges of a given clause, except the one
+// that leads to this node. Edges of OPTIONAL MATCH clauses are applied as optional paths.
+func (c *compiler) compileNode(p *path.Path, n *patternNode, from *patternEdge, group int) (*path.Path, error) {
+	if n.named {
+		p = p.Tag(n.name)
+	}
+	p = c.applyConds(p, n, group)
+	if n.group == group {
+		for _, s := range n.saves {
+			if vc, ok := n.bound[s.pred]; ok {
+				// only save values that satisfy the conditions; the property is required by them,
+				// and values are deduplicated, since they are reached from all nodes with the property
+				p = p.And(path.StartMorphism().Out(s.pred).Follow(vc).Unique().Tag(s.tag).In(s.pred))
+			} else {
+				p = p.SaveOptional(s.pred, s.tag)
+			}
+		}
+	}
+	var (
+		required []*patternEdge
+		optional []int
+		byGroup  = make(map[int][]*patternEdge)
+	)
+	for _, e := range n.edges {
+		if e == from {
+			continue
+		} else if e.group == group {
+			required = append(required, e)
+		} else if group == 0 {
+			if _, ok := byGroup[e.group]; !ok {
+				optional = append(optional, e.group)
+			}
+			byGroup[e.group] = append(byGroup[e.group], e)
+		}
+	}
+	for _, g := range optional {
+		// all relationships of the same OPTIONAL MATCH must match together
+		m := c.applyConds(path.StartMorphism(), n, g)
+		m, err := c.followEdges(m, n, byGroup[g], g)
+		if err != nil {
+			return nil, err
+		}
+		p = p.Optional(m)
+	}
+	return c.followEdges(p, n, required, group)
+}
+
+func (c *compiler) applyConds(p *path.Path, n *patternNode, group int) *path.Path {
+	for _, cond := range n.conds[group] {
+		// filters must not multiply results, thus they are deduplicated
+		p = p.And(cond.Unique())
+		c.ncond--
+	}
+	return p
+}
+
+// followEdges follows the edges from the node. All edges except the last one are checked as
+// subpaths of the node, and the path continues from the node at the end of the last edge.
+func (c *compiler) followEdges(p *path.Path, n *patternNode, edges []*patternEdge, group int) (*path.Path, error) {
+	for i, e := range edges {
+		m, err := c.edgeStep(path.StartMorphism(), n, e)
+		if err != nil {
+			return nil, err
+		}
+		m, err = c.compileNode(m, e.other(n), e, group)
+		if err != nil {
+			return nil, err
+		}
+		if i == len(edges)-1 {
+			p = p.Follow(m)
+		} else {
+			p = p.And(m.Reverse())
+		}
+	}
+	return p, nil
+}
+
+// edgeStep moves the path from node n over the relationship.
+func (c *compiler) edgeStep(p *path.Path, n *patternNode, e *patternEdge) (*path.Path, error) {
+	r := e.rel
+	dir := r.Dir
+	if e.from != n {
+		switch dir {
+		case DirOut:
+			dir = DirIn
+		case DirIn:
+			dir = DirOut
+		}
+	}
+	var via []interface{}
+	if len(r.Types) != 0 {
+		preds := make([]quad.Value, 0, len(r.Types))
+		for _, t := range r.Types {
+			preds = append(preds, iri(t))
+		}
+		via = []interface{}{preds}
+	}
+	hop := func(p *path.Path, tags []string) *path.Path {
+		switch dir {
+		case DirOut:
+			return p.OutWithTags(tags, via...)
+		case DirIn:
+			return p.InWithTags(tags, via...)
+		}
+		return p.BothWithTags(tags, via...)
+	}
+	if !r.VarLength {
+		var tags []string
+		if r.Var != "" {
+			tags = []string{r.Var}
+		}
+		return hop(p, tags), nil
+	}
+	// paths of length min..max are the same as min-1 fixed steps, followed by 1..max-min+1 recursive steps
+	for i := 1; i < r.Min; i++ {
+		p = hop(p, nil)
+	}
+	if r.Max == r.Min {
+		return hop(p, nil), nil
+	}
+	depth := -1
+	if r.Max > 0 {
+		depth = r.Max - r.Min + 1
+	}
+	return p.FollowRecursive(hop(path.StartMorphism(), nil), depth, nil), nil
+}
+
+// splitAnd returns all terms of a conjunction.
+func splitAnd(e Expr, out []Expr) []Expr {
+	if b, ok := e.(*Binary); ok && b.Op == "AND" {
+		out = splitAnd(b.Left, out)
+		return splitAnd(b.Right, out)
+	}
+	return append(out, e)
+}
+
+// exprVars collects all variables referenced in the expression.
+func exprVars(e Expr, vars map[string]struct{}) {
+	switch e := e.(type) {
+	case *Variable:
+		vars[e.Name] = struct{}{}
+	case *PropertyAccess:
+		vars[e.Var] = struct{}{}
+	case *HasLabels:
+		vars[e.Var] = struct{}{}
+	case *Call:
+		for _, a := range e.Args {
+			exprVars(a, vars)
+		}
+	case *Binary:
+		exprVars(e.Left, vars)
+		exprVars(e.Right, vars)
+	case *Not:
+		exprVars(e.Expr, vars)
+	case *IsNull:
+		exprVars(e.Expr, vars)
+	case *List:
+		for _, it := range e.Items {
+			exprVars(it, vars)
+		}
+	}
+}
+
+// compileCond converts a condition on a single variable to a path that matches all nodes that satisfy it.
+func (c *compiler) compileCond(e Expr) (*path.Path, error) {
+	switch e := e.(type) {
+	case *Binary:
+		switch e.Op {
+		case "AND", "OR":
+			l, err := c.compileCond(e.Left)
+			if err != nil {
+				return nil, err
+			}
+			r, err := c.compileCond(e.Right)
+			if err != nil {
+				return nil, err
+			}
+			if e.Op == "AND" {
+				return l.And(r), nil
+			}
+			return l.Or(r), nil
+		case "XOR":
+			return nil, fmt.Errorf("XOR is not supported")
+		}
+		return c.compileCompare(e)
+	case *Not:
+		sub, err := c.compileCond(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return path.StartMorphism().Except(sub), nil
+	case *IsNull:
+		pa, ok := e.Expr.(*PropertyAccess)
+		if !ok {
+			return nil, fmt.Errorf("IS NULL is only supported for properties: %v", e)
+		}
+		has := path.StartMorphism().Has(iri(pa.Key))
+		if e.Not {
+			return has, nil
+		}
+		return path.StartMorphism().Except(has), nil
+	case *HasLabels:
+		p := path.StartMorphism()
+		for _, l := range e.Labels {
+			p = p.Has(rdfType, iri(l))
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unsupported condition: %v", e)
+}
+
+// flipOps lists comparison operators that can be used with swapped operands.
+var flipOps = map[string]string{
+	"=": "=", "<>": "<>",
+	"<": ">", "<=": ">=",
+	">": "<", ">=": "<=",
+}
+
+var compareOperators = map[string]iterator.Operator{
+	"<":  iterator.CompareLT,
+	"<=": iterator.CompareLTE,
+	">":  iterator.CompareGT,
+	">=": iterator.CompareGTE,
+}
+
+func (c *compiler) compileCompare(e *Binary) (*path.Path, error) {
+	op, left, right := e.Op, e.Left, e.Right
+	if isConst(left) {
+		fop, ok := flipOps[op]
+		if !ok {
+			return nil, fmt.Errorf("unsupported condition: %v", e)
+		}
+		op, left, right = fop, right, left
+	}
+	if !isConst(right) {
+		return nil, fmt.Errorf("only comparisons with constants are supported: %v", e)
+	}
+	switch left := left.(type) {
+	case *PropertyAccess:
+		return c.compareProp(iri(left.Key), op, right)
+	case *Variable:
+		return compareNode(op, right, false)
+	case *Call:
+		if len(left.Args) == 1 && left.Func == "id" {
+			if _, ok := left.Args[0].(*Variable); ok {
+				return compareNode(op, right, true)
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported condition: %v", e)
+}
+
+func isConst(e Expr) bool {
+	switch e := e.(type) {
+	case *Literal:
+		return true
+	case *List:
+		for _, it := range e.Items {
+			if !isConst(it) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compareProp returns a path that matches nodes with a property that satisfies the comparison.
+func (c *compiler) compareProp(pred quad.IRI, op string, val Expr) (*path.Path, error) {
+	p := path.StartMorphism()
+	switch op {
+	case "=", "IN", "<>":
+		vals, err := propValues(val, op == "IN")
+		if err != nil {
+			return nil, err
+		}
+		if op != "<>" {
+			return p.Has(pred, vals...), nil
+		}
+		return p.Has(pred).Except(path.StartMorphism().Has(pred, vals...)), nil
+	}
+	f, err := valueFilter(op, val, false)
+	if err != nil {
+		return nil, err
+	}
+	return p.HasFilter(pred, false, f), nil
+}
+
+// valueCond returns a path that matches property values that satisfy the comparison.
+// It returns nil if the comparison can't be checked on a single value.
+func valueCond(op string, val Expr) (*path.Path, error) {
+	switch op {
+	case "=", "IN":
+		vals, err := propValues(val, op == "IN")
+		if err != nil {
+			return nil, err
+		}
+		return path.StartMorphism().Is(vals...), nil
+	case "<>":
+		return nil, nil
+	}
+	f, err := valueFilter(op, val, false)
+	if err != nil {
+		return nil, err
+	}
+	return path.StartMorphism().Filters(f), nil
+}
+
+// compareNode returns a path that matches nodes that satisfy the comparison.
+// If id is set, strings are interpreted as node identifiers, otherwise they match both strings and IRIs.
+func compareNode(op string, val Expr, id bool) (*path.Path, error) {
+	p := path.StartMorphism()
+	switch op {
+	case "=", "IN", "<>":
+		var (
+			vals []quad.Value
+			err  error
+		)
+		if id {
+			vals, err = nodeValues(val, op == "IN")
+		} else {
+			vals, err = propValues(val, op == "IN")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if op != "<>" {
+			return p.Is(vals...), nil
+		}
+		return p.Except(path.StartMorphism().Is(vals...)), nil
+	}
+	f, err := valueFilter(op, val, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.Filters(f), nil
+}
+
+// valueFilter returns a filter for comparison and string matching operators.
+func valueFilter(op string, val Expr, node bool) (shape.ValueFilter, error) {
+	lit, ok := val.(*Literal)
+	if !ok || lit.Value == nil {
+		return nil, fmt.Errorf("operator %s expects a value, got: %v", op, val)
+	}
+	if cop, ok := compareOperators[op]; ok {
+		v, err := literalValue(lit, node)
+		if err != nil {
+			return nil, err
+		}
+		return shape.Comparison{Op: cop, Val: v}, nil
+	}
+	s, ok := lit.Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("operator %s expects a string, got: %v", op, val)
+	}
+	if op == "=~" {
+		// Cypher regular expressions must match the whole string
+		re, err := regexp.Compile("^(?:" + s + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return shape.Regexp{Re: re, Refs: true}, nil
+	}
+	if !strings.ContainsAny(s, "%?") {
+		switch op {
+		case "STARTS WITH":
+			return shape.Wildcard{Pattern: s + "%"}, nil
+		case "ENDS WITH":
+			return shape.Wildcard{Pattern: "%" + s}, nil
+		case "CONTAINS":
+			return shape.Wildcard{Pattern: "%" + s + "%"}, nil
+		}
+	}
+	pattern := regexp.QuoteMeta(s)
+	switch op {
+	case "STARTS WITH":
+		pattern = "^" + pattern
+	case "ENDS WITH":
+		pattern += "$"
+	case "CONTAINS":
+	default:
+		return nil, fmt.Errorf("unsupported operator: %s", op)
+	}
+	return shape.Regexp{Re: regexp.MustCompile(pattern), Refs: true}, nil
+}
+
+// listItems returns all literals of a list, or a single literal.
+func listItems(val Expr, list bool) ([]*Literal, error) {
+	if !list {
+		lit, ok := val.(*Literal)
+		if !ok {
+			return nil, fmt.Errorf("expected a value, got: %v", val)
+		}
+		return []*Literal{lit}, nil
+	}
+	l, ok := val.(*List)
+	if !ok {
+		return nil, fmt.Errorf("IN expects a list, got: %v", val)
+	}
+	out := make([]*Literal, 0, len(l.Items))
+	for _, it := range l.Items {
+		lit, ok := it.(*Literal)
+		if !ok {
+			return nil, fmt.Errorf("expected a value, got: %v", it)
+		}
+		out = append(out, lit)
+	}
+	return out, nil
+}
+
+// propValues converts literals to values of properties and nodes. Strings match both string values and IRIs.
+func propValues(val Expr, list bool) ([]quad.Value, error) {
+	lits, err := listItems(val, list)
+	if err != nil {
+		return nil, err
+	}
+	var out []quad.Value
+	for _, lit := range lits {
+		v, err := literalValue(lit, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if s, ok := lit.Value.(string); ok {
+			out = append(out, iri(s))
+		}
+	}
+	return out, nil
+}
+
+// nodeValues converts literals to node values.
+func nodeValues(val Expr, list bool) ([]quad.Value, error) {
+	lits, err := listItems(val, list)
+	if err != nil {
+		return nil, err
+	}
+	var out []quad.Value
+	for _, lit := range lits {
+		v, err := literalValue(lit, true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// literalValue converts a literal to a quad value. If node is set, strings are interpreted as node identifiers:
+// they can be written in N-Quads notation (<iri>, _:bnode, "string"), or as IRIs with a known namespace prefix.
+func literalValue(lit *Literal, node bool) (quad.Value, error) {
+	switch v := lit.Value.(type) {
+	case nil:
+		return nil, fmt.Errorf("comparison with null is not supported; use IS NULL")
+	case string:
+		if !node {
+			return quad.String(v), nil
+		}
+		if strings.HasPrefix(v, "<") || strings.HasPrefix(v, `"`) || strings.HasPrefix(v, "_:") {
+			if qv := quad.StringToValue(v); qv != nil {
+				return qv, nil
+			}
+		}
+		return iri(v), nil
+	case int64:
+		return quad.Int(v), nil
+	case float64:
+		return quad.Float(v), nil
+	case bool:
+		return quad.Bool(v), nil
+	}
+	return nil, fmt.Errorf("unsupported value: %v", lit)
+}
+
+var rdfType = quad.IRI(rdf.Type).Full()
+
+// iri converts a label, a relationship type or a property name to IRI.
+func iri(s string) quad.IRI {
+	return quad.IRI(s).Full()
+}
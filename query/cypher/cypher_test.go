@@ -0,0 +1,295 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cypher
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph/graphtest/testutil"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+)
+
+var extraQuads = []quad.Quad{
+	quad.MakeIRI("alice", rdf.NS+"type", "Person", ""),
+	quad.MakeIRI("bob", rdf.NS+"type", "Person", ""),
+	quad.Make(quad.IRI("alice"), quad.IRI("name"), quad.String("Alice"), nil),
+	quad.Make(quad.IRI("bob"), quad.IRI("name"), quad.String("Bob"), nil),
+	quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+	quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Int(41), nil),
+}
+
+type jsonRow = map[string]interface{}
+
+var testQueries = []struct {
+	message string
+	query   string
+	ordered bool
+	expect  []jsonRow
+	err     bool
+}{
+	{
+		message: "out relationship",
+		query:   `MATCH (a)-[:follows]->(b) WHERE id(a) = 'alice' RETURN b`,
+		expect:  []jsonRow{{"b": "<bob>"}},
+	},
+	{
+		message: "in relationship",
+		query:   `MATCH (a)<-[:follows]-(b) WHERE id(a) = '<bob>' RETURN a, b.name AS name, b`,
+		expect: []jsonRow{
+			{"a": "<bob>", "b": "<alice>", "name": "Alice"},
+			{"a": "<bob>", "b": "<charlie>", "name": nil},
+			{"a": "<bob>", "b": "<dani>", "name": nil},
+		},
+	},
+	{
+		message: "both directions",
+		query:   `MATCH (a {name: 'Bob'})-[:follows]-(b) RETURN b`,
+		expect:  []jsonRow{{"b": "<alice>"}, {"b": "<charlie>"}, {"b": "<dani>"}, {"b": "<fred>"}},
+	},
+	{
+		message: "labels and order",
+		query:   `MATCH (p:Person) RETURN p.name AS name, p.age ORDER BY p.age DESC`,
+		ordered: true,
+		expect: []jsonRow{
+			{"name": "Bob", "p.age": int64(41)},
+			{"name": "Alice", "p.age": int64(30)},
+		},
+	},
+	{
+		message: "label namespace",
+		query:   "MATCH (p)-[:`rdf:type`]->(t) WHERE p:Person AND id(t) = 'Person' RETURN p",
+		expect:  []jsonRow{{"p": "<alice>"}, {"p": "<bob>"}},
+	},
+	{
+		message: "chain",
+		query:   `MATCH (a)-[:follows]->(b)-[:status]->(s) WHERE s = 'cool_person' RETURN a, b`,
+		expect: []jsonRow{
+			{"a": "<alice>", "b": "<bob>"},
+			{"a": "<charlie>", "b": "<bob>"},
+			{"a": "<dani>", "b": "<bob>"},
+			{"a": "<charlie>", "b": "<dani>"},
+			{"a": "<dani>", "b": "<greg>"},
+			{"a": "<fred>", "b": "<greg>"},
+		},
+	},
+	{
+		message: "multiple patterns",
+		query: `MATCH (a:Person)-[:follows]->(b), (a)-[:age]->(age)
+			WHERE age >= 30 RETURN a, b, age`,
+		expect: []jsonRow{
+			{"a": "<alice>", "b": "<bob>", "age": int64(30)},
+			{"a": "<bob>", "b": "<fred>", "age": int64(41)},
+		},
+	},
+	{
+		message: "variable length",
+		query:   `MATCH (a)-[:follows*1..2]->(b) WHERE id(a) = 'alice' RETURN b`,
+		expect:  []jsonRow{{"b": "<bob>"}, {"b": "<fred>"}},
+	},
+	{
+		message: "variable length with minimum",
+		query:   `MATCH (a)-[:follows*2..3]->(b) WHERE id(a) = 'alice' RETURN b`,
+		expect:  []jsonRow{{"b": "<fred>"}, {"b": "<greg>"}},
+	},
+	{
+		message: "exact length",
+		query:   `MATCH (a)-[:follows*2]->(b) WHERE id(a) = 'alice' RETURN b`,
+		expect:  []jsonRow{{"b": "<fred>"}},
+	},
+	{
+		message: "optional match",
+		query: `MATCH (p:Person) OPTIONAL MATCH (p)-[:status]->(s)
+			RETURN p.name AS name, s AS status ORDER BY name`,
+		ordered: true,
+		expect: []jsonRow{
+			{"name": "Alice", "status": nil},
+			{"name": "Bob", "status": "cool_person"},
+		},
+	},
+	{
+		message: "optional match with conditions",
+		query: `MATCH (p:Person) OPTIONAL MATCH (p)-[:follows]->(f) WHERE f.status IS NOT NULL
+			RETURN p, f`,
+		expect: []jsonRow{
+			{"p": "<alice>", "f": "<bob>"},
+			{"p": "<bob>", "f": nil},
+		},
+	},
+	{
+		message: "branches with variable length",
+		query:   `MATCH (c)<-[:follows*2]-(a)-[:follows]->(b) WHERE id(a) = 'alice' RETURN b, c`,
+		expect:  []jsonRow{{"b": "<bob>", "c": "<fred>"}},
+	},
+	{
+		message: "relationship variable",
+		query:   `MATCH (a)-[r:follows|status]->(b) WHERE id(a) = 'emily' RETURN type(r) AS pred, b ORDER BY pred`,
+		ordered: true,
+		expect: []jsonRow{
+			{"pred": "<follows>", "b": "<fred>"},
+			{"pred": "<status>", "b": "smart_person"},
+		},
+	},
+	{
+		message: "or and comparisons",
+		query:   `MATCH (p) WHERE p.name = 'Alice' OR p.age > 40 RETURN p`,
+		expect:  []jsonRow{{"p": "<alice>"}, {"p": "<bob>"}},
+	},
+	{
+		message: "not and is null",
+		query:   `MATCH (a)-[:follows]->(b) WHERE NOT id(a) IN ['alice', 'charlie'] AND b.status IS NULL RETURN a, b`,
+		expect: []jsonRow{
+			{"a": "<bob>", "b": "<fred>"},
+			{"a": "<emily>", "b": "<fred>"},
+		},
+	},
+	{
+		message: "string matching",
+		query:   `MATCH (p:Person) WHERE p.name STARTS WITH 'Al' OR p.name =~ 'B.b' RETURN p.name`,
+		expect:  []jsonRow{{"p.name": "Alice"}, {"p.name": "Bob"}},
+	},
+	{
+		message: "distinct skip limit",
+		query:   `MATCH (a)-[:follows]->(b) RETURN DISTINCT b ORDER BY b SKIP 1 LIMIT 2`,
+		ordered: true,
+		expect:  []jsonRow{{"b": "<dani>"}, {"b": "<fred>"}},
+	},
+	{
+		message: "condition on a multi-valued property",
+		query:   `MATCH (a) WHERE a.status STARTS WITH 'c' AND a.status <> 'x' RETURN a, a.status`,
+		expect: []jsonRow{
+			{"a": "<bob>", "a.status": "cool_person"},
+			{"a": "<dani>", "a.status": "cool_person"},
+			{"a": "<greg>", "a.status": "cool_person"},
+		},
+	},
+	{
+		message: "property pattern on a multi-valued property",
+		query:   `MATCH (a {status: 'smart_person'}) RETURN a, a.status`,
+		expect: []jsonRow{
+			{"a": "<emily>", "a.status": "smart_person"},
+			{"a": "<greg>", "a.status": "smart_person"},
+		},
+	},
+	{
+		message: "multi-valued property without conditions on it",
+		query:   `MATCH (a)-[:follows]->(:Person {name: 'Bob'}) WHERE a.status = 'cool_person' RETURN a, a.status`,
+		expect: []jsonRow{
+			{"a": "<dani>", "a.status": "cool_person"},
+		},
+	},
+	{
+		message: "limit zero",
+		query:   `MATCH (a) RETURN a LIMIT 0`,
+		expect:  nil,
+	},
+	{
+		message: "return all",
+		query:   `MATCH (a)-[r]->(b:Person) RETURN *`,
+		expect: []jsonRow{
+			{"a": "<charlie>", "r": "<follows>", "b": "<bob>"},
+			{"a": "<dani>", "r": "<follows>", "b": "<bob>"},
+			{"a": "<alice>", "r": "<follows>", "b": "<bob>"},
+		},
+	},
+	{
+		message: "write clause",
+		query:   `CREATE (a)`,
+		err:     true,
+	},
+	{
+		message: "cycle",
+		query:   `MATCH (a)-->(b)-->(a) RETURN a`,
+		err:     true,
+	},
+	{
+		message: "disconnected",
+		query:   `MATCH (a), (b) RETURN a, b`,
+		err:     true,
+	},
+	{
+		message: "comparison of variables",
+		query:   `MATCH (a)-->(b) WHERE a.age > b.age RETURN a`,
+		err:     true,
+	},
+}
+
+func sortRows(rows []jsonRow) {
+	sort.Slice(rows, func(i, j int) bool {
+		a, _ := json.Marshal(rows[i])
+		b, _ := json.Marshal(rows[j])
+		return string(a) < string(b)
+	})
+}
+
+func TestCypher(t *testing.T) {
+	quads := testutil.LoadGraph(t, "../../data/testdata.nq")
+	quads = append(quads, extraQuads...)
+	qs := memstore.New(quads...)
+	ctx := context.TODO()
+	for _, c := range testQueries {
+		t.Run(c.message, func(t *testing.T) {
+			it, err := NewSession(qs).Execute(ctx, c.query, query.Options{Collation: query.JSON})
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer it.Close()
+			var got []jsonRow
+			for it.Next(ctx) {
+				got = append(got, it.Result().(jsonRow))
+			}
+			require.NoError(t, it.Err())
+			if !c.ordered {
+				sortRows(got)
+				sortRows(c.expect)
+			}
+			require.Equal(t, c.expect, got)
+		})
+	}
+}
+
+func TestCypherLimit(t *testing.T) {
+	quads := testutil.LoadGraph(t, "../../data/testdata.nq")
+	qs := memstore.New(quads...)
+	ctx := context.TODO()
+	for _, lim := range []int{0, 3} {
+		it, err := NewSession(qs).Execute(ctx, `MATCH (a)-[:follows]->(b) RETURN a, b LIMIT 4`, query.Options{
+			Collation: query.Raw,
+			Limit:     lim,
+		})
+		require.NoError(t, err)
+		n := 0
+		for it.Next(ctx) {
+			m := it.Result().(map[string]quad.Value)
+			require.Len(t, m, 2)
+			n++
+		}
+		require.NoError(t, it.Err())
+		require.NoError(t, it.Close())
+		if lim == 0 {
+			lim = 4
+		}
+		require.Equal(t, lim, n)
+	}
+}
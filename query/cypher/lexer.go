@@ -0,0 +1,152 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cypher
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cayleygraph/cayley/query"
+)
+
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokIdent            // identifier or keyword
+	tokQuoted           // identifier in backticks
+	tokString
+	tokInt
+	tokFloat
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	case tokQuoted:
+		return "`" + t.text + "`"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// punctuation that consists of more than one character; checked before single characters
+var punct2 = []string{"..", "<=", ">=", "<>", "=~"}
+
+const punct1 = "()[]{}<>-:,.|*=+;"
+
+// lex splits the query into tokens.
+func lex(s string) ([]token, error) {
+	var out []token
+	i := 0
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += n
+			continue
+		case strings.HasPrefix(s[i:], "//"):
+			if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(s)
+			}
+			continue
+		case strings.HasPrefix(s[i:], "/*"):
+			j := strings.Index(s[i+2:], "*/")
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += j + 4
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			j := i + n
+			for j < len(s) {
+				r, n := utf8.DecodeRuneInString(s[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += n
+			}
+			out = append(out, token{kind: tokIdent, text: s[i:j], pos: i})
+			i = j
+			continue
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			kind := tokInt
+			// "1..3" is a range, not a float
+			if j+1 < len(s) && s[j] == '.' && s[j+1] >= '0' && s[j+1] <= '9' {
+				kind = tokFloat
+				j++
+				for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					j++
+				}
+			}
+			out = append(out, token{kind: kind, text: s[i:j], pos: i})
+			i = j
+			continue
+		case r == '\'' || r == '"':
+			str, n, err := query.LexString(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at offset %d", err, i)
+			}
+			out = append(out, token{kind: tokString, text: str, pos: i})
+			i += n
+			continue
+		case r == '`':
+			j := strings.IndexByte(s[i+1:], '`')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated identifier at offset %d", i)
+			}
+			out = append(out, token{kind: tokQuoted, text: s[i+1 : i+1+j], pos: i})
+			i += j + 2
+			continue
+		}
+		found := false
+		for _, p := range punct2 {
+			if strings.HasPrefix(s[i:], p) {
+				out = append(out, token{kind: tokPunct, text: p, pos: i})
+				i += len(p)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if strings.IndexByte(punct1, s[i]) >= 0 {
+			out = append(out, token{kind: tokPunct, text: s[i : i+1], pos: i})
+			i++
+			continue
+		}
+		return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+	}
+	out = append(out, token{kind: tokEOF, pos: len(s)})
+	return out, nil
+}
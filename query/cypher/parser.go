@@ -0,0 +1,818 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cypher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed Cypher query.
+type Query struct {
+	Match  []*Match
+	Return *Return
+}
+
+// Match is a MATCH or OPTIONAL MATCH clause.
+type Match struct {
+	Optional bool
+	Patterns []*Pattern
+	Where    Expr
+}
+
+// Pattern is a chain of nodes connected with relationships. Rels[i] connects Nodes[i] and Nodes[i+1].
+type Pattern struct {
+	Nodes []*NodePattern
+	Rels  []*RelPattern
+}
+
+// NodePattern is a node in a pattern, for example (a:Person {name: 'Bob'}).
+type NodePattern struct {
+	Var    string
+	Labels []string
+	Props  []Property
+}
+
+// Direction of a relationship in a pattern.
+type Direction int
+
+const (
+	DirBoth = Direction(iota) // (a)-[]-(b)
+	DirOut                    // (a)-[]->(b)
+	DirIn                     // (a)<-[]-(b)
+)
+
+// RelPattern is a relationship in a pattern, for example -[r:follows*1..3]->.
+type RelPattern struct {
+	Var   string
+	Types []string
+	Dir   Direction
+	Props []Property
+	// Variable length relationships.
+	VarLength bool
+	Min, Max  int // Max is -1 if unbounded
+}
+
+// Property is a key-value pair in a node or relationship pattern.
+type Property struct {
+	Key   string
+	Value Expr
+}
+
+// Return is a RETURN clause with optional ORDER BY, SKIP and LIMIT.
+type Return struct {
+	Distinct bool
+	All      bool // RETURN *
+	Items    []ReturnItem
+	Order    []SortItem
+	Skip     int64
+	Limit    int64 // negative if not set
+}
+
+// ReturnItem is a single column of the result.
+type ReturnItem struct {
+	Expr  Expr
+	Alias string
+}
+
+// SortItem is a single key of ORDER BY.
+type SortItem struct {
+	Expr Expr
+	Desc bool
+}
+
+// Expr is an expression in WHERE, RETURN or ORDER BY.
+type Expr interface {
+	String() string
+}
+
+// Literal is a string, integer, float, boolean or null constant.
+type Literal struct {
+	Value interface{} // string, int64, float64, bool or nil
+}
+
+func (e *Literal) String() string {
+	switch v := e.Value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	}
+	return fmt.Sprint(e.Value)
+}
+
+// List is a list of expressions, for example [1, 2, 3].
+type List struct {
+	Items []Expr
+}
+
+func (e *List) String() string {
+	s := make([]string, len(e.Items))
+	for i, it := range e.Items {
+		s[i] = it.String()
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+// Variable is a reference to a node or relationship variable.
+type Variable struct {
+	Name string
+}
+
+func (e *Variable) String() string { return e.Name }
+
+// PropertyAccess is a property of a node, for example a.name.
+type PropertyAccess struct {
+	Var string
+	Key string
+}
+
+func (e *PropertyAccess) String() string { return e.Var + "." + e.Key }
+
+// Call is a function call, for example id(a).
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+func (e *Call) String() string {
+	s := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		s[i] = a.String()
+	}
+	return e.Func + "(" + strings.Join(s, ", ") + ")"
+}
+
+// Binary is a binary operation. Op is one of: AND, OR, XOR, =, <>, <, <=, >, >=, =~, IN, STARTS WITH, ENDS WITH, CONTAINS.
+type Binary struct {
+	Op          string
+	Left, Right Expr
+}
+
+func (e *Binary) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+// Not is a negation of an expression.
+type Not struct {
+	Expr Expr
+}
+
+func (e *Not) String() string { return "NOT " + e.Expr.String() }
+
+// IsNull checks if the expression is null, or not null if Not is set.
+type IsNull struct {
+	Expr Expr
+	Not  bool
+}
+
+func (e *IsNull) String() string {
+	if e.Not {
+		return e.Expr.String() + " IS NOT NULL"
+	}
+	return e.Expr.String() + " IS NULL"
+}
+
+// HasLabels checks that a node has all given labels, for example a:Person.
+type HasLabels struct {
+	Var    string
+	Labels []string
+}
+
+func (e *HasLabels) String() string { return e.Var + ":" + strings.Join(e.Labels, ":") }
+
+// Parse parses a Cypher query.
+func Parse(qu string) (*Query, error) {
+	toks, err := lex(qu)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	return p.parseQuery()
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	return fmt.Errorf("%s at offset %d: unexpected %v", fmt.Sprintf(format, args...), t.pos, t)
+}
+
+// isKeyword checks if the current token is a given (case-insensitive) keyword.
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+// isKeywordAt is the same as isKeyword, but checks a token with a given offset.
+func (p *parser) isKeywordAt(off int, kw string) bool {
+	if p.i+off >= len(p.toks) {
+		return false
+	}
+	t := p.toks[p.i+off]
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return p.errorf("expected %s", kw)
+	}
+	return nil
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) acceptPunct(s string) bool {
+	if p.isPunct(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectPunct(s string) error {
+	if !p.acceptPunct(s) {
+		return p.errorf("expected %q", s)
+	}
+	return nil
+}
+
+// keywords that can't be used as unquoted names
+var reserved = map[string]bool{
+	"MATCH": true, "OPTIONAL": true, "WHERE": true, "RETURN": true, "DISTINCT": true,
+	"ORDER": true, "BY": true, "SKIP": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "XOR": true, "NOT": true, "IN": true, "IS": true,
+	"STARTS": true, "ENDS": true, "CONTAINS": true,
+	"ASC": true, "ASCENDING": true, "DESC": true, "DESCENDING": true,
+	"NULL": true, "TRUE": true, "FALSE": true,
+	"CREATE": true, "MERGE": true, "DELETE": true, "DETACH": true, "SET": true, "REMOVE": true,
+	"WITH": true, "UNWIND": true, "UNION": true, "CALL": true,
+}
+
+// parseName reads an identifier, either plain or quoted with backticks.
+func (p *parser) parseName(what string) (string, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokQuoted:
+		p.next()
+		return t.text, nil
+	case t.kind == tokIdent && !reserved[strings.ToUpper(t.text)]:
+		p.next()
+		return t.text, nil
+	}
+	return "", p.errorf("expected %s", what)
+}
+
+// parseKey is the same as parseName, but allows keywords, since they are not ambiguous in this position.
+func (p *parser) parseKey(what string) (string, error) {
+	t := p.peek()
+	if t.kind == tokQuoted || t.kind == tokIdent {
+		p.next()
+		return t.text, nil
+	}
+	return "", p.errorf("expected %s", what)
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{}
+	for {
+		m := &Match{}
+		if p.acceptKeyword("OPTIONAL") {
+			m.Optional = true
+			if err := p.expectKeyword("MATCH"); err != nil {
+				return nil, err
+			}
+		} else if !p.acceptKeyword("MATCH") {
+			break
+		}
+		for {
+			pt, err := p.parsePattern()
+			if err != nil {
+				return nil, err
+			}
+			m.Patterns = append(m.Patterns, pt)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if p.acceptKeyword("WHERE") {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			m.Where = e
+		}
+		q.Match = append(q.Match, m)
+	}
+	if len(q.Match) == 0 {
+		for _, kw := range []string{"CREATE", "MERGE", "DELETE", "DETACH", "SET", "REMOVE", "WITH", "UNWIND", "CALL"} {
+			if p.isKeyword(kw) {
+				return nil, fmt.Errorf("%s clause is not supported", kw)
+			}
+		}
+		return nil, p.errorf("expected MATCH")
+	}
+	if !p.acceptKeyword("RETURN") {
+		if t := p.peek(); t.kind == tokIdent {
+			return nil, fmt.Errorf("%s clause is not supported", strings.ToUpper(t.text))
+		}
+		return nil, p.errorf("expected RETURN")
+	}
+	r, err := p.parseReturn()
+	if err != nil {
+		return nil, err
+	}
+	q.Return = r
+	p.acceptPunct(";")
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("expected end of query")
+	}
+	return q, nil
+}
+
+func (p *parser) parsePattern() (*Pattern, error) {
+	pt := &Pattern{}
+	n, err := p.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	pt.Nodes = append(pt.Nodes, n)
+	for p.isPunct("-") || p.isPunct("<") {
+		r, err := p.parseRel()
+		if err != nil {
+			return nil, err
+		}
+		n, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		pt.Rels = append(pt.Rels, r)
+		pt.Nodes = append(pt.Nodes, n)
+	}
+	return pt, nil
+}
+
+func (p *parser) parseNode() (*NodePattern, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	n := &NodePattern{}
+	if t := p.peek(); t.kind == tokIdent || t.kind == tokQuoted {
+		name, err := p.parseName("variable name")
+		if err != nil {
+			return nil, err
+		}
+		n.Var = name
+	}
+	for p.acceptPunct(":") {
+		label, err := p.parseKey("label")
+		if err != nil {
+			return nil, err
+		}
+		n.Labels = append(n.Labels, label)
+	}
+	if p.isPunct("{") {
+		props, err := p.parseProps()
+		if err != nil {
+			return nil, err
+		}
+		n.Props = props
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseRel() (*RelPattern, error) {
+	r := &RelPattern{Dir: DirBoth}
+	if p.acceptPunct("<") {
+		r.Dir = DirIn
+	}
+	if err := p.expectPunct("-"); err != nil {
+		return nil, err
+	}
+	if p.acceptPunct("[") {
+		if t := p.peek(); t.kind == tokIdent || t.kind == tokQuoted {
+			name, err := p.parseName("variable name")
+			if err != nil {
+				return nil, err
+			}
+			r.Var = name
+		}
+		if p.acceptPunct(":") {
+			for {
+				typ, err := p.parseKey("relationship type")
+				if err != nil {
+					return nil, err
+				}
+				r.Types = append(r.Types, typ)
+				if !p.acceptPunct("|") {
+					break
+				}
+				p.acceptPunct(":")
+			}
+		}
+		if p.acceptPunct("*") {
+			if err := p.parseRange(r); err != nil {
+				return nil, err
+			}
+		}
+		if p.isPunct("{") {
+			props, err := p.parseProps()
+			if err != nil {
+				return nil, err
+			}
+			r.Props = props
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectPunct("-"); err != nil {
+		return nil, err
+	}
+	if p.acceptPunct(">") {
+		if r.Dir == DirIn {
+			return nil, fmt.Errorf("relationship can't point in both directions")
+		}
+		r.Dir = DirOut
+	}
+	return r, nil
+}
+
+// parseRange reads the length of a variable length relationship: *, *n, *n.., *..m or *n..m.
+func (p *parser) parseRange(r *RelPattern) error {
+	r.VarLength = true
+	r.Min, r.Max = 1, -1
+	readInt := func() (int, bool, error) {
+		t := p.peek()
+		if t.kind != tokInt {
+			return 0, false, nil
+		}
+		p.next()
+		v, err := strconv.Atoi(t.text)
+		return v, true, err
+	}
+	v, ok, err := readInt()
+	if err != nil {
+		return err
+	}
+	if !p.acceptPunct("..") {
+		if ok {
+			// exact length
+			r.Min, r.Max = v, v
+		}
+		return nil
+	}
+	if ok {
+		r.Min = v
+	}
+	v, ok, err = readInt()
+	if err != nil {
+		return err
+	} else if ok {
+		r.Max = v
+	}
+	return nil
+}
+
+func (p *parser) parseProps() ([]Property, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var props []Property
+	for !p.acceptPunct("}") {
+		if len(props) != 0 {
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+		key, err := p.parseKey("property name")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, Property{Key: key, Value: v})
+	}
+	return props, nil
+}
+
+func (p *parser) parseReturn() (*Return, error) {
+	r := &Return{Limit: -1}
+	r.Distinct = p.acceptKeyword("DISTINCT")
+	if p.acceptPunct("*") {
+		r.All = true
+	} else {
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			it := ReturnItem{Expr: e}
+			if p.acceptKeyword("AS") {
+				if it.Alias, err = p.parseName("alias"); err != nil {
+					return nil, err
+				}
+			}
+			r.Items = append(r.Items, it)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			it := SortItem{Expr: e}
+			if p.acceptKeyword("DESC") || p.acceptKeyword("DESCENDING") {
+				it.Desc = true
+			} else if !p.acceptKeyword("ASC") {
+				p.acceptKeyword("ASCENDING")
+			}
+			r.Order = append(r.Order, it)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	var err error
+	if p.acceptKeyword("SKIP") {
+		if r.Skip, err = p.parseCount("SKIP"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if r.Limit, err = p.parseCount("LIMIT"); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (p *parser) parseCount(kw string) (int64, error) {
+	t := p.peek()
+	if t.kind != tokInt {
+		return 0, p.errorf("expected a number after %s", kw)
+	}
+	p.next()
+	return strconv.ParseInt(t.text, 10, 64)
+}
+
+// Expressions are parsed with the following precedence (from lowest to highest):
+// OR, XOR, AND, NOT, comparison operators, and primary expressions.
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseBinary(0)
+}
+
+var logicalOps = []string{"OR", "XOR", "AND"}
+
+func (p *parser) parseBinary(level int) (Expr, error) {
+	if level >= len(logicalOps) {
+		return p.parseNot()
+	}
+	op := logicalOps[level]
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword(op) {
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.acceptKeyword("NOT") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: e}, nil
+	}
+	return p.parseComparison()
+}
+
+var compareOps = []string{"=", "<>", "<=", ">=", "<", ">", "=~"}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range compareOps {
+		if p.acceptPunct(op) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return &Binary{Op: op, Left: left, Right: right}, nil
+		}
+	}
+	var op string
+	switch {
+	case p.acceptKeyword("IS"):
+		not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &IsNull{Expr: left, Not: not}, nil
+	case p.acceptKeyword("IN"):
+		op = "IN"
+	case p.acceptKeyword("CONTAINS"):
+		op = "CONTAINS"
+	case p.isKeyword("STARTS") && p.isKeywordAt(1, "WITH"):
+		p.next()
+		p.next()
+		op = "STARTS WITH"
+	case p.isKeyword("ENDS") && p.isKeywordAt(1, "WITH"):
+		p.next()
+		p.next()
+		op = "ENDS WITH"
+	default:
+		return left, nil
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &Binary{Op: op, Left: left, Right: right}, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokString:
+		p.next()
+		return &Literal{Value: t.text}, nil
+	case tokInt, tokFloat:
+		p.next()
+		return parseNumber(t.text, false)
+	case tokPunct:
+		switch t.text {
+		case "-":
+			p.next()
+			t = p.peek()
+			if t.kind != tokInt && t.kind != tokFloat {
+				return nil, p.errorf("expected a number")
+			}
+			p.next()
+			return parseNumber(t.text, true)
+		case "(":
+			p.next()
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		case "[":
+			p.next()
+			l := &List{}
+			for !p.acceptPunct("]") {
+				if len(l.Items) != 0 {
+					if err := p.expectPunct(","); err != nil {
+						return nil, err
+					}
+				}
+				e, err := p.parsePrimary()
+				if err != nil {
+					return nil, err
+				}
+				l.Items = append(l.Items, e)
+			}
+			return l, nil
+		}
+	case tokIdent:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			p.next()
+			return &Literal{Value: nil}, nil
+		case "TRUE":
+			p.next()
+			return &Literal{Value: true}, nil
+		case "FALSE":
+			p.next()
+			return &Literal{Value: false}, nil
+		}
+		if p.i+1 < len(p.toks) && p.toks[p.i+1].kind == tokPunct && p.toks[p.i+1].text == "(" {
+			return p.parseCall()
+		}
+	}
+	name, err := p.parseName("expression")
+	if err != nil {
+		return nil, err
+	}
+	if p.acceptPunct(".") {
+		key, err := p.parseKey("property name")
+		if err != nil {
+			return nil, err
+		}
+		return &PropertyAccess{Var: name, Key: key}, nil
+	} else if p.isPunct(":") {
+		e := &HasLabels{Var: name}
+		for p.acceptPunct(":") {
+			label, err := p.parseKey("label")
+			if err != nil {
+				return nil, err
+			}
+			e.Labels = append(e.Labels, label)
+		}
+		return e, nil
+	}
+	return &Variable{Name: name}, nil
+}
+
+func (p *parser) parseCall() (Expr, error) {
+	c := &Call{Func: strings.ToLower(p.next().text)}
+	p.next() // (
+	for !p.acceptPunct(")") {
+		if len(c.Args) != 0 {
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, e)
+	}
+	return c, nil
+}
+
+func parseNumber(s string, neg bool) (Expr, error) {
+	if neg {
+		s = "-" + s
+	}
+	if strings.Contains(s, ".") {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &Literal{Value: v}, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &Literal{Value: v}, nil
+}
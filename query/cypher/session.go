@@ -0,0 +1,283 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cypher implements a read-only subset of openCypher query language.
+//
+// Queries are compiled to query/path, thus all optimizations of the backend apply to them.
+// Results are rows that map names of RETURN columns to values.
+package cypher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+)
+
+const Name = "cypher"
+
+func init() {
+	query.RegisterLanguage(query.Language{
+		Name: Name,
+		Session: func(qs graph.QuadStore) query.Session {
+			return NewSession(qs)
+		},
+	})
+}
+
+// NewSession creates a new Cypher session on a given quad store.
+func NewSession(qs graph.QuadStore) *Session {
+	return &Session{qs: qs}
+}
+
+type Session struct {
+	qs graph.QuadStore
+}
+
+// Execute runs a Cypher query. Results are rows: map[string]quad.Value for Raw collation,
+// map[string]interface{} with native values for JSON and a string for REPL.
+func (s *Session) Execute(ctx context.Context, qu string, opt query.Options) (query.Iterator, error) {
+	switch opt.Collation {
+	case query.Raw, query.JSON, query.REPL:
+	default:
+		return nil, &query.ErrUnsupportedCollation{Collation: opt.Collation}
+	}
+	q, err := Parse(qu)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := Compile(s.qs, q)
+	if err != nil {
+		return nil, err
+	}
+	limit := plan.Limit
+	if opt.Limit > 0 && (limit < 0 || int64(opt.Limit) < limit) {
+		limit = int64(opt.Limit)
+	}
+	it, _ := plan.Path.BuildIterator(ctx).Optimize(ctx)
+	return &results{
+		qs:    s.qs,
+		col:   opt.Collation,
+		plan:  plan,
+		it:    it.Iterate(),
+		skip:  plan.Skip,
+		limit: limit,
+	}, nil
+}
+
+type row []quad.Value
+
+type results struct {
+	qs   graph.QuadStore
+	col  query.Collation
+	plan *Plan
+	it   iterator.Scanner
+
+	started bool // Next was called on the iterator, so NextPath can be called
+	sorted  []row
+	sortInd int
+	seen    map[string]struct{}
+
+	skip  int64
+	limit int64
+	n     int64
+
+	cur row
+	err error
+}
+
+// nextRow reads the next path of the iterator as a row.
+func (it *results) nextRow(ctx context.Context) (row, bool) {
+	if it.started && it.it.NextPath(ctx) {
+		return it.readRow(), true
+	} else if err := it.it.Err(); err != nil {
+		return nil, false
+	}
+	if !it.it.Next(ctx) {
+		return nil, false
+	}
+	it.started = true
+	return it.readRow(), true
+}
+
+func (it *results) readRow() row {
+	tags := make(map[string]refs.Ref, len(it.plan.Tags))
+	it.it.TagResults(tags)
+	r := make(row, len(it.plan.Tags))
+	for i, t := range it.plan.Tags {
+		if ref, ok := tags[t]; ok && ref != nil {
+			r[i] = it.qs.NameOf(ref)
+		}
+	}
+	return r
+}
+
+// sortRows reads all rows and sorts them.
+func (it *results) sortRows(ctx context.Context) error {
+	gov := governor.FromContext(ctx)
+	for {
+		r, ok := it.nextRow(ctx)
+		if !ok {
+			break
+		}
+		if err := gov.Materialize(int64(len(r))); err != nil {
+			return err
+		}
+		it.sorted = append(it.sorted, r)
+	}
+	if err := it.it.Err(); err != nil {
+		return err
+	}
+	keys := it.plan.Order
+	sort.SliceStable(it.sorted, func(i, j int) bool {
+		a, b := it.sorted[i], it.sorted[j]
+		for _, k := range keys {
+			if c := iterator.CompareValuesNullsLast(a[k.Tag], b[k.Tag]); c != 0 {
+				if k.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return false
+	})
+	if it.sorted == nil {
+		it.sorted = []row{}
+	}
+	return nil
+}
+
+// rowKey returns a key for a projection of the row to result columns.
+func (it *results) rowKey(r row) string {
+	var buf strings.Builder
+	for _, c := range it.plan.Columns {
+		if v := r[c.Tag]; v != nil {
+			buf.WriteString(quad.StringOf(v))
+		}
+		buf.WriteByte(0)
+	}
+	return buf.String()
+}
+
+func (it *results) Next(ctx context.Context) bool {
+	if it.err != nil || (it.limit >= 0 && it.n >= it.limit) {
+		return false
+	}
+	if len(it.plan.Order) != 0 && it.sorted == nil {
+		if it.err = it.sortRows(ctx); it.err != nil {
+			return false
+		}
+	}
+	for {
+		var (
+			r  row
+			ok bool
+		)
+		if it.sorted != nil {
+			if it.sortInd < len(it.sorted) {
+				r, ok = it.sorted[it.sortInd], true
+				it.sortInd++
+			}
+		} else {
+			r, ok = it.nextRow(ctx)
+		}
+		if !ok {
+			return false
+		}
+		if it.plan.Distinct {
+			if it.seen == nil {
+				it.seen = make(map[string]struct{})
+			}
+			key := it.rowKey(r)
+			if _, ok := it.seen[key]; ok {
+				continue
+			}
+			if it.err = governor.FromContext(ctx).Materialize(int64(len(it.plan.Columns))); it.err != nil {
+				return false
+			}
+			it.seen[key] = struct{}{}
+		}
+		if it.skip > 0 {
+			it.skip--
+			continue
+		}
+		it.cur = r
+		it.n++
+		return true
+	}
+}
+
+func (it *results) Result() interface{} {
+	if it.cur == nil {
+		return nil
+	}
+	switch it.col {
+	case query.Raw:
+		m := make(map[string]quad.Value, len(it.plan.Columns))
+		for _, c := range it.plan.Columns {
+			m[c.Name] = it.cur[c.Tag]
+		}
+		return m
+	case query.JSON:
+		m := make(map[string]interface{}, len(it.plan.Columns))
+		for _, c := range it.plan.Columns {
+			m[c.Name] = toNative(it.cur[c.Tag])
+		}
+		return m
+	case query.REPL:
+		var buf strings.Builder
+		buf.WriteString("****\n")
+		for _, c := range it.plan.Columns {
+			s := "null"
+			if v := it.cur[c.Tag]; v != nil {
+				s = quad.StringOf(v)
+			}
+			fmt.Fprintf(&buf, "%s : %s\n", c.Name, s)
+		}
+		return buf.String()
+	}
+	return nil
+}
+
+// toNative converts a value to a native Go type that can be encoded to JSON.
+// Values without a native representation (IRIs, blank nodes) are returned in N-Quads notation.
+func toNative(v quad.Value) interface{} {
+	if v == nil {
+		return nil
+	}
+	out := v.Native()
+	if nv, ok := out.(quad.Value); ok && v == nv {
+		return quad.StringOf(v)
+	}
+	return out
+}
+
+func (it *results) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
+
+func (it *results) Close() error {
+	it.sorted, it.seen = nil, nil
+	return it.it.Close()
+}
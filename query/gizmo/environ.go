@@ -36,6 +36,9 @@ import (
 //
 // This is the only special object in the environment, generates the query objects.
 // Under the hood, they're simple objects that get compiled to a Go iterator tree when executed.
+//
+// Parameters of the query are available as global variables. Values are converted the same way as in results,
+// thus IRIs are represented as `"<iri>"` strings.
 // Methods starting with "New" are accessible in JavaScript with a capital letter (e.g. NewV becomes V)
 type graphObject struct {
 	s *Session
@@ -397,6 +400,27 @@ func toVia(via []interface{}) []interface{} {
 	return via
 }
 
+// toSingleVia converts arguments to a single value that can be passed as via to path functions:
+// a path, a predicate or a list of predicates. It returns nil if no arguments are set.
+func toSingleVia(args []interface{}) (interface{}, error) {
+	preds := toVia(args)
+	switch len(preds) {
+	case 0:
+		return nil, nil
+	case 1:
+		return preds[0], nil
+	}
+	vals := make([]quad.Value, 0, len(preds))
+	for _, v := range preds {
+		qv, ok := v.(quad.Value)
+		if !ok {
+			return nil, fmt.Errorf("expected one path or a list of predicates, got: %T", v)
+		}
+		vals = append(vals, qv)
+	}
+	return vals, nil
+}
+
 func toViaData(objs []interface{}) (predicates []interface{}, tags []string, ok bool) {
 	if len(objs) != 0 {
 		predicates = toVia([]interface{}{objs[0]})
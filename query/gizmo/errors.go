@@ -14,11 +14,20 @@
 
 package gizmo
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+)
 
 var (
 	errNoVia       = fmt.Errorf("expected predicate list")
 	errRegexpOnIRI = fmt.Errorf("regexps are not allowed on IRIs")
+	errReadOnly    = query.ErrReadOnly
+	errNoFunction  = fmt.Errorf("expected a function")
+	errNoProcName  = fmt.Errorf("expected a procedure name")
+	errCallDepth   = fmt.Errorf("procedure calls are nested too deep")
 )
 
 type errArgCount2 struct {
@@ -45,3 +54,11 @@ type errNotQuadValue struct {
 func (e errNotQuadValue) Error() string {
 	return fmt.Sprintf("not a quad.Value: %T", e.Val)
 }
+
+type errInvalidQuad struct {
+	Quad quad.Quad
+}
+
+func (e errInvalidQuad) Error() string {
+	return fmt.Sprintf("invalid quad: %v", e.Quad)
+}
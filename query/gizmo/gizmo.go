@@ -21,12 +21,15 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/dop251/goja"
 
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/schema"
@@ -89,14 +92,21 @@ type Session struct {
 	sch *schema.Config
 	col query.Collation
 
-	last string
-	p    *goja.Program
+	p      *goja.Program
+	params []string // names of globals set from query parameters
 
 	out   chan *Result
 	ctx   context.Context
 	limit int
 	count int
 
+	qw graph.QuadWriter   // nil if the session is read-only
+	tx *graph.Transaction // changes made by the script
+
+	depth int // nesting of procedure calls
+
+	waiting int32 // set while the script waits for the client to read a result
+
 	err error
 }
 
@@ -104,6 +114,51 @@ func (s *Session) context() context.Context {
 	return s.ctx
 }
 
+// vmTick is how often the time spent by the script is accounted in the governor.
+const vmTick = 10 * time.Millisecond
+
+// watch starts a watchdog that accounts the time spent by the script in the governor
+// and interrupts the script once the limit is reached. Time spent waiting for the client
+// to read results is not accounted. It returns a function that stops the watchdog.
+func (s *Session) watch() func() {
+	gov := governor.FromContext(s.ctx)
+	if gov == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(vmTick)
+		defer t.Stop()
+		last := time.Now()
+		account := func(now time.Time) error {
+			d := now.Sub(last)
+			last = now
+			if atomic.LoadInt32(&s.waiting) != 0 {
+				return nil
+			}
+			return gov.Execute(int64(d / time.Millisecond))
+		}
+		for {
+			select {
+			case <-done:
+				account(time.Now())
+				return
+			case now := <-t.C:
+				if err := account(now); err != nil {
+					s.vm.Interrupt(err)
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
 func (s *Session) buildEnv() error {
 	if s.vm != nil {
 		return nil
@@ -125,6 +180,17 @@ func (s *Session) quadValueToNative(v quad.Value) interface{} {
 	if v == nil {
 		return nil
 	}
+	if p, ok := v.(graph.PathValue); ok {
+		out := make([]interface{}, 0, len(p))
+		for _, h := range p {
+			hop := map[string]interface{}{"node": s.quadValueToNative(h.Node)}
+			if h.Pred != nil {
+				hop["pred"] = s.quadValueToNative(h.Pred)
+			}
+			out = append(out, hop)
+		}
+		return out
+	}
 	if s.col == query.JSONLD {
 		return jsonld.FromValue(v)
 	}
@@ -136,9 +202,17 @@ func (s *Session) quadValueToNative(v quad.Value) interface{} {
 }
 
 func (s *Session) tagsToValueMap(m map[string]graph.Ref) map[string]interface{} {
+	vm := make(map[string]quad.Value, len(m))
+	for k, v := range m {
+		vm[k] = s.qs.NameOf(v)
+	}
+	return s.valuesToNativeMap(vm)
+}
+
+func (s *Session) valuesToNativeMap(m map[string]quad.Value) map[string]interface{} {
 	outputMap := make(map[string]interface{})
 	for k, v := range m {
-		if o := s.quadValueToNative(s.qs.NameOf(v)); o != nil {
+		if o := s.quadValueToNative(v); o != nil {
 			outputMap[k] = o
 		}
 	}
@@ -151,8 +225,8 @@ func (s *Session) runIteratorToArray(it iterator.Shape, limit int) ([]map[string
 	ctx := s.context()
 
 	output := make([]map[string]interface{}, 0)
-	err := iterator.Iterate(ctx, it).Limit(limit).TagEach(func(tags map[string]graph.Ref) {
-		tm := s.tagsToValueMap(tags)
+	err := iterator.Iterate(ctx, it).Limit(limit).TagValues(s.qs, func(tags map[string]quad.Value) {
+		tm := s.valuesToNativeMap(tags)
 		if tm == nil {
 			return
 		}
@@ -213,6 +287,8 @@ func (s *Session) send(ctx context.Context, r *Result) bool {
 	if ctx == nil {
 		ctx = s.ctx
 	}
+	atomic.StoreInt32(&s.waiting, 1)
+	defer atomic.StoreInt32(&s.waiting, 0)
 	select {
 	case s.out <- r:
 	case <-ctx.Done():
@@ -255,17 +331,58 @@ func (r *Result) Result() interface{} {
 	return r.Val
 }
 
+// maxPrograms is the maximal number of compiled scripts kept in memory.
+const maxPrograms = 1024
+
+// programs caches compiled scripts and procedures. Programs are immutable and can be shared between sessions.
+var programs = query.NewPlanCache(maxPrograms)
+
+// compileProgram compiles a script, or returns a program compiled previously.
+func compileProgram(src string) (*goja.Program, error) {
+	if p, ok := programs.Get(src); ok {
+		return p.(*goja.Program), nil
+	}
+	p, err := goja.Compile("", src, false)
+	if err != nil {
+		return nil, err
+	}
+	programs.Put(src, p)
+	return p, nil
+}
+
 func (s *Session) compile(qu string) error {
-	var p *goja.Program
-	if s.last == qu && s.last != "" {
-		p = s.p
-	} else {
-		var err error
-		p, err = goja.Compile("", qu, false)
-		if err != nil {
-			return err
+	p, err := compileProgram(qu)
+	if err != nil {
+		return err
+	}
+	s.p = p
+	return nil
+}
+
+// setParams exposes query parameters as global variables. Parameters of the previous query are reset.
+//
+// Values are converted the same way as in results, thus IRIs are represented as "<iri>" strings.
+func (s *Session) setParams(params map[string]quad.Value) error {
+	for _, name := range s.params {
+		s.vm.Set(name, goja.Undefined())
+	}
+	s.params = s.params[:0]
+	for name, v := range params {
+		if !reProcParam.MatchString(name) {
+			return fmt.Errorf("invalid parameter name: %q", name)
 		}
-		s.last, s.p = qu, p
+		if cur := s.vm.Get(name); cur != nil && !goja.IsUndefined(cur) {
+			return fmt.Errorf("parameter %q conflicts with a global variable", name)
+		}
+		var jv interface{}
+		if v != nil {
+			jv = v.Native()
+			if nv, ok := jv.(quad.Value); ok && v == nv {
+				jv = quad.StringOf(v)
+			}
+		}
+		s.vm.Set(name, jv)
+		s.params = append(s.params, name)
 	}
 	return nil
 }
@@ -275,6 +392,17 @@ func (s *Session) run() (goja.Value, error) {
 	if e, ok := err.(*goja.Exception); ok && e.Value() != nil {
 		if er, ok := e.Value().Export().(error); ok {
 			err = er
+		} else if o, ok := e.Value().(*goja.Object); ok {
+			// errors returned from Go functions are wrapped into GoError
+			if v := o.Get("value"); v != nil {
+				if er, ok := v.Export().(*governor.ErrLimitExceeded); ok {
+					err = er
+				}
+			}
+		}
+	} else if e, ok := err.(*goja.InterruptedError); ok {
+		if er, ok := e.Value().(*governor.ErrLimitExceeded); ok {
+			err = er
 		}
 	}
 	return v, err
@@ -288,9 +416,22 @@ func (s *Session) Execute(ctx context.Context, qu string, opt query.Options) (qu
 	if err := s.compile(qu); err != nil {
 		return nil, err
 	}
+	if err := s.setParams(opt.Params); err != nil {
+		return nil, err
+	}
 	s.limit = opt.Limit
 	s.count = 0
-	ctx, cancel := context.WithCancel(context.Background())
+	s.tx = nil
+	s.depth = 0
+	// script runs independently of the request context, but it should share the same resource limits
+	bctx := context.Background()
+	if gov := governor.FromContext(ctx); gov != nil {
+		bctx = governor.WithGovernor(bctx, gov)
+	}
+	if n := iterator.Parallelism(ctx); n > 1 {
+		bctx = iterator.WithParallelism(bctx, n)
+	}
+	ctx, cancel := context.WithCancel(bctx)
 	s.ctx = ctx
 	s.col = opt.Collation
 	return &results{
@@ -329,7 +470,12 @@ func (it *results) Next(ctx context.Context) bool {
 		it.running = true
 		go func() {
 			defer close(it.errc)
+			stop := it.s.watch()
 			v, err := it.s.run()
+			stop()
+			if err == nil {
+				err = it.s.commit()
+			}
 			if err != nil {
 				it.errc <- err
 				return
@@ -344,6 +490,8 @@ func (it *results) Next(ctx context.Context) bool {
 		it.cur = r
 		return true
 	case err := <-it.errc:
+		// script is finished, thus closing the iterator must not interrupt the next one
+		it.running = false
 		if err != nil {
 			it.err = err
 		}
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
 	"github.com/cayleygraph/cayley/graph/graphtest/testutil"
 	_ "github.com/cayleygraph/cayley/graph/memstore"
 	"github.com/cayleygraph/cayley/query"
@@ -611,6 +612,95 @@ var testQueries = []struct {
 		`,
 		expect: []string{"<bob>", "<dani>", "<fred>", "<greg>"},
 	},
+	{
+		message: "shortest path",
+		query: `
+			g.V("<alice>", "<charlie>").shortestPath(g.V("<greg>"), "<follows>").all();
+		`,
+		tag: "path",
+		expect: []string{
+			"<alice> -<follows>-> <bob> -<follows>-> <fred> -<follows>-> <greg>",
+			"<charlie> -<follows>-> <dani> -<follows>-> <greg>",
+		},
+	},
+	{
+		message: "shortest path hops",
+		query: `
+			g.V("<charlie>").shortestPath(g.V("<greg>"), null, 2).forEach(function(d){
+				g.emit(d.path.map(function(h){ return h.node + " " + h.pred }).join(", "))
+			});
+		`,
+		expect: []string{"<charlie> <follows>, <dani> <follows>, <greg> undefined"},
+	},
+	{
+		message: "all paths",
+		query: `
+			g.V("<charlie>").allPaths(g.V("<greg>"), g.V("<follows>"), 3).all();
+		`,
+		tag: "path",
+		expect: []string{
+			"<charlie> -<follows>-> <bob> -<follows>-> <fred> -<follows>-> <greg>",
+			"<charlie> -<follows>-> <dani> -<follows>-> <greg>",
+		},
+	},
+	{
+		message: "weighted shortest path",
+		data: []quad.Quad{
+			quad.MakeIRI("a", "road", "b", "r1"),
+			quad.MakeIRI("b", "road", "c", "r2"),
+			quad.MakeIRI("a", "road", "c", "r3"),
+			quad.Make(quad.IRI("r1"), quad.IRI("km"), quad.Int(2), nil),
+			quad.Make(quad.IRI("r2"), quad.IRI("km"), quad.Int(3), nil),
+			quad.Make(quad.IRI("r3"), quad.IRI("km"), quad.Int(7), nil),
+		},
+		query: `
+			g.V("<a>").weightedShortestPath(g.V("<b>", "<c>"), {via: "<road>", weight: "<km>"}).forEach(function(d){
+				g.emit(d.id + " " + d.cost + " " + d.path.length)
+			});
+		`,
+		expect: []string{"<b> 2 2", "<c> 5 3"},
+	},
+	{
+		message: "weighted shortest path without weight",
+		query: `
+			g.V("<alice>").weightedShortestPath(g.V("<bob>"), {via: "<follows>"}).all();
+		`,
+		err: true,
+	},
+	{
+		message: "group by and aggregate",
+		data: []quad.Quad{
+			quad.MakeIRI("alice", "city", "ny", ""),
+			quad.MakeIRI("bob", "city", "ny", ""),
+			quad.MakeIRI("carol", "city", "sf", ""),
+			quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+			quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Int(41), nil),
+			quad.Make(quad.IRI("carol"), quad.IRI("age"), quad.Int(20), nil),
+		},
+		query: `
+			g.V().save("<age>", "age").out("<city>").tag("city").groupBy("city")
+				.aggregate("count").aggregate("avg", "age", "avgAge").aggregate("max", "age")
+				.forEach(function(d){
+					g.emit(d.id + " " + d.count + " " + d.avgAge + " " + d.max)
+				});
+		`,
+		expect: []string{"<ny> 2 35.5 41", "<sf> 1 20 20"},
+	},
+	{
+		message: "aggregate without grouping",
+		query: `
+			g.V().out("<follows>").aggregate("count", "", "n").all();
+		`,
+		tag:    "n",
+		expect: []string{`"8"^^<xsd:integer>`},
+	},
+	{
+		message: "aggregate unknown function",
+		query: `
+			g.V().aggregate("median", "x").all();
+		`,
+		err: true,
+	},
 	{
 		message: "find non-existent",
 		query: `
@@ -667,6 +757,27 @@ var testQueries = []struct {
 			"smart_person",
 		},
 	},
+	{
+		message: "order by tag",
+		query: `
+			g.V().has("<follows>").tag("person").order({by: "person", desc: true}).limit(2).all()
+		`,
+		expect: []string{"<emily>", "<fred>"},
+	},
+	{
+		message: "order by property",
+		query: `
+			g.V().has("<status>").order({via: "<status>", desc: true}, "person").limit(2).all()
+		`,
+		expect: []string{"<emily>", "<greg>"},
+	},
+	{
+		message: "order unknown option",
+		query: `
+			g.V().order({by: "person", asc: true}).all()
+		`,
+		err: true,
+	},
 	{
 		message: "use order tags",
 		query: `
@@ -841,3 +952,379 @@ func issue718Nodes() []string {
 	}
 	return nodes
 }
+
+func TestGizmoLimits(t *testing.T) {
+	simpleGraph := testutil.LoadGraph(t, "../../data/testdata.nq")
+	cases := []struct {
+		name   string
+		query  string
+		limits governor.Limits
+		res    governor.Resource
+	}{
+		{
+			name:   "steps",
+			query:  `g.V().out("<follows>").out("<follows>").all()`,
+			limits: governor.Limits{Steps: 5},
+			res:    governor.Steps,
+		},
+		{
+			name:   "refs",
+			query:  `g.V().followRecursive("<follows>").all()`,
+			limits: governor.Limits{Refs: 2},
+			res:    governor.Refs,
+		},
+		{
+			name:   "vm time",
+			query:  `var s = ""; for (;;) { s = s + "x" }`,
+			limits: governor.Limits{VMTime: 50},
+			res:    governor.VMTime,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ses := makeTestSession(simpleGraph)
+			ctx := governor.WithGovernor(context.TODO(), governor.New(c.limits))
+			it, err := ses.Execute(ctx, c.query, query.Options{Collation: query.Raw})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer it.Close()
+			for it.Next(ctx) {
+			}
+			if e, ok := it.Err().(*governor.ErrLimitExceeded); !ok {
+				t.Fatalf("unexpected error: %v", it.Err())
+			} else if e.Resource != c.res {
+				t.Fatalf("unexpected resource: %v", e.Resource)
+			}
+		})
+	}
+}
+
+func TestGizmoWrite(t *testing.T) {
+	data := []quad.Quad{
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("bob", "follows", "fred", ""),
+		quad.MakeIRI("fred", "follows", "bob", ""),
+	}
+	cases := []struct {
+		name   string
+		query  string
+		expect []string
+		err    bool
+	}{
+		{
+			name: "add and remove",
+			query: `
+				g.addQuad("<charlie>", "<follows>", "<alice>")
+				g.addQuad("<charlie>", "<status>", "cool", "<graph>")
+				g.removeQuad("<alice>", "<follows>", "<bob>")`,
+			expect: []string{
+				`<bob> <follows> <fred> .`,
+				`<charlie> <follows> <alice> .`,
+				`<charlie> <status> "cool" <graph> .`,
+				`<fred> <follows> <bob> .`,
+			},
+		},
+		{
+			name: "remove node",
+			query: `
+				g.addQuad("<charlie>", "<follows>", "<bob>")
+				g.removeNode("<bob>")`,
+		},
+		{
+			name: "transaction",
+			query: `
+				g.tx(function() {
+					g.addQuad("<charlie>", "<follows>", "<alice>")
+				})
+				try {
+					g.tx(function() {
+						g.removeNode("<alice>")
+						g.addQuad("<dani>", "<follows>", "<alice>")
+						throw "rollback"
+					})
+				} catch (e) {}`,
+			expect: []string{
+				`<alice> <follows> <bob> .`,
+				`<bob> <follows> <fred> .`,
+				`<charlie> <follows> <alice> .`,
+				`<fred> <follows> <bob> .`,
+			},
+		},
+		{
+			name: "script error",
+			query: `
+				g.addQuad("<charlie>", "<follows>", "<alice>")
+				throw "error"`,
+			err: true,
+		},
+		{
+			name:  "invalid quad",
+			query: `g.addQuad("<charlie>", "<follows>")`,
+			err:   true,
+		},
+		{
+			name:  "missing node",
+			query: `g.removeNode("<charlie>")`,
+			err:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			qs, _ := graph.NewQuadStore("memstore", "", nil)
+			w, _ := graph.NewQuadWriter("single", qs, nil)
+			if err := w.AddQuadSet(data); err != nil {
+				t.Fatal(err)
+			}
+			ses := NewSession(qs)
+			ses.SetWriter(w)
+			ctx := context.TODO()
+			it, err := ses.Execute(ctx, c.query, query.Options{Collation: query.Raw})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for it.Next(ctx) {
+			}
+			err = it.Err()
+			it.Close()
+			if c.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				c.expect = []string{
+					`<alice> <follows> <bob> .`,
+					`<bob> <follows> <fred> .`,
+					`<fred> <follows> <bob> .`,
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			qit := qs.QuadsAllIterator().Iterate()
+			for qit.Next(ctx) {
+				got = append(got, qs.Quad(qit.Result()).NQuad())
+			}
+			qit.Close()
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, c.expect) {
+				t.Fatalf("unexpected quads:\n%q\nvs\n%q", got, c.expect)
+			}
+		})
+	}
+}
+
+func TestGizmoReadOnly(t *testing.T) {
+	ses := makeTestSession(nil)
+	ctx := context.TODO()
+	it, err := ses.Execute(ctx, `g.addQuad("<alice>", "<follows>", "<bob>")`, query.Options{Collation: query.Raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	for it.Next(ctx) {
+	}
+	if it.Err() != errReadOnly {
+		t.Fatalf("unexpected error: %v", it.Err())
+	}
+}
+
+func TestGizmoProcedures(t *testing.T) {
+	ctx := context.TODO()
+	qs, _ := graph.NewQuadStore("memstore", "", nil)
+	w, _ := graph.NewQuadWriter("single", qs, nil)
+	if err := w.AddQuadSet(testutil.LoadGraph(t, "../../data/testdata.nq")); err != nil {
+		t.Fatal(err)
+	}
+	procs := []Procedure{
+		{Name: "follows", Params: []string{"who", "pred"}, Body: `return g.V(who).out(pred || "<follows>")`},
+		{Name: "status", Params: []string{"who"}, Body: `return g.call("follows", {who: who}).out("<status>")`},
+		{Name: "add", Params: []string{"who"}, Body: `g.addQuad(who, "<status>", "new")`},
+		{Name: "count", Body: `g.emit("done"); return 1`},
+		{Name: "loop", Body: `return g.call("loop")`},
+	}
+	for _, p := range procs {
+		if err := SaveProcedure(ctx, qs, w, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveProcedure(ctx, qs, w, Procedure{Name: "bad", Body: `return (`}); err == nil {
+		t.Fatal("expected a compilation error")
+	}
+	if err := SaveProcedure(ctx, qs, w, Procedure{Name: "bad", Params: []string{"a b"}}); err == nil {
+		t.Fatal("expected an error for an invalid parameter")
+	}
+	// replace the procedure
+	procs[0].Body = `return g.V(who).out(pred || "<follows>").unique()`
+	if err := SaveProcedure(ctx, qs, w, procs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ListProcedures(ctx, qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].Name < procs[j].Name
+	})
+	if !reflect.DeepEqual(list, procs) {
+		t.Fatalf("unexpected procedures:\n%#v\nvs\n%#v", list, procs)
+	}
+
+	run := func(it query.Iterator, err error) ([]string, error) {
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		var out []string
+		for it.Next(ctx) {
+			out = append(out, fmt.Sprint(it.Result()))
+		}
+		sort.Strings(out)
+		return out, it.Err()
+	}
+	cases := []struct {
+		name   string
+		query  string
+		expect []string
+		err    bool
+	}{
+		{
+			name:   "call",
+			query:  `g.call("follows", {who: "<bob>"}).all()`,
+			expect: []string{"map[id:<fred>]"},
+		},
+		{
+			name:   "nested call",
+			query:  `g.call("status", {who: "<dani>"}).all()`,
+			expect: []string{"map[id:cool_person]", "map[id:cool_person]", "map[id:smart_person]"},
+		},
+		{
+			name:   "use result",
+			query:  `g.call("follows", {who: "<charlie>", pred: "<follows>"}).in("<follows>").all()`,
+			expect: []string{"map[id:<alice>]", "map[id:<charlie>]", "map[id:<charlie>]", "map[id:<dani>]"},
+		},
+		{
+			name:  "unknown procedure",
+			query: `g.call("unknown")`,
+			err:   true,
+		},
+		{
+			name:  "recursion",
+			query: `g.call("loop")`,
+			err:   true,
+		},
+		{
+			name:  "read-only",
+			query: `g.call("add", {who: "<bob>"})`,
+			err:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := run(NewSession(qs).Execute(ctx, c.query, query.Options{Collation: query.JSON}))
+			if c.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.expect) {
+				t.Fatalf("unexpected results:\n%q\nvs\n%q", got, c.expect)
+			}
+		})
+	}
+
+	got, err := run(NewSession(qs).ExecuteProcedure(ctx, "follows", map[string]interface{}{"who": "<bob>"}, query.Options{Collation: query.JSON}))
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"map[id:<fred>]"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected results: %q", got)
+	}
+	got, err = run(NewSession(qs).ExecuteProcedure(ctx, "count", nil, query.Options{Collation: query.JSON}))
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"1", "done"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected results: %q", got)
+	}
+	if _, err = NewSession(qs).ExecuteProcedure(ctx, "unknown", nil, query.Options{Collation: query.JSON}); err != ErrProcedureNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ses := NewSession(qs)
+	ses.SetWriter(w)
+	if _, err = run(ses.ExecuteProcedure(ctx, "add", map[string]interface{}{"who": "<bob>"}, query.Options{Collation: query.JSON})); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = run(NewSession(qs).Execute(ctx, `g.V("<bob>").out("<status>").all()`, query.Options{Collation: query.JSON})); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"map[id:cool_person]", "map[id:new]"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected results: %q", got)
+	}
+
+	if err = DeleteProcedure(ctx, qs, w, "count"); err != nil {
+		t.Fatal(err)
+	}
+	if err = DeleteProcedure(ctx, qs, w, "count"); err != ErrProcedureNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = GetProcedure(ctx, qs, "count"); err != ErrProcedureNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGizmoParams(t *testing.T) {
+	ctx := context.TODO()
+	qs, _ := graph.NewQuadStore("memstore", "", nil)
+	w, _ := graph.NewQuadWriter("single", qs, nil)
+	if err := w.AddQuadSet(testutil.LoadGraph(t, "../../data/testdata.nq")); err != nil {
+		t.Fatal(err)
+	}
+	run := func(ses *Session, qu string, params map[string]quad.Value) ([]string, error) {
+		it, err := ses.Execute(ctx, qu, query.Options{Collation: query.JSON, Params: params})
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		var out []string
+		for it.Next(ctx) {
+			out = append(out, fmt.Sprint(it.Result()))
+		}
+		sort.Strings(out)
+		return out, it.Err()
+	}
+	const qu = `g.V(who).out("<follows>").has("<status>", status).all()`
+
+	ses := NewSession(qs)
+	got, err := run(ses, qu, map[string]quad.Value{
+		"who": quad.IRI("dani"), "status": quad.String("cool_person"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"map[id:<bob>]", "map[id:<greg>]"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected results: %q", got)
+	}
+	// the same query with different parameters
+	got, err = run(ses, qu, map[string]quad.Value{
+		"who": quad.IRI("dani"), "status": quad.String("smart_person"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"map[id:<greg>]"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected results: %q", got)
+	}
+	// parameters of the previous query are reset
+	got, err = run(ses, `g.emit(typeof who)`, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"undefined"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected results: %q", got)
+	}
+	if _, err = run(ses, qu, map[string]quad.Value{"g": quad.IRI("bob")}); err == nil {
+		t.Fatal("expected an error for a parameter that conflicts with a global")
+	}
+	if _, err = run(ses, qu, map[string]quad.Value{"a b": quad.IRI("bob")}); err == nil {
+		t.Fatal("expected an error for an invalid parameter name")
+	}
+}
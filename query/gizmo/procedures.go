@@ -0,0 +1,305 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gizmo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+)
+
+// ProcedureLabel is a label of quads that store procedures in the graph.
+const ProcedureLabel = quad.IRI("cayley:procedures")
+
+const (
+	procPrefix = "cayley:procedures/"
+	procType   = quad.IRI("cayley:procedure")
+	procName   = quad.IRI("cayley:name")
+	procParams = quad.IRI("cayley:params")
+	procBody   = quad.IRI("cayley:body")
+)
+
+// maxCallDepth limits the nesting of procedure calls.
+const maxCallDepth = 32
+
+// ErrProcedureNotFound is returned when a procedure with a given name is not stored in the graph.
+var ErrProcedureNotFound = errors.New("procedure not found")
+
+var (
+	reProcName  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	reProcParam = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+)
+
+// Procedure is a named Gizmo function stored in the graph.
+type Procedure struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+	Body   string   `json:"body"`
+}
+
+func (p *Procedure) id() quad.IRI {
+	return quad.IRI(procPrefix + p.Name)
+}
+
+// source returns a script that evaluates to the procedure function.
+func (p *Procedure) source() string {
+	return "(function(" + strings.Join(p.Params, ", ") + ") {\n" + p.Body + "\n})"
+}
+
+// Validate checks the name and the parameters of the procedure and compiles its body.
+func (p *Procedure) Validate() error {
+	if !reProcName.MatchString(p.Name) {
+		return fmt.Errorf("invalid procedure name: %q", p.Name)
+	}
+	seen := make(map[string]struct{}, len(p.Params))
+	for _, name := range p.Params {
+		if !reProcParam.MatchString(name) {
+			return fmt.Errorf("invalid parameter name: %q", name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate parameter: %q", name)
+		}
+		seen[name] = struct{}{}
+	}
+	_, err := compileProgram(p.source())
+	return err
+}
+
+func (p *Procedure) quads() []quad.Quad {
+	id := p.id()
+	out := []quad.Quad{
+		quad.Make(id, quad.IRI(rdf.Type), procType, ProcedureLabel),
+		quad.Make(id, procName, quad.String(p.Name), ProcedureLabel),
+		quad.Make(id, procBody, quad.String(p.Body), ProcedureLabel),
+	}
+	if len(p.Params) != 0 {
+		// parameters are ordered, thus they are stored as a single value
+		out = append(out, quad.Make(id, procParams, quad.String(strings.Join(p.Params, ",")), ProcedureLabel))
+	}
+	return out
+}
+
+// setField sets a field of the procedure from a stored quad. It returns false if the quad is not a procedure type.
+func (p *Procedure) setField(q quad.Quad) bool {
+	switch q.Predicate {
+	case procName:
+		p.Name = quad.ToString(q.Object)
+	case procBody:
+		p.Body = quad.ToString(q.Object)
+	case procParams:
+		if s := quad.ToString(q.Object); s != "" {
+			p.Params = strings.Split(s, ",")
+		}
+	case quad.IRI(rdf.Type):
+		return q.Object == procType
+	}
+	return false
+}
+
+// procedureQuads returns all quads with a given value in a given direction stored with the procedure label.
+func procedureQuads(ctx context.Context, qs graph.QuadStore, d quad.Direction, v quad.Value) ([]quad.Quad, error) {
+	ref := qs.ValueOf(v)
+	if ref == nil {
+		return nil, nil
+	}
+	it := qs.QuadIterator(d, ref).Iterate()
+	defer it.Close()
+	var out []quad.Quad
+	for it.Next(ctx) {
+		if q := qs.Quad(it.Result()); q.Label == ProcedureLabel {
+			out = append(out, q)
+		}
+	}
+	return out, it.Err()
+}
+
+// ListProcedures returns all procedures stored in the graph, sorted by name.
+func ListProcedures(ctx context.Context, qs graph.QuadStore) ([]Procedure, error) {
+	quads, err := procedureQuads(ctx, qs, quad.Label, ProcedureLabel)
+	if err != nil {
+		return nil, err
+	}
+	procs := make(map[quad.Value]*Procedure)
+	typed := make(map[quad.Value]bool)
+	for _, q := range quads {
+		p := procs[q.Subject]
+		if p == nil {
+			p = &Procedure{}
+			procs[q.Subject] = p
+		}
+		if p.setField(q) {
+			typed[q.Subject] = true
+		}
+	}
+	out := make([]Procedure, 0, len(typed))
+	for id := range typed {
+		out = append(out, *procs[id])
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// GetProcedure loads a procedure with a given name from the graph.
+// It returns ErrProcedureNotFound if there is no such procedure.
+func GetProcedure(ctx context.Context, qs graph.QuadStore, name string) (*Procedure, error) {
+	quads, err := procedureQuads(ctx, qs, quad.Subject, (&Procedure{Name: name}).id())
+	if err != nil {
+		return nil, err
+	}
+	p := &Procedure{}
+	found := false
+	for _, q := range quads {
+		if p.setField(q) {
+			found = true
+		}
+	}
+	if !found {
+		return nil, ErrProcedureNotFound
+	}
+	return p, nil
+}
+
+// SaveProcedure validates a procedure and stores it in the graph, replacing a procedure with the same name.
+func SaveProcedure(ctx context.Context, qs graph.QuadStore, qw graph.QuadWriter, p Procedure) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	old, err := procedureQuads(ctx, qs, quad.Subject, p.id())
+	if err != nil {
+		return err
+	}
+	tx := graph.NewTransactionN(len(old) + 4)
+	for _, q := range old {
+		tx.RemoveQuad(q)
+	}
+	for _, q := range p.quads() {
+		tx.AddQuad(q)
+	}
+	return qw.ApplyTransaction(tx)
+}
+
+// DeleteProcedure removes a procedure with a given name from the graph.
+// It returns ErrProcedureNotFound if there is no such procedure.
+func DeleteProcedure(ctx context.Context, qs graph.QuadStore, qw graph.QuadWriter, name string) error {
+	old, err := procedureQuads(ctx, qs, quad.Subject, (&Procedure{Name: name}).id())
+	if err != nil {
+		return err
+	} else if len(old) == 0 {
+		return ErrProcedureNotFound
+	}
+	tx := graph.NewTransactionN(len(old))
+	for _, q := range old {
+		tx.RemoveQuad(q)
+	}
+	return qw.ApplyTransaction(tx)
+}
+
+// procedureCall is a script that calls a procedure and returns results of a path returned by it,
+// or a value returned by it.
+const procedureCall = `(function(r) {
+	if (r !== null && typeof r === "object" && typeof r.all === "function") {
+		r.all()
+	} else if (r !== undefined && r !== null) {
+		g.emit(r)
+	}
+})(g.call(%s, %s))`
+
+// ExecuteProcedure runs a stored procedure with given arguments.
+// If the procedure returns a path, results of the path are returned.
+// It returns ErrProcedureNotFound if there is no such procedure.
+func (s *Session) ExecuteProcedure(ctx context.Context, name string, args map[string]interface{}, opt query.Options) (query.Iterator, error) {
+	if _, err := GetProcedure(ctx, s.qs, name); err != nil {
+		return nil, err
+	}
+	jname, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	jargs, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Execute(ctx, fmt.Sprintf(procedureCall, jname, jargs), opt)
+}
+
+// Call runs a stored procedure and returns its result.
+// Signature: (name, [args])
+//
+// Arguments are passed as an object with parameter names as keys. Missing parameters are undefined.
+//
+//	// javascript
+//	g.call("friends", {name: "<alice>"}).all()
+func (g *graphObject) Call(call goja.FunctionCall) goja.Value {
+	name, ok := call.Argument(0).Export().(string)
+	if !ok {
+		return throwErr(g.s.vm, errNoProcName)
+	}
+	if g.s.depth >= maxCallDepth {
+		return throwErr(g.s.vm, errCallDepth)
+	}
+	p, err := GetProcedure(g.s.ctx, g.s.qs, name)
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	prog, err := compileProgram(p.source())
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	fv, err := g.s.vm.RunProgram(prog)
+	if err != nil {
+		panic(err)
+	}
+	fnc, ok := goja.AssertFunction(fv)
+	if !ok {
+		return throwErr(g.s.vm, errNoFunction)
+	}
+	args := make([]goja.Value, len(p.Params))
+	var obj *goja.Object
+	if v := call.Argument(1); !goja.IsUndefined(v) && !goja.IsNull(v) {
+		obj = v.ToObject(g.s.vm)
+	}
+	for i, name := range p.Params {
+		args[i] = goja.Undefined()
+		if obj != nil {
+			if v := obj.Get(name); v != nil {
+				args[i] = v
+			}
+		}
+	}
+	g.s.depth++
+	v, err := fnc(goja.Undefined(), args...)
+	g.s.depth--
+	if err != nil {
+		// rethrow an exception or an interrupt
+		panic(err)
+	}
+	return v
+}
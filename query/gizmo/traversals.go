@@ -264,6 +264,136 @@ func (p *pathObject) FollowRecursive(call goja.FunctionCall) goja.Value {
 	return p.newVal(np)
 }
 
+// ShortestPath finds the shortest chain of links from each node to any node of the target path.
+//
+// Signature: (targetPath, [predicatePath], [maxDepth])
+//
+// Arguments:
+//
+// * `targetPath`: a path with target nodes.
+//
+// * `predicatePath` (Optional): a predicate, a list of predicates or a path that returns predicates to follow.
+// Null or undefined means any predicate.
+//
+// * `maxDepth` (Optional): a maximal number of links in the chain. Defaults to 50, -1 means no limit.
+//
+// Results are the target nodes, and the chain itself is saved to the "path" tag as a list of hops,
+// each of them with "node" and "pred" fields. The last hop has no predicate.
+//
+// Example:
+// 	// javascript
+//	// Returns greg with a path from charlie through dani to greg.
+//	g.V("<charlie>").shortestPath(g.V("<greg>"), "<follows>").all()
+func (p *pathObject) ShortestPath(call goja.FunctionCall) goja.Value {
+	return p.pathSearch(call, false)
+}
+
+// AllPaths is the same as ShortestPath, but returns all chains of links without loops, each as a separate result.
+//
+// Signature: (targetPath, [predicatePath], [maxDepth])
+//
+// Example:
+// 	// javascript
+//	// Returns greg three times, once for each chain of follows from charlie.
+//	g.V("<charlie>").allPaths(g.V("<greg>"), "<follows>").all()
+func (p *pathObject) AllPaths(call goja.FunctionCall) goja.Value {
+	return p.pathSearch(call, true)
+}
+
+func (p *pathObject) pathSearch(call goja.FunctionCall, all bool) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) == 0 {
+		return throwErr(p.s.vm, errors.New("expected a target path"))
+	}
+	to, ok := args[0].(*path.Path)
+	if !ok {
+		return throwErr(p.s.vm, fmt.Errorf("expected a target path, got: %T", args[0]))
+	}
+	args = args[1:]
+	maxDepth := 0
+	if len(args) != 0 {
+		if d, ok := toInt(args[len(args)-1]); ok {
+			maxDepth = d
+			args = args[:len(args)-1]
+		}
+	}
+	via, err := toSingleVia(args)
+	if err != nil {
+		return throwErr(p.s.vm, err)
+	}
+	np := p.clonePath()
+	if all {
+		np = np.AllPaths(to, via, maxDepth)
+	} else {
+		np = np.ShortestPath(to, via, maxDepth)
+	}
+	return p.newVal(np)
+}
+
+// WeightedShortestPath finds the cheapest chain of links from each node to each reachable node of the target path.
+//
+// Signature: (targetPath, options)
+//
+// Arguments:
+//
+// * `targetPath`: a path with target nodes.
+//
+// * `options`: an object with the following fields:
+//   * `weight`: a predicate of a numeric cost of the link.
+//   * `via` (Optional): a predicate, a list of predicates or a path that link nodes. Any predicate is followed by default.
+//   * `next` (Optional): a predicate, a list of predicates or a path that link the edge node with the next node.
+//     If set, links are reified: "via" links the node with the edge node, and the weight is a property of the edge node.
+//     Otherwise, the weight is a property of the quad label.
+//   * `heuristic` (Optional): a predicate of the lower bound estimate of the remaining cost, turns the search into A*.
+//
+// Results are the target nodes ordered by the total cost. The chain is saved to the "path" tag as in ShortestPath,
+// and its total cost is saved to the "cost" tag.
+//
+// Example:
+// 	// javascript
+//	// Returns the cheapest road from the warehouse to each shop.
+//	g.V("<warehouse>").weightedShortestPath(g.V().has("<type>", "<Shop>"), {via: "<road>", weight: "<km>"}).all()
+func (p *pathObject) WeightedShortestPath(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) != 2 {
+		return throwErr(p.s.vm, errors.New("expected a target path and options"))
+	}
+	to, ok := args[0].(*path.Path)
+	if !ok {
+		return throwErr(p.s.vm, fmt.Errorf("expected a target path, got: %T", args[0]))
+	}
+	opts, ok := args[1].(map[string]interface{})
+	if !ok {
+		return throwErr(p.s.vm, fmt.Errorf("expected an options object, got: %T", args[1]))
+	}
+	var (
+		w   path.Weights
+		err error
+	)
+	for k, v := range opts {
+		switch k {
+		case "weight":
+			w.Weight, err = toQuadValue(v)
+		case "heuristic":
+			w.Heuristic, err = toQuadValue(v)
+		case "via":
+			w.Via, err = toSingleVia([]interface{}{v})
+		case "next":
+			w.Next, err = toSingleVia([]interface{}{v})
+		default:
+			err = fmt.Errorf("unknown option: %q", k)
+		}
+		if err != nil {
+			return throwErr(p.s.vm, err)
+		}
+	}
+	if w.Weight == nil {
+		return throwErr(p.s.vm, errors.New("weight predicate is not set"))
+	}
+	np := p.clonePath().WeightedShortestPath(to, w)
+	return p.newVal(np)
+}
+
 // And is an alias for Intersect.
 func (p *pathObject) And(path *pathObject) *pathObject {
 	return p.Intersect(path)
@@ -702,11 +832,125 @@ func (p *pathObject) Skip(offset int) *pathObject {
 	return p.new(np)
 }
 
-func (p *pathObject) Order() *pathObject {
-	np := p.clonePath().Order()
+// GroupBy groups results by a value of a tag. Each result of the path will be a distinct value of this tag.
+//
+// Use aggregate to compute values for each group.
+//
+// Arguments:
+//
+// * `tag`: A tag to group results by.
+//
+// Example:
+// 	// javascript
+//	// Count followers of each person.
+//	g.V().tag("follower").out("<follows>").tag("person").groupBy("person").aggregate("count", "follower", "followers").all()
+func (p *pathObject) GroupBy(tag string) *pathObject {
+	np := p.clonePath().GroupBy(tag)
 	return p.new(np)
 }
 
+// Aggregate computes a value for each group of results and saves it to a tag.
+// If results are not grouped with groupBy, a single value is computed for all results.
+//
+// Arguments:
+//
+// * `func`: Aggregate function to use: "count", "sum", "avg", "min" or "max".
+// * `tag` (Optional): A tag with values to aggregate. Can be omitted for "count".
+// * `as` (Optional): A tag to save the computed value to. Defaults to the function name.
+//
+// Example:
+// 	// javascript
+//	// Compute an average age of people in each city.
+//	g.V().save("<age>", "age").out("<city>").tag("city").groupBy("city").aggregate("avg", "age", "avgAge").all()
+func (p *pathObject) Aggregate(fnc string, args ...string) (*pathObject, error) {
+	if len(args) > 2 {
+		return nil, errArgCount2{Expected: 3, Got: len(args) + 1}
+	}
+	f, err := iterator.ParseAggregateFunc(fnc)
+	if err != nil {
+		return nil, err
+	}
+	tag, as := "", f.String()
+	if len(args) > 0 {
+		tag = args[0]
+	}
+	if len(args) > 1 {
+		as = args[1]
+	}
+	if tag == "" && f != iterator.AggregateCount {
+		return nil, fmt.Errorf("%v requires a tag to aggregate", f)
+	}
+	np := p.clonePath().Aggregate(f, tag, as)
+	return p.new(np), nil
+}
+
+// Order sorts the results. If no keys are given, results are sorted by node values in ascending order.
+//
+// Signature: ([key], [key]...)
+//
+// Arguments:
+//
+// * `key` (Optional): a tag to sort by, or an object with the following fields:
+//   * `by` (Optional): a tag to sort by. If neither "by" nor "via" are set, results are sorted by node values.
+//   * `via` (Optional): a predicate or a path to sort by. Its values are not added to the results.
+//   * `desc` (Optional): sort in descending order.
+//
+// Values are compared using their native types. Results without a value are placed last.
+//
+// Example:
+// 	// javascript
+//	// Sort people by status, and then by name in descending order.
+//	g.V().has("<status>").tag("person").order({via: "<status>"}, {by: "person", desc: true}).all()
+func (p *pathObject) Order(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	keys := make([]path.OrderKey, 0, len(args))
+	for _, a := range args {
+		var (
+			key path.OrderKey
+			err error
+		)
+		switch a := a.(type) {
+		case string:
+			key.Tag = a
+		case map[string]interface{}:
+			for k, v := range a {
+				switch k {
+				case "by":
+					if s, ok := v.(string); ok {
+						key.Tag = s
+					} else {
+						err = fmt.Errorf("expected a tag name, got: %T", v)
+					}
+				case "via":
+					key.Via, err = toSingleVia([]interface{}{v})
+				case "desc":
+					if b, ok := v.(bool); ok {
+						key.Desc = b
+					} else {
+						err = fmt.Errorf("expected a boolean, got: %T", v)
+					}
+				default:
+					err = fmt.Errorf("unknown option: %q", k)
+				}
+				if err != nil {
+					break
+				}
+			}
+		default:
+			err = fmt.Errorf("expected a tag or an object, got: %T", a)
+		}
+		if err != nil {
+			return throwErr(p.s.vm, err)
+		}
+		if key.Tag != "" && key.Via != nil {
+			return throwErr(p.s.vm, errors.New("only one of \"by\" and \"via\" can be set"))
+		}
+		keys = append(keys, key)
+	}
+	np := p.clonePath().OrderBy(keys...)
+	return p.newVal(np)
+}
+
 // Backwards compatibility
 func (p *pathObject) CapitalizedIs(call goja.FunctionCall) goja.Value {
 	return p.Is(call)
@@ -729,6 +973,15 @@ func (p *pathObject) CapitalizedFollowR(path *pathObject) *pathObject {
 func (p *pathObject) CapitalizedFollowRecursive(call goja.FunctionCall) goja.Value {
 	return p.FollowRecursive(call)
 }
+func (p *pathObject) CapitalizedShortestPath(call goja.FunctionCall) goja.Value {
+	return p.ShortestPath(call)
+}
+func (p *pathObject) CapitalizedAllPaths(call goja.FunctionCall) goja.Value {
+	return p.AllPaths(call)
+}
+func (p *pathObject) CapitalizedWeightedShortestPath(call goja.FunctionCall) goja.Value {
+	return p.WeightedShortestPath(call)
+}
 func (p *pathObject) CapitalizedAnd(path *pathObject) *pathObject {
 	return p.And(path)
 }
@@ -804,3 +1057,12 @@ func (p *pathObject) CapitalizedLimit(limit int) *pathObject {
 func (p *pathObject) CapitalizedSkip(offset int) *pathObject {
 	return p.Skip(offset)
 }
+func (p *pathObject) CapitalizedGroupBy(tag string) *pathObject {
+	return p.GroupBy(tag)
+}
+func (p *pathObject) CapitalizedOrder(call goja.FunctionCall) goja.Value {
+	return p.Order(call)
+}
+func (p *pathObject) CapitalizedAggregate(fnc string, args ...string) (*pathObject, error) {
+	return p.Aggregate(fnc, args...)
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gizmo
+
+import (
+	"github.com/dop251/goja"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/quad"
+)
+
+// SetWriter sets a writer for changes made by the script.
+// If writer is not set, the session is read-only and scripts that modify the graph fail.
+func (s *Session) SetWriter(w graph.QuadWriter) {
+	s.qw = w
+}
+
+// transaction returns a transaction that buffers changes made by the script.
+func (s *Session) transaction() (*graph.Transaction, error) {
+	if s.qw == nil {
+		return nil, errReadOnly
+	}
+	if s.tx == nil {
+		s.tx = graph.NewTransaction()
+	}
+	return s.tx, nil
+}
+
+// commit applies all changes made by the script.
+func (s *Session) commit() error {
+	tx := s.tx
+	s.tx = nil
+	if tx == nil || len(tx.Deltas) == 0 {
+		return nil
+	}
+	return s.qw.ApplyTransaction(tx)
+}
+
+func (s *Session) toQuad(call goja.FunctionCall) (quad.Quad, error) {
+	args := exportArgs(call.Arguments)
+	if len(args) != 3 && len(args) != 4 {
+		return quad.Quad{}, errArgCount{Got: len(args)}
+	}
+	vals, err := toQuadValues(args)
+	if err != nil {
+		return quad.Quad{}, err
+	}
+	q := quad.Quad{Subject: vals[0], Predicate: vals[1], Object: vals[2]}
+	if len(vals) == 4 {
+		q.Label = vals[3]
+	}
+	if !q.IsValid() {
+		return quad.Quad{}, errInvalidQuad{Quad: q}
+	}
+	return q, nil
+}
+
+// AddQuad adds a quad to the graph.
+// Signature: (subject, predicate, object, [label])
+//
+// Changes are buffered and applied in a single transaction when the script finishes successfully.
+// Fails if the graph is read-only.
+//
+//	// javascript
+//	g.addQuad("<bob>", "<follows>", "<alice>")
+func (g *graphObject) AddQuad(call goja.FunctionCall) goja.Value {
+	q, err := g.s.toQuad(call)
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	tx, err := g.s.transaction()
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	if err = governor.FromContext(g.s.ctx).Materialize(1); err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	tx.AddQuad(q)
+	return goja.Null()
+}
+
+// RemoveQuad removes a quad from the graph.
+// Signature: (subject, predicate, object, [label])
+//
+// Changes are buffered and applied in a single transaction when the script finishes successfully.
+// Fails if the graph is read-only.
+func (g *graphObject) RemoveQuad(call goja.FunctionCall) goja.Value {
+	q, err := g.s.toQuad(call)
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	tx, err := g.s.transaction()
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	if err = governor.FromContext(g.s.ctx).Materialize(1); err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	tx.RemoveQuad(q)
+	return goja.Null()
+}
+
+// RemoveNode removes all quads with a given node in any direction, including quads added by the script.
+// Signature: (node)
+//
+// Fails if the graph is read-only, or if there are no quads with this node.
+func (g *graphObject) RemoveNode(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) != 1 {
+		return throwErr(g.s.vm, errArgCount2{Expected: 1, Got: len(args)})
+	}
+	v, err := toQuadValue(args[0])
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	if err = g.s.removeNode(v); err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	return goja.Null()
+}
+
+func (s *Session) removeNode(v quad.Value) error {
+	tx, err := s.transaction()
+	if err != nil {
+		return err
+	}
+	gov := governor.FromContext(s.ctx)
+	total := 0
+	// changes are buffered, so quads added by the script are not in the store yet
+	for _, d := range append([]graph.Delta(nil), tx.Deltas...) {
+		if d.Action == graph.Add && hasNode(d.Quad, v) {
+			tx.RemoveQuad(d.Quad)
+			total++
+		}
+	}
+	if ref := s.qs.ValueOf(v); ref != nil {
+		for _, d := range quad.Directions {
+			it := s.qs.QuadIterator(d, ref).Iterate()
+			for it.Next(s.ctx) {
+				if err = gov.Materialize(1); err != nil {
+					break
+				}
+				tx.RemoveQuad(s.qs.Quad(it.Result()))
+				total++
+			}
+			if err == nil {
+				err = it.Err()
+			}
+			it.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if total == 0 {
+		return graph.ErrNodeNotExists
+	}
+	return nil
+}
+
+func hasNode(q quad.Quad, v quad.Value) bool {
+	for _, d := range quad.Directions {
+		if q.Get(d) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Tx runs a function and keeps changes made by it only if the function succeeds.
+// Signature: (function)
+//
+// If the function throws an exception, all changes made by it are discarded and the exception is passed to the caller.
+// Changes made by the script are applied when it finishes, thus it can catch exceptions of the block and continue.
+//
+//	// javascript
+//	g.tx(function() {
+//	  g.removeNode("<bob>")
+//	  g.addQuad("<alice>", "<follows>", "<fred>")
+//	})
+func (g *graphObject) Tx(call goja.FunctionCall) goja.Value {
+	fnc, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		return throwErr(g.s.vm, errNoFunction)
+	}
+	prev, err := g.s.transaction()
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	// run the block on a copy of changes, so they can be discarded
+	tx := graph.NewTransactionN(len(prev.Deltas))
+	for _, d := range prev.Deltas {
+		if d.Action == graph.Add {
+			tx.AddQuad(d.Quad)
+		} else {
+			tx.RemoveQuad(d.Quad)
+		}
+	}
+	g.s.tx = tx
+	v, err := fnc(goja.Undefined())
+	if err != nil {
+		g.s.tx = prev
+		// rethrow an exception or an interrupt
+		panic(err)
+	}
+	return v
+}
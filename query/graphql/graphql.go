@@ -2,10 +2,13 @@ package graphql
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -19,6 +22,7 @@ import (
 	"github.com/cayleygraph/cayley/graph/refs"
 	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/cayley/query/shape"
 	"github.com/cayleygraph/quad"
 )
 
@@ -62,7 +66,7 @@ func (s *Session) Execute(ctx context.Context, qu string, opt query.Options) (qu
 	default:
 		return nil, &query.ErrUnsupportedCollation{Collation: opt.Collation}
 	}
-	q, err := Parse(strings.NewReader(qu))
+	q, err := ParseWithParams(strings.NewReader(qu), opt.Params)
 	if err != nil {
 		return nil, err
 	}
@@ -115,9 +119,36 @@ var (
 	ValueKey = "id"
 	LimitKey = "first"
 	SkipKey  = "offset"
+	AfterKey = "after"
+	OrderKey = "order"
 	AnyKey   = "*"
 )
 
+// Field names of Relay connections.
+const (
+	edgesKey       = "edges"
+	nodeKey        = "node"
+	cursorKey      = "cursor"
+	pageInfoKey    = "pageInfo"
+	hasNextKey     = "hasNextPage"
+	hasPreviousKey = "hasPreviousPage"
+	startCursorKey = "startCursor"
+	endCursorKey   = "endCursor"
+)
+
+// filterSuffixes maps suffixes of argument names to comparison operators.
+var filterSuffixes = []struct {
+	Suffix string
+	Op     iterator.Operator
+}{
+	{"_gte", iterator.CompareGTE},
+	{"_gt", iterator.CompareGT},
+	{"_lte", iterator.CompareLTE},
+	{"_lt", iterator.CompareLT},
+}
+
+const regexSuffix = "_regex"
+
 type Query struct {
 	fields []field
 }
@@ -129,6 +160,22 @@ type has struct {
 	Labels []quad.Value
 }
 
+type filter struct {
+	Via     quad.IRI
+	Rev     bool
+	Labels  []quad.Value
+	Filters []shape.ValueFilter
+}
+
+// connection describes which fields of a Relay connection were requested.
+type connection struct {
+	Edges    string  // alias of the edges list; empty if not requested
+	Cursor   string  // alias of the cursor of an edge
+	Node     string  // alias of the node of an edge
+	PageInfo string  // alias of the page info object; empty if not requested
+	Page     []field // requested fields of the page info
+}
+
 type field struct {
 	Via       quad.IRI
 	Alias     string
@@ -136,12 +183,17 @@ type field struct {
 	Opt       bool
 	Labels    []quad.Value
 	Has       []has
+	Filters   []filter
 	Fields    []field
 	AllFields bool // fetch all fields
 	UnNest    bool // all fields will be saved to parent object
+	Order     []path.OrderKey
+	Conn      *connection // field is a connection; Fields describe its nodes
 }
 
-func (f field) isSave() bool { return len(f.Has)+len(f.Fields) == 0 && !f.AllFields }
+func (f field) isSave() bool {
+	return len(f.Has)+len(f.Filters)+len(f.Fields) == 0 && !f.AllFields && f.Conn == nil
+}
 
 type object struct {
 	id     graph.Ref
@@ -162,6 +214,7 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 	var (
 		limit = -1
 		skip  = 0
+		after *cursor
 	)
 
 	for _, h := range f.Has {
@@ -184,6 +237,19 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 					skip = 0
 				}
 			}
+		case quad.IRI(AfterKey): // cursor of the last node of the previous page
+			if len(h.Values) != 1 {
+				return nil, fmt.Errorf("unexpected arguments: %v (%d)", h.Values, len(h.Values))
+			}
+			s, ok := h.Values[0].(quad.String)
+			if !ok {
+				return nil, fmt.Errorf("unexpected value type for %v: %T", string(h.Via), h.Values[0])
+			}
+			c, err := parseCursor(string(s))
+			if err != nil {
+				return nil, err
+			}
+			after = c
 		default: // everything else - Has constraint
 			if len(h.Labels) != 0 {
 				p = p.LabelContext(h.Labels)
@@ -198,14 +264,54 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 			}
 		}
 	}
+	for _, flt := range f.Filters {
+		if flt.Via == quad.IRI(ValueKey) {
+			p = p.Filters(flt.Filters...)
+			continue
+		}
+		if len(flt.Labels) != 0 {
+			p = p.LabelContext(flt.Labels)
+		}
+		p = p.HasFilter(flt.Via, flt.Rev, flt.Filters...)
+		if len(flt.Labels) != 0 {
+			p = p.LabelContext()
+		}
+	}
+	fetch := limit
+	if f.Conn != nil && limit >= 0 {
+		fetch++ // one more node to check if there is a next page
+	}
+	pg := &page{limit: fetch, after: after}
 	tail := func() {
-		if skip > 0 {
-			p = p.Skip(int64(skip))
+		if len(f.Order) != 0 {
+			p = p.OrderBy(f.Order...)
+		}
+		// the page starts at the node of the cursor, which is checked and skipped while iterating
+		off := int64(skip)
+		if after != nil {
+			off += after.pos
 		}
-		if limit >= 0 {
-			p = p.Limit(int64(limit))
+		if off > 0 {
+			p = p.Skip(off)
+		}
+		if fetch >= 0 {
+			n := int64(fetch)
+			if after != nil {
+				n++
+			}
+			p = p.Limit(n)
 		}
 	}
+	var ids []graph.Ref
+	finish := func(out []map[string]interface{}) ([]map[string]interface{}, error) {
+		if after != nil && !pg.found {
+			return nil, errors.New("invalid cursor: node was not found at the position of the cursor")
+		}
+		if f.Conn == nil {
+			return out, nil
+		}
+		return []map[string]interface{}{f.Conn.result(out, ids, pg.start(), limit, after != nil || skip > 0)}, nil
+	}
 	if f.AllFields {
 		tail()
 
@@ -214,7 +320,7 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 
 		// we don't care about alternative paths to nodes here, so we will not call NextPath
 		// and we haven't tagged anything, so we will not call TagResult either
-		for i := 0; limit < 0 || i < limit; i++ {
+		for !pg.full() {
 			select {
 			case <-ctx.Done():
 				return out, ctx.Err()
@@ -224,6 +330,9 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 				break
 			}
 			nv := it.Result()
+			if !pg.accept(nv) {
+				continue
+			}
 			obj := make(map[string]interface{})
 			obj[ValueKey] = qs.NameOf(nv)
 			func() {
@@ -239,8 +348,12 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 				}
 			}()
 			out = append(out, obj)
+			ids = append(ids, nv)
 		}
-		return out, it.Err()
+		if err := it.Err(); err != nil {
+			return out, err
+		}
+		return finish(out)
 	}
 	unnest := make(map[string]bool)
 	for _, f2 := range f.Fields {
@@ -281,7 +394,7 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 	defer it.Close()
 
 	var results []object
-	for i := 0; limit < 0 || i < limit; i++ {
+	for !pg.full() {
 		select {
 		case <-ctx.Done():
 			return out, ctx.Err()
@@ -290,6 +403,9 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 		if !it.Next(ctx) {
 			break
 		}
+		if !pg.accept(it.Result()) {
+			continue
+		}
 		fields := make(map[string][]graph.Ref)
 
 		tags := make(map[string]graph.Ref)
@@ -385,8 +501,127 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 			}
 		}
 		out = append(out, obj)
+		ids = append(ids, r.id)
 	}
-	return out, nil
+	return finish(out)
+}
+
+// page selects nodes of a single page. It skips nodes up to the one with the after cursor
+// and stops when the limit is reached.
+type page struct {
+	limit   int     // -1 if not limited
+	after   *cursor // cursor of the last node of the previous page
+	checked bool    // the node at the position of the after cursor was checked
+	found   bool    // the node at the position of the after cursor matches the cursor
+	n       int
+}
+
+// start returns the position of the first node of the page.
+func (pg *page) start() int64 {
+	if pg.after == nil {
+		return 0
+	}
+	return pg.after.pos + 1
+}
+
+// full reports if the page already has enough nodes, or if the cursor doesn't match.
+func (pg *page) full() bool {
+	if pg.checked && !pg.found {
+		return true
+	}
+	return pg.limit >= 0 && pg.n >= pg.limit
+}
+
+// accept reports if a node belongs to the page. The first node is checked against
+// the after cursor, if it is set.
+func (pg *page) accept(ref graph.Ref) bool {
+	if pg.after != nil && !pg.checked {
+		pg.checked = true
+		pg.found = keyOf(ref) == pg.after.key
+		return false
+	}
+	pg.n++
+	return true
+}
+
+// cursor is a position of a node in the results, and the key of that node.
+type cursor struct {
+	pos int64
+	key string
+}
+
+func keyOf(ref graph.Ref) string {
+	return fmt.Sprint(refs.ToKey(ref))
+}
+
+// cursorOf returns an opaque cursor of a node at a given position. Position is counted
+// after skipped nodes. Cursors are built from ref keys, thus they are only valid for the same quad store.
+func cursorOf(pos int64, ref graph.Ref) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(pos, 10) + ":" + keyOf(ref)))
+}
+
+// parseCursor decodes a cursor returned by cursorOf.
+func parseCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %q", s)
+	}
+	i := strings.IndexByte(string(data), ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid cursor: %q", s)
+	}
+	pos, err := strconv.ParseInt(string(data[:i]), 10, 64)
+	if err != nil || pos < 0 {
+		return nil, fmt.Errorf("invalid cursor: %q", s)
+	}
+	return &cursor{pos: pos, key: string(data[i+1:])}, nil
+}
+
+// result wraps nodes of the page into a connection object. Nodes must include one more node
+// than the limit if there is a next page. Start is the position of the first node.
+func (c *connection) result(nodes []map[string]interface{}, ids []graph.Ref, start int64, limit int, hasPrev bool) map[string]interface{} {
+	hasNext := limit >= 0 && len(nodes) > limit
+	if hasNext {
+		nodes, ids = nodes[:limit], ids[:limit]
+	}
+	out := make(map[string]interface{})
+	if c.Edges != "" {
+		edges := make([]map[string]interface{}, 0, len(nodes))
+		for i, obj := range nodes {
+			e := make(map[string]interface{})
+			if c.Cursor != "" {
+				e[c.Cursor] = cursorOf(start+int64(i), ids[i])
+			}
+			if c.Node != "" {
+				e[c.Node] = obj
+			}
+			edges = append(edges, e)
+		}
+		out[c.Edges] = edges
+	}
+	if c.PageInfo != "" {
+		info := make(map[string]interface{}, len(c.Page))
+		for _, f := range c.Page {
+			var v interface{}
+			switch string(f.Via) {
+			case hasNextKey:
+				v = hasNext
+			case hasPreviousKey:
+				v = hasPrev
+			case startCursorKey:
+				if len(ids) != 0 {
+					v = cursorOf(start, ids[0])
+				}
+			case endCursorKey:
+				if len(ids) != 0 {
+					v = cursorOf(start+int64(len(ids)-1), ids[len(ids)-1])
+				}
+			}
+			info[f.Alias] = v
+		}
+		out[c.PageInfo] = info
+	}
+	return out
 }
 
 func (q *Query) Execute(ctx context.Context, qs graph.QuadStore) (map[string]interface{}, error) {
@@ -407,12 +642,34 @@ func (q *Query) Execute(ctx context.Context, qs graph.QuadStore) (map[string]int
 	return out, nil
 }
 
+// documents caches parsed queries. Variables are bound when a query is converted, thus documents can be shared.
+var documents = query.NewPlanCache(1024)
+
+// parseDocument parses a query, or returns a document parsed previously.
+func parseDocument(text string) (*ast.Document, error) {
+	if doc, ok := documents.Get(text); ok {
+		return doc.(*ast.Document), nil
+	}
+	doc, err := parser.Parse(parser.ParseParams{Source: text})
+	if err != nil {
+		return nil, err
+	}
+	documents.Put(text, doc)
+	return doc, nil
+}
+
 func Parse(r io.Reader) (*Query, error) {
+	return ParseWithParams(r, nil)
+}
+
+// ParseWithParams parses a query and binds its variables to values of given parameters.
+// Parameter names don't include the "$" prefix of variables.
+func ParseWithParams(r io.Reader, params map[string]quad.Value) (*Query, error) {
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	doc, err := parser.Parse(parser.ParseParams{Source: string(data)})
+	doc, err := parseDocument(string(data))
 	if err != nil {
 		return nil, err
 	}
@@ -425,7 +682,11 @@ func Parse(r io.Reader) (*Query, error) {
 	} else if def.Operation != "query" {
 		return nil, fmt.Errorf("unsupported operation: %s", def.Operation)
 	}
-	fields, all, err := setToFields(def.SelectionSet, nil)
+	vars, err := bindVariables(def.VariableDefinitions, params)
+	if err != nil {
+		return nil, err
+	}
+	fields, all, err := setToFields(def.SelectionSet, nil, vars)
 	if err != nil {
 		return nil, err
 	} else if all {
@@ -434,21 +695,46 @@ func Parse(r io.Reader) (*Query, error) {
 	return &Query{fields: fields}, nil
 }
 
-func setToFields(set *ast.SelectionSet, labels []quad.Value) (out []field, all bool, _ error) {
+// bindVariables returns values of declared variables. Default values are used for missing parameters.
+func bindVariables(defs []*ast.VariableDefinition, params map[string]quad.Value) (map[string]quad.Value, error) {
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]quad.Value, len(defs))
+	for _, d := range defs {
+		name := d.Variable.Name.Value
+		if v, ok := params[name]; ok {
+			vars[name] = v
+			continue
+		} else if d.DefaultValue == nil {
+			return nil, fmt.Errorf("variable is not set: $%s", name)
+		}
+		vals, err := convValue(d.DefaultValue, nil)
+		if err != nil {
+			return nil, err
+		} else if len(vals) != 1 {
+			return nil, fmt.Errorf("unexpected default value of $%s: %v", name, vals)
+		}
+		vars[name] = vals[0]
+	}
+	return vars, nil
+}
+
+func setToFields(set *ast.SelectionSet, labels []quad.Value, vars map[string]quad.Value) (out []field, all bool, _ error) {
 	if set == nil {
 		return
 	}
 	for _, s := range set.Selections {
 		switch sel := s.(type) {
 		case *ast.Field:
-			fld, err := convField(sel, labels)
+			fld, err := convField(sel, labels, vars)
 			if err != nil {
 				return nil, false, err
 			}
 			if fld.Via == quad.IRI(AnyKey) {
 				if len(set.Selections) != 1 {
 					return nil, false, fmt.Errorf("expand all cannot be used with other fields")
-				} else if len(fld.Has) != 0 || len(fld.Fields) != 0 {
+				} else if len(fld.Has) != 0 || len(fld.Filters) != 0 || len(fld.Fields) != 0 {
 					return nil, false, fmt.Errorf("filters inside expand all are not supported")
 				}
 				return nil, true, nil
@@ -472,11 +758,11 @@ func stringToVia(s string) (_ quad.IRI, rev bool) {
 	return quad.IRI(s), rev
 }
 
-func argsToHas(dst []has, args []*ast.Argument, rev bool, labels []quad.Value) (out []has, err error) {
+func argsToHas(dst []has, args []*ast.Argument, rev bool, labels []quad.Value, vars map[string]quad.Value) (out []has, err error) {
 	out = dst
 	for _, arg := range args {
 		var vals []quad.Value
-		vals, err = convValue(arg.Value)
+		vals, err = convValue(arg.Value, vars)
 		if err != nil {
 			return
 		}
@@ -488,7 +774,7 @@ func argsToHas(dst []has, args []*ast.Argument, rev bool, labels []quad.Value) (
 	return
 }
 
-func convField(fld *ast.Field, labels []quad.Value) (out field, err error) {
+func convField(fld *ast.Field, labels []quad.Value, vars map[string]quad.Value) (out field, err error) {
 	out.Labels = labels
 	name := fld.Name.Value
 	if fld.Alias != nil && fld.Alias.Value != "" {
@@ -511,7 +797,7 @@ func convField(fld *ast.Field, labels []quad.Value) (out field, err error) {
 			} else if a := d.Arguments[0]; a.Name == nil || a.Name.Value != "v" {
 				return out, fmt.Errorf("label directive should have 'v' argument")
 			} else {
-				vals, err := convValue(a.Value)
+				vals, err := convValue(a.Value, vars)
 				if err != nil {
 					return out, fmt.Errorf("error parsing label: %v", err)
 				}
@@ -528,7 +814,7 @@ func convField(fld *ast.Field, labels []quad.Value) (out field, err error) {
 			if len(d.Arguments) == 0 {
 				out.Rev = out.Rev != true
 			} else {
-				out.Has, err = argsToHas(out.Has, d.Arguments, true, out.Labels)
+				out.Has, err = argsToHas(out.Has, d.Arguments, true, out.Labels, vars)
 				if err != nil {
 					return
 				}
@@ -539,22 +825,235 @@ func convField(fld *ast.Field, labels []quad.Value) (out field, err error) {
 			// already processed
 		case "unnest":
 			out.UnNest = true
+		case "order":
+			key, err := convOrder(d.Arguments)
+			if err != nil {
+				return out, err
+			}
+			out.Order = append(out.Order, key)
 		default:
 			return out, fmt.Errorf("unknown directive: %q", d.Name.Value)
 		}
 	}
-	out.Fields, out.AllFields, err = setToFields(fld.SelectionSet, out.Labels)
+	out.Fields, out.AllFields, err = setToFields(fld.SelectionSet, out.Labels, vars)
 	if err != nil {
 		return
 	}
-	out.Has, err = argsToHas(out.Has, fld.Arguments, false, out.Labels)
+	if err = convConnection(&out); err != nil {
+		return
+	}
+	args := make([]*ast.Argument, 0, len(fld.Arguments))
+	for _, a := range fld.Arguments {
+		if a.Name == nil {
+			continue
+		}
+		if a.Name.Value == OrderKey {
+			keys, err := convOrderValue(a.Value)
+			if err != nil {
+				return out, err
+			}
+			out.Order = append(out.Order, keys...)
+			continue
+		}
+		flt, ok, err := convFilter(a, out.Labels, vars)
+		if err != nil {
+			return out, err
+		} else if ok {
+			out.Filters = addFilter(out.Filters, flt)
+			continue
+		}
+		args = append(args, a)
+	}
+	out.Has, err = argsToHas(out.Has, args, false, out.Labels, vars)
 	if err != nil {
 		return
 	}
 	return
 }
 
-func convValue(v ast.Value) (out []quad.Value, _ error) {
+// convConnection checks if the field is a Relay connection, and if so, replaces its fields
+// with fields of the connection node.
+//
+// A field is considered a connection if it selects only "edges" and "pageInfo".
+func convConnection(f *field) error {
+	if len(f.Fields) == 0 {
+		return nil
+	}
+	for _, f2 := range f.Fields {
+		if f2.Via != edgesKey && f2.Via != pageInfoKey {
+			return nil
+		}
+	}
+	c := &connection{}
+	var node *field
+	for i, f2 := range f.Fields {
+		if f2.AllFields {
+			return fmt.Errorf("expand all is not supported in %q of a connection", f2.Via)
+		}
+		switch f2.Via {
+		case edgesKey:
+			c.Edges = f2.Alias
+			for j, e := range f2.Fields {
+				switch e.Via {
+				case cursorKey:
+					c.Cursor = e.Alias
+				case nodeKey:
+					if e.isSave() {
+						return fmt.Errorf("%q of a connection must select fields", nodeKey)
+					}
+					c.Node = e.Alias
+					node = &f.Fields[i].Fields[j]
+				default:
+					return fmt.Errorf("unknown field of a connection edge: %q", e.Via)
+				}
+			}
+		case pageInfoKey:
+			c.PageInfo = f2.Alias
+			for _, e := range f2.Fields {
+				switch e.Via {
+				case hasNextKey, hasPreviousKey, startCursorKey, endCursorKey:
+				default:
+					return fmt.Errorf("unknown field of a connection page info: %q", e.Via)
+				}
+			}
+			c.Page = f2.Fields
+		}
+	}
+	f.Conn = c
+	f.Fields, f.AllFields = nil, false
+	if node != nil {
+		f.Fields, f.AllFields = node.Fields, node.AllFields
+	}
+	return nil
+}
+
+// convFilter converts an argument with a filter suffix (like "age_gt" or "name_regex") to a value filter.
+// It returns false if the argument is not a filter.
+func convFilter(a *ast.Argument, labels []quad.Value, vars map[string]quad.Value) (out filter, _ bool, _ error) {
+	name := a.Name.Value
+	if strings.HasSuffix(name, regexSuffix) && len(name) > len(regexSuffix) {
+		out.Via, out.Rev = stringToVia(strings.TrimSuffix(name, regexSuffix))
+		vals, err := convValue(a.Value, vars)
+		if err != nil {
+			return out, false, err
+		}
+		var pattern quad.String
+		if len(vals) == 1 {
+			pattern, _ = vals[0].(quad.String)
+		}
+		if pattern == "" {
+			return out, false, fmt.Errorf("regex filter %q expects a string, got: %v", name, vals)
+		}
+		re, err := regexp.Compile(string(pattern))
+		if err != nil {
+			return out, false, fmt.Errorf("invalid regex in %q: %v", name, err)
+		}
+		// match IRIs only when filtering node ids
+		out.Filters = []shape.ValueFilter{shape.Regexp{Re: re, Refs: out.Via == quad.IRI(ValueKey)}}
+		out.Labels = labels
+		return out, true, nil
+	}
+	for _, fs := range filterSuffixes {
+		if !strings.HasSuffix(name, fs.Suffix) || len(name) == len(fs.Suffix) {
+			continue
+		}
+		out.Via, out.Rev = stringToVia(strings.TrimSuffix(name, fs.Suffix))
+		vals, err := convValue(a.Value, vars)
+		if err != nil {
+			return out, false, err
+		} else if len(vals) != 1 {
+			return out, false, fmt.Errorf("filter %q expects a single value, got: %d", name, len(vals))
+		}
+		out.Filters = []shape.ValueFilter{shape.Comparison{Op: fs.Op, Val: vals[0]}}
+		out.Labels = labels
+		return out, true, nil
+	}
+	return out, false, nil
+}
+
+// addFilter adds a filter to the list. Filters on the same predicate are merged,
+// thus a single value must pass all of them.
+func addFilter(arr []filter, f filter) []filter {
+	for i, f2 := range arr {
+		if f2.Via == f.Via && f2.Rev == f.Rev {
+			arr[i].Filters = append(arr[i].Filters, f.Filters...)
+			return arr
+		}
+	}
+	return append(arr, f)
+}
+
+// convOrderValue converts a value of the "order" argument to sort keys.
+// The value is either an object with "by" and "desc" fields, or a list of such objects.
+func convOrderValue(v ast.Value) ([]path.OrderKey, error) {
+	switch v := v.(type) {
+	case *ast.ObjectValue:
+		args := make([]*ast.Argument, 0, len(v.Fields))
+		for _, f := range v.Fields {
+			args = append(args, &ast.Argument{Name: f.Name, Value: f.Value})
+		}
+		key, err := convOrder(args)
+		if err != nil {
+			return nil, err
+		}
+		return []path.OrderKey{key}, nil
+	case *ast.ListValue:
+		var out []path.OrderKey
+		for _, sv := range v.Values {
+			keys, err := convOrderValue(sv)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, keys...)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%s argument expects an object, got: %T", OrderKey, v)
+	}
+}
+
+// convOrder converts arguments of the "order" directive or fields of the "order" argument to a sort key.
+//
+// The "by" argument sets a predicate to order by, or ValueKey to order by node values.
+// The "desc" argument sets a descending order.
+func convOrder(args []*ast.Argument) (key path.OrderKey, _ error) {
+	for _, a := range args {
+		if a.Name == nil {
+			continue
+		}
+		switch a.Name.Value {
+		case "by":
+			var name string
+			switch v := a.Value.(type) {
+			case *ast.EnumValue:
+				name = v.Value
+			case *ast.StringValue:
+				name = v.Value
+			default:
+				return key, fmt.Errorf("order expects a predicate name, got: %T", a.Value)
+			}
+			if name == ValueKey {
+				continue
+			}
+			via, rev := stringToVia(name)
+			if rev {
+				return key, fmt.Errorf("order doesn't support reverse predicates")
+			}
+			key.Via = via
+		case "desc":
+			v, ok := a.Value.(*ast.BooleanValue)
+			if !ok {
+				return key, fmt.Errorf("order expects a boolean, got: %T", a.Value)
+			}
+			key.Desc = v.Value
+		default:
+			return key, fmt.Errorf("unknown argument of order: %q", a.Name.Value)
+		}
+	}
+	return key, nil
+}
+
+func convValue(v ast.Value, vars map[string]quad.Value) (out []quad.Value, _ error) {
 	switch v := v.(type) {
 	case *ast.EnumValue:
 		s := v.Value
@@ -575,9 +1074,16 @@ func convValue(v ast.Value) (out []quad.Value, _ error) {
 		return []quad.Value{quad.Float(pv)}, nil
 	case *ast.BooleanValue:
 		return []quad.Value{quad.Bool(v.Value)}, nil
+	case *ast.Variable:
+		name := v.Name.Value
+		val, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable: $%s", name)
+		}
+		return []quad.Value{val}, nil
 	case *ast.ListValue:
 		for _, sv := range v.Values {
-			cv, err := convValue(sv)
+			cv, err := convValue(sv, vars)
 			if err != nil {
 				return nil, err
 			} else if len(cv) != 1 {
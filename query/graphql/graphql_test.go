@@ -5,13 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/graphtest/testutil"
+	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/cayley/query/shape"
 	"github.com/cayleygraph/quad"
 	"github.com/cayleygraph/quad/voc/rdf"
 )
@@ -82,6 +87,38 @@ var casesParse = []struct {
 			},
 		}},
 	},
+	{
+		`{
+	users(age_gt: 20, age_lte: 40, name_regex: "^A", order: {by: name, desc: true}) {
+		edges {
+			cursor
+			node { name }
+		}
+		pageInfo { hasNextPage }
+	}
+}`,
+		[]field{{
+			Via: "users", Alias: "users",
+			Filters: []filter{
+				{Via: "age", Filters: []shape.ValueFilter{
+					shape.Comparison{Op: iterator.CompareGT, Val: quad.Int(20)},
+					shape.Comparison{Op: iterator.CompareLTE, Val: quad.Int(40)},
+				}},
+				{Via: "name", Filters: []shape.ValueFilter{
+					shape.Regexp{Re: regexp.MustCompile("^A")},
+				}},
+			},
+			Order: []path.OrderKey{{Via: quad.IRI("name"), Desc: true}},
+			Fields: []field{
+				{Via: "name", Alias: "name"},
+			},
+			Conn: &connection{
+				Edges: "edges", Cursor: "cursor", Node: "node",
+				PageInfo: "pageInfo",
+				Page:     []field{{Via: "hasNextPage", Alias: "hasNextPage"}},
+			},
+		}},
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -267,6 +304,95 @@ var casesExecute = []struct {
 			},
 		},
 	},
+	{
+		"order by id",
+		`{
+  nodes(follows: bob) @order(by: ` + ValueKey + `, desc: true) {
+    id
+  }
+}`,
+		M{
+			"nodes": []M{
+				{"id": quad.IRI("dani")},
+				{"id": quad.IRI("charlie")},
+				{"id": quad.IRI("alice")},
+			},
+		},
+	},
+	{
+		"order by predicate",
+		`{
+  nodes(` + LimitKey + `: 3) @order(by: status, desc: true) @order(by: ` + ValueKey + `) {
+    id
+  }
+}`,
+		M{
+			"nodes": []M{
+				{"id": quad.IRI("emily")},
+				{"id": quad.IRI("greg")},
+				{"id": quad.IRI("bob")},
+			},
+		},
+	},
+	{
+		"order argument",
+		`{
+  nodes(follows: bob, ` + OrderKey + `: {by: ` + ValueKey + `, desc: true}) {
+    id
+  }
+}`,
+		M{
+			"nodes": []M{
+				{"id": quad.IRI("dani")},
+				{"id": quad.IRI("charlie")},
+				{"id": quad.IRI("alice")},
+			},
+		},
+	},
+	{
+		"filter by regex",
+		`{
+  nodes(status_regex: "^smart", ` + OrderKey + `: {by: ` + ValueKey + `}) {
+    id
+  }
+}`,
+		M{
+			"nodes": []M{
+				{"id": quad.IRI("emily")},
+				{"id": quad.IRI("greg")},
+			},
+		},
+	},
+	{
+		"filter id by regex",
+		`{
+  nodes(id_regex: "^[ab]", ` + OrderKey + `: [{by: ` + ValueKey + `}]) {
+    id
+  }
+}`,
+		M{
+			"nodes": []M{
+				{"id": quad.IRI("alice")},
+				{"id": quad.IRI("are")},
+				{"id": quad.IRI("bob")},
+			},
+		},
+	},
+	{
+		"filter by range",
+		`{
+  nodes(status_gt: "a", status_lt: "d", ` + OrderKey + `: {by: ` + ValueKey + `}) {
+    id
+  }
+}`,
+		M{
+			"nodes": []M{
+				{"id": quad.IRI("bob")},
+				{"id": quad.IRI("dani")},
+				{"id": quad.IRI("greg")},
+			},
+		},
+	},
 	{
 		"all optional",
 		`{
@@ -307,12 +433,17 @@ func toJSON(o interface{}) string {
 	return buf2.String()
 }
 
-func TestExecute(t *testing.T) {
+func loadTestData(t testing.TB) graph.QuadStore {
 	qs := memstore.New()
 	qw := testutil.MakeWriter(t, qs, nil)
 	quads := testutil.LoadGraph(t, "../../data/testdata.nq")
 	err := qw.AddQuadSet(quads)
 	require.NoError(t, err)
+	return qs
+}
+
+func TestExecute(t *testing.T) {
+	qs := loadTestData(t)
 
 	for _, c := range casesExecute {
 		t.Run(c.name, func(t *testing.T) {
@@ -324,3 +455,108 @@ func TestExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestConnection(t *testing.T) {
+	qs := loadTestData(t)
+
+	exec := func(args string) (map[string]interface{}, error) {
+		q, err := Parse(strings.NewReader(`{
+  nodes(follows: bob, ` + LimitKey + `: 2, ` + OrderKey + `: {by: ` + ValueKey + `}` + args + `) {
+    edges {
+      cursor
+      node { id }
+    }
+    pageInfo {
+      hasNextPage
+      hasPreviousPage
+      endCursor
+    }
+  }
+}`))
+		require.NoError(t, err)
+		return q.Execute(context.Background(), qs)
+	}
+	run := func(args string) M {
+		out, err := exec(args)
+		require.NoError(t, err)
+		return out["nodes"].(M)
+	}
+	nodes := func(conn M) (out []quad.Value) {
+		for _, e := range conn["edges"].([]M) {
+			out = append(out, e["node"].(M)["id"].(quad.Value))
+		}
+		return out
+	}
+
+	page := run("")
+	require.Equal(t, []quad.Value{quad.IRI("alice"), quad.IRI("charlie")}, nodes(page))
+	info := page["pageInfo"].(M)
+	require.Equal(t, true, info["hasNextPage"])
+	require.Equal(t, false, info["hasPreviousPage"])
+	edges := page["edges"].([]M)
+	require.Equal(t, edges[1]["cursor"], info["endCursor"])
+
+	page = run(`, ` + AfterKey + `: "` + info["endCursor"].(string) + `"`)
+	require.Equal(t, []quad.Value{quad.IRI("dani")}, nodes(page))
+	info = page["pageInfo"].(M)
+	require.Equal(t, false, info["hasNextPage"])
+	require.Equal(t, true, info["hasPreviousPage"])
+	require.Equal(t, page["edges"].([]M)[0]["cursor"], info["endCursor"])
+
+	page = run(`, ` + AfterKey + `: "` + edges[0]["cursor"].(string) + `"`)
+	require.Equal(t, []quad.Value{quad.IRI("charlie"), quad.IRI("dani")}, nodes(page))
+	require.Equal(t, false, page["pageInfo"].(M)["hasNextPage"])
+
+	// cursors are relative to skipped nodes
+	page = run(`, ` + SkipKey + `: 1`)
+	require.Equal(t, []quad.Value{quad.IRI("charlie"), quad.IRI("dani")}, nodes(page))
+	page = run(`, ` + SkipKey + `: 1, ` + AfterKey + `: "` + page["edges"].([]M)[0]["cursor"].(string) + `"`)
+	require.Equal(t, []quad.Value{quad.IRI("dani")}, nodes(page))
+
+	for _, c := range []string{
+		"invalid",
+		cursorOf(0, qs.ValueOf(quad.IRI("dani"))),  // wrong node at the position
+		cursorOf(10, qs.ValueOf(quad.IRI("dani"))), // position is out of range
+	} {
+		_, err := exec(`, ` + AfterKey + `: "` + c + `"`)
+		require.Error(t, err, c)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	qs := loadTestData(t)
+
+	qu := `query ($who: ID, $status: String = "cool_person", $n: Int = 10) {
+  nodes(id: $who) {
+    follows(status: $status, ` + LimitKey + `: $n) @order(by: ` + ValueKey + `) {
+      id
+    }
+  }
+}`
+	run := func(params map[string]quad.Value) (M, error) {
+		q, err := ParseWithParams(strings.NewReader(qu), params)
+		if err != nil {
+			return nil, err
+		}
+		return q.Execute(context.Background(), qs)
+	}
+
+	out, err := run(map[string]quad.Value{"who": quad.IRI("dani")})
+	require.NoError(t, err)
+	require.Equal(t, M{"nodes": M{"follows": []M{
+		{"id": quad.IRI("bob")},
+		{"id": quad.IRI("greg")},
+	}}}, out)
+
+	out, err = run(map[string]quad.Value{
+		"who": quad.IRI("dani"), "status": quad.String("smart_person"), "n": quad.Int(1),
+	})
+	require.NoError(t, err)
+	require.Equal(t, M{"nodes": M{"follows": M{"id": quad.IRI("greg")}}}, out)
+
+	_, err = run(nil)
+	require.Error(t, err, "variable without a default value must be set")
+
+	_, err = ParseWithParams(strings.NewReader(`{ nodes(id: $who) { id } }`), map[string]quad.Value{"who": quad.IRI("bob")})
+	require.Error(t, err, "variables must be declared")
+}
@@ -25,7 +25,7 @@ func httpError(w query.ResponseWriter, err error) {
 }
 
 func httpQuery(ctx context.Context, qs graph.QuadStore, w query.ResponseWriter, r io.Reader) {
-	q, err := Parse(r)
+	q, err := ParseWithParams(r, query.ParamsFromContext(ctx))
 	if err != nil {
 		httpError(w, err)
 		return
@@ -0,0 +1,1109 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gremlin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/cayley/query/shape"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+)
+
+// ResultKind describes the kind of values returned by a traversal.
+type ResultKind int
+
+const (
+	ResultNodes = ResultKind(iota) // current node of each traverser
+	ResultMap                      // select with multiple labels
+	ResultPath                     // path()
+	ResultEdges                    // g.E()
+)
+
+// Plan is a compiled traversal.
+type Plan struct {
+	Result ResultKind
+	// Path to iterate. It is nil for ResultEdges.
+	Path *path.Path
+	// Predicates of edges for ResultEdges. Empty list means all edges.
+	Edges []quad.Value
+	// IDs is set if nodes must be returned as identifiers instead of vertices.
+	IDs bool
+
+	// Keys and tags of values for ResultMap.
+	Keys []string
+	Tags []string
+	// Tags and labels of each path position for ResultPath.
+	PathTags   []string
+	PathLabels [][]string
+
+	// Keys to sort results by, if the order of the path is not preserved after order().
+	Order []OrderKey
+
+	// Modifiers applied to results after the projection.
+	Dedup bool
+	Skip  int64
+	Limit int64 // negative means no limit
+	Count bool
+}
+
+// OrderKey is a key to sort results by. Values of keys are saved to tags.
+type OrderKey struct {
+	Tag  string
+	Desc bool
+}
+
+// Compile converts a traversal to a path.
+func Compile(qs graph.QuadStore, t *Traversal) (*Plan, error) {
+	c := &compiler{
+		qs:     qs,
+		plan:   &Plan{Limit: -1},
+		labels: make(map[string]bool),
+		byKeys: make(map[string]quad.IRI),
+	}
+	if err := c.compile(t); err != nil {
+		return nil, err
+	}
+	return c.plan, nil
+}
+
+const (
+	pathTagPrefix  = "__path"
+	byTagPrefix    = "__by_"
+	orderTagPrefix = "__order"
+)
+
+// scope describes where steps are compiled.
+type scope int
+
+const (
+	rootScope = scope(iota) // root traversal
+	loopScope               // body of repeat() unrolled into the root traversal
+	anonScope               // anonymous traversal compiled to a morphism
+)
+
+type compiler struct {
+	qs   graph.QuadStore
+	plan *Plan
+
+	labels    map[string]bool     // labels set with as()
+	byKeys    map[string]quad.IRI // labels that are projected with select(...).by(key)
+	track     bool                // path() is used; positions of traversers are tagged
+	projected bool                // only modifiers of results are allowed after projection steps
+	edges     bool                // results are edges of g.E()
+	sorted    bool                // the path is sorted by order() and the order wasn't changed by other steps
+	norder    int                 // number of order keys
+}
+
+func (c *compiler) compile(t *Traversal) error {
+	if len(t.Steps) == 0 {
+		return fmt.Errorf("traversal must start with V() or E()")
+	}
+	for i, s := range t.Steps {
+		switch s.Name {
+		case "path":
+			c.track = true
+		case "select":
+			if err := c.scanSelect(s, t.Steps[i+1:]); err != nil {
+				return err
+			}
+		}
+	}
+	first := t.Steps[0]
+	var p *path.Path
+	switch first.Name {
+	case "V":
+		ids, err := nodeValues(first.Args)
+		if err != nil {
+			return err
+		}
+		p = c.position(path.StartPath(c.qs, ids...))
+	case "E":
+		if len(first.Args) != 0 {
+			return fmt.Errorf("E() with identifiers is not supported")
+		}
+		c.edges = true
+		c.plan.Result = ResultEdges
+	default:
+		return fmt.Errorf("traversal must start with V() or E(), got %v", first)
+	}
+	p, err := c.steps(p, t.Steps[1:], rootScope)
+	if err != nil {
+		return err
+	}
+	if c.sorted || c.plan.Count {
+		// order of the path is preserved
+		c.plan.Order = nil
+	}
+	c.plan.Path = p
+	return nil
+}
+
+// scanSelect records properties of by() modulators for select with multiple labels.
+// Properties are saved at the position of the label, thus it must be known before compiling as() steps.
+func (c *compiler) scanSelect(s *Step, next []*Step) error {
+	if len(s.Args) < 2 {
+		return nil
+	}
+	keys, err := stringArgs(s)
+	if err != nil {
+		return err
+	}
+	nby := 0
+	for nby < len(next) && next[nby].Name == "by" {
+		nby++
+	}
+	for i, by := range next[:nby] {
+		if len(by.Args) == 0 {
+			continue
+		}
+		key, ok := by.Args[0].(string)
+		if len(by.Args) != 1 || !ok {
+			return fmt.Errorf("only property names are supported in by() of select: %v", by)
+		}
+		// by() modulators are applied to labels in a round-robin fashion
+		for j := i; j < len(keys); j += nby {
+			if _, ok := c.byKeys[keys[j]]; ok {
+				return fmt.Errorf("label %q is projected more than once", keys[j])
+			}
+			c.byKeys[keys[j]] = iri(key)
+		}
+	}
+	return nil
+}
+
+// position tags the current position of traversers if path() is used.
+func (c *compiler) position(p *path.Path) *path.Path {
+	if !c.track {
+		return p
+	}
+	tag := pathTagPrefix + strconv.Itoa(len(c.plan.PathTags))
+	c.plan.PathTags = append(c.plan.PathTags, tag)
+	c.plan.PathLabels = append(c.plan.PathLabels, nil)
+	return p.Tag(tag)
+}
+
+// morphism compiles an anonymous traversal to a path that starts from any node.
+func (c *compiler) morphism(t *Traversal) (*path.Path, error) {
+	return c.steps(path.StartMorphism(), t.Steps, anonScope)
+}
+
+// filter compiles an anonymous traversal to a path that matches all nodes for which the traversal returns results.
+// Each node is matched once, regardless of the number of results of the traversal.
+func (c *compiler) filter(arg interface{}) (*path.Path, error) {
+	t, ok := arg.(*Traversal)
+	if !ok {
+		return nil, fmt.Errorf("expected a traversal, got: %v", argString(arg))
+	}
+	m, err := c.morphism(t)
+	if err != nil {
+		return nil, err
+	}
+	return m.Reverse().Unique(), nil
+}
+
+// exists keeps traversers at nodes that match the condition. Filters must not multiply traversers,
+// for example for each value of a property, thus the condition is only checked for existence.
+func exists(p, cond *path.Path) *path.Path {
+	return p.And(cond.Unique())
+}
+
+// steps applies a list of steps to the path. Anonymous traversals only support steps that move or filter traversers.
+func (c *compiler) steps(p *path.Path, steps []*Step, sc scope) (*path.Path, error) {
+	anon := sc != rootScope
+	for i := 0; i < len(steps); i++ {
+		s := steps[i]
+		if c.edges && !anon {
+			var err error
+			p, err = c.edgeStep(s)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if c.projected && !anon {
+			if err := c.modifier(s); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var (
+			err  error
+			move bool // step moves traversers to other nodes
+			ids  bool
+			root bool
+		)
+		switch s.Name {
+		case "out", "in", "both":
+			var via []quad.Value
+			if via, err = keyArgs(s); err != nil {
+				return nil, err
+			}
+			p, move = traverse(p, s.Name, via), true
+		case "outE", "inE", "bothE":
+			// edges are not materialized, but an edge step followed by a vertex step is a plain traversal
+			var via []quad.Value
+			if via, err = keyArgs(s); err != nil {
+				return nil, err
+			}
+			vertex := map[string]string{"outE": "inV", "inE": "outV", "bothE": "otherV"}[s.Name]
+			if i+1 >= len(steps) || steps[i+1].Name != vertex {
+				return nil, fmt.Errorf("%s() must be followed by %s()", s.Name, vertex)
+			}
+			i++
+			p, move = traverse(p, strings.TrimSuffix(s.Name, "E"), via), true
+		case "values":
+			var via []quad.Value
+			if via, err = keyArgs(s); err != nil {
+				return nil, err
+			}
+			p, move = traverse(p, "out", via), true
+		case "label":
+			// types are returned as identifiers, since they are not vertices in Gremlin
+			p, move, ids = p.Out(rdfType), true, true
+		case "id":
+			ids = true
+		case "has":
+			p, err = c.has(p, s)
+		case "hasLabel":
+			p, err = hasValues(p, rdfType, s.Args, true)
+		case "hasId":
+			p, err = isValues(p, s.Args, true)
+		case "hasNot":
+			var keys []quad.Value
+			if keys, err = keyArgs(s); err == nil && len(keys) != 1 {
+				err = fmt.Errorf("hasNot() expects a single property name")
+			}
+			if err == nil {
+				p = p.Except(path.StartMorphism().Has(keys[0]))
+			}
+		case "is":
+			p, err = isValues(p, s.Args, false)
+		case "where", "and":
+			if s.Name == "where" && len(s.Args) == 1 {
+				if _, ok := s.Args[0].(*Predicate); ok {
+					return nil, fmt.Errorf("where() with predicates is not supported: %v", s)
+				}
+			}
+			for _, a := range s.Args {
+				var f *path.Path
+				if f, err = c.filter(a); err != nil {
+					return nil, err
+				}
+				p = p.And(f)
+			}
+		case "or":
+			var u *path.Path
+			for _, a := range s.Args {
+				f, err := c.filter(a)
+				if err != nil {
+					return nil, err
+				}
+				if u == nil {
+					u = f
+				} else {
+					u = u.Or(f)
+				}
+			}
+			if u == nil {
+				return nil, fmt.Errorf("or() expects at least one traversal")
+			}
+			p = exists(p, u)
+		case "not":
+			if len(s.Args) != 1 {
+				return nil, fmt.Errorf("not() expects a single traversal")
+			}
+			var f *path.Path
+			if f, err = c.filter(s.Args[0]); err == nil {
+				p = p.Except(f)
+			}
+		case "repeat", "times", "until", "emit":
+			var n int
+			p, n, err = c.repeat(p, steps[i:], sc)
+			i += n - 1
+		case "dedup":
+			if len(s.Args) != 0 {
+				return nil, fmt.Errorf("dedup() with arguments is not supported")
+			}
+			p = p.Unique()
+		case "simplePath", "identity", "barrier":
+			// traversals are already acyclic and there is no bulking
+		default:
+			if anon {
+				return nil, fmt.Errorf("step %s() is not supported in anonymous traversals", s.Name)
+			}
+			var n int
+			p, n, err = c.rootStep(p, steps[i:])
+			i += n - 1
+			root = true
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sc != anonScope && move {
+			p = c.position(p)
+		}
+		if !anon {
+			if !root && s.Name != "dedup" {
+				c.sorted = false
+			}
+			if move || ids || s.Name == "repeat" {
+				c.plan.IDs = ids
+			}
+		}
+	}
+	return p, nil
+}
+
+// rootStep applies a step that is only allowed in the root traversal and returns the number of consumed steps.
+func (c *compiler) rootStep(p *path.Path, steps []*Step) (*path.Path, int, error) {
+	s := steps[0]
+	switch s.Name {
+	case "as":
+		labels, err := stringArgs(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(labels) == 0 {
+			return nil, 0, fmt.Errorf("as() expects at least one label")
+		}
+		p = p.Tag(labels...)
+		for _, l := range labels {
+			c.labels[l] = true
+			if c.track {
+				n := len(c.plan.PathLabels) - 1
+				c.plan.PathLabels[n] = append(c.plan.PathLabels[n], l)
+			}
+			if key, ok := c.byKeys[l]; ok {
+				p = p.SaveOptional(key, byTagPrefix+l)
+			}
+		}
+		return p, 1, nil
+	case "select":
+		labels, err := stringArgs(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, l := range labels {
+			if !c.labels[l] {
+				return nil, 0, fmt.Errorf("unknown label %q in %v", l, s)
+			}
+		}
+		n := 1
+		for n < len(steps) && steps[n].Name == "by" {
+			n++
+		}
+		switch len(labels) {
+		case 0:
+			return nil, 0, fmt.Errorf("select() expects at least one label")
+		case 1:
+			p = p.Back(labels[0])
+			if n > 2 {
+				return nil, 0, fmt.Errorf("select() with a single label expects at most one by()")
+			} else if n == 2 && len(steps[1].Args) != 0 {
+				key, ok := steps[1].Args[0].(string)
+				if len(steps[1].Args) != 1 || !ok {
+					return nil, 0, fmt.Errorf("only property names are supported in by() of select: %v", steps[1])
+				}
+				p = p.Out(iri(key))
+			}
+			c.plan.IDs = false
+			c.sorted = false
+			return c.position(p), n, nil
+		}
+		c.plan.Result = ResultMap
+		c.plan.Keys = labels
+		for _, l := range labels {
+			tag := l
+			if _, ok := c.byKeys[l]; ok {
+				tag = byTagPrefix + l
+			}
+			c.plan.Tags = append(c.plan.Tags, tag)
+		}
+		c.projected = true
+		return p, n, nil
+	case "path":
+		if len(s.Args) != 0 {
+			return nil, 0, fmt.Errorf("path() doesn't accept arguments")
+		}
+		c.plan.Result = ResultPath
+		c.projected = true
+		return p, 1, nil
+	case "count":
+		if len(s.Args) != 0 {
+			return nil, 0, fmt.Errorf("count() with arguments is not supported")
+		}
+		c.plan.Count = true
+		c.projected = true
+		return p, 1, nil
+	case "order":
+		if len(s.Args) != 0 {
+			return nil, 0, fmt.Errorf("order() with arguments is not supported")
+		}
+		var keys []path.OrderKey
+		n := 1
+		for ; n < len(steps) && steps[n].Name == "by"; n++ {
+			k, err := orderKey(steps[n])
+			if err != nil {
+				return nil, 0, err
+			}
+			keys = append(keys, k)
+		}
+		if len(keys) == 0 {
+			keys = append(keys, path.OrderKey{})
+		}
+		// keys are also saved to sort results again if the following steps change the order
+		c.plan.Order = c.plan.Order[:0]
+		for _, k := range keys {
+			tag := orderTagPrefix + strconv.Itoa(c.norder)
+			c.norder++
+			if k.Via != nil {
+				p = p.SaveOptional(k.Via, tag)
+			} else {
+				p = p.Tag(tag)
+			}
+			c.plan.Order = append(c.plan.Order, OrderKey{Tag: tag, Desc: k.Desc})
+		}
+		c.sorted = true
+		return p.OrderBy(keys...), n, nil
+	case "limit", "skip", "range":
+		skip, limit, err := rangeArgs(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(c.plan.Order) != 0 && !c.sorted {
+			// results must be sorted first
+			c.window(skip, limit)
+			c.projected = true
+			return p, 1, nil
+		}
+		if skip > 0 {
+			p = p.Skip(skip)
+		}
+		if limit == 0 {
+			// zero limit of a path means no limit
+			c.window(0, 0)
+		} else if limit > 0 {
+			p = p.Limit(limit)
+		}
+		return p, 1, nil
+	case "by":
+		return nil, 0, fmt.Errorf("by() must follow order() or select()")
+	}
+	return nil, 0, fmt.Errorf("unsupported step: %s()", s.Name)
+}
+
+// modifier applies a step that follows the projection of results.
+func (c *compiler) modifier(s *Step) error {
+	if c.plan.Count {
+		return fmt.Errorf("step %s() is not supported after count()", s.Name)
+	}
+	switch s.Name {
+	case "dedup":
+		if len(s.Args) != 0 {
+			return fmt.Errorf("dedup() with arguments is not supported")
+		}
+		if c.plan.Skip > 0 || c.plan.Limit >= 0 {
+			return fmt.Errorf("dedup() can't follow range steps after %s", c.projection())
+		}
+		c.plan.Dedup = true
+		return nil
+	case "limit", "skip", "range":
+		skip, limit, err := rangeArgs(s)
+		if err != nil {
+			return err
+		}
+		c.window(skip, limit)
+		return nil
+	case "count":
+		c.plan.Count = true
+		return nil
+	}
+	return fmt.Errorf("step %s() is not supported after %s", s.Name, c.projection())
+}
+
+// window narrows the range of results returned after the projection.
+func (c *compiler) window(skip, limit int64) {
+	c.plan.Skip += skip
+	if c.plan.Limit >= 0 {
+		// only the rest of the previous window is left
+		rest := c.plan.Limit - skip
+		if rest < 0 {
+			rest = 0
+		}
+		if limit < 0 || rest < limit {
+			limit = rest
+		}
+	}
+	c.plan.Limit = limit
+}
+
+func (c *compiler) projection() string {
+	switch {
+	case c.plan.Result == ResultPath:
+		return "path()"
+	case c.plan.Result == ResultMap:
+		return "select()"
+	}
+	return "E()"
+}
+
+// edgeStep applies a step to edges returned by g.E().
+func (c *compiler) edgeStep(s *Step) (*path.Path, error) {
+	switch s.Name {
+	case "hasLabel":
+		if c.projected {
+			break
+		}
+		for _, a := range s.Args {
+			l, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("only edge labels are supported in hasLabel() after E(): %v", s)
+			}
+			c.plan.Edges = append(c.plan.Edges, iri(l))
+		}
+		return nil, nil
+	case "outV", "inV", "bothV":
+		if c.projected {
+			break
+		}
+		// vertices of all edges can be found without listing edges
+		dir := map[string]string{"outV": "in", "inV": "out", "bothV": "both"}[s.Name]
+		c.edges = false
+		c.plan.Result = ResultNodes
+		return c.position(traverse(path.StartPath(c.qs), dir, c.plan.Edges)), nil
+	}
+	c.projected = true
+	return nil, c.modifier(s)
+}
+
+// repeat applies a repeat() step together with its modulators and returns the number of consumed steps.
+//
+// Loops with times() are unrolled. Loops with until() or emit() are compiled to FollowRecursive,
+// so nodes are visited only once and positions of traversers inside the loop are not tracked.
+func (c *compiler) repeat(p *path.Path, steps []*Step, sc scope) (*path.Path, int, error) {
+	var (
+		body, until, cond *Traversal
+		times             int64
+		emit              bool
+		n                 int
+	)
+loop:
+	for ; n < len(steps); n++ {
+		s := steps[n]
+		switch s.Name {
+		case "repeat":
+			if body != nil {
+				break loop
+			}
+			t, err := traversalArg(s)
+			if err != nil {
+				return nil, 0, err
+			}
+			body = t
+		case "times":
+			v, ok := intArg(s)
+			if !ok || v <= 0 {
+				return nil, 0, fmt.Errorf("times() expects a positive number")
+			}
+			times = v
+		case "until", "emit":
+			if body == nil {
+				return nil, 0, fmt.Errorf("%s() before repeat() is not supported", s.Name)
+			}
+			if s.Name == "emit" {
+				emit = true
+				if len(s.Args) == 0 {
+					continue
+				}
+			}
+			t, err := traversalArg(s)
+			if err != nil {
+				return nil, 0, err
+			}
+			if s.Name == "emit" {
+				cond = t
+			} else {
+				until = t
+			}
+		default:
+			break loop
+		}
+	}
+	if body == nil {
+		return nil, 0, fmt.Errorf("%s() must be used with repeat()", steps[0].Name)
+	}
+	if until == nil && !emit {
+		if times <= 0 {
+			return nil, 0, fmt.Errorf("repeat() must be used with times(), until() or emit()")
+		}
+		inner := loopScope
+		if sc == anonScope {
+			inner = anonScope
+		}
+		for i := int64(0); i < times; i++ {
+			var err error
+			if p, err = c.steps(p, body.Steps, inner); err != nil {
+				return nil, 0, err
+			}
+		}
+		return p, n, nil
+	}
+	if c.track && sc != anonScope {
+		return nil, 0, fmt.Errorf("path() is not supported after repeat() with until() or emit()")
+	}
+	m, err := c.morphism(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	depth := -1
+	if times > 0 {
+		depth = int(times)
+	}
+	if until == nil {
+		p = p.FollowRecursive(m, depth, nil)
+		if cond != nil {
+			f, err := c.filter(cond)
+			if err != nil {
+				return nil, 0, err
+			}
+			p = p.And(f)
+		}
+		return p, n, nil
+	}
+	if sc == anonScope {
+		return nil, 0, fmt.Errorf("until() is not supported in anonymous traversals")
+	}
+	stop, err := c.filter(until)
+	if err != nil {
+		return nil, 0, err
+	}
+	// the body is applied at least once, then traversers only continue from nodes that don't satisfy until()
+	p = p.Follow(m)
+	if depth != 1 {
+		if depth > 0 {
+			depth--
+		}
+		p = p.Or(p.FollowRecursive(path.StartMorphism().Except(stop).Follow(m), depth, nil)).Unique()
+	}
+	if !emit {
+		return p.And(stop), n, nil
+	} else if cond != nil {
+		f, err := c.filter(cond)
+		if err != nil {
+			return nil, 0, err
+		}
+		// traversers that stopped at until() are emitted as well
+		p = exists(p, f.Or(stop))
+	}
+	return p, n, nil
+}
+
+// traverse follows links with given predicates in a direction named by the step.
+func traverse(p *path.Path, dir string, via []quad.Value) *path.Path {
+	var args []interface{}
+	if len(via) != 0 {
+		args = append(args, via)
+	}
+	switch dir {
+	case "in":
+		return p.In(args...)
+	case "both":
+		return p.Both(args...)
+	}
+	return p.Out(args...)
+}
+
+func (c *compiler) has(p *path.Path, s *Step) (*path.Path, error) {
+	args := s.Args
+	if len(args) == 3 {
+		// has(label, key, value)
+		var err error
+		if p, err = hasValues(p, rdfType, args[:1], true); err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("has() expects one to three arguments: %v", s)
+	}
+	switch key := args[0].(type) {
+	case Token:
+		if len(args) != 2 {
+			return nil, fmt.Errorf("has() expects a value for %s", key)
+		}
+		switch key {
+		case "id":
+			return isValues(p, args[1:], true)
+		case "label":
+			return hasValues(p, rdfType, args[1:], true)
+		}
+		return nil, fmt.Errorf("unsupported key: %s", key)
+	case string:
+		if len(args) == 1 {
+			return exists(p, path.StartMorphism().Has(iri(key))), nil
+		}
+		return hasValues(p, iri(key), args[1:], false)
+	}
+	return nil, fmt.Errorf("has() expects a property name, got: %v", argString(args[0]))
+}
+
+// cond is a condition on a value parsed from values or a predicate.
+type cond struct {
+	vals    []quad.Value
+	filters []shape.ValueFilter
+	neg     bool
+}
+
+// hasValues filters nodes that have a property that matches given values or a predicate.
+func hasValues(p *path.Path, pred quad.Value, args []interface{}, id bool) (*path.Path, error) {
+	c, err := parseCond(args, id)
+	if err != nil {
+		return nil, err
+	}
+	m := path.StartMorphism()
+	if c.vals != nil {
+		m = m.Has(pred, c.vals...)
+	} else {
+		m = m.HasFilter(pred, false, c.filters...)
+	}
+	if c.neg {
+		m = path.StartMorphism().Has(pred).Except(m)
+	}
+	return exists(p, m), nil
+}
+
+// isValues filters nodes that match given values or a predicate.
+func isValues(p *path.Path, args []interface{}, id bool) (*path.Path, error) {
+	c, err := parseCond(args, id)
+	if err != nil {
+		return nil, err
+	}
+	m := p
+	if c.neg {
+		m = path.StartMorphism()
+	}
+	if c.vals != nil {
+		m = m.Is(c.vals...)
+	} else {
+		m = m.Filters(c.filters...)
+	}
+	if c.neg {
+		return p.Except(m), nil
+	}
+	return m, nil
+}
+
+var compareOperators = map[string]iterator.Operator{
+	"lt":  iterator.CompareLT,
+	"lte": iterator.CompareLTE,
+	"gt":  iterator.CompareGT,
+	"gte": iterator.CompareGTE,
+}
+
+// parseCond parses a list of values or a single predicate.
+// If id is set, strings are interpreted as node identifiers, otherwise they match both strings and IRIs.
+func parseCond(args []interface{}, id bool) (*cond, error) {
+	values := propValues
+	if id {
+		values = nodeValues
+	}
+	if len(args) != 1 {
+		vals, err := values(args)
+		if err != nil {
+			return nil, err
+		} else if len(vals) == 0 {
+			return nil, fmt.Errorf("expected a value or a predicate")
+		}
+		return &cond{vals: vals}, nil
+	}
+	pr, ok := args[0].(*Predicate)
+	if !ok {
+		vals, err := values(args)
+		if err != nil {
+			return nil, err
+		}
+		return &cond{vals: vals}, nil
+	}
+	switch pr.Name {
+	case "eq", "neq", "within", "without":
+		if (pr.Name == "eq" || pr.Name == "neq") && len(pr.Args) != 1 {
+			return nil, fmt.Errorf("%s() expects a single value", pr.Name)
+		}
+		vals, err := values(pr.Args)
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("%s() expects at least one value", pr.Name)
+		}
+		return &cond{vals: vals, neg: pr.Name == "neq" || pr.Name == "without"}, nil
+	case "lt", "lte", "gt", "gte":
+		if len(pr.Args) != 1 {
+			return nil, fmt.Errorf("%s() expects a single value", pr.Name)
+		}
+		v, err := value(pr.Args[0], id)
+		if err != nil {
+			return nil, err
+		}
+		return &cond{filters: []shape.ValueFilter{
+			shape.Comparison{Op: compareOperators[pr.Name], Val: v},
+		}}, nil
+	case "inside", "outside", "between":
+		if len(pr.Args) != 2 {
+			return nil, fmt.Errorf("%s() expects two values", pr.Name)
+		}
+		lo, err := value(pr.Args[0], id)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := value(pr.Args[1], id)
+		if err != nil {
+			return nil, err
+		}
+		ops := map[string][2]iterator.Operator{
+			"inside":  {iterator.CompareGT, iterator.CompareLT},
+			"between": {iterator.CompareGTE, iterator.CompareLT},
+			// outside is the negation of an inclusive range
+			"outside": {iterator.CompareGTE, iterator.CompareLTE},
+		}[pr.Name]
+		return &cond{filters: []shape.ValueFilter{
+			shape.Comparison{Op: ops[0], Val: lo},
+			shape.Comparison{Op: ops[1], Val: hi},
+		}, neg: pr.Name == "outside"}, nil
+	}
+	if len(pr.Args) != 1 {
+		return nil, fmt.Errorf("%s() expects a single string", pr.Name)
+	}
+	s, ok := pr.Args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s() expects a string, got: %v", pr.Name, argString(pr.Args[0]))
+	}
+	neg := strings.HasPrefix(pr.Name, "not")
+	f, err := textFilter(strings.TrimPrefix(pr.Name, "not"), s)
+	if err != nil {
+		return nil, err
+	}
+	return &cond{filters: []shape.ValueFilter{f}, neg: neg}, nil
+}
+
+// textFilter returns a filter for text predicates.
+func textFilter(name, s string) (shape.ValueFilter, error) {
+	name = strings.ToLower(name[:1]) + name[1:]
+	if name == "regex" {
+		// Gremlin regular expressions match any part of the string
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		return shape.Regexp{Re: re, Refs: true}, nil
+	}
+	if !strings.ContainsAny(s, "%?") {
+		switch name {
+		case "startingWith":
+			return shape.Wildcard{Pattern: s + "%"}, nil
+		case "endingWith":
+			return shape.Wildcard{Pattern: "%" + s}, nil
+		case "containing":
+			return shape.Wildcard{Pattern: "%" + s + "%"}, nil
+		}
+	}
+	pattern := regexp.QuoteMeta(s)
+	switch name {
+	case "startingWith":
+		pattern = "^" + pattern
+	case "endingWith":
+		pattern += "$"
+	case "containing":
+	default:
+		return nil, fmt.Errorf("unsupported predicate: %s", name)
+	}
+	return shape.Regexp{Re: regexp.MustCompile(pattern), Refs: true}, nil
+}
+
+// orderKey parses a by() modulator of order().
+func orderKey(s *Step) (path.OrderKey, error) {
+	var k path.OrderKey
+	args := s.Args
+	if len(args) != 0 {
+		switch a := args[0].(type) {
+		case string:
+			k.Via = iri(a)
+			args = args[1:]
+		case Token:
+			if a == "id" {
+				args = args[1:]
+			}
+		}
+	}
+	if len(args) > 1 {
+		return k, fmt.Errorf("unsupported modulator: %v", s)
+	} else if len(args) == 1 {
+		switch args[0] {
+		case Token("asc"), Token("incr"):
+		case Token("desc"), Token("decr"):
+			k.Desc = true
+		default:
+			return k, fmt.Errorf("unsupported order: %v", argString(args[0]))
+		}
+	}
+	return k, nil
+}
+
+// rangeArgs returns the number of results to skip and the limit (-1 if unbounded) for range steps.
+func rangeArgs(s *Step) (skip, limit int64, err error) {
+	var nums []int64
+	for _, a := range s.Args {
+		v, ok := a.(int64)
+		if !ok {
+			return 0, 0, fmt.Errorf("%s() expects integers: %v", s.Name, s)
+		}
+		nums = append(nums, v)
+	}
+	switch {
+	case s.Name == "limit" && len(nums) == 1 && nums[0] >= 0:
+		return 0, nums[0], nil
+	case s.Name == "skip" && len(nums) == 1 && nums[0] >= 0:
+		return nums[0], -1, nil
+	case s.Name == "range" && len(nums) == 2 && nums[0] >= 0:
+		if nums[1] < 0 {
+			return nums[0], -1, nil
+		} else if nums[1] < nums[0] {
+			return 0, 0, fmt.Errorf("invalid range: %v", s)
+		}
+		return nums[0], nums[1] - nums[0], nil
+	}
+	return 0, 0, fmt.Errorf("invalid arguments: %v", s)
+}
+
+func traversalArg(s *Step) (*Traversal, error) {
+	if len(s.Args) == 1 {
+		if t, ok := s.Args[0].(*Traversal); ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s() expects a single traversal", s.Name)
+}
+
+func intArg(s *Step) (int64, bool) {
+	if len(s.Args) != 1 {
+		return 0, false
+	}
+	v, ok := s.Args[0].(int64)
+	return v, ok
+}
+
+func stringArgs(s *Step) ([]string, error) {
+	out := make([]string, 0, len(s.Args))
+	for _, a := range s.Args {
+		str, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects strings, got: %v", s.Name, argString(a))
+		}
+		out = append(out, str)
+	}
+	return out, nil
+}
+
+// keyArgs returns property names or edge labels passed to a step.
+func keyArgs(s *Step) ([]quad.Value, error) {
+	keys, err := stringArgs(s)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]quad.Value, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, iri(k))
+	}
+	return out, nil
+}
+
+// flatten expands lists in arguments.
+func flatten(args []interface{}) []interface{} {
+	var out []interface{}
+	for _, a := range args {
+		if l, ok := a.([]interface{}); ok {
+			out = append(out, flatten(l)...)
+		} else {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// propValues converts arguments to property values. Strings match both string values and IRIs.
+func propValues(args []interface{}) ([]quad.Value, error) {
+	var out []quad.Value
+	for _, a := range flatten(args) {
+		v, err := value(a, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if s, ok := a.(string); ok {
+			out = append(out, iri(s))
+		}
+	}
+	return out, nil
+}
+
+// nodeValues converts arguments to node identifiers.
+func nodeValues(args []interface{}) ([]quad.Value, error) {
+	var out []quad.Value
+	for _, a := range flatten(args) {
+		v, err := value(a, true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// value converts an argument to a value. If node is set, strings are parsed as node identifiers.
+func value(a interface{}, node bool) (quad.Value, error) {
+	switch v := a.(type) {
+	case string:
+		if !node {
+			return quad.String(v), nil
+		}
+		if strings.HasPrefix(v, "<") || strings.HasPrefix(v, `"`) || strings.HasPrefix(v, "_:") {
+			if qv := quad.StringToValue(v); qv != nil {
+				return qv, nil
+			}
+		}
+		return iri(v), nil
+	case int64:
+		return quad.Int(v), nil
+	case float64:
+		return quad.Float(v), nil
+	case bool:
+		return quad.Bool(v), nil
+	}
+	return nil, fmt.Errorf("unsupported value: %v", argString(a))
+}
+
+var rdfType = quad.IRI(rdf.Type).Full()
+
+// iri converts a label, an edge label or a property name to IRI.
+func iri(s string) quad.IRI {
+	return quad.IRI(s).Full()
+}
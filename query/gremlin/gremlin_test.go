@@ -0,0 +1,383 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gremlin
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph/graphtest/testutil"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+)
+
+var extraQuads = []quad.Quad{
+	quad.MakeIRI("alice", rdf.NS+"type", "Person", ""),
+	quad.MakeIRI("bob", rdf.NS+"type", "Person", ""),
+	quad.Make(quad.IRI("alice"), quad.IRI("name"), quad.String("Alice"), nil),
+	quad.Make(quad.IRI("bob"), quad.IRI("name"), quad.String("Bob"), nil),
+	quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+	quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Int(41), nil),
+}
+
+func vertex(id string) interface{} {
+	return map[string]interface{}{
+		"@type":  "g:Vertex",
+		"@value": map[string]interface{}{"id": id, "label": "vertex"},
+	}
+}
+
+func vertices(ids ...string) []interface{} {
+	out := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, vertex(id))
+	}
+	return out
+}
+
+func int64Value(v int64) interface{} {
+	return map[string]interface{}{"@type": "g:Int64", "@value": v}
+}
+
+var testQueries = []struct {
+	message string
+	query   string
+	ordered bool
+	expect  []interface{}
+	err     bool
+}{
+	{
+		message: "out",
+		query:   `g.V('alice').out('follows')`,
+		expect:  vertices("<bob>"),
+	},
+	{
+		message: "in with values",
+		query:   `g.V('<bob>').in('follows').values('name')`,
+		expect:  []interface{}{"Alice"},
+	},
+	{
+		message: "both",
+		query:   `g.V().has('name', 'Bob').both('follows').id()`,
+		expect:  []interface{}{"<alice>", "<charlie>", "<dani>", "<fred>"},
+	},
+	{
+		message: "edge steps",
+		query:   `g.V('bob').inE('follows').outV().id().toList()`,
+		expect:  []interface{}{"<alice>", "<charlie>", "<dani>"},
+	},
+	{
+		message: "has label and order",
+		query:   `g.V().hasLabel('Person').order().by('age', desc).values('name')`,
+		ordered: true,
+		expect:  []interface{}{"Bob", "Alice"},
+	},
+	{
+		message: "has with predicates",
+		query:   `g.V().has('age', gt(35)).id()`,
+		expect:  []interface{}{"<bob>"},
+	},
+	{
+		message: "has with range and text predicates",
+		query:   `g.V().or(has('age', P.between(20, 31)), has('name', TextP.startingWith('B'))).id()`,
+		expect:  []interface{}{"<alice>", "<bob>"},
+	},
+	{
+		message: "has label key value",
+		query:   `g.V().has('Person', 'name', within('Alice', 'Carol')).id()`,
+		expect:  []interface{}{"<alice>"},
+	},
+	{
+		message: "has not",
+		query:   `g.V().hasLabel('Person').hasNot('status').id()`,
+		expect:  []interface{}{"<alice>"},
+	},
+	{
+		message: "has does not multiply traversers",
+		query:   `g.V().has('status').count()`,
+		expect:  []interface{}{int64Value(4)},
+	},
+	{
+		message: "values after has",
+		query:   `g.V().has('status', within('cool_person', 'smart_person')).values('status')`,
+		expect:  []interface{}{"cool_person", "cool_person", "cool_person", "smart_person", "smart_person"},
+	},
+	{
+		message: "has not with a multi-valued property",
+		query:   `g.V().has('status', neq('smart_person')).count()`,
+		expect:  []interface{}{int64Value(2)},
+	},
+	{
+		message: "where does not multiply traversers",
+		query:   `g.V().where(out('status')).count()`,
+		expect:  []interface{}{int64Value(4)},
+	},
+	{
+		message: "or does not multiply traversers",
+		query:   `g.V().or(has('status'), has('name')).count()`,
+		expect:  []interface{}{int64Value(5)},
+	},
+	{
+		message: "is",
+		query:   `g.V('bob').in('follows').is(neq('alice')).id()`,
+		expect:  []interface{}{"<charlie>", "<dani>"},
+	},
+	{
+		message: "where",
+		query:   `g.V().where(out('status').is('smart_person')).id()`,
+		expect:  []interface{}{"<emily>", "<greg>"},
+	},
+	{
+		message: "where not",
+		query:   `g.V('bob').out('follows').where(__.not(out('status'))).id()`,
+		expect:  []interface{}{"<fred>"},
+	},
+	{
+		message: "not",
+		query:   `g.V('emily').out('follows').in('follows').not(has('status', 'cool_person')).id()`,
+		expect:  []interface{}{"<emily>"},
+	},
+	{
+		message: "repeat times",
+		query:   `g.V('alice').repeat(out('follows')).times(2).id()`,
+		expect:  []interface{}{"<fred>"},
+	},
+	{
+		message: "repeat emit times",
+		query:   `g.V('alice').repeat(out('follows')).emit().times(2).id()`,
+		expect:  []interface{}{"<bob>", "<fred>"},
+	},
+	{
+		message: "repeat until",
+		query:   `g.V('alice').repeat(out('follows')).until(has('status', 'smart_person')).id()`,
+		expect:  []interface{}{"<greg>"},
+	},
+	{
+		message: "repeat until stops traversers",
+		query:   `g.V('alice').repeat(out('follows')).until(has('status', 'cool_person')).id()`,
+		expect:  []interface{}{"<bob>"},
+	},
+	{
+		message: "repeat emit until",
+		query:   `g.V('alice').repeat(out('follows')).emit().until(has('status', 'smart_person')).id()`,
+		expect:  []interface{}{"<bob>", "<fred>", "<greg>"},
+	},
+	{
+		message: "as and select",
+		query:   `g.V().hasLabel('Person').as('p').out('follows').as('f').select('p', 'f').by('name')`,
+		expect: []interface{}{
+			map[string]interface{}{"@type": "g:Map", "@value": []interface{}{"p", "Alice", "f", "Bob"}},
+			map[string]interface{}{"@type": "g:Map", "@value": []interface{}{"p", "Bob", "f", nil}},
+		},
+	},
+	{
+		message: "select single",
+		query:   `g.V().as('a').out('status').is('smart_person').select('a').id()`,
+		expect:  []interface{}{"<emily>", "<greg>"},
+	},
+	{
+		message: "dedup and range",
+		query:   `g.V().out('follows').dedup().order().range(1, 3).id()`,
+		ordered: true,
+		expect:  []interface{}{"<dani>", "<fred>"},
+	},
+	{
+		message: "order before limit",
+		query:   `g.V().hasLabel('Person').order().by('name', Order.desc).limit(1).values('age')`,
+		expect:  []interface{}{int64Value(41)},
+	},
+	{
+		message: "limit after order and other steps",
+		query:   `g.V().hasLabel('Person').order().by('age').values('name').limit(1)`,
+		expect:  []interface{}{"Alice"},
+	},
+	{
+		message: "count",
+		query:   `g.V().out('follows').count()`,
+		expect:  []interface{}{int64Value(8)},
+	},
+	{
+		message: "count after dedup",
+		query:   `g.V().out('follows').dedup().count()`,
+		expect:  []interface{}{int64Value(4)},
+	},
+	{
+		message: "path",
+		query:   `g.V('alice').as('a').out('follows').out('follows').path()`,
+		expect: []interface{}{map[string]interface{}{
+			"@type": "g:Path",
+			"@value": map[string]interface{}{
+				"labels": map[string]interface{}{"@type": "g:List", "@value": []interface{}{
+					map[string]interface{}{"@type": "g:Set", "@value": []interface{}{"a"}},
+					map[string]interface{}{"@type": "g:Set", "@value": []interface{}{}},
+					map[string]interface{}{"@type": "g:Set", "@value": []interface{}{}},
+				}},
+				"objects": map[string]interface{}{"@type": "g:List", "@value": vertices("<alice>", "<bob>", "<fred>")},
+			},
+		}},
+	},
+	{
+		message: "path after repeat times",
+		query:   `g.V('alice').repeat(out('follows')).times(2).path()`,
+		expect: []interface{}{map[string]interface{}{
+			"@type": "g:Path",
+			"@value": map[string]interface{}{
+				"labels": map[string]interface{}{"@type": "g:List", "@value": []interface{}{
+					map[string]interface{}{"@type": "g:Set", "@value": []interface{}{}},
+					map[string]interface{}{"@type": "g:Set", "@value": []interface{}{}},
+					map[string]interface{}{"@type": "g:Set", "@value": []interface{}{}},
+				}},
+				"objects": map[string]interface{}{"@type": "g:List", "@value": vertices("<alice>", "<bob>", "<fred>")},
+			},
+		}},
+	},
+	{
+		message: "edges",
+		query:   `g.E().hasLabel('status').limit(1).count()`,
+		expect:  []interface{}{int64Value(1)},
+	},
+	{
+		message: "edge vertices",
+		query:   `g.E().hasLabel('status').outV().dedup().id()`,
+		expect:  []interface{}{"<bob>", "<dani>", "<emily>", "<greg>"},
+	},
+	{
+		message: "unsupported step",
+		query:   `g.V().addV('person')`,
+		err:     true,
+	},
+	{
+		message: "repeat without modulators",
+		query:   `g.V().repeat(out())`,
+		err:     true,
+	},
+	{
+		message: "path after repeat until",
+		query:   `g.V('alice').repeat(out('follows')).until(has('status', 'cool_person')).path()`,
+		err:     true,
+	},
+	{
+		message: "repeat until in anonymous traversal",
+		query:   `g.V().where(repeat(out('follows')).until(has('status', 'cool_person'))).id()`,
+		err:     true,
+	},
+	{
+		message: "unknown label",
+		query:   `g.V().select('a')`,
+		err:     true,
+	},
+	{
+		message: "syntax error",
+		query:   `g.V(.out()`,
+		err:     true,
+	},
+}
+
+func sortResults(vals []interface{}) {
+	sort.Slice(vals, func(i, j int) bool {
+		a, _ := json.Marshal(vals[i])
+		b, _ := json.Marshal(vals[j])
+		return string(a) < string(b)
+	})
+}
+
+func TestGremlin(t *testing.T) {
+	quads := testutil.LoadGraph(t, "../../data/testdata.nq")
+	quads = append(quads, extraQuads...)
+	qs := memstore.New(quads...)
+	ctx := context.TODO()
+	for _, c := range testQueries {
+		t.Run(c.message, func(t *testing.T) {
+			it, err := NewSession(qs).Execute(ctx, c.query, query.Options{Collation: query.JSON})
+			if c.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer it.Close()
+			got := []interface{}{}
+			for it.Next(ctx) {
+				got = append(got, it.Result())
+			}
+			require.NoError(t, it.Err())
+			if !c.ordered {
+				sortResults(got)
+				sortResults(c.expect)
+			}
+			// compare encoded values to ignore differences between map types
+			exp, err := json.Marshal(c.expect)
+			require.NoError(t, err)
+			act, err := json.Marshal(got)
+			require.NoError(t, err)
+			require.JSONEq(t, string(exp), string(act))
+		})
+	}
+}
+
+func TestGremlinRaw(t *testing.T) {
+	quads := testutil.LoadGraph(t, "../../data/testdata.nq")
+	qs := memstore.New(quads...)
+	ctx := context.TODO()
+
+	it, err := NewSession(qs).Execute(ctx, `g.E().hasLabel('status')`, query.Options{Collation: query.Raw})
+	require.NoError(t, err)
+	var got []quad.Quad
+	for it.Next(ctx) {
+		got = append(got, it.Result().(quad.Quad))
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	require.Len(t, got, 5)
+
+	for _, lim := range []int{0, 3} {
+		it, err := NewSession(qs).Execute(ctx, `g.V().out('follows').limit(4)`, query.Options{
+			Collation: query.Raw,
+			Limit:     lim,
+		})
+		require.NoError(t, err)
+		n := 0
+		for it.Next(ctx) {
+			_, ok := it.Result().(quad.Value)
+			require.True(t, ok)
+			n++
+		}
+		require.NoError(t, it.Err())
+		require.NoError(t, it.Close())
+		if lim == 0 {
+			lim = 4
+		}
+		require.Equal(t, lim, n)
+	}
+}
+
+func TestGremlinREPL(t *testing.T) {
+	quads := testutil.LoadGraph(t, "../../data/testdata.nq")
+	qs := memstore.New(quads...)
+	ctx := context.TODO()
+
+	it, err := NewSession(qs).Execute(ctx, `g.V('alice').out('follows').path()`, query.Options{Collation: query.REPL})
+	require.NoError(t, err)
+	defer it.Close()
+	require.True(t, it.Next(ctx))
+	require.Equal(t, "==>path[<alice>, <bob>]", it.Result())
+	require.False(t, it.Next(ctx))
+	require.NoError(t, it.Err())
+}
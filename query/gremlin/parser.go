@@ -0,0 +1,414 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gremlin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cayleygraph/cayley/query"
+)
+
+// Traversal is a parsed Gremlin traversal. Source is "g" for the root traversal and "__" for anonymous ones.
+type Traversal struct {
+	Source string
+	Steps  []*Step
+}
+
+func (t *Traversal) String() string {
+	var buf strings.Builder
+	buf.WriteString(t.Source)
+	for _, s := range t.Steps {
+		buf.WriteByte('.')
+		buf.WriteString(s.String())
+	}
+	return buf.String()
+}
+
+// Step is a single step of a traversal, for example out('follows').
+//
+// Arguments are one of: string, int64, float64, bool, []interface{}, Token, *Predicate or *Traversal.
+type Step struct {
+	Name string
+	Args []interface{}
+}
+
+func (s *Step) String() string {
+	return s.Name + "(" + argsString(s.Args) + ")"
+}
+
+// Token is an enumeration value, such as desc or T.id. Only the last part of the qualified name is kept.
+type Token string
+
+// Predicate is a value predicate, for example gt(30) or P.within('a', 'b').
+type Predicate struct {
+	Name string
+	Args []interface{}
+}
+
+func (p *Predicate) String() string {
+	return p.Name + "(" + argsString(p.Args) + ")"
+}
+
+func argsString(args []interface{}) string {
+	strs := make([]string, 0, len(args))
+	for _, a := range args {
+		strs = append(strs, argString(a))
+	}
+	return strings.Join(strs, ", ")
+}
+
+func argString(a interface{}) string {
+	switch a := a.(type) {
+	case string:
+		return strconv.Quote(a)
+	case []interface{}:
+		return "[" + argsString(a) + "]"
+	case Token:
+		return string(a)
+	}
+	return fmt.Sprint(a)
+}
+
+// predicates lists all supported predicate names.
+var predicates = map[string]bool{
+	"eq": true, "neq": true,
+	"lt": true, "lte": true, "gt": true, "gte": true,
+	"inside": true, "outside": true, "between": true,
+	"within": true, "without": true,
+	"startingWith": true, "endingWith": true, "containing": true,
+	"notStartingWith": true, "notEndingWith": true, "notContaining": true,
+	"regex": true,
+}
+
+// terminal steps only trigger an evaluation of the traversal and are ignored.
+var terminalSteps = map[string]bool{
+	"toList": true, "toSet": true, "next": true, "iterate": true,
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokInt
+	tokFloat
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+const punct = "().,[];-"
+
+// lex splits the query into tokens.
+func lex(s string) ([]token, error) {
+	var out []token
+	i := 0
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += n
+		case strings.HasPrefix(s[i:], "//"):
+			if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(s)
+			}
+		case r == '_' || unicode.IsLetter(r):
+			j := i + n
+			for j < len(s) {
+				r, n := utf8.DecodeRuneInString(s[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += n
+			}
+			out = append(out, token{kind: tokIdent, text: s[i:j], pos: i})
+			i = j
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			kind := tokInt
+			if j+1 < len(s) && s[j] == '.' && s[j+1] >= '0' && s[j+1] <= '9' {
+				kind = tokFloat
+				j++
+				for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					j++
+				}
+			}
+			text := s[i:j]
+			// Groovy type suffixes: 10L, 1.5d, 2f
+			if j < len(s) {
+				switch s[j] {
+				case 'l', 'L', 'i', 'I':
+					j++
+				case 'd', 'D', 'f', 'F':
+					kind = tokFloat
+					j++
+				}
+			}
+			out = append(out, token{kind: kind, text: text, pos: i})
+			i = j
+		case r == '\'' || r == '"':
+			str, n, err := query.LexString(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at offset %d", err, i)
+			}
+			out = append(out, token{kind: tokString, text: str, pos: i})
+			i += n
+		case strings.IndexByte(punct, s[i]) >= 0:
+			out = append(out, token{kind: tokPunct, text: s[i : i+1], pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+		}
+	}
+	out = append(out, token{kind: tokEOF, pos: len(s)})
+	return out, nil
+}
+
+// Parse parses a Gremlin traversal that starts with "g".
+func Parse(qu string) (*Traversal, error) {
+	toks, err := lex(qu)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if t := p.next(); t.kind != tokIdent || t.text != "g" {
+		return nil, p.errorf(t, "traversal must start with g")
+	}
+	if err := p.expect("."); err != nil {
+		return nil, err
+	}
+	steps, err := p.parseSteps()
+	if err != nil {
+		return nil, err
+	}
+	p.accept(";")
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %v", t)
+	}
+	return &Traversal{Source: "g", Steps: steps}, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) peekAt(off int) token {
+	if i := p.pos + off; i < len(p.toks) {
+		return p.toks[i]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at offset %d", fmt.Sprintf(format, args...), t.pos)
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) accept(s string) bool {
+	if p.isPunct(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		t := p.peek()
+		return p.errorf(t, "expected %q, got %v", s, t)
+	}
+	return nil
+}
+
+// parseSteps parses a chain of steps separated by dots.
+func (p *parser) parseSteps() ([]*Step, error) {
+	var steps []*Step
+	for {
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, p.errorf(t, "expected a step, got %v", t)
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		args, err := p.parseArgs(")")
+		if err != nil {
+			return nil, err
+		}
+		if !terminalSteps[t.text] {
+			steps = append(steps, &Step{Name: t.text, Args: args})
+		}
+		if !p.isPunct(".") || p.peekAt(1).kind != tokIdent {
+			return steps, nil
+		}
+		p.next()
+	}
+}
+
+// parseArgs parses a comma-separated list of arguments up to a closing bracket.
+func (p *parser) parseArgs(end string) ([]interface{}, error) {
+	var args []interface{}
+	if p.accept(end) {
+		return args, nil
+	}
+	for {
+		a, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+		if p.accept(end) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseArg() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return t.text, nil
+	case tokInt, tokFloat:
+		return parseNumber(t, false)
+	case tokPunct:
+		switch t.text {
+		case "-":
+			n := p.next()
+			if n.kind != tokInt && n.kind != tokFloat {
+				return nil, p.errorf(n, "expected a number, got %v", n)
+			}
+			return parseNumber(n, true)
+		case "[":
+			return p.parseArgs("]")
+		}
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return t.text == "true", nil
+		case "null":
+			return nil, p.errorf(t, "null values are not supported")
+		case "__":
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			steps, err := p.parseSteps()
+			if err != nil {
+				return nil, err
+			}
+			return &Traversal{Source: "__", Steps: steps}, nil
+		}
+		if p.isPunct(".") && (t.text == "P" || t.text == "TextP") {
+			p.next()
+			n := p.next()
+			if n.kind != tokIdent || !predicates[n.text] {
+				return nil, p.errorf(n, "unknown predicate %v", n)
+			}
+			return p.parsePredicate(n.text)
+		}
+		if p.isPunct("(") {
+			if predicates[t.text] {
+				return p.parsePredicate(t.text)
+			}
+			// anonymous traversal without the __ prefix
+			p.pos--
+			steps, err := p.parseSteps()
+			if err != nil {
+				return nil, err
+			}
+			return &Traversal{Source: "__", Steps: steps}, nil
+		}
+		name := t.text
+		for p.isPunct(".") && p.peekAt(1).kind == tokIdent && p.peekAt(2).text != "(" {
+			p.next()
+			name = p.next().text
+		}
+		return Token(name), nil
+	}
+	return nil, p.errorf(t, "unexpected %v", t)
+}
+
+func (p *parser) parsePredicate(name string) (*Predicate, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args, err := p.parseArgs(")")
+	if err != nil {
+		return nil, err
+	}
+	return &Predicate{Name: name, Args: args}, nil
+}
+
+func parseNumber(t token, neg bool) (interface{}, error) {
+	text := t.text
+	if neg {
+		text = "-" + text
+	}
+	if t.kind == tokInt {
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", text, t.pos)
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q at offset %d", text, t.pos)
+	}
+	return v, nil
+}
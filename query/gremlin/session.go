@@ -0,0 +1,454 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gremlin implements a read-only subset of Apache TinkerPop Gremlin traversal language.
+//
+// Traversals are compiled to query/path, thus all optimizations of the backend apply to them.
+// Results are encoded as GraphSON 3.0 for JSON collation, so existing Gremlin tools can read them.
+package gremlin
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/refs"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/cayley/query/shape"
+	"github.com/cayleygraph/quad"
+)
+
+const Name = "gremlin"
+
+func init() {
+	query.RegisterLanguage(query.Language{
+		Name: Name,
+		Session: func(qs graph.QuadStore) query.Session {
+			return NewSession(qs)
+		},
+	})
+}
+
+// NewSession creates a new Gremlin session on a given quad store.
+func NewSession(qs graph.QuadStore) *Session {
+	return &Session{qs: qs}
+}
+
+type Session struct {
+	qs graph.QuadStore
+}
+
+// Execute runs a Gremlin traversal. For Raw collation results are quad.Value for nodes, quad.Quad for edges,
+// map[string]quad.Value for select, []quad.Value for path and quad.Int for count.
+// JSON collation returns GraphSON values and REPL returns strings in the format of Gremlin console.
+func (s *Session) Execute(ctx context.Context, qu string, opt query.Options) (query.Iterator, error) {
+	switch opt.Collation {
+	case query.Raw, query.JSON, query.REPL:
+	default:
+		return nil, &query.ErrUnsupportedCollation{Collation: opt.Collation}
+	}
+	t, err := Parse(qu)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := Compile(s.qs, t)
+	if err != nil {
+		return nil, err
+	}
+	limit := plan.Limit
+	if !plan.Count && opt.Limit > 0 && (limit < 0 || int64(opt.Limit) < limit) {
+		limit = int64(opt.Limit)
+	}
+	var it iterator.Shape
+	if plan.Result == ResultEdges {
+		var sh shape.Shape = shape.Quads{}
+		if len(plan.Edges) != 0 {
+			sh = shape.Quads{{Dir: quad.Predicate, Values: shape.Lookup(plan.Edges)}}
+		}
+		it = shape.BuildIterator(ctx, s.qs, sh)
+	} else {
+		it, _ = plan.Path.BuildIterator(ctx).Optimize(ctx)
+	}
+	return &results{
+		qs:    s.qs,
+		col:   opt.Collation,
+		plan:  plan,
+		it:    it.Iterate(),
+		skip:  plan.Skip,
+		limit: limit,
+	}, nil
+}
+
+// row is a single traverser. It holds a node, values of select() or path() tags, or directions of an edge.
+type row []quad.Value
+
+type results struct {
+	qs   graph.QuadStore
+	col  query.Collation
+	plan *Plan
+	it   iterator.Scanner
+
+	started bool // Next was called on the iterator, so NextPath can be called
+	sorted  []row
+	sortInd int
+	seen    map[string]struct{}
+	counted bool
+
+	skip  int64
+	limit int64
+	n     int64
+
+	cur row
+	err error
+}
+
+// nextRow reads the next traverser.
+func (it *results) nextRow(ctx context.Context) (row, bool) {
+	if it.started && it.plan.Result != ResultEdges && it.it.NextPath(ctx) {
+		return it.readRow(), true
+	} else if err := it.it.Err(); err != nil {
+		return nil, false
+	}
+	if !it.it.Next(ctx) {
+		return nil, false
+	}
+	it.started = true
+	return it.readRow(), true
+}
+
+// readRow reads values of the current traverser, followed by values of order keys.
+func (it *results) readRow() row {
+	var (
+		r     row
+		names []string
+	)
+	switch it.plan.Result {
+	case ResultNodes:
+		r = row{it.qs.NameOf(it.it.Result())}
+	case ResultEdges:
+		q := it.qs.Quad(it.it.Result())
+		return row{q.Subject, q.Predicate, q.Object, q.Label}
+	case ResultMap:
+		names = it.plan.Tags
+	case ResultPath:
+		names = it.plan.PathTags
+	}
+	for _, k := range it.plan.Order {
+		names = append(names[:len(names):len(names)], k.Tag)
+	}
+	if len(names) == 0 {
+		return r
+	}
+	tags := make(map[string]refs.Ref, len(names))
+	it.it.TagResults(tags)
+	for _, t := range names {
+		var v quad.Value
+		if ref, ok := tags[t]; ok && ref != nil {
+			v = it.qs.NameOf(ref)
+		}
+		r = append(r, v)
+	}
+	return r
+}
+
+// sortRows reads all rows and sorts them by order keys.
+func (it *results) sortRows(ctx context.Context) error {
+	gov := governor.FromContext(ctx)
+	for {
+		r, ok := it.nextRow(ctx)
+		if !ok {
+			break
+		}
+		if err := gov.Materialize(int64(len(r))); err != nil {
+			return err
+		}
+		it.sorted = append(it.sorted, r)
+	}
+	if err := it.it.Err(); err != nil {
+		return err
+	}
+	keys := it.plan.Order
+	sort.SliceStable(it.sorted, func(i, j int) bool {
+		// values of keys are at the end of each row
+		a, b := it.sorted[i], it.sorted[j]
+		off := len(a) - len(keys)
+		for k, key := range keys {
+			if c := iterator.CompareValuesNullsLast(a[off+k], b[off+k]); c != 0 {
+				if key.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return false
+	})
+	if it.sorted == nil {
+		it.sorted = []row{}
+	}
+	return nil
+}
+
+// rowKey returns a key of the row for dedup().
+func rowKey(r row) string {
+	var buf strings.Builder
+	for _, v := range r {
+		if v != nil {
+			buf.WriteString(quad.StringOf(v))
+		}
+		buf.WriteByte(0)
+	}
+	return buf.String()
+}
+
+// nextResult returns the next row after dedup() and range steps.
+func (it *results) nextResult(ctx context.Context) (row, bool) {
+	if it.limit >= 0 && it.n >= it.limit {
+		return nil, false
+	}
+	if len(it.plan.Order) != 0 && it.sorted == nil {
+		if it.err = it.sortRows(ctx); it.err != nil {
+			return nil, false
+		}
+	}
+	for {
+		var (
+			r  row
+			ok bool
+		)
+		if it.sorted != nil {
+			if it.sortInd < len(it.sorted) {
+				r, ok = it.sorted[it.sortInd], true
+				it.sortInd++
+			}
+		} else {
+			r, ok = it.nextRow(ctx)
+		}
+		if !ok {
+			return nil, false
+		}
+		// drop values of order keys
+		r = r[:len(r)-len(it.plan.Order)]
+		if it.plan.Dedup {
+			if it.seen == nil {
+				it.seen = make(map[string]struct{})
+			}
+			key := rowKey(r)
+			if _, ok := it.seen[key]; ok {
+				continue
+			}
+			if it.err = governor.FromContext(ctx).Materialize(int64(len(r))); it.err != nil {
+				return nil, false
+			}
+			it.seen[key] = struct{}{}
+		}
+		if it.skip > 0 {
+			it.skip--
+			continue
+		}
+		it.n++
+		return r, true
+	}
+}
+
+func (it *results) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.plan.Count {
+		r, ok := it.nextResult(ctx)
+		it.cur = r
+		return ok
+	}
+	if it.counted {
+		return false
+	}
+	for {
+		if _, ok := it.nextResult(ctx); !ok {
+			break
+		}
+	}
+	if it.err != nil || it.it.Err() != nil {
+		return false
+	}
+	it.counted = true
+	it.cur = row{quad.Int(it.n)}
+	return true
+}
+
+func (it *results) Result() interface{} {
+	if it.cur == nil {
+		return nil
+	}
+	switch it.col {
+	case query.Raw:
+		return it.rawResult()
+	case query.JSON:
+		return it.jsonResult()
+	case query.REPL:
+		return "==>" + it.replResult()
+	}
+	return nil
+}
+
+func (it *results) rawResult() interface{} {
+	if it.plan.Count {
+		return it.cur[0]
+	}
+	switch it.plan.Result {
+	case ResultMap:
+		m := make(map[string]quad.Value, len(it.plan.Keys))
+		for i, k := range it.plan.Keys {
+			m[k] = it.cur[i]
+		}
+		return m
+	case ResultPath:
+		return []quad.Value(it.cur)
+	case ResultEdges:
+		return quad.Quad{Subject: it.cur[0], Predicate: it.cur[1], Object: it.cur[2], Label: it.cur[3]}
+	}
+	return it.cur[0]
+}
+
+func (it *results) jsonResult() interface{} {
+	if it.plan.Count {
+		return graphSON(it.cur[0], false)
+	}
+	switch it.plan.Result {
+	case ResultMap:
+		m := make([]interface{}, 0, 2*len(it.plan.Keys))
+		for i, k := range it.plan.Keys {
+			m = append(m, k, graphSON(it.cur[i], it.plan.IDs))
+		}
+		return typed("g:Map", m)
+	case ResultPath:
+		labels := make([]interface{}, 0, len(it.cur))
+		objects := make([]interface{}, 0, len(it.cur))
+		for i, v := range it.cur {
+			set := make([]interface{}, 0, len(it.plan.PathLabels[i]))
+			for _, l := range it.plan.PathLabels[i] {
+				set = append(set, l)
+			}
+			labels = append(labels, typed("g:Set", set))
+			objects = append(objects, graphSON(v, false))
+		}
+		return typed("g:Path", map[string]interface{}{
+			"labels":  typed("g:List", labels),
+			"objects": typed("g:List", objects),
+		})
+	case ResultEdges:
+		q := quad.Quad{Subject: it.cur[0], Predicate: it.cur[1], Object: it.cur[2], Label: it.cur[3]}
+		return typed("g:Edge", map[string]interface{}{
+			"id":        q.NQuad(),
+			"label":     label(q.Predicate),
+			"outV":      quad.StringOf(q.Subject),
+			"outVLabel": vertexLabel,
+			"inV":       quad.StringOf(q.Object),
+			"inVLabel":  vertexLabel,
+		})
+	}
+	return graphSON(it.cur[0], it.plan.IDs)
+}
+
+func (it *results) replResult() string {
+	if it.plan.Count {
+		return quad.StringOf(it.cur[0])
+	}
+	switch it.plan.Result {
+	case ResultMap:
+		parts := make([]string, 0, len(it.plan.Keys))
+		for i, k := range it.plan.Keys {
+			parts = append(parts, k+":"+replValue(it.cur[i]))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case ResultPath:
+		parts := make([]string, 0, len(it.cur))
+		for _, v := range it.cur {
+			parts = append(parts, replValue(v))
+		}
+		return "path[" + strings.Join(parts, ", ") + "]"
+	case ResultEdges:
+		return "e[" + replValue(it.cur[0]) + "-" + label(it.cur[1]) + "->" + replValue(it.cur[2]) + "]"
+	}
+	return replValue(it.cur[0])
+}
+
+func replValue(v quad.Value) string {
+	if v == nil {
+		return "null"
+	}
+	return quad.StringOf(v)
+}
+
+// vertexLabel is a label of all vertices. Types of nodes are not used as labels, since node can have many of them.
+const vertexLabel = "vertex"
+
+// label returns a label of an edge with a given predicate.
+func label(pred quad.Value) string {
+	if iri, ok := pred.(quad.IRI); ok {
+		return string(iri.Short())
+	}
+	return quad.StringOf(pred)
+}
+
+func typed(typ string, v interface{}) map[string]interface{} {
+	return map[string]interface{}{"@type": typ, "@value": v}
+}
+
+// graphSON converts a value to GraphSON 3.0. Nodes are returned as vertices,
+// or as identifiers in N-Quads notation if ids is set.
+func graphSON(v quad.Value, ids bool) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case quad.IRI, quad.BNode:
+		if ids {
+			return quad.StringOf(v)
+		}
+		return typed("g:Vertex", map[string]interface{}{
+			"id":    quad.StringOf(v),
+			"label": vertexLabel,
+		})
+	case quad.String:
+		return string(v)
+	case quad.Int:
+		return typed("g:Int64", int64(v))
+	case quad.Float:
+		return typed("g:Double", float64(v))
+	case quad.Bool:
+		return bool(v)
+	case quad.Time:
+		return typed("g:Date", time.Time(v).UnixNano()/int64(time.Millisecond))
+	}
+	out := v.Native()
+	if nv, ok := out.(quad.Value); ok && v == nv {
+		return quad.StringOf(v)
+	}
+	return out
+}
+
+func (it *results) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
+
+func (it *results) Close() error {
+	it.sorted, it.seen = nil, nil
+	return it.it.Close()
+}
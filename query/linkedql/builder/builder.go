@@ -0,0 +1,72 @@
+// Package builder provides a fluent API for building LinkedQL queries in Go.
+//
+//	q := builder.V(quad.IRI("alice")).Out(builder.Props("follows")).As("person").Select(nil, false)
+//	data, err := linkedql.Marshal(q)
+//
+// Functions and methods for all the registered LinkedQL steps are generated by linkedqlgen.
+package builder
+
+import (
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+)
+
+//go:generate go run ../../../cmd/linkedqlgen/linkedqlgen.go -o steps_gen.go
+
+// Path is a LinkedQL path step that can be extended with further steps.
+type Path struct {
+	s linkedql.PathStep
+}
+
+// NewPath wraps a path step into a Path.
+func NewPath(s linkedql.PathStep) *Path {
+	return &Path{s: s}
+}
+
+// Step returns the last step of the path.
+func (p *Path) Step() linkedql.PathStep {
+	return p.step()
+}
+
+// step returns the last step of the path, or nil for a nil path.
+func (p *Path) step() linkedql.PathStep {
+	if p == nil {
+		return nil
+	}
+	return p.s
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Path) MarshalJSON() ([]byte, error) {
+	return linkedql.Marshal(p.step())
+}
+
+func pathSteps(paths []*Path) []linkedql.PathStep {
+	if len(paths) == 0 {
+		return nil
+	}
+	out := make([]linkedql.PathStep, 0, len(paths))
+	for _, p := range paths {
+		out = append(out, p.step())
+	}
+	return out
+}
+
+// V is a shorthand for Vertex.
+func V(values ...quad.Value) *Path {
+	return Vertex(values...)
+}
+
+// Props returns a property path of given property IRIs.
+func Props(iris ...quad.IRI) *linkedql.PropertyPath {
+	props := make(linkedql.PropertyIRIs, 0, len(iris))
+	for _, iri := range iris {
+		props = append(props, linkedql.PropertyIRI(iri))
+	}
+	return linkedql.NewPropertyPath(props)
+}
+
+// PropsPath returns a property path of the values of a given path.
+func PropsPath(p *Path) *linkedql.PropertyPath {
+	return linkedql.NewPropertyPath(p.step())
+}
@@ -0,0 +1,175 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+)
+
+var builderCases = []struct {
+	name string
+	step linkedql.Step
+	exp  string
+}{
+	{
+		name: "vertex",
+		step: V(quad.IRI("http://example.com/alice"), quad.String("bob"), quad.Int(3)).Step(),
+		exp: `{
+	"@context": {"@vocab": "http://cayley.io/linkedql#"},
+	"@type": "Vertex",
+	"values": [{"@id": "http://example.com/alice"}, "bob", 3]
+}`,
+	},
+	{
+		name: "select",
+		step: V(quad.IRI("http://example.com/alice")).
+			Out(Props("http://example.com/follows")).As("person").
+			Out(Props("http://example.com/name", "http://example.com/nick")).As("name").
+			Limit(10).
+			Select([]string{"person", "name"}, false),
+		exp: `{
+	"@context": {"@vocab": "http://cayley.io/linkedql#"},
+	"@type": "Select",
+	"properties": ["person", "name"],
+	"from": {
+		"@type": "Limit",
+		"limit": 10,
+		"from": {
+			"@type": "As",
+			"name": "name",
+			"from": {
+				"@type": "Out",
+				"properties": ["http://example.com/name", "http://example.com/nick"],
+				"from": {
+					"@type": "As",
+					"name": "person",
+					"from": {
+						"@type": "Out",
+						"properties": "http://example.com/follows",
+						"from": {
+							"@type": "Vertex",
+							"values": {"@id": "http://example.com/alice"}
+						}
+					}
+				}
+			}
+		}
+	}
+}`,
+	},
+	{
+		name: "computed",
+		step: Match(linkedql.GraphPattern{"http://example.com/name": map[string]interface{}{}}).
+			Union(V(quad.IRI("http://example.com/bob"))).
+			Select(nil, true,
+				Compute("label", Concat(Tag("name"), Constant(quad.String("!")))),
+			),
+		exp: `{
+	"@context": {"@vocab": "http://cayley.io/linkedql#"},
+	"@type": "Select",
+	"excludeID": true,
+	"computed": {
+		"@type": "Compute",
+		"name": "label",
+		"value": {
+			"@type": "Concat",
+			"values": [
+				{"@type": "Tag", "name": "name"},
+				{"@type": "Constant", "value": "!"}
+			]
+		}
+	},
+	"from": {
+		"@type": "Union",
+		"steps": {
+			"@type": "Vertex",
+			"values": {"@id": "http://example.com/bob"}
+		},
+		"from": {
+			"@type": "Match",
+			"pattern": {"http://example.com/name": {}}
+		}
+	}
+}`,
+	},
+	{
+		name: "update",
+		step: Update(
+			V().Has(Props("http://example.com/age"), quad.Int(30)).As("person"),
+			"",
+			`_:person <http://example.com/status> "thirty" .`,
+		),
+		exp: `{
+	"@context": {"@vocab": "http://cayley.io/linkedql#"},
+	"@type": "Update",
+	"insert": "_:person <http://example.com/status> \"thirty\" .",
+	"where": {
+		"@type": "As",
+		"name": "person",
+		"from": {
+			"@type": "Has",
+			"property": "http://example.com/age",
+			"values": 30,
+			"from": {"@type": "Vertex"}
+		}
+	}
+}`,
+	},
+}
+
+func TestBuilder(t *testing.T) {
+	for _, c := range builderCases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := linkedql.Marshal(c.step)
+			require.NoError(t, err)
+			require.JSONEq(t, c.exp, string(data))
+
+			step, err := linkedql.Unmarshal(data)
+			require.NoError(t, err)
+			data2, err := linkedql.Marshal(step)
+			require.NoError(t, err)
+			require.JSONEq(t, string(data), string(data2))
+		})
+	}
+}
+
+func TestPathMarshalJSON(t *testing.T) {
+	p := V(quad.IRI("http://example.com/alice")).Out(Props("http://example.com/follows"))
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	exp, err := linkedql.Marshal(p.Step())
+	require.NoError(t, err)
+	require.JSONEq(t, string(exp), string(data))
+}
+
+func TestBuilderQuery(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("alice", "follows", "carol", ""),
+		quad.MakeIRI("bob", "follows", "carol", ""),
+	)
+	q := V(quad.IRI("alice")).Out(Props("follows"))
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	ses := linkedql.NewSession(qs)
+	it, err := ses.Execute(context.Background(), string(data), query.Options{Collation: query.JSONLD, Limit: -1})
+	require.NoError(t, err)
+	defer it.Close()
+	var got []interface{}
+	for it.Next(context.Background()) {
+		got = append(got, it.Result())
+	}
+	require.NoError(t, it.Err())
+	require.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"@id": "bob"},
+		map[string]interface{}{"@id": "carol"},
+	}, got)
+}
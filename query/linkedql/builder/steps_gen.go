@@ -0,0 +1,359 @@
+// Code generated by linkedqlgen. DO NOT EDIT.
+
+package builder
+
+import (
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/query/linkedql/steps"
+	"github.com/cayleygraph/quad"
+)
+
+// Add resolves to the sum of the values of given expressions. It is undefined if any of the values
+// is undefined or is not a number.
+func Add(values ...linkedql.Expression) *steps.Add {
+	return &steps.Add{Values: values}
+}
+
+// Aggregate computes an aggregate function (count, sum, avg, min or max) of the values assigned to
+// a given name for each group of the from step and assigns it to the as name (the function name by
+// default). If the from step is not a GroupBy step, a single value is computed for all the resolved
+// values. It resolves to the values of the from step.
+func (p *Path) Aggregate(function, name, as string) *Path {
+	return &Path{s: &steps.Aggregate{From: p.step(), Function: function, Name: name, As: as}}
+}
+
+// AllPaths resolves to the values of the to step that can be reached by following the given
+// properties (or any property) from the current objects, once for each path without loops. The list
+// of visited values and properties is saved to the "path" tag.
+func (p *Path) AllPaths(to *Path, properties *linkedql.PropertyPath, maxDepth int) *Path {
+	return &Path{s: &steps.AllPaths{From: p.step(), To: to.step(), Properties: properties, MaxDepth: maxDepth}}
+}
+
+// As assigns the resolved values of the from step to a given name. The name can be used with the
+// Select and Documents steps to retrieve the values or to return to the values in further steps
+// with the Back step. It resolves to the values of the from step.
+func (p *Path) As(name string) *Path {
+	return &Path{s: &steps.As{From: p.step(), Name: name}}
+}
+
+// Back resolves to the values of the previous the step or the values assigned to name in a former
+// step.
+func (p *Path) Back(name string) *Path {
+	return &Path{s: &steps.Back{From: p.step(), Name: name}}
+}
+
+// Both is like View but resolves to both the object values and references to the values of the
+// given properties in via. It is the equivalent for the Union of View and ViewReverse of the same
+// property.
+func (p *Path) Both(properties *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.Both{From: p.step(), Properties: properties}}
+}
+
+// Collect recursively resolves values of a list (also known as RDF collection).
+func (p *Path) Collect(name quad.IRI) *Path {
+	return &Path{s: &steps.Collect{From: p.step(), Name: name}}
+}
+
+// Compute assigns the value of an expression to a name in each result of a Select step. Results
+// with undefined values of the expression don't have the name.
+func Compute(name string, value linkedql.Expression) *steps.Compute {
+	return &steps.Compute{Name: name, Value: value}
+}
+
+// Concat resolves to a string that joins the string forms of the values of given expressions.
+// Strings are used as is, identifiers are converted to their IRIs and other literals to their
+// lexical forms. It is undefined if any of the values is undefined.
+func Concat(values ...linkedql.Expression) *steps.Concat {
+	return &steps.Concat{Values: values}
+}
+
+// Constant resolves to a given value.
+func Constant(value quad.Value) *steps.Constant {
+	return &steps.Constant{Value: value}
+}
+
+// Count resolves to the number of the resolved values of the from step.
+func (p *Path) Count() *Path {
+	return &Path{s: &steps.Count{From: p.step()}}
+}
+
+// Datatype resolves to the IRI of the datatype of a value of a given expression. It is undefined
+// for identifiers.
+func Datatype(value linkedql.Expression) *steps.Datatype {
+	return &steps.Datatype{Value: value}
+}
+
+// Delete deletes quads of a JSON-LD document, a list of quads in N-Quads format and quads of the
+// entities matched by the from step in a single transaction. If properties are given, only these
+// properties of the matched entities are deleted, otherwise all quads referencing the entities are
+// deleted. It resolves to the counts of inserted and deleted quads.
+func (p *Path) Delete(properties *linkedql.PropertyPath, document linkedql.GraphPattern, quads string) *steps.Delete {
+	return &steps.Delete{From: p.step(), Properties: properties, Document: document, Quads: quads}
+}
+
+// Delete deletes quads of a JSON-LD document, a list of quads in N-Quads format and quads of the
+// entities matched by the from step in a single transaction. If properties are given, only these
+// properties of the matched entities are deleted, otherwise all quads referencing the entities are
+// deleted. It resolves to the counts of inserted and deleted quads.
+func Delete(properties *linkedql.PropertyPath, document linkedql.GraphPattern, quads string) *steps.Delete {
+	return &steps.Delete{Properties: properties, Document: document, Quads: quads}
+}
+
+// Difference resolves to all the values resolved by the from step different then the values
+// resolved by the provided steps. Caution: it might be slow to execute.
+func (p *Path) Difference(paths ...*Path) *Path {
+	return &Path{s: &steps.Difference{From: p.step(), Steps: pathSteps(paths)}}
+}
+
+// Divide resolves to the value of the first expression divided by the values of the rest of the
+// expressions. The result is always a floating point number. It is undefined if any of the values
+// is undefined, is not a number or if it divides by zero.
+func Divide(values ...linkedql.Expression) *steps.Divide {
+	return &steps.Divide{Values: values}
+}
+
+// Documents return documents of the tags matched in the query associated with their entity.
+func (p *Path) Documents() *steps.Documents {
+	return &steps.Documents{From: p.step()}
+}
+
+// GreaterThan greater than equals filters out values that are not greater than given value.
+func (p *Path) GreaterThan(value quad.Value) *Path {
+	return &Path{s: &steps.GreaterThan{From: p.step(), Value: value}}
+}
+
+// GreaterThanEquals greater than equals filters out values that are not greater than or equal given
+// value.
+func (p *Path) GreaterThanEquals(value quad.Value) *Path {
+	return &Path{s: &steps.GreaterThanEquals{From: p.step(), Value: value}}
+}
+
+// GroupBy groups the resolved values of the from step by the values assigned to a given name. It
+// resolves to the distinct values of the name. Use the Aggregate step to compute values for each
+// group.
+func (p *Path) GroupBy(name string) *Path {
+	return &Path{s: &steps.GroupBy{From: p.step(), Name: name}}
+}
+
+// Has filters all paths which are, at this point, on the subject for the given predicate and
+// object, but do not follow the path, merely filter the possible paths. Usually useful for starting
+// with all nodes, or limiting to a subset depending on some predicate/value pair.
+func (p *Path) Has(property *linkedql.PropertyPath, values ...quad.Value) *Path {
+	return &Path{s: &steps.Has{From: p.step(), Property: property, Values: values}}
+}
+
+// HasReverse is the same as Has, but sets constraint in reverse direction.
+func (p *Path) HasReverse(property *linkedql.PropertyPath, values ...quad.Value) *Path {
+	return &Path{s: &steps.HasReverse{From: p.step(), Property: property, Values: values}}
+}
+
+// In aliases for ViewReverse.
+func (p *Path) In(properties *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.In{VisitReverse: steps.VisitReverse{From: p.step(), Properties: properties}}}
+}
+
+// Insert inserts quads of a JSON-LD document and a list of quads in N-Quads format in a single
+// transaction. Blank nodes are replaced with new blank nodes. It resolves to the counts of inserted
+// and deleted quads.
+func Insert(document linkedql.GraphPattern, quads string) *steps.Insert {
+	return &steps.Insert{Document: document, Quads: quads}
+}
+
+// Intersect resolves to all the same values resolved by the from step and the provided steps.
+func (p *Path) Intersect(paths ...*Path) *Path {
+	return &Path{s: &steps.Intersect{From: p.step(), Steps: pathSteps(paths)}}
+}
+
+// Labels gets the list of inbound and outbound quad labels.
+func (p *Path) Labels() *Path {
+	return &Path{s: &steps.Labels{From: p.step()}}
+}
+
+// Lang resolves to the language tag of a value of a given expression. It is an empty string for
+// literals without a language tag and undefined for identifiers.
+func Lang(value linkedql.Expression) *steps.Lang {
+	return &steps.Lang{Value: value}
+}
+
+// LessThan less than filters out values that are not less than given value.
+func (p *Path) LessThan(value quad.Value) *Path {
+	return &Path{s: &steps.LessThan{From: p.step(), Value: value}}
+}
+
+// LessThanEquals less than equals filters out values that are not less than or equal given value.
+func (p *Path) LessThanEquals(value quad.Value) *Path {
+	return &Path{s: &steps.LessThanEquals{From: p.step(), Value: value}}
+}
+
+// Like filters out values that do not match given pattern.
+func (p *Path) Like(pattern string) *Path {
+	return &Path{s: &steps.Like{From: p.step(), Pattern: pattern}}
+}
+
+// Limit limits a number of nodes for current path.
+func (p *Path) Limit(limit int64) *Path {
+	return &Path{s: &steps.Limit{From: p.step(), Limit: limit}}
+}
+
+// Match filters all paths which are, at this point, on the subject for the given predicate and
+// object, but do not follow the path, merely filter the possible paths. Usually useful for starting
+// with all nodes, or limiting to a subset depending on some predicate/value pair.
+func (p *Path) Match(pattern linkedql.GraphPattern) *Path {
+	return &Path{s: &steps.Match{From: p.step(), Pattern: pattern}}
+}
+
+// Match filters all paths which are, at this point, on the subject for the given predicate and
+// object, but do not follow the path, merely filter the possible paths. Usually useful for starting
+// with all nodes, or limiting to a subset depending on some predicate/value pair.
+func Match(pattern linkedql.GraphPattern) *Path {
+	return &Path{s: &steps.Match{Pattern: pattern}}
+}
+
+// Multiply resolves to the product of the values of given expressions. It is undefined if any of
+// the values is undefined or is not a number.
+func Multiply(values ...linkedql.Expression) *steps.Multiply {
+	return &steps.Multiply{Values: values}
+}
+
+// Optional attempts to follow the given path from the current entity / value, if fails the entity /
+// value will still be kept in the results.
+func (p *Path) Optional(step *Path) *Path {
+	return &Path{s: &steps.Optional{From: p.step(), Step: step.step()}}
+}
+
+// Order sorts the results according to the value of a name, a property or the current entity /
+// value if neither is set. Values are compared by their types. Results without a value are placed
+// last. The order is stable, so multiple keys can be used by nesting Order steps, with the
+// outermost step being the primary key.
+func (p *Path) Order(name string, property *linkedql.PropertyPath, descending bool) *Path {
+	return &Path{s: &steps.Order{From: p.step(), Name: name, Property: property, Descending: descending}}
+}
+
+// Out aliases for View.
+func (p *Path) Out(properties *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.Out{Visit: steps.Visit{From: p.step(), Properties: properties}}}
+}
+
+// Placeholder is like Vertex but resolves to the values in the context it is placed in. It should
+// only be used where a linkedql.PathStep is expected and can't be resolved on its own. If a name is
+// set, it resolves to the value of a query parameter with this name instead.
+func Placeholder(name string) *Path {
+	return &Path{s: &steps.Placeholder{Name: name}}
+}
+
+// Properties adds tags for all properties of the current entity.
+func (p *Path) Properties(names *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.Properties{From: p.step(), Names: names}}
+}
+
+// PropertyNames gets the list of predicates that are pointing out from a node.
+func (p *Path) PropertyNames() *Path {
+	return &Path{s: &steps.PropertyNames{From: p.step()}}
+}
+
+// PropertyNamesAs tags the list of predicates that are pointing out from a node.
+func (p *Path) PropertyNamesAs(tag string) *Path {
+	return &Path{s: &steps.PropertyNamesAs{From: p.step(), Tag: tag}}
+}
+
+// RegExp filters out values that do not match given pattern. If includeIRIs is set to true it
+// matches IRIs in addition to literals.
+func (p *Path) RegExp(expression string, includeIRIs bool) *Path {
+	return &Path{s: &steps.RegExp{From: p.step(), Expression: expression, IncludeIRIs: includeIRIs}}
+}
+
+// ReverseProperties gets all the properties the current entity / value is referenced at.
+func (p *Path) ReverseProperties(names *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.ReverseProperties{From: p.step(), Names: names}}
+}
+
+// ReversePropertyNames gets the list of predicates that are pointing in to a node.
+func (p *Path) ReversePropertyNames() *Path {
+	return &Path{s: &steps.ReversePropertyNames{From: p.step()}}
+}
+
+// ReversePropertyNamesAs tags the list of predicates that are pointing in to a node.
+func (p *Path) ReversePropertyNamesAs(tag string) *Path {
+	return &Path{s: &steps.ReversePropertyNamesAs{From: p.step(), Tag: tag}}
+}
+
+// Select returns flat records of tags matched in the query and values computed from them.
+func (p *Path) Select(properties []string, excludeID bool, computed ...linkedql.Expression) *steps.Select {
+	return &steps.Select{Properties: properties, From: p.step(), ExcludeID: excludeID, Computed: computed}
+}
+
+// ShortestPath resolves to the values of the to step that are reached first by following the given
+// properties (or any property) from the current objects. The list of visited values and properties
+// is saved to the "path" tag.
+func (p *Path) ShortestPath(to *Path, properties *linkedql.PropertyPath, maxDepth int) *Path {
+	return &Path{s: &steps.ShortestPath{From: p.step(), To: to.step(), Properties: properties, MaxDepth: maxDepth}}
+}
+
+// Skip skips a number of nodes for current path.
+func (p *Path) Skip(offset int64) *Path {
+	return &Path{s: &steps.Skip{From: p.step(), Offset: offset}}
+}
+
+// Subtract resolves to the value of the first expression minus the values of the rest of the
+// expressions. It is undefined if any of the values is undefined or is not a number.
+func Subtract(values ...linkedql.Expression) *steps.Subtract {
+	return &steps.Subtract{Values: values}
+}
+
+// Tag resolves to the value assigned to a given name in the query. It is undefined if the name is
+// not assigned in a result.
+func Tag(name string) *steps.Tag {
+	return &steps.Tag{Name: name}
+}
+
+// Union returns the combined paths of the two queries. Notice that it's per-path, not per-node.
+// Once again, if multiple paths reach the same destination, they might have had different ways of
+// getting there (and different tags).
+func (p *Path) Union(paths ...*Path) *Path {
+	return &Path{s: &steps.Union{From: p.step(), Steps: pathSteps(paths)}}
+}
+
+// Unique removes duplicate values from the path.
+func (p *Path) Unique() *Path {
+	return &Path{s: &steps.Unique{From: p.step()}}
+}
+
+// Update deletes and inserts quads for each result of the where step in a single transaction, like
+// SPARQL Update. Templates are lists of quads in N-Quads format, where blank nodes refer to the
+// values of the names assigned in the where step. Template quads with names that are not assigned
+// in a result are skipped. Other blank nodes in inserted quads are replaced with new blank nodes
+// for each result. It resolves to the counts of inserted and deleted quads.
+func Update(where *Path, del, insert string) *steps.Update {
+	return &steps.Update{Where: where.step(), Delete: del, Insert: insert}
+}
+
+// Vertex resolves to all the existing objects and primitive values in the graph. If provided with
+// values resolves to a sublist of all the existing values in the graph.
+func Vertex(values ...quad.Value) *Path {
+	return &Path{s: &steps.Vertex{Values: values}}
+}
+
+// Visit resolves to the values of the given property or properties in via of the current objects.
+// If via is a path it's resolved values will be used as properties.
+func (p *Path) Visit(properties *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.Visit{From: p.step(), Properties: properties}}
+}
+
+// VisitReverse is the inverse of View. Starting with the nodes in `path` on the object, follow the
+// quads with predicates defined by `predicatePath` to their subjects.
+func (p *Path) VisitReverse(properties *linkedql.PropertyPath) *Path {
+	return &Path{s: &steps.VisitReverse{From: p.step(), Properties: properties}}
+}
+
+// WeightedShortestPath resolves to the values of the to step ordered by the total weight of the
+// cheapest path from the current objects. Weight is a property of the quad label, or of the edge
+// value if next properties are set. The list of visited values and properties is saved to the
+// "path" tag and the total weight to the "cost" tag.
+func (p *Path) WeightedShortestPath(to *Path, properties, next *linkedql.PropertyPath, weight, heuristic quad.IRI) *Path {
+	return &Path{s: &steps.WeightedShortestPath{From: p.step(), To: to.step(), Properties: properties, Next: next, Weight: weight, Heuristic: heuristic}}
+}
+
+// Where filters results that fulfill a specified condition.
+func (p *Path) Where(condition *Path) *Path {
+	return &Path{s: &steps.Where{From: p.step(), Condition: condition.step()}}
+}
@@ -0,0 +1,17 @@
+package linkedql
+
+import "github.com/cayleygraph/quad"
+
+// Expression computes a value from the tags of a single result.
+type Expression interface {
+	RegistryItem
+	// Evaluate computes the value for the tag values of a result.
+	// It returns nil if the value is not defined for the result.
+	Evaluate(tags map[string]quad.Value) (quad.Value, error)
+}
+
+// Projection is a computed value assigned to a name in each result.
+type Projection struct {
+	Name       string
+	Expression Expression
+}
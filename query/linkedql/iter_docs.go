@@ -37,7 +37,8 @@ func (it *DocumentIterator) getDataset(ctx context.Context) (*ld.RDFDataset, err
 		if r == nil {
 			continue
 		}
-		err := it.tagsIt.addResultsToDataset(d, r)
+		// paths can't be represented in the dataset, thus they are omitted from documents
+		_, err := it.tagsIt.addResultsToDataset(d, r)
 		if err != nil {
 			return nil, err
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/refs"
 	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/quad"
@@ -19,7 +20,9 @@ type TagsIterator struct {
 	ValueIt   *ValueIterator
 	Selected  []string
 	ExcludeID bool
-	err       error
+	// Computed values are added to each result after the tags.
+	Computed []Projection
+	err      error
 }
 
 // NewTagsIterator creates a new TagsIterator
@@ -37,9 +40,15 @@ func (it *TagsIterator) Next(ctx context.Context) bool {
 	return it.ValueIt.Next(ctx)
 }
 
-func (it *TagsIterator) addQuadFromRef(dataset *ld.RDFDataset, subject ld.Node, tag string, ref refs.Ref) error {
+func (it *TagsIterator) addQuad(dataset *ld.RDFDataset, subject ld.Node, tag string, v quad.Value) error {
 	p := ld.NewIRI(tag)
-	o, err := jsonld.ToNode(it.ValueIt.Namer.NameOf(ref))
+	if ts, ok := v.(quad.TypedStringer); ok {
+		// numbers, booleans and times are only supported as typed strings
+		s := ts.TypedString()
+		s.Type = s.Type.Full()
+		v = s
+	}
+	o, err := jsonld.ToNode(v)
 	if err != nil {
 		return err
 	}
@@ -57,27 +66,79 @@ func toSubject(namer refs.Namer, result refs.Ref) (ld.Node, error) {
 	return jsonld.ToNode(id)
 }
 
-func (it *TagsIterator) addResultsToDataset(dataset *ld.RDFDataset, result refs.Ref) error {
+// addResultsToDataset adds tags of the result to the dataset. Paths found by
+// ShortestPath and AllPaths can't be represented as a single node, so they are
+// returned separately in their JSON form.
+func (it *TagsIterator) addResultsToDataset(dataset *ld.RDFDataset, result refs.Ref) (map[string]interface{}, error) {
 	s, err := toSubject(it.ValueIt.Namer, result)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	refTags := make(map[string]refs.Ref)
 
 	it.ValueIt.scanner.TagResults(refTags)
 
-	if len(it.Selected) == 0 {
-		for tag, ref := range refTags {
-			it.addQuadFromRef(dataset, s, tag, ref)
+	tags := it.Selected
+	if len(tags) == 0 {
+		for tag := range refTags {
+			tags = append(tags, tag)
+		}
+	}
+	var computed map[string]struct{}
+	if len(it.Computed) != 0 {
+		computed = make(map[string]struct{}, len(it.Computed))
+		for _, c := range it.Computed {
+			computed[c.Name] = struct{}{}
+		}
+	}
+	var paths map[string]interface{}
+	for _, tag := range tags {
+		if _, ok := computed[tag]; ok {
+			continue
+		}
+		v := it.ValueIt.Namer.NameOf(refTags[tag])
+		if p, ok := v.(graph.PathValue); ok {
+			if paths == nil {
+				paths = make(map[string]interface{})
+			}
+			paths[tag] = pathToJSON(p)
+			continue
+		}
+		it.addQuad(dataset, s, tag, v)
+	}
+	if len(it.Computed) == 0 {
+		return paths, nil
+	}
+	values := make(map[string]quad.Value, len(refTags))
+	for tag, ref := range refTags {
+		values[tag] = it.ValueIt.Namer.NameOf(ref)
+	}
+	for _, c := range it.Computed {
+		v, err := c.Expression.Evaluate(values)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", c.Name, err)
+		} else if v == nil {
+			continue
 		}
-	} else {
-		for _, tag := range it.Selected {
-			it.addQuadFromRef(dataset, s, tag, refTags[tag])
+		if err = it.addQuad(dataset, s, c.Name, v); err != nil {
+			return nil, err
 		}
 	}
+	return paths, nil
+}
 
-	return nil
+// pathToJSON converts a path to a list of hops with "node" and "pred" fields.
+func pathToJSON(p graph.PathValue) []interface{} {
+	out := make([]interface{}, 0, len(p))
+	for _, h := range p {
+		hop := map[string]interface{}{"node": jsonld.FromValue(h.Node)}
+		if h.Pred != nil {
+			hop["pred"] = jsonld.FromValue(h.Pred)
+		}
+		out = append(out, hop)
+	}
+	return out
 }
 
 // Result implements query.Iterator.
@@ -88,18 +149,26 @@ func (it *TagsIterator) Result() interface{} {
 		return nil
 	}
 	d := ld.NewRDFDataset()
-	err := it.addResultsToDataset(d, r)
+	paths, err := it.addResultsToDataset(d, r)
 	if err != nil {
 		it.err = err
 		return nil
 	}
-	doc, err := singleDocumentFromRDF(d)
-	if err != nil {
+	var doc interface{}
+	if len(d.Graphs["@default"]) == 0 {
+		// only paths were selected
+		doc = jsonld.FromValue(it.ValueIt.Namer.NameOf(r))
+	} else if doc, err = singleDocumentFromRDF(d); err != nil {
 		it.err = err
 		return nil
 	}
-	if !it.ExcludeID {
-		m := doc.(map[string]interface{})
+	m, ok := doc.(map[string]interface{})
+	if ok {
+		for tag, p := range paths {
+			m[tag] = p
+		}
+	}
+	if !it.ExcludeID && ok {
 		delete(m, "@id")
 		return m
 	}
@@ -30,11 +30,12 @@ func init() {
 	})
 }
 
-var _ query.Session = &Session{}
+var _ query.WriterSession = &Session{}
 
 // Session represents a LinkedQL query processing.
 type Session struct {
 	qs graph.QuadStore
+	qw graph.QuadWriter
 }
 
 // NewSession creates a new Session.
@@ -44,9 +45,35 @@ func NewSession(qs graph.QuadStore) *Session {
 	}
 }
 
+// SetWriter sets a writer for MutationSteps. The session is read-only if it's not set.
+func (s *Session) SetWriter(qw graph.QuadWriter) {
+	s.qw = qw
+}
+
+// plans caches unmarshaled queries. Parameters are bound to a copy of a query, thus queries can be shared.
+var plans = query.NewPlanCache(1024)
+
+// unmarshalQuery unmarshals a query, or returns a query unmarshaled previously.
+func unmarshalQuery(text string) (RegistryItem, error) {
+	if item, ok := plans.Get(text); ok {
+		return item.(RegistryItem), nil
+	}
+	item, err := Unmarshal([]byte(text))
+	if err != nil {
+		return nil, err
+	}
+	plans.Put(text, item)
+	return item, nil
+}
+
 // Execute for a given context, query and options return an iterator of results.
+// Parameters from options are bound to named placeholders of the query.
 func (s *Session) Execute(ctx context.Context, query string, opt query.Options) (query.Iterator, error) {
-	item, err := Unmarshal([]byte(query))
+	item, err := unmarshalQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	item, err = Bind(item, opt.Params)
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +82,12 @@ func (s *Session) Execute(ctx context.Context, query string, opt query.Options)
 	if !ok {
 		return nil, errors.New("must execute a Step")
 	}
+	if m, ok := step.(MutationStep); ok {
+		if s.qw == nil {
+			return nil, ErrReadOnly
+		}
+		return NewMutationIterator(m, s.qs, s.qw, &ns), nil
+	}
 	return BuildIterator(step, s.qs, &ns)
 }
 
@@ -65,6 +98,8 @@ func BuildIterator(step Step, qs graph.QuadStore, ns *voc.Namespaces) (query.Ite
 		return s.BuildIterator(qs, ns)
 	case PathStep:
 		return NewValueIteratorFromPathStep(s, qs, ns)
+	case MutationStep:
+		return nil, ErrReadOnly
 	}
 	return nil, errors.New("must execute a IteratorStep or PathStep")
 }
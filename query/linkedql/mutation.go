@@ -0,0 +1,122 @@
+package linkedql
+
+import (
+	"context"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc"
+)
+
+// ErrReadOnly is returned when a MutationStep is executed without a QuadWriter.
+var ErrReadOnly = query.ErrReadOnly
+
+// NewTransaction returns a transaction that deletes and then inserts given quads.
+// Only stored quads are deleted and only quads that are not stored are inserted, thus
+// the transaction contains only the changes that affect the graph.
+func NewTransaction(ctx context.Context, qs graph.QuadStore, del, ins []quad.Quad) (*graph.Transaction, error) {
+	tx := graph.NewTransactionN(len(del) + len(ins))
+	removed := make(map[quad.Quad]struct{}, len(del))
+	for _, q := range del {
+		if _, ok := removed[q]; ok {
+			continue
+		}
+		ok, err := graph.HasQuad(ctx, qs, q)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			tx.RemoveQuad(q)
+			removed[q] = struct{}{}
+		}
+	}
+	for _, q := range ins {
+		if _, ok := removed[q]; ok {
+			// cancels the deletion
+			tx.AddQuad(q)
+			continue
+		}
+		ok, err := graph.HasQuad(ctx, qs, q)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			tx.AddQuad(q)
+		}
+	}
+	return tx, nil
+}
+
+var _ query.Iterator = (*MutationIterator)(nil)
+
+// MutationIterator applies changes of a MutationStep and returns a single result with counts of affected quads.
+type MutationIterator struct {
+	step MutationStep
+	qs   graph.QuadStore
+	qw   graph.QuadWriter
+	ns   *voc.Namespaces
+
+	done     bool
+	inserted int
+	deleted  int
+	err      error
+}
+
+// NewMutationIterator returns a new MutationIterator. Changes are applied on the first call to Next.
+func NewMutationIterator(step MutationStep, qs graph.QuadStore, qw graph.QuadWriter, ns *voc.Namespaces) *MutationIterator {
+	return &MutationIterator{step: step, qs: qs, qw: qw, ns: ns}
+}
+
+func (it *MutationIterator) apply(ctx context.Context) error {
+	tx, err := it.step.BuildTransaction(ctx, it.qs, it.ns)
+	if err != nil {
+		return err
+	}
+	if len(tx.Deltas) == 0 {
+		return nil
+	}
+	if err = it.qw.ApplyTransaction(tx); err != nil {
+		return err
+	}
+	for _, d := range tx.Deltas {
+		if d.Action == graph.Add {
+			it.inserted++
+		} else {
+			it.deleted++
+		}
+	}
+	return nil
+}
+
+// Next implements query.Iterator.
+func (it *MutationIterator) Next(ctx context.Context) bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	it.done = true
+	if err := it.apply(ctx); err != nil {
+		it.err = err
+		return false
+	}
+	return true
+}
+
+// Result implements query.Iterator.
+func (it *MutationIterator) Result() interface{} {
+	if !it.done || it.err != nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"inserted": it.inserted,
+		"deleted":  it.deleted,
+	}
+}
+
+// Err implements query.Iterator.
+func (it *MutationIterator) Err() error {
+	return it.err
+}
+
+// Close implements query.Iterator.
+func (it *MutationIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,117 @@
+package linkedql
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cayleygraph/quad"
+)
+
+// Parameter is implemented by items that refer to query parameters.
+type Parameter interface {
+	RegistryItem
+	// Bind returns an item that uses values of given parameters instead of this one.
+	// It returns the item itself if it doesn't refer to any parameter.
+	Bind(params map[string]quad.Value) (RegistryItem, error)
+}
+
+// Bind binds all parameters of the item to given values.
+// Parts of the item that refer to parameters are copied, thus the item itself is not modified
+// and can be shared between queries.
+func Bind(item RegistryItem, params map[string]quad.Value) (RegistryItem, error) {
+	v, _, err := bindValue(reflect.ValueOf(&item).Elem(), params)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface().(RegistryItem), nil
+}
+
+// bindValue returns a copy of the value with bound parameters. It returns the value itself
+// and false if the value doesn't refer to any parameter.
+func bindValue(v reflect.Value, params map[string]quad.Value) (reflect.Value, bool, error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false, nil
+		}
+		var (
+			nv      reflect.Value
+			changed bool
+		)
+		if p, ok := v.Interface().(Parameter); ok {
+			b, err := p.Bind(params)
+			if err != nil {
+				return v, false, err
+			}
+			nv, changed = reflect.ValueOf(b), b != RegistryItem(p)
+			if changed && !nv.Type().AssignableTo(v.Type()) {
+				return v, false, fmt.Errorf("cannot use %T as %v", b, v.Type())
+			}
+		} else {
+			var err error
+			nv, changed, err = bindValue(v.Elem(), params)
+			if err != nil {
+				return v, false, err
+			}
+		}
+		if !changed {
+			return v, false, nil
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(nv)
+		return out, true, nil
+	case reflect.Ptr:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return v, false, nil
+		}
+		ev, changed, err := bindValue(v.Elem(), params)
+		if err != nil || !changed {
+			return v, false, err
+		}
+		out := reflect.New(ev.Type())
+		out.Elem().Set(ev)
+		return out, true, nil
+	case reflect.Struct:
+		var out reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			fv, changed, err := bindValue(v.Field(i), params)
+			if err != nil {
+				return v, false, err
+			} else if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(v.Type()).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(fv)
+		}
+		if !out.IsValid() {
+			return v, false, nil
+		}
+		return out, true, nil
+	case reflect.Slice:
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			ev, changed, err := bindValue(v.Index(i), params)
+			if err != nil {
+				return v, false, err
+			} else if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(out, v)
+			}
+			out.Index(i).Set(ev)
+		}
+		if !out.IsValid() {
+			return v, false, nil
+		}
+		return out, true, nil
+	}
+	return v, false, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/jsonld"
 	"github.com/piprate/json-gold/ld"
 )
 
@@ -83,8 +84,24 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 		return nil, fmt.Errorf("unsupported item: %q", typ)
 	}
 	item := reflect.New(tp).Elem()
+	if err := unmarshalFields(item, m); err != nil {
+		return nil, err
+	}
+	return item.Addr().Interface().(RegistryItem), nil
+}
+
+// unmarshalFields sets fields of a struct from a normalized JSON-LD object.
+// Fields of embedded structs are set as if they were fields of the struct itself.
+func unmarshalFields(item reflect.Value, m map[string]json.RawMessage) error {
+	tp := item.Type()
 	for i := 0; i < tp.NumField(); i++ {
 		f := tp.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := unmarshalFields(item.Field(i), m); err != nil {
+				return err
+			}
+			continue
+		}
 		name := f.Name
 		tag := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
 		if tag == "-" {
@@ -102,19 +119,27 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			var a interface{}
 			err := json.Unmarshal(v, &a)
 			if err != nil {
-				return nil, err
+				return err
+			}
+			if arr, ok := a.([]interface{}); ok {
+				// multiple entities are compacted to an array
+				a = GraphPattern{"@graph": arr}
 			}
-			fv.Set(reflect.ValueOf(a))
+			pattern, ok := a.(GraphPattern)
+			if !ok {
+				return fmt.Errorf("Expected a JSON-LD document but received %v instead", a)
+			}
+			fv.Set(reflect.ValueOf(pattern))
 			continue
 		case quadValue:
 			var a interface{}
 			err := json.Unmarshal(v, &a)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			value, err := parseValue(a)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			fv.Set(reflect.ValueOf(value))
 			continue
@@ -122,7 +147,7 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			var a interface{}
 			err := json.Unmarshal(v, &a)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			arr, ok := a.([]interface{})
 			if !ok {
@@ -132,7 +157,7 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			for _, item := range arr {
 				value, err := parseValue(item)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				values = append(values, value)
 			}
@@ -142,15 +167,15 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			var a interface{}
 			err := json.Unmarshal(v, &a)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			s, ok := a.(string)
 			if !ok {
-				return nil, fmt.Errorf("Expected a string but received %v instead", a)
+				return fmt.Errorf("Expected a string but received %v instead", a)
 			}
 			val, err := parseIRI(s)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			fv.Set(reflect.ValueOf(val))
 			continue
@@ -158,7 +183,7 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			var a interface{}
 			err := json.Unmarshal(v, &a)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			arr, ok := a.([]interface{})
 			if !ok {
@@ -168,11 +193,11 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			for _, item := range arr {
 				s, ok := item.(string)
 				if !ok {
-					return nil, fmt.Errorf("Expected a string but received %v instead", item)
+					return fmt.Errorf("Expected a string but received %v instead", item)
 				}
 				val, err := parseIRI(s)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				values = append(values, val)
 			}
@@ -183,7 +208,7 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 		case reflect.Interface:
 			s, err := Unmarshal(v)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			fv.Set(reflect.ValueOf(s))
 		case reflect.Slice:
@@ -191,7 +216,12 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			if el.Kind() != reflect.Interface {
 				err := json.Unmarshal(v, fv.Addr().Interface())
 				if err != nil {
-					return nil, err
+					// a single value is compacted to a value instead of an array
+					ev := reflect.New(el)
+					if iErr := json.Unmarshal(v, ev.Interface()); iErr != nil {
+						return err
+					}
+					fv.Set(reflect.Append(reflect.MakeSlice(f.Type, 0, 1), ev.Elem()))
 				}
 			} else {
 				var arr []json.RawMessage
@@ -200,7 +230,7 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 					var i json.RawMessage
 					iErr := json.Unmarshal(v, &i)
 					if iErr != nil {
-						return nil, err
+						return err
 					}
 					arr = []json.RawMessage{i}
 				}
@@ -209,7 +239,7 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 					for i, v := range arr {
 						s, err := Unmarshal(v)
 						if err != nil {
-							return nil, err
+							return err
 						}
 						va.Index(i).Set(reflect.ValueOf(s))
 					}
@@ -218,12 +248,150 @@ func Unmarshal(data []byte) (RegistryItem, error) {
 			}
 		default:
 			err := json.Unmarshal(v, fv.Addr().Interface())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Marshal encodes an Item as a compacted JSON-LD document that can be decoded with Unmarshal.
+func Marshal(item RegistryItem) ([]byte, error) {
+	doc, err := marshalItem(item)
+	if err != nil {
+		return nil, err
+	}
+	ctx := map[string]interface{}{"@vocab": Namespace}
+	doc["@context"] = ctx
+	processor := ld.NewJsonLdProcessor()
+	opts := ld.NewJsonLdOptions("")
+	compact, err := processor.Compact(doc, map[string]interface{}{"@context": ctx}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(compact)
+}
+
+func marshalItem(item RegistryItem) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(item)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("unexpected nil item: %T", item)
+		}
+		rv = rv.Elem()
+	}
+	tp := rv.Type()
+	name, ok := nameByType[tp]
+	if !ok {
+		return nil, fmt.Errorf("unsupported item: %T", item)
+	}
+	m := map[string]interface{}{"@type": strings.TrimPrefix(name, Namespace)}
+	if err := marshalFields(rv, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// marshalFields adds non-zero fields of a struct to a JSON-LD object.
+func marshalFields(rv reflect.Value, m map[string]interface{}) error {
+	tp := rv.Type()
+	for i := 0; i < tp.NumField(); i++ {
+		f := tp.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := marshalFields(rv.Field(i), m); err != nil {
+				return err
+			}
+			continue
+		}
+		name := f.Name
+		tag := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fv := rv.Field(i)
+		if isZero(fv) {
+			continue
+		}
+		v, err := marshalField(fv)
+		if err != nil {
+			return err
+		}
+		m[name] = v
+	}
+	return nil
+}
+
+func marshalField(fv reflect.Value) (interface{}, error) {
+	switch v := fv.Interface().(type) {
+	case quad.Value:
+		return marshalValue(v), nil
+	case []quad.Value:
+		arr := make([]interface{}, 0, len(v))
+		for _, qv := range v {
+			arr = append(arr, marshalValue(qv))
+		}
+		return arr, nil
+	case quad.IRI:
+		return string(v), nil
+	case *PropertyPath:
+		if item, ok := v.PropertyPathI.(RegistryItem); ok {
+			return marshalItem(item)
+		}
+		return toJSONValue(v.PropertyPathI)
+	case RegistryItem:
+		return marshalItem(v)
+	}
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Interface {
+		arr := make([]interface{}, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			v, err := marshalField(fv.Index(i))
 			if err != nil {
 				return nil, err
 			}
+			arr = append(arr, v)
 		}
+		return arr, nil
 	}
-	return item.Addr().Interface().(RegistryItem), nil
+	return toJSONValue(fv.Interface())
+}
+
+// toJSONValue converts a value to a generic JSON form expected by the JSON-LD processor.
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// marshalValue converts a value to a form parsed by parseValue.
+func marshalValue(v quad.Value) interface{} {
+	switch v := v.(type) {
+	case quad.Int:
+		return int64(v)
+	case quad.Bool:
+		return bool(v)
+	case quad.Float:
+		if f := float64(v); f != float64(int64(f)) {
+			return f
+		}
+	}
+	return jsonld.FromValue(v)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.Interface() == reflect.Zero(v.Type()).Interface()
 }
 
 func normalizeQuery(data []byte) ([]byte, error) {
@@ -122,3 +122,15 @@ func TestUnmarshalStep(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalStep(t *testing.T) {
+	for _, c := range unmarshalCases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := Marshal(c.exp)
+			require.NoError(t, err)
+			s, err := Unmarshal(data)
+			require.NoError(t, err)
+			require.Equal(t, c.exp, s)
+		})
+	}
+}
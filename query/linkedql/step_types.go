@@ -1,6 +1,8 @@
 package linkedql
 
 import (
+	"context"
+
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/query/path"
@@ -23,3 +25,9 @@ type PathStep interface {
 	Step
 	BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error)
 }
+
+// MutationStep is a Step that modifies the graph.
+type MutationStep interface {
+	Step
+	BuildTransaction(ctx context.Context, qs graph.QuadStore, ns *voc.Namespaces) (*graph.Transaction, error)
+}
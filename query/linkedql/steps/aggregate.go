@@ -0,0 +1,50 @@
+package steps
+
+import (
+	"errors"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&Aggregate{})
+}
+
+var _ linkedql.PathStep = (*Aggregate)(nil)
+
+// Aggregate corresponds to .aggregate().
+type Aggregate struct {
+	From     linkedql.PathStep `json:"from"`
+	Function string            `json:"function"`
+	Name     string            `json:"name" minCardinality:"0"`
+	As       string            `json:"as" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *Aggregate) Description() string {
+	return "computes an aggregate function (count, sum, avg, min or max) of the values assigned to a given name for each group of the from step and assigns it to the as name (the function name by default). If the from step is not a GroupBy step, a single value is computed for all the resolved values. It resolves to the values of the from step."
+}
+
+// BuildPath implements linkedql.PathStep.
+func (s *Aggregate) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	fnc, err := iterator.ParseAggregateFunc(s.Function)
+	if err != nil {
+		return nil, err
+	}
+	if s.Name == "" && fnc != iterator.AggregateCount {
+		return nil, errors.New("aggregate: name is required for " + fnc.String())
+	}
+	fromPath, err := s.From.BuildPath(qs, ns)
+	if err != nil {
+		return nil, err
+	}
+	as := s.As
+	if as == "" {
+		as = fnc.String()
+	}
+	return fromPath.Aggregate(fnc, s.Name, as), nil
+}
@@ -0,0 +1,36 @@
+package steps
+
+import (
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&AllPaths{})
+}
+
+var _ linkedql.PathStep = (*AllPaths)(nil)
+
+// AllPaths corresponds to .allPaths().
+type AllPaths struct {
+	From       linkedql.PathStep      `json:"from"`
+	To         linkedql.PathStep      `json:"to"`
+	Properties *linkedql.PropertyPath `json:"properties" minCardinality:"0"`
+	MaxDepth   int                    `json:"maxDepth" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *AllPaths) Description() string {
+	return "resolves to the values of the to step that can be reached by following the given properties (or any property) from the current objects, once for each path without loops. The list of visited values and properties is saved to the \"path\" tag."
+}
+
+// BuildPath implements linkedql.PathStep.
+func (s *AllPaths) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	fromPath, toPath, via, err := buildPathSearch(qs, ns, s.From, s.To, s.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return fromPath.AllPaths(toPath, via, s.MaxDepth), nil
+}
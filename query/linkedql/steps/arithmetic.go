@@ -0,0 +1,145 @@
+package steps
+
+import (
+	"errors"
+
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+)
+
+func init() {
+	linkedql.Register(&Add{})
+	linkedql.Register(&Subtract{})
+	linkedql.Register(&Multiply{})
+	linkedql.Register(&Divide{})
+}
+
+var _ linkedql.Expression = (*Add)(nil)
+
+// Add is an expression that sums numbers.
+type Add struct {
+	Values []linkedql.Expression `json:"values"`
+}
+
+// Description implements Step.
+func (s *Add) Description() string {
+	return "resolves to the sum of the values of given expressions. It is undefined if any of the values is undefined or is not a number."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Add) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return arithmetic('+', s.Values, tags)
+}
+
+var _ linkedql.Expression = (*Subtract)(nil)
+
+// Subtract is an expression that subtracts numbers from the first one.
+type Subtract struct {
+	Values []linkedql.Expression `json:"values"`
+}
+
+// Description implements Step.
+func (s *Subtract) Description() string {
+	return "resolves to the value of the first expression minus the values of the rest of the expressions. It is undefined if any of the values is undefined or is not a number."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Subtract) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return arithmetic('-', s.Values, tags)
+}
+
+var _ linkedql.Expression = (*Multiply)(nil)
+
+// Multiply is an expression that multiplies numbers.
+type Multiply struct {
+	Values []linkedql.Expression `json:"values"`
+}
+
+// Description implements Step.
+func (s *Multiply) Description() string {
+	return "resolves to the product of the values of given expressions. It is undefined if any of the values is undefined or is not a number."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Multiply) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return arithmetic('*', s.Values, tags)
+}
+
+var _ linkedql.Expression = (*Divide)(nil)
+
+// Divide is an expression that divides the first number by the rest of numbers.
+type Divide struct {
+	Values []linkedql.Expression `json:"values"`
+}
+
+// Description implements Step.
+func (s *Divide) Description() string {
+	return "resolves to the value of the first expression divided by the values of the rest of the expressions. The result is always a floating point number. It is undefined if any of the values is undefined, is not a number or if it divides by zero."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Divide) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return arithmetic('/', s.Values, tags)
+}
+
+// toNumber converts a numeric value to a float and an integer, and reports if it's an integer.
+// The last return value is false if the value is not a number.
+func toNumber(v quad.Value) (f float64, i int64, isInt, ok bool) {
+	if ts, ok := v.(quad.TypedString); ok {
+		if pv, err := ts.ParseValue(); err == nil {
+			v = pv
+		}
+	}
+	switch v := v.(type) {
+	case quad.Int:
+		return float64(v), int64(v), true, true
+	case quad.Float:
+		return float64(v), int64(v), false, true
+	}
+	return 0, 0, false, false
+}
+
+// arithmetic applies an operation to the values of given expressions from left to right.
+// The result is an integer if all values are integers and the operation is not a division.
+func arithmetic(op byte, exprs []linkedql.Expression, tags map[string]quad.Value) (quad.Value, error) {
+	if len(exprs) == 0 {
+		return nil, errors.New("at least one value is required")
+	}
+	vals, err := evaluateAll(exprs, tags)
+	if vals == nil || err != nil {
+		return nil, err
+	}
+	var (
+		accF  float64
+		accI  int64
+		isInt = op != '/'
+	)
+	for i, v := range vals {
+		f, n, vInt, ok := toNumber(v)
+		if !ok {
+			return nil, nil
+		}
+		isInt = isInt && vInt
+		if i == 0 {
+			accF, accI = f, n
+			continue
+		}
+		switch op {
+		case '+':
+			accF, accI = accF+f, accI+n
+		case '-':
+			accF, accI = accF-f, accI-n
+		case '*':
+			accF, accI = accF*f, accI*n
+		case '/':
+			if f == 0 {
+				return nil, nil
+			}
+			accF = accF / f
+		}
+	}
+	if isInt {
+		return quad.Int(accI), nil
+	}
+	return quad.Float(accF), nil
+}
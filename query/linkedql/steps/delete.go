@@ -0,0 +1,100 @@
+package steps
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&Delete{})
+}
+
+var _ linkedql.MutationStep = (*Delete)(nil)
+
+// Delete corresponds to .delete().
+type Delete struct {
+	From       linkedql.PathStep      `json:"from" minCardinality:"0"`
+	Properties *linkedql.PropertyPath `json:"properties" minCardinality:"0"`
+	Document   linkedql.GraphPattern  `json:"document" minCardinality:"0"`
+	Quads      string                 `json:"quads" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *Delete) Description() string {
+	return "deletes quads of a JSON-LD document, a list of quads in N-Quads format and quads of the entities matched by the from step in a single transaction. If properties are given, only these properties of the matched entities are deleted, otherwise all quads referencing the entities are deleted. It resolves to the counts of inserted and deleted quads."
+}
+
+// BuildTransaction implements linkedql.MutationStep.
+func (s *Delete) BuildTransaction(ctx context.Context, qs graph.QuadStore, ns *voc.Namespaces) (*graph.Transaction, error) {
+	quads, err := readQuads(s.Document, s.Quads, ns)
+	if err != nil {
+		return nil, err
+	}
+	if s.From != nil {
+		matched, err := s.matchedQuads(ctx, qs, ns)
+		if err != nil {
+			return nil, err
+		}
+		quads = append(quads, matched...)
+	} else if s.Properties != nil {
+		return nil, errors.New("delete: properties require a from step")
+	} else if len(quads) == 0 {
+		return nil, errors.New("delete: no quads to delete")
+	}
+	return linkedql.NewTransaction(ctx, qs, quads, nil)
+}
+
+// matchedQuads returns quads of the entities matched by the from step.
+func (s *Delete) matchedQuads(ctx context.Context, qs graph.QuadStore, ns *voc.Namespaces) ([]quad.Quad, error) {
+	dirs := quad.Directions
+	var preds map[quad.Value]struct{}
+	if s.Properties != nil {
+		names, err := resolveNames(s.Properties)
+		if err != nil {
+			return nil, err
+		}
+		preds = make(map[quad.Value]struct{}, len(names))
+		for _, n := range names {
+			preds[quad.IRI(n).FullWith(ns)] = struct{}{}
+		}
+		dirs = []quad.Direction{quad.Subject}
+	}
+	p, err := s.From.BuildPath(qs, ns)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []graph.Ref
+	err = iterator.Iterate(ctx, p.BuildIterator(ctx)).EachValuePair(qs, func(ref graph.Ref, _ quad.Value) {
+		nodes = append(nodes, ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []quad.Quad
+	for _, ref := range nodes {
+		for _, d := range dirs {
+			it := qs.QuadIterator(d, ref).Iterate()
+			for it.Next(ctx) {
+				q := qs.Quad(it.Result())
+				if preds != nil {
+					if _, ok := preds[q.Predicate]; !ok {
+						continue
+					}
+				}
+				out = append(out, q)
+			}
+			err = it.Err()
+			it.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
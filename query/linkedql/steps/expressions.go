@@ -0,0 +1,186 @@
+package steps
+
+import (
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc/rdf"
+	"github.com/cayleygraph/quad/voc/xsd"
+)
+
+func init() {
+	linkedql.Register(&Compute{})
+	linkedql.Register(&Tag{})
+	linkedql.Register(&Constant{})
+	linkedql.Register(&Concat{})
+	linkedql.Register(&Lang{})
+	linkedql.Register(&Datatype{})
+}
+
+var _ linkedql.Expression = (*Compute)(nil)
+
+// Compute assigns a computed value to a name in the results of Select.
+type Compute struct {
+	Name  string              `json:"name"`
+	Value linkedql.Expression `json:"value"`
+}
+
+// Description implements Step.
+func (s *Compute) Description() string {
+	return "assigns the value of an expression to a name in each result of a Select step. Results with undefined values of the expression don't have the name."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Compute) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return s.Value.Evaluate(tags)
+}
+
+var _ linkedql.Expression = (*Tag)(nil)
+
+// Tag is an expression of a value assigned to a name in the query.
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Description implements Step.
+func (s *Tag) Description() string {
+	return "resolves to the value assigned to a given name in the query. It is undefined if the name is not assigned in a result."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Tag) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return tags[s.Name], nil
+}
+
+var _ linkedql.Expression = (*Constant)(nil)
+
+// Constant is an expression of a given value.
+type Constant struct {
+	Value quad.Value `json:"value"`
+}
+
+// Description implements Step.
+func (s *Constant) Description() string {
+	return "resolves to a given value."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Constant) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	return s.Value, nil
+}
+
+var _ linkedql.Expression = (*Concat)(nil)
+
+// Concat is an expression that joins string forms of values.
+type Concat struct {
+	Values []linkedql.Expression `json:"values"`
+}
+
+// Description implements Step.
+func (s *Concat) Description() string {
+	return "resolves to a string that joins the string forms of the values of given expressions. Strings are used as is, identifiers are converted to their IRIs and other literals to their lexical forms. It is undefined if any of the values is undefined."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Concat) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	vals, err := evaluateAll(s.Values, tags)
+	if vals == nil || err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, v := range vals {
+		out = append(out, lexicalForm(v)...)
+	}
+	return quad.String(out), nil
+}
+
+var _ linkedql.Expression = (*Lang)(nil)
+
+// Lang corresponds to lang() function of SPARQL.
+type Lang struct {
+	Value linkedql.Expression `json:"value"`
+}
+
+// Description implements Step.
+func (s *Lang) Description() string {
+	return "resolves to the language tag of a value of a given expression. It is an empty string for literals without a language tag and undefined for identifiers."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Lang) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	v, err := s.Value.Evaluate(tags)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case quad.IRI, quad.BNode:
+		return nil, nil
+	case quad.LangString:
+		return quad.String(v.Lang), nil
+	}
+	return quad.String(""), nil
+}
+
+var _ linkedql.Expression = (*Datatype)(nil)
+
+// Datatype corresponds to datatype() function of SPARQL.
+type Datatype struct {
+	Value linkedql.Expression `json:"value"`
+}
+
+// Description implements Step.
+func (s *Datatype) Description() string {
+	return "resolves to the IRI of the datatype of a value of a given expression. It is undefined for identifiers."
+}
+
+// Evaluate implements linkedql.Expression.
+func (s *Datatype) Evaluate(tags map[string]quad.Value) (quad.Value, error) {
+	v, err := s.Value.Evaluate(tags)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case quad.IRI, quad.BNode:
+		return nil, nil
+	case quad.String:
+		return quad.IRI(xsd.String).Full(), nil
+	case quad.LangString:
+		return quad.IRI(rdf.LangString).Full(), nil
+	case quad.TypedString:
+		return v.Type.Full(), nil
+	case quad.TypedStringer:
+		return v.TypedString().Type.Full(), nil
+	}
+	return nil, nil
+}
+
+// evaluateAll evaluates all given expressions. It returns nil if any of the values is undefined.
+func evaluateAll(exprs []linkedql.Expression, tags map[string]quad.Value) ([]quad.Value, error) {
+	out := make([]quad.Value, 0, len(exprs))
+	for _, e := range exprs {
+		v, err := e.Evaluate(tags)
+		if v == nil || err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// lexicalForm returns a string form of a value without quotes, language tags or types.
+func lexicalForm(v quad.Value) string {
+	switch v := v.(type) {
+	case quad.String:
+		return string(v)
+	case quad.LangString:
+		return string(v.Value)
+	case quad.IRI:
+		return string(v.Full())
+	case quad.BNode:
+		return v.String()
+	case quad.TypedString:
+		return string(v.Value)
+	case quad.TypedStringer:
+		return string(v.TypedString().Value)
+	}
+	return quad.ToString(v)
+}
@@ -0,0 +1,34 @@
+package steps
+
+import (
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&GroupBy{})
+}
+
+var _ linkedql.PathStep = (*GroupBy)(nil)
+
+// GroupBy corresponds to .groupBy().
+type GroupBy struct {
+	From linkedql.PathStep `json:"from"`
+	Name string            `json:"name"`
+}
+
+// Description implements Step.
+func (s *GroupBy) Description() string {
+	return "groups the resolved values of the from step by the values assigned to a given name. It resolves to the distinct values of the name. Use the Aggregate step to compute values for each group."
+}
+
+// BuildPath implements linkedql.PathStep.
+func (s *GroupBy) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	fromPath, err := s.From.BuildPath(qs, ns)
+	if err != nil {
+		return nil, err
+	}
+	return fromPath.GroupBy(s.Name), nil
+}
@@ -0,0 +1,38 @@
+package steps
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&Insert{})
+}
+
+var _ linkedql.MutationStep = (*Insert)(nil)
+
+// Insert corresponds to .insert().
+type Insert struct {
+	Document linkedql.GraphPattern `json:"document" minCardinality:"0"`
+	Quads    string                `json:"quads" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *Insert) Description() string {
+	return "inserts quads of a JSON-LD document and a list of quads in N-Quads format in a single transaction. Blank nodes are replaced with new blank nodes. It resolves to the counts of inserted and deleted quads."
+}
+
+// BuildTransaction implements linkedql.MutationStep.
+func (s *Insert) BuildTransaction(ctx context.Context, qs graph.QuadStore, ns *voc.Namespaces) (*graph.Transaction, error) {
+	quads, err := readQuads(s.Document, s.Quads, ns)
+	if err != nil {
+		return nil, err
+	} else if len(quads) == 0 {
+		return nil, errors.New("insert: no quads to insert")
+	}
+	return linkedql.NewTransaction(ctx, qs, nil, replaceBNodes(quads))
+}
@@ -0,0 +1,69 @@
+package steps
+
+import (
+	"strings"
+
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/nquads"
+	"github.com/cayleygraph/quad/voc"
+)
+
+// readNQuads parses a list of quads in N-Quads format.
+func readNQuads(s string) ([]quad.Quad, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return quad.ReadAll(nquads.NewReader(strings.NewReader(s), false))
+}
+
+// readQuads parses quads of a JSON-LD document and a list of quads in N-Quads format.
+func readQuads(doc linkedql.GraphPattern, nq string, ns *voc.Namespaces) ([]quad.Quad, error) {
+	var quads []quad.Quad
+	if len(doc) != 0 {
+		var err error
+		quads, err = parsePattern(doc, ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	list, err := readNQuads(nq)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]quad.Quad, 0, len(quads)+len(list))
+	for _, q := range append(quads, list...) {
+		if !isSingleEntityQuad(q) {
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+// replaceBNodes replaces blank nodes in quads with new blank nodes, so inserted quads
+// don't reference existing blank nodes. The same blank node is replaced with the same value.
+func replaceBNodes(quads []quad.Quad) []quad.Quad {
+	nodes := make(map[quad.BNode]quad.BNode)
+	replace := func(v quad.Value) quad.Value {
+		b, ok := v.(quad.BNode)
+		if !ok {
+			return v
+		}
+		n, ok := nodes[b]
+		if !ok {
+			n = quad.RandomBlankNode()
+			nodes[b] = n
+		}
+		return n
+	}
+	out := make([]quad.Quad, 0, len(quads))
+	for _, q := range quads {
+		out = append(out, quad.Quad{
+			Subject:   replace(q.Subject),
+			Predicate: q.Predicate,
+			Object:    replace(q.Object),
+			Label:     replace(q.Label),
+		})
+	}
+	return out
+}
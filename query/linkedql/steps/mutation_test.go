@@ -0,0 +1,219 @@
+package steps
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/writer"
+	"github.com/cayleygraph/quad"
+	"github.com/stretchr/testify/require"
+)
+
+var age = quad.IRI(ns + "age")
+
+var mutationTestData = []quad.Quad{
+	quad.Make(alice, likes, bob, nil),
+	quad.Make(alice, name, quad.String("Alice"), nil),
+	quad.Make(alice, age, quad.Int(30), nil),
+	quad.Make(bob, likes, alice, nil),
+	quad.Make(bob, age, quad.Int(41), nil),
+}
+
+var mutationTestCases = []struct {
+	name     string
+	query    string
+	inserted int
+	deleted  int
+	expected []string
+	err      bool
+}{
+	{
+		name: "insert document",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Insert",
+			"document": {
+				"@id": "http://example.com/carol",
+				"http://example.com/likes": [{"@id": "http://example.com/alice"}, {"@id": "http://example.com/bob"}],
+				"http://example.com/name": "Carol"
+			}
+		}`,
+		inserted: 3,
+		expected: []string{
+			`<http://example.com/carol> <http://example.com/likes> <http://example.com/alice> .`,
+			`<http://example.com/carol> <http://example.com/likes> <http://example.com/bob> .`,
+			`<http://example.com/carol> <http://example.com/name> "Carol" .`,
+		},
+	},
+	{
+		name: "insert quads",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Insert",
+			"quads": "<http://example.com/carol> <http://example.com/likes> <http://example.com/bob> <http://example.com/graph> .\n<http://example.com/alice> <http://example.com/likes> <http://example.com/bob> ."
+		}`,
+		inserted: 1,
+		expected: []string{
+			`<http://example.com/carol> <http://example.com/likes> <http://example.com/bob> <http://example.com/graph> .`,
+		},
+	},
+	{
+		name: "delete quads",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Delete",
+			"quads": "<http://example.com/alice> <http://example.com/likes> <http://example.com/bob> .\n<http://example.com/alice> <http://example.com/likes> <http://example.com/carol> ."
+		}`,
+		deleted: 1,
+		expected: []string{
+			`-<http://example.com/alice> <http://example.com/likes> <http://example.com/bob> .`,
+		},
+	},
+	{
+		name: "delete properties of matched entities",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Delete",
+			"from": {"@type": "Match", "pattern": {"http://example.com/likes": {"@id": "http://example.com/bob"}}},
+			"properties": ["http://example.com/age", "http://example.com/name"]
+		}`,
+		deleted: 2,
+		expected: []string{
+			`-<http://example.com/alice> <http://example.com/age> "30"^^<xsd:integer> .`,
+			`-<http://example.com/alice> <http://example.com/name> "Alice" .`,
+		},
+	},
+	{
+		name: "delete matched entities",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Delete",
+			"from": {"@type": "Match", "pattern": {"@id": "http://example.com/bob"}}
+		}`,
+		deleted: 3,
+		expected: []string{
+			`-<http://example.com/alice> <http://example.com/likes> <http://example.com/bob> .`,
+			`-<http://example.com/bob> <http://example.com/age> "41"^^<xsd:integer> .`,
+			`-<http://example.com/bob> <http://example.com/likes> <http://example.com/alice> .`,
+		},
+	},
+	{
+		name: "update",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Update",
+			"where": {
+				"@type": "As",
+				"name": "age",
+				"from": {
+					"@type": "Visit",
+					"properties": "http://example.com/age",
+					"from": {"@type": "As", "name": "person", "from": {"@type": "Match", "pattern": {}}}
+				}
+			},
+			"delete": "_:person <http://example.com/age> _:age .\n_:person <http://example.com/unknown> _:missing .",
+			"insert": "_:person <http://example.com/oldAge> _:age .\n_:person <http://example.com/age> \"50\"^^<http://www.w3.org/2001/XMLSchema#integer> ."
+		}`,
+		inserted: 4,
+		deleted:  2,
+		expected: []string{
+			`+<http://example.com/alice> <http://example.com/age> "50"^^<xsd:integer> .`,
+			`+<http://example.com/bob> <http://example.com/age> "50"^^<xsd:integer> .`,
+			`+<http://example.com/alice> <http://example.com/oldAge> "30"^^<xsd:integer> .`,
+			`+<http://example.com/bob> <http://example.com/oldAge> "41"^^<xsd:integer> .`,
+			`-<http://example.com/alice> <http://example.com/age> "30"^^<xsd:integer> .`,
+			`-<http://example.com/bob> <http://example.com/age> "41"^^<xsd:integer> .`,
+		},
+	},
+	{
+		name: "invalid quads",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Insert",
+			"quads": "<http://example.com/carol> <http://example.com/likes> ."
+		}`,
+		err: true,
+	},
+	{
+		name: "nothing to delete",
+		query: `{
+			"@context": {"@vocab": "http://cayley.io/linkedql#"},
+			"@type": "Delete"
+		}`,
+		err: true,
+	},
+}
+
+func TestMutations(t *testing.T) {
+	ctx := context.TODO()
+	for _, c := range mutationTestCases {
+		t.Run(c.name, func(t *testing.T) {
+			qs := memstore.New(mutationTestData...)
+			qw, err := writer.NewSingle(qs, graph.IgnoreOpts{})
+			require.NoError(t, err)
+			ses := linkedql.NewSession(qs)
+			ses.SetWriter(qw)
+
+			it, err := ses.Execute(ctx, c.query, query.Options{Collation: query.JSON})
+			require.NoError(t, err)
+			defer it.Close()
+			if c.err {
+				require.False(t, it.Next(ctx))
+				require.Error(t, it.Err())
+				return
+			}
+			require.True(t, it.Next(ctx))
+			require.NoError(t, it.Err())
+			require.Equal(t, map[string]interface{}{
+				"inserted": c.inserted,
+				"deleted":  c.deleted,
+			}, it.Result())
+			require.False(t, it.Next(ctx))
+
+			// compare the resulting graph with the original one
+			orig := make(map[string]struct{})
+			for _, q := range mutationTestData {
+				orig[q.NQuad()] = struct{}{}
+			}
+			var got []string
+			all := qs.QuadsAllIterator().Iterate()
+			for all.Next(ctx) {
+				s := qs.Quad(all.Result()).NQuad()
+				if _, ok := orig[s]; ok {
+					delete(orig, s)
+					continue
+				}
+				got = append(got, s)
+			}
+			require.NoError(t, all.Err())
+			all.Close()
+			for s := range orig {
+				got = append(got, "-"+s)
+			}
+			sort.Strings(got)
+			expected := append([]string{}, c.expected...)
+			for i, s := range expected {
+				if s[0] == '+' {
+					expected[i] = s[1:]
+				}
+			}
+			sort.Strings(expected)
+			require.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestMutationReadOnly(t *testing.T) {
+	qs := memstore.New(mutationTestData...)
+	_, err := linkedql.NewSession(qs).Execute(context.TODO(), `{
+		"@context": {"@vocab": "http://cayley.io/linkedql#"},
+		"@type": "Delete",
+		"quads": "<http://example.com/alice> <http://example.com/likes> <http://example.com/bob> ."
+	}`, query.Options{Collation: query.JSON})
+	require.Equal(t, linkedql.ErrReadOnly, err)
+}
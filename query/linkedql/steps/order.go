@@ -1,6 +1,8 @@
 package steps
 
 import (
+	"errors"
+
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/query/linkedql"
 	"github.com/cayleygraph/cayley/query/path"
@@ -15,12 +17,15 @@ var _ linkedql.PathStep = (*Order)(nil)
 
 // Order corresponds to .order().
 type Order struct {
-	From linkedql.PathStep `json:"from"`
+	From       linkedql.PathStep      `json:"from"`
+	Name       string                 `json:"name" minCardinality:"0"`
+	Property   *linkedql.PropertyPath `json:"property" minCardinality:"0"`
+	Descending bool                   `json:"descending" minCardinality:"0"`
 }
 
 // Description implements Step.
 func (s *Order) Description() string {
-	return "sorts the results in ascending order according to the current entity / value"
+	return "sorts the results according to the value of a name, a property or the current entity / value if neither is set. Values are compared by their types. Results without a value are placed last. The order is stable, so multiple keys can be used by nesting Order steps, with the outermost step being the primary key."
 }
 
 // BuildPath implements linkedql.PathStep.
@@ -29,5 +34,15 @@ func (s *Order) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, e
 	if err != nil {
 		return nil, err
 	}
-	return fromPath.Order(), nil
+	if s.Name != "" && s.Property != nil {
+		return nil, errors.New("order: only one of name and property can be set")
+	}
+	key := path.OrderKey{Tag: s.Name, Desc: s.Descending}
+	if s.Property != nil {
+		key.Via, err = s.Property.BuildPath(qs, ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fromPath.OrderBy(key), nil
 }
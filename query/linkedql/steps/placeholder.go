@@ -1,9 +1,12 @@
 package steps
 
 import (
+	"fmt"
+
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/query/linkedql"
 	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad"
 	"github.com/cayleygraph/quad/voc"
 )
 
@@ -12,16 +15,52 @@ func init() {
 }
 
 var _ linkedql.PathStep = (*Placeholder)(nil)
+var _ linkedql.Parameter = (*Placeholder)(nil)
 
 // Placeholder corresponds to .Placeholder().
-type Placeholder struct{}
+type Placeholder struct {
+	Name string `json:"name" minCardinality:"0"`
+}
 
 // Description implements Step.
 func (s *Placeholder) Description() string {
-	return "is like Vertex but resolves to the values in the context it is placed in. It should only be used where a linkedql.PathStep is expected and can't be resolved on its own."
+	return "is like Vertex but resolves to the values in the context it is placed in. It should only be used where a linkedql.PathStep is expected and can't be resolved on its own. If a name is set, it resolves to the value of a query parameter with this name instead."
 }
 
 // BuildPath implements linkedql.PathStep.
 func (s *Placeholder) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	if s.Name != "" {
+		return nil, fmt.Errorf("parameter is not set: %q", s.Name)
+	}
 	return path.StartMorphism(), nil
 }
+
+// Bind implements linkedql.Parameter.
+func (s *Placeholder) Bind(params map[string]quad.Value) (linkedql.RegistryItem, error) {
+	if s.Name == "" {
+		return s, nil
+	}
+	v, ok := params[s.Name]
+	if !ok || v == nil {
+		return nil, fmt.Errorf("parameter is not set: %q", s.Name)
+	}
+	return &boundPlaceholder{name: s.Name, value: v}, nil
+}
+
+var _ linkedql.PathStep = (*boundPlaceholder)(nil)
+
+// boundPlaceholder is a named Placeholder bound to a value of a query parameter.
+type boundPlaceholder struct {
+	name  string
+	value quad.Value
+}
+
+// Description implements Step.
+func (s *boundPlaceholder) Description() string {
+	return "resolves to the value of the " + s.name + " parameter."
+}
+
+// BuildPath implements linkedql.PathStep.
+func (s *boundPlaceholder) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	return path.StartPath(qs, s.value), nil
+}
@@ -0,0 +1,34 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+)
+
+func TestPlaceholderBind(t *testing.T) {
+	step := &Visit{
+		From:       &Intersect{From: &Vertex{}, Steps: []linkedql.PathStep{&Placeholder{Name: "who"}}},
+		Properties: &linkedql.PropertyPath{},
+	}
+	item, err := linkedql.Bind(step, map[string]quad.Value{"who": quad.IRI("alice")})
+	require.NoError(t, err)
+	bound := item.(*Visit)
+	require.True(t, bound != step, "expected a copy of the step")
+	require.Equal(t, step.Properties, bound.Properties)
+	require.Equal(t, &boundPlaceholder{name: "who", value: quad.IRI("alice")}, bound.From.(*Intersect).Steps[0])
+	// original step is not modified
+	require.Equal(t, &Placeholder{Name: "who"}, step.From.(*Intersect).Steps[0])
+
+	_, err = linkedql.Bind(step, nil)
+	require.Error(t, err)
+
+	// steps without named placeholders are not copied
+	plain := &Visit{From: &Placeholder{}}
+	item, err = linkedql.Bind(plain, nil)
+	require.NoError(t, err)
+	require.True(t, item.(*Visit) == plain)
+}
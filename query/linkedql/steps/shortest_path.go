@@ -0,0 +1,56 @@
+package steps
+
+import (
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&ShortestPath{})
+}
+
+var _ linkedql.PathStep = (*ShortestPath)(nil)
+
+// ShortestPath corresponds to .shortestPath().
+type ShortestPath struct {
+	From       linkedql.PathStep      `json:"from"`
+	To         linkedql.PathStep      `json:"to"`
+	Properties *linkedql.PropertyPath `json:"properties" minCardinality:"0"`
+	MaxDepth   int                    `json:"maxDepth" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *ShortestPath) Description() string {
+	return "resolves to the values of the to step that are reached first by following the given properties (or any property) from the current objects. The list of visited values and properties is saved to the \"path\" tag."
+}
+
+// BuildPath implements linkedql.PathStep.
+func (s *ShortestPath) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	fromPath, toPath, via, err := buildPathSearch(qs, ns, s.From, s.To, s.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return fromPath.ShortestPath(toPath, via, s.MaxDepth), nil
+}
+
+// buildPathSearch builds paths for the arguments of ShortestPath and AllPaths.
+func buildPathSearch(qs graph.QuadStore, ns *voc.Namespaces, from, to linkedql.PathStep, props *linkedql.PropertyPath) (*path.Path, *path.Path, interface{}, error) {
+	fromPath, err := from.BuildPath(qs, ns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	toPath, err := to.BuildPath(qs, ns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if props == nil || props.PropertyPathI == nil {
+		return fromPath, toPath, nil, nil
+	}
+	viaPath, err := props.BuildPath(qs, ns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return fromPath, toPath, viaPath, nil
+}
@@ -1,6 +1,8 @@
 package steps
 
 import (
+	"fmt"
+
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/query/linkedql"
@@ -16,14 +18,15 @@ var _ linkedql.IteratorStep = (*Select)(nil)
 
 // Select corresponds to .select().
 type Select struct {
-	Properties []string          `json:"properties"`
-	From       linkedql.PathStep `json:"from"`
-	ExcludeID  bool              `json:"excludeID"`
+	Properties []string              `json:"properties"`
+	From       linkedql.PathStep     `json:"from"`
+	ExcludeID  bool                  `json:"excludeID"`
+	Computed   []linkedql.Expression `json:"computed"`
 }
 
 // Description implements Step.
 func (s *Select) Description() string {
-	return "Select returns flat records of tags matched in the query"
+	return "Select returns flat records of tags matched in the query and values computed from them"
 }
 
 // BuildIterator implements IteratorStep
@@ -33,6 +36,13 @@ func (s *Select) BuildIterator(qs graph.QuadStore, ns *voc.Namespaces) (query.It
 		return nil, err
 	}
 	it := linkedql.NewTagsIterator(valueIt, s.Properties, s.ExcludeID)
+	for _, e := range s.Computed {
+		c, ok := e.(*Compute)
+		if !ok {
+			return nil, fmt.Errorf("select: computed values must be named with Compute, got %T", e)
+		}
+		it.Computed = append(it.Computed, linkedql.Projection{Name: c.Name, Expression: c.Value})
+	}
 	return &it, nil
 }
 
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/query/linkedql"
 	"github.com/cayleygraph/quad"
 	"github.com/cayleygraph/quad/jsonld"
@@ -19,9 +20,10 @@ import (
 )
 
 type TestCase struct {
-	Data    interface{} `json:"data"`
-	Query   interface{} `json:"query"`
-	Results interface{} `json:"results"`
+	Data    interface{}     `json:"data"`
+	Query   interface{}     `json:"query"`
+	Params  json.RawMessage `json:"params"`
+	Results interface{}     `json:"results"`
 }
 
 func readData(data interface{}) ([]quad.Quad, error) {
@@ -38,7 +40,7 @@ func readData(data interface{}) ([]quad.Quad, error) {
 	return quads, nil
 }
 
-func readQuery(raw interface{}) (linkedql.Step, error) {
+func readQuery(raw interface{}, params json.RawMessage) (linkedql.Step, error) {
 	d, err := json.Marshal(raw)
 	if err != nil {
 		return nil, err
@@ -47,6 +49,15 @@ func readQuery(raw interface{}) (linkedql.Step, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(params) != 0 {
+		p, err := query.ParseParams(params)
+		if err != nil {
+			return nil, err
+		}
+		if q, err = linkedql.Bind(q, p); err != nil {
+			return nil, err
+		}
+	}
 	query, ok := q.(linkedql.Step)
 	if !ok {
 		return nil, fmt.Errorf("Expected linkedql.Step")
@@ -78,7 +89,7 @@ func TestLinkedQL(t *testing.T) {
 			require.NoError(t, err, fileName)
 			require.NotEmpty(t, data, fileName)
 
-			query, err := readQuery(c.Query)
+			query, err := readQuery(c.Query, c.Params)
 			require.NoError(t, err, fileName)
 			require.NotNil(t, query, fileName)
 			store := memstore.New(data...)
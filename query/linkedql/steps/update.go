@@ -0,0 +1,105 @@
+package steps
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&Update{})
+}
+
+var _ linkedql.MutationStep = (*Update)(nil)
+
+// Update corresponds to .update().
+type Update struct {
+	Where  linkedql.PathStep `json:"where"`
+	Delete string            `json:"delete" minCardinality:"0"`
+	Insert string            `json:"insert" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *Update) Description() string {
+	return "deletes and inserts quads for each result of the where step in a single transaction, like SPARQL Update. Templates are lists of quads in N-Quads format, where blank nodes refer to the values of the names assigned in the where step. Template quads with names that are not assigned in a result are skipped. Other blank nodes in inserted quads are replaced with new blank nodes for each result. It resolves to the counts of inserted and deleted quads."
+}
+
+// BuildTransaction implements linkedql.MutationStep.
+func (s *Update) BuildTransaction(ctx context.Context, qs graph.QuadStore, ns *voc.Namespaces) (*graph.Transaction, error) {
+	delTmpl, err := readNQuads(s.Delete)
+	if err != nil {
+		return nil, err
+	}
+	insTmpl, err := readNQuads(s.Insert)
+	if err != nil {
+		return nil, err
+	}
+	if len(delTmpl) == 0 && len(insTmpl) == 0 {
+		return nil, errors.New("update: no templates are given")
+	}
+	p, err := s.Where.BuildPath(qs, ns)
+	if err != nil {
+		return nil, err
+	}
+	var del, ins []quad.Quad
+	err = iterator.Iterate(ctx, p.BuildIterator(ctx)).Paths(true).TagValues(qs, func(tags map[string]quad.Value) {
+		del = append(del, instantiate(delTmpl, tags, false)...)
+		ins = append(ins, instantiate(insTmpl, tags, true)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return linkedql.NewTransaction(ctx, qs, del, ins)
+}
+
+// instantiate replaces blank nodes in the template quads with the values of names with the same label.
+// Quads with blank nodes that have no value are skipped, or get new blank nodes if fresh is set.
+func instantiate(tmpl []quad.Quad, tags map[string]quad.Value, fresh bool) []quad.Quad {
+	var nodes map[quad.BNode]quad.BNode
+	bind := func(v quad.Value) (quad.Value, bool) {
+		b, ok := v.(quad.BNode)
+		if !ok {
+			return v, true
+		}
+		if tv, ok := tags[string(b)]; ok && tv != nil {
+			return tv, true
+		}
+		if !fresh {
+			return nil, false
+		}
+		if nodes == nil {
+			nodes = make(map[quad.BNode]quad.BNode)
+		}
+		n, ok := nodes[b]
+		if !ok {
+			n = quad.RandomBlankNode()
+			nodes[b] = n
+		}
+		return n, true
+	}
+	out := make([]quad.Quad, 0, len(tmpl))
+next:
+	for _, t := range tmpl {
+		var q quad.Quad
+		for _, d := range quad.Directions {
+			v := t.Get(d)
+			if v == nil {
+				continue
+			}
+			v, ok := bind(v)
+			if !ok {
+				continue next
+			}
+			q.Set(d, v)
+		}
+		if q.IsValid() {
+			out = append(out, q)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package steps
+
+import (
+	"errors"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query/linkedql"
+	"github.com/cayleygraph/cayley/query/path"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/voc"
+)
+
+func init() {
+	linkedql.Register(&WeightedShortestPath{})
+}
+
+var _ linkedql.PathStep = (*WeightedShortestPath)(nil)
+
+// WeightedShortestPath corresponds to .weightedShortestPath().
+type WeightedShortestPath struct {
+	From       linkedql.PathStep      `json:"from"`
+	To         linkedql.PathStep      `json:"to"`
+	Properties *linkedql.PropertyPath `json:"properties" minCardinality:"0"`
+	Next       *linkedql.PropertyPath `json:"next" minCardinality:"0"`
+	Weight     quad.IRI               `json:"weight"`
+	Heuristic  quad.IRI               `json:"heuristic" minCardinality:"0"`
+}
+
+// Description implements Step.
+func (s *WeightedShortestPath) Description() string {
+	return "resolves to the values of the to step ordered by the total weight of the cheapest path from the current objects. Weight is a property of the quad label, or of the edge value if next properties are set. The list of visited values and properties is saved to the \"path\" tag and the total weight to the \"cost\" tag."
+}
+
+// BuildPath implements linkedql.PathStep.
+func (s *WeightedShortestPath) BuildPath(qs graph.QuadStore, ns *voc.Namespaces) (*path.Path, error) {
+	if s.Weight == "" {
+		return nil, errors.New("weight property is not set")
+	}
+	fromPath, toPath, via, err := buildPathSearch(qs, ns, s.From, s.To, s.Properties)
+	if err != nil {
+		return nil, err
+	}
+	w := path.Weights{
+		Via:    via,
+		Weight: s.Weight.FullWith(ns),
+	}
+	if s.Next != nil && s.Next.PropertyPathI != nil {
+		if w.Next, err = s.Next.BuildPath(qs, ns); err != nil {
+			return nil, err
+		}
+	}
+	if s.Heuristic != "" {
+		w.Heuristic = s.Heuristic.FullWith(ns)
+	}
+	return fromPath.WeightedShortestPath(toPath, w), nil
+}
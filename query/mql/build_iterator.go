@@ -21,6 +21,7 @@ import (
 	"math"
 	"strings"
 
+	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/query/shape"
 	"github.com/cayleygraph/quad"
 )
@@ -29,6 +30,27 @@ func buildFixed(s string) shape.Shape {
 	return shape.Lookup{quad.StringToValue(s)}
 }
 
+// jsonValue converts a JSON scalar to a value. It returns false if the value is not a scalar.
+func jsonValue(v interface{}) (quad.Value, bool) {
+	switch t := v.(type) {
+	case bool:
+		// for JSON booleans
+		return quad.Bool(t), true
+	case float64:
+		// for JSON numbers
+		// Damn you, Javascript, and your lack of integer values.
+		if math.Floor(t) == t {
+			// Treat it like an integer.
+			return quad.Int(t), true
+		}
+		return quad.Float(t), true
+	case string:
+		// for JSON strings
+		return quad.StringToValue(t), true
+	}
+	return nil, false
+}
+
 func buildAllResult(path Path) shape.Shape {
 	return shape.Save{
 		From: shape.AllNodes{},
@@ -39,8 +61,7 @@ func buildAllResult(path Path) shape.Shape {
 func (q *Query) BuildIteratorTree(ctx context.Context, query interface{}) {
 	q.isRepeated = make(map[Path]bool)
 	q.queryStructure = make(map[Path]map[string]interface{})
-	q.queryResult = make(map[ResultPath]map[string]interface{})
-	q.queryResult[""] = make(map[string]interface{})
+	q.resetResults()
 
 	var (
 		opt bool
@@ -50,28 +71,19 @@ func (q *Query) BuildIteratorTree(ctx context.Context, query interface{}) {
 	if q.err == nil && opt {
 		q.err = errors.New("optional iterator at the top level")
 	}
-	q.it = shape.BuildIterator(ctx, q.ses.qs, s)
+	// the same tree is used to look up all the paths of each top-level value in collectResults
+	q.paths = shape.BuildIterator(ctx, q.ses.qs, s)
+	q.it = iterator.NewUnique(q.paths)
 }
 
 func (q *Query) buildShape(query interface{}, path Path) (s shape.Shape, optional bool, err error) {
 	err = nil
 	optional = false
 	switch t := query.(type) {
-	case bool:
-		// for JSON booleans
-		s = shape.Lookup{quad.Bool(t)}
-	case float64:
-		// for JSON numbers
-		// Damn you, Javascript, and your lack of integer values.
-		if math.Floor(t) == t {
-			// Treat it like an integer.
-			s = shape.Lookup{quad.Int(t)}
-		} else {
-			s = shape.Lookup{quad.Float(t)}
-		}
-	case string:
-		// for JSON strings
-		s = buildFixed(t)
+	case bool, float64, string:
+		// for JSON scalars
+		v, _ := jsonValue(t)
+		s = shape.Lookup{v}
 	case []interface{}:
 		// for JSON arrays
 		q.isRepeated[path] = true
@@ -112,18 +124,7 @@ func (q *Query) buildShapeMap(query map[string]interface{}, path Path) (shape.Sh
 	for key, subquery := range query {
 		optional := false
 		outputStructure[key] = nil
-		reverse := false
-		pred := key
-		if strings.HasPrefix(pred, "@") {
-			i := strings.Index(pred, ":")
-			if i != -1 {
-				pred = pred[(i + 1):]
-			}
-		}
-		if strings.HasPrefix(pred, "!") {
-			reverse = true
-			pred = strings.TrimPrefix(pred, "!")
-		}
+		pred, reverse := parseKey(key)
 
 		// Other special constructs here
 		var subit shape.Shape
@@ -167,6 +168,23 @@ func (q *Query) buildShapeMap(query map[string]interface{}, path Path) (shape.Sh
 	return it, nil
 }
 
+// parseKey returns a predicate of a query key and reports if it should be followed in reverse.
+// Keys may have a "@name:" prefix to use the same predicate multiple times, and a "!" prefix for reverse links.
+func parseKey(key string) (pred string, reverse bool) {
+	pred = key
+	if strings.HasPrefix(pred, "@") {
+		i := strings.Index(pred, ":")
+		if i != -1 {
+			pred = pred[(i + 1):]
+		}
+	}
+	if strings.HasPrefix(pred, "!") {
+		reverse = true
+		pred = strings.TrimPrefix(pred, "!")
+	}
+	return pred, reverse
+}
+
 type byRecordLength []ResultPath
 
 func (p byRecordLength) Len() int {
@@ -15,10 +15,12 @@
 package mql
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/quad"
 )
 
@@ -113,6 +115,28 @@ func (q *Query) treeifyResult(tags map[string]graph.Ref) map[ResultPath]string {
 	return resultPaths
 }
 
+// collectResults builds results for a single top-level value from all the paths of the iterator tree.
+// Paths are looked up in the index of the tree, thus the cost is a single Contains call per value,
+// instead of running the query again.
+func (q *Query) collectResults(ctx context.Context, idx iterator.Index, ref graph.Ref) error {
+	q.resetResults()
+	if idx.Contains(ctx, ref) {
+		m := make(map[string]graph.Ref)
+		idx.TagResults(m)
+		q.treeifyResult(m)
+		for idx.NextPath(ctx) {
+			m = make(map[string]graph.Ref, len(m))
+			idx.TagResults(m)
+			q.treeifyResult(m)
+		}
+	}
+	if err := idx.Err(); err != nil {
+		return err
+	}
+	q.buildResults()
+	return nil
+}
+
 func (q *Query) buildResults() {
 	for _, v := range q.resultOrder {
 		q.results = append(q.results, q.queryResult[""][v])
@@ -17,6 +17,7 @@ package mql
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -162,7 +163,10 @@ var testQueries = []struct {
 }
 
 func runQuery(t testing.TB, g []quad.Quad, qu string) interface{} {
-	s := makeTestSession(g)
+	return runQueryOn(t, makeTestSession(g), qu)
+}
+
+func runQueryOn(t testing.TB, s *Session, qu string) interface{} {
 	ctx := context.TODO()
 	it, err := s.Execute(ctx, qu, query.Options{Collation: query.JSON})
 	if err != nil {
@@ -187,3 +191,150 @@ func TestMQL(t *testing.T) {
 		})
 	}
 }
+
+var testWrites = []struct {
+	message string
+	query   string
+	expect  string
+	added   []quad.Quad
+	removed []quad.Quad
+}{
+	{
+		message: "insert a link",
+		query:   `{"id": "<alice>", "<follows>": {"id": "<fred>", "connect": "insert"}}`,
+		expect:  `[{"id": "<alice>", "<follows>": {"id": "<fred>", "connect": "inserted"}}]`,
+		added:   []quad.Quad{quad.MakeIRI("alice", "follows", "fred", "")},
+	},
+	{
+		message: "insert an existing link",
+		query:   `{"id": "<alice>", "<follows>": {"id": "<bob>", "connect": "insert"}}`,
+		expect:  `[{"id": "<alice>", "<follows>": {"id": "<bob>", "connect": "present"}}]`,
+	},
+	{
+		message: "insert a value",
+		query:   `[{"id": "<alice>", "<status>": [{"value": "smart_person", "connect": "insert"}]}]`,
+		expect:  `[{"id": "<alice>", "<status>": [{"value": "smart_person", "connect": "inserted"}]}]`,
+		added:   []quad.Quad{quad.Make(quad.IRI("alice"), quad.IRI("status"), "smart_person", nil)},
+	},
+	{
+		message: "delete links",
+		query: `{"id": "<charlie>", "<follows>": [
+			{"id": "<bob>", "connect": "delete"},
+			{"id": "<alice>", "connect": "delete"}
+		]}`,
+		expect: `[{"id": "<charlie>", "<follows>": [
+			{"id": "<bob>", "connect": "deleted"},
+			{"id": "<alice>", "connect": "absent"}
+		]}]`,
+		removed: []quad.Quad{quad.MakeIRI("charlie", "follows", "bob", "")},
+	},
+	{
+		message: "insert a reverse link",
+		query:   `{"id": "<alice>", "!<follows>": {"id": "<greg>", "connect": "insert"}}`,
+		expect:  `[{"id": "<alice>", "!<follows>": {"id": "<greg>", "connect": "inserted"}}]`,
+		added:   []quad.Quad{quad.MakeIRI("greg", "follows", "alice", "")},
+	},
+	{
+		message: "create an existing node",
+		query:   `{"id": null, "create": "unless_exists", "<status>": "cool_person", "<follows>": "<fred>"}`,
+		expect:  `[{"id": "<bob>", "create": "existed", "<status>": "cool_person", "<follows>": "<fred>"}]`,
+	},
+	{
+		message: "create a new node",
+		query: `{"id": "<harry>", "create": "unless_exists", "<follows>": "<alice>",
+			"!<follows>": {"id": "<bob>"}}`,
+		expect: `[{"id": "<harry>", "create": "created", "<follows>": "<alice>",
+			"!<follows>": {"id": "<bob>"}}]`,
+		added: []quad.Quad{
+			quad.MakeIRI("harry", "follows", "alice", ""),
+			quad.MakeIRI("bob", "follows", "harry", ""),
+		},
+	},
+	{
+		message: "create a linked node",
+		query:   `{"id": "<alice>", "<follows>": {"id": "<harry>", "create": "unconditional"}}`,
+		expect:  `[{"id": "<alice>", "<follows>": {"id": "<harry>", "create": "created"}}]`,
+		added:   []quad.Quad{quad.MakeIRI("alice", "follows", "harry", "")},
+	},
+}
+
+func TestMQLWrite(t *testing.T) {
+	simpleGraph := testutil.LoadGraph(t, "../../data/testdata.nq")
+	ctx := context.TODO()
+	for _, test := range testWrites {
+		t.Run(test.message, func(t *testing.T) {
+			qs, _ := graph.NewQuadStore("memstore", "", nil)
+			w, _ := graph.NewQuadWriter("single", qs, nil)
+			require.NoError(t, w.AddQuadSet(simpleGraph))
+			s := NewSession(qs)
+			s.SetWriter(w)
+
+			it, err := s.Execute(ctx, test.query, query.Options{Collation: query.JSON})
+			require.NoError(t, err)
+			var got []interface{}
+			for it.Next(ctx) {
+				got = append(got, it.Result())
+			}
+			require.NoError(t, it.Err())
+			var expect interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.expect), &expect))
+			require.Equal(t, expect, toJSON(t, got))
+
+			for _, q := range test.added {
+				ok, err := graph.HasQuad(ctx, qs, q)
+				require.NoError(t, err)
+				require.True(t, ok, "expected %v to be added", q)
+			}
+			for _, q := range test.removed {
+				ok, err := graph.HasQuad(ctx, qs, q)
+				require.NoError(t, err)
+				require.False(t, ok, "expected %v to be removed", q)
+			}
+		})
+	}
+}
+
+func TestMQLWriteCreated(t *testing.T) {
+	ctx := context.TODO()
+	s := makeTestSession(nil)
+	w, _ := graph.NewQuadWriter("single", s.qs, nil)
+	s.SetWriter(w)
+	it, err := s.Execute(ctx, `{"create": "unconditional", "<name>": "Harry"}`, query.Options{Collation: query.JSON})
+	require.NoError(t, err)
+	require.True(t, it.Next(ctx))
+	res := it.Result().(map[string]interface{})
+	require.Equal(t, "created", res["create"])
+	id, _ := res["id"].(string)
+	require.True(t, strings.HasPrefix(id, "_:"), "expected a blank node, got %q", id)
+
+	got := runQueryOn(t, s, `[{"id": null, "<name>": "Harry"}]`)
+	require.Equal(t, []interface{}{map[string]interface{}{"id": id, "<name>": "Harry"}}, got)
+}
+
+func TestMQLWriteErrors(t *testing.T) {
+	simpleGraph := testutil.LoadGraph(t, "../../data/testdata.nq")
+	ctx := context.TODO()
+	s := makeTestSession(simpleGraph)
+	_, err := s.Execute(ctx, `{"id": "<alice>", "<follows>": {"id": "<fred>", "connect": "insert"}}`, query.Options{Collation: query.JSON})
+	require.Equal(t, ErrReadOnly, err)
+
+	w, _ := graph.NewQuadWriter("single", s.qs, nil)
+	s.SetWriter(w)
+	for _, q := range []string{
+		`{"<follows>": {"id": "<fred>", "connect": "insert"}}`,
+		`{"id": "<alice>", "connect": "insert"}`,
+		`{"id": "<alice>", "<follows>": {"id": "<fred>", "connect": "replace"}}`,
+		`{"create": "unless_exists", "<status>": "cool_person"}`,
+	} {
+		_, err = s.Execute(ctx, q, query.Options{Collation: query.JSON})
+		require.Error(t, err, q)
+	}
+}
+
+func toJSON(t testing.TB, v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out interface{}
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
@@ -29,6 +29,7 @@ type (
 type Query struct {
 	ses            *Session
 	it             iterator.Shape
+	paths          iterator.Shape
 	isRepeated     map[Path]bool
 	queryStructure map[Path]map[string]interface{}
 	queryResult    map[ResultPath]map[string]interface{}
@@ -41,6 +42,14 @@ func (q *Query) isError() bool {
 	return q.err != nil
 }
 
+// resetResults clears results collected by treeifyResult.
+func (q *Query) resetResults() {
+	q.queryResult = make(map[ResultPath]map[string]interface{})
+	q.queryResult[""] = make(map[string]interface{})
+	q.results = nil
+	q.resultOrder = nil
+}
+
 func (q *Query) copyPathStructure(path Path) map[string]interface{} {
 	output := make(map[string]interface{})
 	for k, v := range q.queryStructure[path] {
@@ -108,6 +117,8 @@ func (p Path) ToResultPathFromMap(resultMap map[Path]string) ResultPath {
 func NewQuery(ses *Session) *Query {
 	var q Query
 	q.ses = ses
+	q.isRepeated = make(map[Path]bool)
+	q.queryStructure = make(map[Path]map[string]interface{})
 	q.err = nil
 	return &q
 }
@@ -34,61 +34,68 @@ func init() {
 	})
 }
 
+var _ query.WriterSession = (*Session)(nil)
+
 type Session struct {
 	qs graph.QuadStore
+	qw graph.QuadWriter
 }
 
 func NewSession(qs graph.QuadStore) *Session {
 	return &Session{qs: qs}
 }
 
+// SetWriter sets a QuadWriter used by write queries.
+func (s *Session) SetWriter(qw graph.QuadWriter) {
+	s.qw = qw
+}
+
 type mqlIterator struct {
 	q   *Query
 	col query.Collation
 	it  iterator.Scanner
-	res []interface{}
+	idx iterator.Index
+	res interface{}
+	err error
 }
 
+// Next implements query.Iterator. The iterator tree resolves to distinct top-level values,
+// and a result is built for each of them as it is found, thus results are streamed.
 func (it *mqlIterator) Next(ctx context.Context) bool {
-	// TODO: stream results
-	if it.res != nil {
-		if len(it.res) == 0 {
-			return false
-		}
-		it.res = it.res[1:]
-		return len(it.res) != 0
+	it.res = nil
+	if it.err != nil {
+		return false
 	}
 	for it.it.Next(ctx) {
-		m := make(map[string]graph.Ref)
-		it.it.TagResults(m)
-		it.q.treeifyResult(m)
-		for it.it.NextPath(ctx) {
-			m = make(map[string]graph.Ref, len(m))
-			it.it.TagResults(m)
-			it.q.treeifyResult(m)
+		if err := it.q.collectResults(ctx, it.idx, it.it.Result()); err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.q.results) != 0 {
+			it.res = it.q.results[0]
+			return true
 		}
 	}
-	if err := it.it.Err(); err != nil {
-		return false
-	}
-	it.q.buildResults()
-	it.res = it.q.results
-	return len(it.res) != 0
+	return false
 }
 
 func (it *mqlIterator) Result() interface{} {
-	if len(it.res) == 0 {
-		return nil
-	}
-	return it.res[0]
+	return it.res
 }
 
 func (it *mqlIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
 	return it.it.Err()
 }
 
 func (it *mqlIterator) Close() error {
-	return it.it.Close()
+	err := it.it.Close()
+	if err2 := it.idx.Close(); err == nil {
+		err = err2
+	}
+	return err
 }
 
 func (s *Session) Execute(ctx context.Context, input string, opt query.Options) (query.Iterator, error) {
@@ -101,6 +108,16 @@ func (s *Session) Execute(ctx context.Context, input string, opt query.Options)
 	if err := json.Unmarshal([]byte(input), &mqlQuery); err != nil {
 		return nil, err
 	}
+	if isWriteQuery(mqlQuery) {
+		if s.qw == nil {
+			return nil, ErrReadOnly
+		}
+		res, err := s.write(ctx, mqlQuery)
+		if err != nil {
+			return nil, err
+		}
+		return &writeIterator{res: res}, nil
+	}
 	q := NewQuery(s)
 	q.BuildIteratorTree(ctx, mqlQuery)
 	if q.isError() {
@@ -115,6 +132,7 @@ func (s *Session) Execute(ctx context.Context, input string, opt query.Options)
 		q:   q,
 		col: opt.Collation,
 		it:  it,
+		idx: q.paths.Lookup(),
 	}, nil
 }
 
@@ -0,0 +1,350 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/cayley/query/shape"
+	"github.com/cayleygraph/quad"
+)
+
+// ErrReadOnly is returned for write queries when the session has no QuadWriter.
+var ErrReadOnly = query.ErrReadOnly
+
+// Special keys of write queries.
+const (
+	keyID      = "id"
+	keyValue   = "value"
+	keyConnect = "connect"
+	keyCreate  = "create"
+)
+
+// Write directives and the statuses they are replaced with in the results, as in Freebase MQL.
+const (
+	connectInsert       = "insert"
+	connectDelete       = "delete"
+	createUnlessExists  = "unless_exists"
+	createUnconditional = "unconditional"
+
+	statusInserted = "inserted"
+	statusPresent  = "present"
+	statusDeleted  = "deleted"
+	statusAbsent   = "absent"
+	statusCreated  = "created"
+	statusExisted  = "existed"
+)
+
+// isWriteQuery reports if a query has any write directives.
+func isWriteQuery(query interface{}) bool {
+	switch t := query.(type) {
+	case []interface{}:
+		for _, v := range t {
+			if isWriteQuery(v) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if _, ok := t[keyConnect]; ok {
+			return true
+		}
+		if _, ok := t[keyCreate]; ok {
+			return true
+		}
+		for _, v := range t {
+			if isWriteQuery(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readQuery returns a copy of a write query object without write directives and properties
+// that have them. It is used to match existing nodes.
+func readQuery(obj map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if k == keyConnect || k == keyCreate || isWriteQuery(v) {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func makeQuad(node, pred, target quad.Value, reverse bool) quad.Quad {
+	if reverse {
+		node, target = target, node
+	}
+	return quad.Make(node, pred, target, nil)
+}
+
+type writer struct {
+	ctx context.Context
+	ses *Session
+	tx  *graph.Transaction
+}
+
+// write applies a write query in a single transaction. It returns the query objects
+// with write directives replaced by their statuses, and with ids of created nodes.
+func (s *Session) write(ctx context.Context, query interface{}) ([]interface{}, error) {
+	var objs []interface{}
+	switch t := query.(type) {
+	case []interface{}:
+		objs = t
+	case map[string]interface{}:
+		objs = []interface{}{t}
+	default:
+		return nil, fmt.Errorf("write query must be an object or an array of objects, got %T", query)
+	}
+	w := &writer{ctx: ctx, ses: s, tx: graph.NewTransaction()}
+	out := make([]interface{}, 0, len(objs))
+	for _, o := range objs {
+		obj, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("write query must be an object or an array of objects, got %T", o)
+		} else if _, ok = obj[keyConnect]; ok {
+			return nil, errors.New("connect directive is not allowed at the top level")
+		}
+		_, res, _, err := w.writeNode(obj)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	if len(w.tx.Deltas) != 0 {
+		if err := s.qw.ApplyTransaction(w.tx); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// writeNode applies write directives of a query object. It returns the node the object refers to,
+// the result object and reports if the node was created.
+func (w *writer) writeNode(obj map[string]interface{}) (quad.Value, map[string]interface{}, bool, error) {
+	res := make(map[string]interface{}, len(obj))
+	node, created, err := w.resolveNode(obj, res)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for key, v := range obj {
+		switch key {
+		case keyID, keyCreate, keyConnect:
+			// handled by resolveNode and the caller
+			continue
+		}
+		pred, reverse := parseKey(key)
+		r, err := w.writeProperty(node, quad.StringToValue(pred), reverse, v, created)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		res[key] = r
+	}
+	return node, res, created, nil
+}
+
+// resolveNode returns a node that the query object refers to. For create directives a new node
+// is created, unless the directive is "unless_exists" and an existing node matches the object.
+// It sets the id and the create status in the result and reports if the node was created.
+func (w *writer) resolveNode(obj, res map[string]interface{}) (quad.Value, bool, error) {
+	var id quad.Value
+	if v := obj[keyID]; v != nil {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("id must be a string, got %T", v)
+		}
+		id = quad.StringToValue(s)
+	}
+	c, ok := obj[keyCreate]
+	if !ok {
+		if id == nil {
+			return nil, false, errors.New("objects with write directives must have an id or a create directive")
+		}
+		res[keyID] = obj[keyID]
+		return id, false, nil
+	}
+	switch c {
+	case createUnlessExists:
+		node, err := w.match(obj)
+		if err != nil {
+			return nil, false, err
+		} else if node != nil {
+			res[keyID] = quadValueToNative(node)
+			res[keyCreate] = statusExisted
+			return node, false, nil
+		}
+	case createUnconditional:
+	default:
+		return nil, false, fmt.Errorf("unsupported create directive: %v", c)
+	}
+	if id == nil {
+		id = quad.RandomBlankNode()
+	}
+	res[keyID] = quadValueToNative(id)
+	res[keyCreate] = statusCreated
+	return id, true, nil
+}
+
+// match finds an existing node that matches the query object. It returns nil if there is no such node,
+// and an error if there are multiple nodes.
+func (w *writer) match(obj map[string]interface{}) (quad.Value, error) {
+	q := NewQuery(w.ses)
+	s, _, err := q.buildShape(readQuery(obj), NewPath())
+	if err != nil {
+		return nil, err
+	}
+	it := shape.BuildIterator(w.ctx, w.ses.qs, shape.Unique{From: s}).Iterate()
+	defer it.Close()
+	var found graph.Ref
+	for it.Next(w.ctx) {
+		if found != nil {
+			return nil, errors.New("create unless_exists: multiple nodes match the query")
+		}
+		found = it.Result()
+	}
+	if err := it.Err(); err != nil || found == nil {
+		return nil, err
+	}
+	return w.ses.qs.NameOf(found), nil
+}
+
+// writeProperty applies write directives of a property value. If link is set, all the values
+// are linked to the node, which is the case for properties of created nodes.
+func (w *writer) writeProperty(node, pred quad.Value, reverse bool, v interface{}, link bool) (interface{}, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(t))
+		for _, e := range t {
+			r, err := w.writeProperty(node, pred, reverse, e, link)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	case map[string]interface{}:
+		return w.writeLink(node, pred, reverse, t, link)
+	}
+	if link {
+		val, ok := jsonValue(v)
+		if !ok {
+			return nil, fmt.Errorf("unsupported value: %T", v)
+		}
+		w.tx.AddQuad(makeQuad(node, pred, val, reverse))
+	}
+	return v, nil
+}
+
+// writeLink applies write directives of a property value that is an object.
+func (w *writer) writeLink(node, pred quad.Value, reverse bool, obj map[string]interface{}, link bool) (interface{}, error) {
+	directive, connect := obj[keyConnect]
+	if !connect && !link && !isWriteQuery(obj) {
+		// only a constraint
+		return obj, nil
+	}
+	var (
+		target  quad.Value
+		res     map[string]interface{}
+		created bool
+	)
+	_, hasID := obj[keyID]
+	_, hasCreate := obj[keyCreate]
+	if v, ok := obj[keyValue]; ok && !hasID && !hasCreate {
+		// a literal value
+		val, ok := jsonValue(v)
+		if !ok {
+			return nil, fmt.Errorf("unsupported value: %T", v)
+		}
+		target, res = val, map[string]interface{}{keyValue: v}
+	} else {
+		var err error
+		target, res, created, err = w.writeNode(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+	q := makeQuad(node, pred, target, reverse)
+	if connect {
+		status, err := w.connect(q, directive)
+		if err != nil {
+			return nil, err
+		}
+		res[keyConnect] = status
+	} else if link || created {
+		// created nodes are connected to the parent
+		w.tx.AddQuad(q)
+	}
+	return res, nil
+}
+
+// connect applies a connect directive to a quad and returns its status.
+func (w *writer) connect(q quad.Quad, directive interface{}) (string, error) {
+	exists, err := graph.HasQuad(w.ctx, w.ses.qs, q)
+	if err != nil {
+		return "", err
+	}
+	switch directive {
+	case connectInsert:
+		if exists {
+			return statusPresent, nil
+		}
+		w.tx.AddQuad(q)
+		return statusInserted, nil
+	case connectDelete:
+		if !exists {
+			return statusAbsent, nil
+		}
+		w.tx.RemoveQuad(q)
+		return statusDeleted, nil
+	}
+	return "", fmt.Errorf("unsupported connect directive: %v", directive)
+}
+
+var _ query.Iterator = (*writeIterator)(nil)
+
+// writeIterator returns results of a write query.
+type writeIterator struct {
+	res []interface{}
+	cur interface{}
+}
+
+func (it *writeIterator) Next(ctx context.Context) bool {
+	if len(it.res) == 0 {
+		it.cur = nil
+		return false
+	}
+	it.cur, it.res = it.res[0], it.res[1:]
+	return true
+}
+
+func (it *writeIterator) Result() interface{} {
+	return it.cur
+}
+
+func (it *writeIterator) Err() error {
+	return nil
+}
+
+func (it *writeIterator) Close() error {
+	return nil
+}
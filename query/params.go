@@ -0,0 +1,104 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cayleygraph/quad"
+)
+
+type paramsKey struct{}
+
+// WithParams returns a context that carries values of query parameters.
+// It is used to pass parameters to Language.HTTPQuery handlers.
+func WithParams(ctx context.Context, params map[string]quad.Value) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// ParamsFromContext returns values of query parameters from the context, or nil if they are not set.
+func ParamsFromContext(ctx context.Context) map[string]quad.Value {
+	params, _ := ctx.Value(paramsKey{}).(map[string]quad.Value)
+	return params
+}
+
+// ParseParams decodes query parameters from a JSON object.
+//
+// Strings are parsed the same way as node names in the HTTP API, thus "<iri>" is an IRI
+// and "_:id" is a blank node. Numbers and booleans are converted to corresponding values.
+// JSON-LD objects with "@id" or "@value" keys can be used for other types of values.
+func ParseParams(data []byte) (map[string]quad.Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("cannot decode parameters: %v", err)
+	}
+	out := make(map[string]quad.Value, len(m))
+	for k, v := range m {
+		qv, err := paramValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %v", k, err)
+		}
+		out[k] = qv
+	}
+	return out, nil
+}
+
+func paramValue(v interface{}) (quad.Value, error) {
+	switch v := v.(type) {
+	case string:
+		return quad.StringToValue(v), nil
+	case bool:
+		return quad.Bool(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return quad.Int(i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return quad.Float(f), nil
+	case map[string]interface{}:
+		if id, ok := v["@id"].(string); ok {
+			if len(id) > 2 && id[:2] == "_:" {
+				return quad.BNode(id[2:]), nil
+			}
+			return quad.IRI(id), nil
+		}
+		val, ok := v["@value"].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an object with @id or a string @value")
+		}
+		if typ, ok := v["@type"].(string); ok {
+			ts := quad.TypedString{Value: quad.String(val), Type: quad.IRI(typ)}
+			if pv, err := ts.ParseValue(); err == nil {
+				// use a native type of the value, if it's known
+				return pv, nil
+			}
+			return ts, nil
+		}
+		if lang, ok := v["@language"].(string); ok {
+			return quad.LangString{Value: quad.String(val), Lang: lang}, nil
+		}
+		return quad.String(val), nil
+	default:
+		return nil, fmt.Errorf("unsupported value: %v", v)
+	}
+}
@@ -335,6 +335,63 @@ func followRecursiveMorphism(p *Path, maxDepth int, depthTags []string) morphism
 	}
 }
 
+// pathSearchMorphism finds chains of links from the current nodes to the nodes of the given path.
+func pathSearchMorphism(to *Path, via interface{}, maxDepth int, all, rev bool) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) {
+			return pathSearchMorphism(to, via, maxDepth, all, !rev), ctx
+		},
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			return iteratorBuilder(func(qs graph.QuadStore) iterator.Shape {
+				var preds iterator.Shape
+				if via != nil {
+					preds = buildVia(via).BuildIterator(qs)
+				}
+				from, dst := in.BuildIterator(qs), to.Shape().BuildIterator(qs)
+				var it *graph.PathSearch
+				if all {
+					it = graph.NewAllPaths(qs, from, dst, preds, maxDepth)
+				} else {
+					it = graph.NewShortestPath(qs, from, dst, preds, maxDepth)
+				}
+				it.SetReverse(rev)
+				it.AddPathTag(PathTag)
+				return it
+			}), ctx
+		},
+	}
+}
+
+// weightedPathMorphism finds the cheapest chains of links from the current nodes to the nodes of the given path.
+func weightedPathMorphism(to *Path, w Weights, rev bool) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) {
+			return weightedPathMorphism(to, w, !rev), ctx
+		},
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			return iteratorBuilder(func(qs graph.QuadStore) iterator.Shape {
+				ew := graph.EdgeWeights{
+					Weight: qs.ValueOf(w.Weight),
+				}
+				if w.Via != nil {
+					ew.Via = buildVia(w.Via).BuildIterator(qs)
+				}
+				if w.Next != nil {
+					ew.Next = buildVia(w.Next).BuildIterator(qs)
+				}
+				if w.Heuristic != nil {
+					ew.Heuristic = qs.ValueOf(w.Heuristic)
+				}
+				it := graph.NewWeightedPath(qs, in.BuildIterator(qs), to.Shape().BuildIterator(qs), ew)
+				it.SetReverse(rev)
+				it.AddPathTag(PathTag)
+				it.AddCostTag(CostTag)
+				return it
+			}), ctx
+		},
+	}
+}
+
 // exceptMorphism removes all results on p.(*Path) from the current iterators.
 func exceptMorphism(p *Path) morphism {
 	return morphism{
@@ -437,11 +494,23 @@ func skipMorphism(v int64) morphism {
 	}
 }
 
-func orderMorphism() morphism {
+// orderMorphism sorts results by given keys. The id is used to generate unique names for hidden tags.
+func orderMorphism(id int, keys ...OrderKey) morphism {
 	return morphism{
-		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return orderMorphism(), ctx },
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return orderMorphism(id, keys...), ctx },
 		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
-			return shape.Sort{From: in}, ctx
+			var skeys []iterator.SortKey
+			for i, k := range keys {
+				sk := iterator.SortKey{Tag: k.Tag, Desc: k.Desc}
+				if k.Via != nil {
+					// save property values to a hidden tag and sort by it
+					sk.Tag = fmt.Sprintf("_order%d_%d", id, i)
+					sk.Hidden = true
+					in = shape.SaveViaLabels(in, buildVia(k.Via), ctx.labelSet, sk.Tag, false, true)
+				}
+				skeys = append(skeys, sk)
+			}
+			return shape.Sort{From: in, Keys: skeys}, ctx
 		},
 	}
 }
@@ -461,6 +530,31 @@ func limitMorphism(v int64) morphism {
 	}
 }
 
+// groupByMorphism groups values by a tag.
+func groupByMorphism(tag string) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return groupByMorphism(tag), ctx },
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			return shape.GroupBy{From: in, Tag: tag}, ctx
+		},
+	}
+}
+
+// aggregateMorphism adds an aggregate to a preceding GroupBy, or aggregates all values if there is none.
+func aggregateMorphism(agg iterator.Aggregate) morphism {
+	return morphism{
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return aggregateMorphism(agg), ctx },
+		Apply: func(in shape.Shape, ctx *pathContext) (shape.Shape, *pathContext) {
+			g, ok := in.(shape.GroupBy)
+			if !ok {
+				g = shape.GroupBy{From: in}
+			}
+			g.Aggregates = append(append([]iterator.Aggregate{}, g.Aggregates...), agg)
+			return g, ctx
+		},
+	}
+}
+
 // countMorphism will return count of values.
 func countMorphism() morphism {
 	return morphism{
@@ -400,6 +400,66 @@ func (p *Path) FollowRecursive(via interface{}, maxDepth int, depthTags []string
 	return np
 }
 
+// PathTag is a tag that holds the chain of nodes and predicates found by ShortestPath
+// and AllPaths. The value of the tag is a graph.PathValue.
+const PathTag = "path"
+
+// ShortestPath finds the shortest chain of links from each of the current nodes to
+// any node of the "to" path. It follows links with predicates given by "via", which
+// can be a predicate value, a list of values or a Path. If via is nil, links with any
+// predicate are followed.
+//
+// Results are the nodes of the "to" path that are reached first. The found chain of
+// nodes and predicates is saved to PathTag as a graph.PathValue.
+//
+// The "maxDepth" argument limits the number of links in the chain and follows the
+// same rules as for FollowRecursive.
+func (p *Path) ShortestPath(to *Path, via interface{}, maxDepth int) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, pathSearchMorphism(to, via, maxDepth, false, false))
+	return np
+}
+
+// AllPaths is the same as ShortestPath, but returns all chains of links without loops.
+// Each chain is returned as a separate result.
+//
+// This is a very expensive operation in practice. Be sure to limit the depth.
+func (p *Path) AllPaths(to *Path, via interface{}, maxDepth int) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, pathSearchMorphism(to, via, maxDepth, true, false))
+	return np
+}
+
+// CostTag is a tag that holds the total cost of the path found by WeightedShortestPath.
+const CostTag = "cost"
+
+// Weights describes how WeightedShortestPath finds links between nodes and their costs.
+type Weights struct {
+	// Via is a predicate, a list of predicates or a Path that link nodes. If nil, any predicate is followed.
+	// For reified links it links the node with the edge node.
+	Via interface{}
+	// Next is a predicate, a list of predicates or a Path that link the edge node with the next node.
+	// If set, links are reified and the weight is a property of the edge node.
+	// Otherwise, the weight is a property of the quad label.
+	Next interface{}
+	// Weight is a predicate of a non-negative numeric cost of the link.
+	Weight quad.Value
+	// Heuristic is an optional predicate of the lower bound estimate of the remaining cost to the
+	// closest target node. If set, the search runs A* instead of Dijkstra's algorithm.
+	Heuristic quad.Value
+}
+
+// WeightedShortestPath finds the cheapest chain of links from each of the current nodes to
+// each reachable node of the "to" path. The cost of a chain is a sum of weights of all its links.
+//
+// Results are the nodes of the "to" path, ordered by the cost of the chain. The found chain of
+// nodes and predicates is saved to PathTag as a graph.PathValue, and the cost is saved to CostTag.
+func (p *Path) WeightedShortestPath(to *Path, w Weights) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, weightedPathMorphism(to, w, false))
+	return np
+}
+
 // Save will, from the current nodes in the path, retrieve the node
 // one linkage away (given by either a path or a predicate), add the given
 // tag, and propagate that to the result set.
@@ -543,8 +603,25 @@ func (p *Path) Skip(v int64) *Path {
 	return p
 }
 
+// Order sorts results by node values in ascending order.
 func (p *Path) Order() *Path {
-	p.stack = append(p.stack, orderMorphism())
+	p.stack = append(p.stack, orderMorphism(len(p.stack)))
+	return p
+}
+
+// OrderKey describes a single key to order results by.
+type OrderKey struct {
+	// Tag is a tag to order by. If both Tag and Via are empty, results are ordered by the current node.
+	Tag string
+	// Via is a predicate or a path to order by. Results without a value are placed last.
+	Via interface{}
+	// Desc sets a descending order for this key.
+	Desc bool
+}
+
+// OrderBy sorts results by a list of keys. Values are compared using their native types and the order is stable.
+func (p *Path) OrderBy(keys ...OrderKey) *Path {
+	p.stack = append(p.stack, orderMorphism(len(p.stack), keys...))
 	return p
 }
 
@@ -560,6 +637,24 @@ func (p *Path) Count() *Path {
 	return p
 }
 
+// GroupBy groups results by a value of a tag. Results of the path will be distinct values of this tag.
+//
+// Use Aggregate to compute values for each group.
+func (p *Path) GroupBy(tag string) *Path {
+	p.stack = append(p.stack, groupByMorphism(tag))
+	return p
+}
+
+// Aggregate computes a value for each group of results and saves it in a given tag.
+// Values are taken from a tag set earlier on the path. Tag can be empty for iterator.AggregateCount.
+//
+// If the path is not grouped with GroupBy, all results are aggregated to a single value.
+// Multiple aggregates can be computed by calling Aggregate multiple times.
+func (p *Path) Aggregate(fnc iterator.AggregateFunc, tag, as string) *Path {
+	p.stack = append(p.stack, aggregateMorphism(iterator.Aggregate{Func: fnc, Tag: tag, As: as}))
+	return p
+}
+
 // Iterate is an shortcut for graph.Iterate.
 func (p *Path) Iterate(ctx context.Context) *iterator.Chain {
 	return shape.Iterate(ctx, p.qs, p.Shape())
@@ -19,6 +19,7 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -451,6 +452,73 @@ func testSet(qs graph.QuadStore) []test {
 			path:    path.StartPath(qs, vCharlie).FollowRecursive(vFollows, 1, nil),
 			expect:  []quad.Value{vBob, vDani},
 		},
+		{
+			message: "shortest path",
+			path:    path.StartPath(qs, vAlice, vDani).ShortestPath(path.StartPath(qs, vGreg), vFollows, 0),
+			expect:  []quad.Value{vGreg, vGreg},
+		},
+		{
+			message: "shortest path (hops)",
+			path:    path.StartPath(qs, vAlice).ShortestPath(path.StartPath(qs, vGreg), vFollows, 0),
+			tag:     path.PathTag,
+			expect: []quad.Value{graph.PathValue{
+				{Node: vAlice, Pred: vFollows},
+				{Node: vBob, Pred: vFollows},
+				{Node: vFred, Pred: vFollows},
+				{Node: vGreg},
+			}},
+		},
+		{
+			message: "shortest path (limit depth)",
+			path:    path.StartPath(qs, vAlice).ShortestPath(path.StartPath(qs, vGreg), vFollows, 2),
+			expect:  nil,
+		},
+		{
+			message: "all paths",
+			path:    path.StartPath(qs, vCharlie).AllPaths(path.StartPath(qs, vGreg), vFollows, 0),
+			tag:     path.PathTag,
+			expect: []quad.Value{
+				graph.PathValue{
+					{Node: vCharlie, Pred: vFollows},
+					{Node: vBob, Pred: vFollows},
+					{Node: vFred, Pred: vFollows},
+					{Node: vGreg},
+				},
+				graph.PathValue{
+					{Node: vCharlie, Pred: vFollows},
+					{Node: vDani, Pred: vFollows},
+					{Node: vBob, Pred: vFollows},
+					{Node: vFred, Pred: vFollows},
+					{Node: vGreg},
+				},
+				graph.PathValue{
+					{Node: vCharlie, Pred: vFollows},
+					{Node: vDani, Pred: vFollows},
+					{Node: vGreg},
+				},
+			},
+		},
+		{
+			message: "all paths (limit depth)",
+			path:    path.StartPath(qs, vCharlie).AllPaths(path.StartPath(qs, vGreg), vFollows, 2),
+			tag:     path.PathTag,
+			expect: []quad.Value{graph.PathValue{
+				{Node: vCharlie, Pred: vFollows},
+				{Node: vDani, Pred: vFollows},
+				{Node: vGreg},
+			}},
+		},
+		{
+			message: "reverse shortest path",
+			path: path.StartPath(qs, vGreg).FollowReverse(
+				path.StartMorphism().ShortestPath(path.StartPath(qs, vDani), vFollows, 0),
+			),
+			tag: path.PathTag,
+			expect: []quad.Value{graph.PathValue{
+				{Node: vGreg, Pred: vFollows},
+				{Node: vDani},
+			}},
+		},
 		{
 			message: "find non-existent",
 			path:    path.StartPath(qs, quad.IRI("<not-existing>")),
@@ -508,7 +576,6 @@ func testSet(qs graph.QuadStore) []test {
 			path:     path.StartPath(qs).Order().Has(vFollows, vBob),
 			expect:   []quad.Value{vAlice, vCharlie, vDani},
 			unsorted: true,
-			skip:     true, // TODO(dennwc): optimize Order in And properly
 		},
 		{
 			message: "optional path",
@@ -524,6 +591,9 @@ func RunTestMorphisms(t *testing.T, fnc testutil.DatabaseFunc) {
 	for _, ftest := range []func(*testing.T, testutil.DatabaseFunc){
 		testFollowRecursive,
 		testFollowRecursiveHas,
+		testWeightedShortestPath,
+		testGroupBy,
+		testOrderBy,
 	} {
 		ftest(t, fnc)
 	}
@@ -626,6 +696,61 @@ func testFollowRecursive(t *testing.T, fnc testutil.DatabaseFunc) {
 	}
 }
 
+func testWeightedShortestPath(t *testing.T, fnc testutil.DatabaseFunc) {
+	qs, closer := makeTestStore(t, fnc, []quad.Quad{
+		quad.MakeIRI("a", "road", "b", "r1"),
+		quad.MakeIRI("b", "road", "c", "r2"),
+		quad.MakeIRI("a", "road", "c", "r3"),
+		quad.Make(quad.IRI("r1"), quad.IRI("km"), quad.Int(2), nil),
+		quad.Make(quad.IRI("r2"), quad.IRI("km"), quad.Int(3), nil),
+		quad.Make(quad.IRI("r3"), quad.IRI("km"), quad.Int(7), nil),
+	}...)
+	defer closer()
+
+	qu := path.StartPath(qs, quad.IRI("a")).WeightedShortestPath(
+		path.StartPath(qs, quad.IRI("b"), quad.IRI("c")),
+		path.Weights{Via: quad.IRI("road"), Weight: quad.IRI("km")},
+	)
+
+	expect := []map[string]quad.Value{
+		{
+			path.PathTag: graph.PathValue{
+				{Node: quad.IRI("a"), Pred: quad.IRI("road")},
+				{Node: quad.IRI("b")},
+			},
+			path.CostTag: quad.Float(2),
+		},
+		{
+			path.PathTag: graph.PathValue{
+				{Node: quad.IRI("a"), Pred: quad.IRI("road")},
+				{Node: quad.IRI("b"), Pred: quad.IRI("road")},
+				{Node: quad.IRI("c")},
+			},
+			path.CostTag: quad.Float(5),
+		},
+	}
+
+	const msg = "weighted shortest path"
+
+	for _, opt := range []bool{true, false} {
+		unopt := ""
+		if !opt {
+			unopt = " (unoptimized)"
+		}
+		t.Run(msg+unopt, func(t *testing.T) {
+			got, err := runAllTags(qs, qu, opt)
+			if err != nil {
+				t.Errorf("Failed to check %s%s: %v", msg, unopt, err)
+				return
+			}
+			// results are ordered by cost
+			if !reflect.DeepEqual(got, expect) {
+				t.Errorf("Failed to %s%s, got: %v(%d) expected: %v(%d)", msg, unopt, got, len(got), expect, len(expect))
+			}
+		})
+	}
+}
+
 type byTags struct {
 	tags []string
 	arr  []map[string]quad.Value
@@ -700,3 +825,145 @@ func testFollowRecursiveHas(t *testing.T, fnc testutil.DatabaseFunc) {
 		})
 	}
 }
+
+func testGroupBy(t *testing.T, fnc testutil.DatabaseFunc) {
+	qs, closer := makeTestStore(t, fnc, []quad.Quad{
+		quad.MakeIRI("alice", "city", "ny", ""),
+		quad.MakeIRI("bob", "city", "ny", ""),
+		quad.MakeIRI("carol", "city", "sf", ""),
+		quad.MakeIRI("dave", "city", "sf", ""),
+		quad.MakeIRI("eve", "city", "sf", ""),
+		quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+		quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Float(41.5), nil),
+		quad.Make(quad.IRI("carol"), quad.IRI("age"), quad.Int(20), nil),
+		quad.Make(quad.IRI("dave"), quad.IRI("age"), quad.Int(25), nil),
+		quad.Make(quad.IRI("eve"), quad.IRI("age"), quad.String("unknown"), nil),
+	}...)
+	defer closer()
+
+	people := func() *path.Path {
+		return path.StartPath(qs).Save(quad.IRI("age"), "age").Out(quad.IRI("city")).Tag("city")
+	}
+
+	for _, c := range []struct {
+		msg    string
+		path   *path.Path
+		expect []map[string]quad.Value
+	}{
+		{
+			msg: "group by",
+			path: people().GroupBy("city").
+				Aggregate(iterator.AggregateCount, "", "n").
+				Aggregate(iterator.AggregateSum, "age", "sum").
+				Aggregate(iterator.AggregateAvg, "age", "avg").
+				Aggregate(iterator.AggregateMin, "age", "min").
+				Aggregate(iterator.AggregateMax, "age", "max"),
+			expect: []map[string]quad.Value{
+				{"city": quad.IRI("ny"), "n": quad.Int(2), "sum": quad.Float(71.5), "avg": quad.Float(35.75), "min": quad.Int(30), "max": quad.Float(41.5)},
+				{"city": quad.IRI("sf"), "n": quad.Int(3), "sum": quad.Int(45), "avg": quad.Float(22.5), "min": quad.Int(20), "max": quad.Int(25)},
+			},
+		},
+		{
+			msg:  "aggregate all",
+			path: people().Aggregate(iterator.AggregateMax, "age", "max"),
+			expect: []map[string]quad.Value{
+				{"max": quad.Float(41.5)},
+			},
+		},
+	} {
+		c := c
+		sortTags := []string{"city"}
+		for _, opt := range []bool{true, false} {
+			unopt := ""
+			if !opt {
+				unopt = " (unoptimized)"
+			}
+			t.Run(c.msg+unopt, func(t *testing.T) {
+				got, err := runAllTags(qs, c.path, opt)
+				require.NoError(t, err)
+				sort.Sort(byTags{
+					tags: sortTags,
+					arr:  got,
+				})
+				require.Equal(t, c.expect, got)
+			})
+		}
+	}
+}
+
+func testOrderBy(t *testing.T, fnc testutil.DatabaseFunc) {
+	qs, closer := makeTestStore(t, fnc, []quad.Quad{
+		quad.MakeIRI("alice", "city", "ny", ""),
+		quad.MakeIRI("bob", "city", "ny", ""),
+		quad.MakeIRI("carol", "city", "sf", ""),
+		quad.MakeIRI("dave", "city", "sf", ""),
+		quad.MakeIRI("eve", "city", "ny", ""),
+		quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+		quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Float(9.5), nil),
+		quad.Make(quad.IRI("carol"), quad.IRI("age"), quad.Int(100), nil),
+		quad.Make(quad.IRI("dave"), quad.IRI("age"), quad.Int(31), nil),
+		quad.Make(quad.IRI("dave"), quad.IRI("age"), quad.Int(35), nil),
+	}...)
+	defer closer()
+
+	people := func() *path.Path {
+		return path.StartPath(qs).Has(quad.IRI("city")).Tag("person")
+	}
+	var (
+		vCarol = quad.IRI("carol")
+		vDave  = quad.IRI("dave")
+		vEve   = quad.IRI("eve")
+	)
+
+	for _, c := range []struct {
+		msg    string
+		path   *path.Path
+		expect []quad.Value
+	}{
+		{
+			msg:    "order by property",
+			path:   people().OrderBy(path.OrderKey{Via: quad.IRI("age")}),
+			expect: []quad.Value{vBob, vAlice, vDave, vCarol, vEve},
+		},
+		{
+			msg:    "order by property desc",
+			path:   people().OrderBy(path.OrderKey{Via: quad.IRI("age"), Desc: true}),
+			expect: []quad.Value{vCarol, vDave, vAlice, vBob, vEve},
+		},
+		{
+			msg: "order by tag and property",
+			path: people().Save(quad.IRI("city"), "city").OrderBy(
+				path.OrderKey{Tag: "city", Desc: true},
+				path.OrderKey{Via: quad.IRI("age"), Desc: true},
+			),
+			expect: []quad.Value{vCarol, vDave, vAlice, vBob, vEve},
+		},
+		{
+			msg: "order in intersection",
+			path: people().OrderBy(
+				path.OrderKey{Via: quad.IRI("age"), Desc: true},
+			).Has(quad.IRI("city"), quad.IRI("ny")),
+			expect: []quad.Value{vAlice, vBob, vEve},
+		},
+	} {
+		c := c
+		for _, opt := range []bool{true, false} {
+			unopt := ""
+			if !opt {
+				unopt = " (unoptimized)"
+			}
+			t.Run(c.msg+unopt, func(t *testing.T) {
+				got, err := runAllTags(qs, c.path, opt)
+				require.NoError(t, err)
+				var nodes []quad.Value
+				for _, tags := range got {
+					for tag := range tags {
+						require.False(t, strings.HasPrefix(tag, "_order"), "hidden tag in results: %q", tag)
+					}
+					nodes = append(nodes, tags["person"])
+				}
+				require.Equal(t, c.expect, nodes)
+			})
+		}
+	}
+}
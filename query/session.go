@@ -22,10 +22,14 @@ import (
 	"io"
 
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/quad"
 )
 
 var ErrParseMore = errors.New("query: more input required")
 
+// ErrReadOnly is returned by sessions for queries that modify the graph when no QuadWriter is set.
+var ErrReadOnly = errors.New("graph is read-only")
+
 type ErrUnsupportedCollation struct {
 	Collation Collation
 }
@@ -67,6 +71,10 @@ const (
 type Options struct {
 	Limit     int
 	Collation Collation
+	// Params are values of query parameters. Parameters are passed separately from the query text,
+	// thus they are never interpreted as a part of the query. See documentation of each language
+	// for the way parameters are referenced.
+	Params map[string]quad.Value
 }
 
 type Session interface {
@@ -77,6 +85,13 @@ type Session interface {
 
 type REPLSession = Session
 
+// WriterSession is an optional interface for sessions of query languages that can modify the graph.
+type WriterSession interface {
+	Session
+	// SetWriter sets a writer for changes made by queries. The session is read-only if it's not set.
+	SetWriter(w graph.QuadWriter)
+}
+
 // ResponseWriter is a subset of http.ResponseWriter
 type ResponseWriter interface {
 	Write([]byte) (int, error)
@@ -90,6 +105,7 @@ type Language struct {
 
 	// Custom HTTP handlers
 
+	// HTTPQuery handles a query received over HTTP. Query parameters, if any, can be read with ParamsFromContext.
 	HTTPQuery func(ctx context.Context, qs graph.QuadStore, w ResponseWriter, r io.Reader)
 	HTTPError func(w ResponseWriter, err error)
 }
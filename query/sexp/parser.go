@@ -21,6 +21,7 @@ import (
 
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/query/shape"
 	"github.com/cayleygraph/quad"
 )
@@ -34,8 +35,14 @@ func BuildIteratorTreeForQuery(ctx context.Context, qs graph.QuadStore, query st
 }
 
 func BuildShape(ctx context.Context, query string) (shape.Shape, error) {
-	tree := parseQuery(query)
-	s, _ := buildShape(tree)
+	return BuildShapeWithParams(ctx, query, nil)
+}
+
+// BuildShapeWithParams builds a shape for the query and binds its variables to values of given parameters.
+// Parameter names don't include the "$" prefix of variables. Variables without a parameter match any node.
+func BuildShapeWithParams(ctx context.Context, query string, params map[string]quad.Value) (shape.Shape, error) {
+	tree := parseQueryCached(query)
+	s, _ := buildShape(tree, params)
 	s, _ = shape.Optimize(ctx, s, nil)
 	return s, nil
 }
@@ -182,6 +189,19 @@ func parseQuery(input string) *peg.ExpressionTree {
 	return newParser().Parse(input)
 }
 
+// trees caches parsed queries. Parameters are bound when a shape is built, thus trees can be shared.
+var trees = query.NewPlanCache(1024)
+
+// parseQueryCached parses a query, or returns a tree parsed previously.
+func parseQueryCached(input string) *peg.ExpressionTree {
+	if tree, ok := trees.Get(input); ok {
+		return tree.(*peg.ExpressionTree)
+	}
+	tree := parseQuery(input)
+	trees.Put(input, tree)
+	return tree
+}
+
 func getIdentString(tree *peg.ExpressionTree) string {
 	out := ""
 	if len(tree.Children) > 0 {
@@ -200,16 +220,20 @@ func lookup(s string) shape.Shape {
 	return shape.Lookup{quad.StringToValue(s)}
 }
 
-func buildShape(tree *peg.ExpressionTree) (_ shape.Shape, opt bool) {
+func buildShape(tree *peg.ExpressionTree, params map[string]quad.Value) (_ shape.Shape, opt bool) {
 	switch tree.Name {
 	case "Start":
-		return buildShape(tree.Children[0])
+		return buildShape(tree.Children[0], params)
 	case "NodeIdentifier":
 		var out shape.Shape
 		nodeID := getIdentString(tree)
 		if tree.Children[0].Name == "Variable" {
+			var from shape.Shape = shape.AllNodes{}
+			if v, ok := params[nodeID[1:]]; ok {
+				from = shape.Lookup{v}
+			}
 			out = shape.Save{
-				From: shape.AllNodes{},
+				From: from,
 				Tags: []string{nodeID},
 			}
 		} else {
@@ -226,7 +250,7 @@ func buildShape(tree *peg.ExpressionTree) (_ shape.Shape, opt bool) {
 			//Taken care of below
 			i++
 		}
-		it, _ := buildShape(tree.Children[i])
+		it, _ := buildShape(tree.Children[i], params)
 		return shape.Quads{
 			{Dir: quad.Predicate, Values: it},
 		}, false
@@ -237,7 +261,7 @@ func buildShape(tree *peg.ExpressionTree) (_ shape.Shape, opt bool) {
 			case "NodeIdentifier":
 				fallthrough
 			case "Constraint":
-				it, opt := buildShape(c)
+				it, opt := buildShape(c, params)
 				if opt {
 					and.AddOptional(it)
 				} else {
@@ -261,7 +285,7 @@ func buildShape(tree *peg.ExpressionTree) (_ shape.Shape, opt bool) {
 					topLevelDir = quad.Object
 					subItDir = quad.Subject
 				}
-				it, opt := buildShape(c)
+				it, opt := buildShape(c, params)
 				if opt {
 					subAnd.AddOptional(it)
 				} else {
@@ -276,7 +300,7 @@ func buildShape(tree *peg.ExpressionTree) (_ shape.Shape, opt bool) {
 			case "NodeIdentifier":
 				fallthrough
 			case "RootConstraint":
-				it, opt := buildShape(c)
+				it, opt := buildShape(c, params)
 				l := shape.Quads{
 					{Dir: subItDir, Values: it},
 				}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/cayleygraph/cayley/graph/graphtest/testutil"
 	_ "github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/query"
 	sh "github.com/cayleygraph/cayley/query/shape"
 	_ "github.com/cayleygraph/cayley/writer"
 	"github.com/stretchr/testify/require"
@@ -189,3 +190,37 @@ func TestSexp(t *testing.T) {
 		})
 	}
 }
+
+func TestSexpParams(t *testing.T) {
+	ctx := context.TODO()
+	qs, _ := graph.NewQuadStore("memstore", "", nil)
+	_ = testutil.MakeWriter(t, qs, nil,
+		quad.Make("i", "can", "win", nil),
+		quad.Make("you", "can", "lose", nil),
+	)
+	ses := NewSession(qs)
+	for _, c := range []struct {
+		params map[string]quad.Value
+		expect string
+	}{
+		{params: map[string]quad.Value{"b": quad.String("win")}, expect: "i"},
+		{params: map[string]quad.Value{"b": quad.String("lose")}, expect: "you"},
+		{params: map[string]quad.Value{"b": quad.String("draw")}},
+	} {
+		it, err := ses.Execute(ctx, "($a (:can $b))", query.Options{Collation: query.Raw, Params: c.params})
+		require.NoError(t, err)
+		var got []string
+		for it.Next(ctx) {
+			tags := it.Result().(map[string]graph.Ref)
+			got = append(got, quad.ToString(qs.NameOf(tags["$a"])))
+			require.Equal(t, c.params["b"], qs.NameOf(tags["$b"]))
+		}
+		require.NoError(t, it.Err())
+		require.NoError(t, it.Close())
+		if c.expect == "" {
+			require.Empty(t, got)
+		} else {
+			require.Equal(t, []string{c.expect}, got)
+		}
+	}
+}
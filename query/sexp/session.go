@@ -25,6 +25,7 @@ import (
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/iterator"
 	"github.com/cayleygraph/cayley/query"
+	"github.com/cayleygraph/cayley/query/shape"
 )
 
 const Name = "sexp"
@@ -78,7 +79,11 @@ func (s *Session) Execute(ctx context.Context, input string, opt query.Options)
 	default:
 		return nil, &query.ErrUnsupportedCollation{Collation: opt.Collation}
 	}
-	it := BuildIteratorTreeForQuery(ctx, s.qs, input).Iterate()
+	sh, err := BuildShapeWithParams(ctx, input, opt.Params)
+	if err != nil {
+		return nil, err
+	}
+	it := shape.BuildIterator(ctx, s.qs, sh).Iterate()
 	if err := it.Err(); err != nil {
 		return nil, err
 	}
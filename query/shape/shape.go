@@ -79,6 +79,15 @@ func Optimize(ctx context.Context, s Shape, qs graph.QuadStore) (Shape, bool) {
 		return Null{}, true
 	}
 	opt = opt || opt1
+	// reorder intersections if quadstore collects statistics
+	if _, ok := qs.(graph.StatsProvider); ok && s != nil {
+		var opt2 bool
+		s, opt2 = s.Optimize(ctx, statsOptimizer{qs: qs})
+		opt = opt || opt2
+		if s == nil {
+			return Null{}, true
+		}
+	}
 	// apply quadstore-specific optimizations
 	if so, ok := qs.(Optimizer); ok && s != nil {
 		var opt2 bool
@@ -433,6 +442,45 @@ func (s Count) Optimize(ctx context.Context, r Optimizer) (Shape, bool) {
 	return s, opt
 }
 
+// GroupBy groups results of a source shape by a value of a tag and computes aggregates for each group.
+//
+// The shape returns one value per group - a value of the group tag. Aggregates are stored in tags.
+// If the tag is empty, all results form a single group, and the first aggregate is returned as a value.
+type GroupBy struct {
+	From       Shape
+	Tag        string
+	Aggregates []iterator.Aggregate
+}
+
+func (s GroupBy) BuildIterator(qs graph.QuadStore) iterator.Shape {
+	var it iterator.Shape
+	if IsNull(s.From) {
+		it = iterator.NewNull()
+	} else {
+		it = s.From.BuildIterator(qs)
+	}
+	return iterator.NewGroupBy(it, qs, s.Tag, s.Aggregates)
+}
+func (s GroupBy) Optimize(ctx context.Context, r Optimizer) (Shape, bool) {
+	if IsNull(s.From) {
+		if s.Tag != "" {
+			// no groups
+			return nil, true
+		}
+		return s, false
+	}
+	var opt bool
+	s.From, opt = s.From.Optimize(ctx, r)
+	if IsNull(s.From) && s.Tag != "" {
+		return nil, true
+	}
+	if r != nil {
+		ns, nopt := r.OptimizeShape(ctx, s)
+		return ns, opt || nopt
+	}
+	return s, opt
+}
+
 // QuadFilter is a constraint used to filter quads that have a certain set of values on a given direction.
 // Analog of LinksTo iterator.
 type QuadFilter struct {
@@ -472,8 +520,30 @@ func (s Quads) BuildIterator(qs graph.QuadStore) iterator.Shape {
 	if len(its) == 1 {
 		return its[0]
 	}
+	// let other filters know the predicate, so they can estimate the size with stats
+	if pred, ok := s.predicate(); ok {
+		for _, it := range its {
+			if lt, ok := it.(*graph.LinksTo); ok {
+				lt.SetPredicateHint(pred)
+			}
+		}
+	}
 	return iterator.NewAnd(its...)
 }
+
+// predicate returns a predicate value, if quads are constrained to a single one.
+func (s Quads) predicate() (graph.Ref, bool) {
+	for _, f := range s {
+		if f.Dir != quad.Predicate || f.Values == nil {
+			continue
+		}
+		if v, ok := One(f.Values); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
 func (s Quads) Optimize(ctx context.Context, r Optimizer) (Shape, bool) {
 	var opt bool
 	sw := 0
@@ -960,6 +1030,7 @@ func (s Intersect) Optimize(ctx context.Context, r Optimizer) (sout Shape, opt b
 		hasAll   = false
 		fixed    []Fixed  // we will collect all Fixed, and will place it as a first iterator
 		tags     []string // if we find a Save inside, we will push it outside of Intersect
+		sorts    []Sort   // the same applies to Sort, since it won't work as a Contains branch
 		quads    Quads    // also, collect all quad filters into a single set
 		optional []Shape
 	)
@@ -1005,9 +1076,32 @@ func (s Intersect) Optimize(ctx context.Context, r Optimizer) (sout Shape, opt b
 			tags = append(tags, c.Tags...)
 			s[i] = c.From
 			i--
+		case Sort: // push Sort outside of Intersect
+			realloc()
+			opt = true
+			sorts = append(sorts, c)
+			s[i] = c.From
+			i--
 		}
 		onlyAll = false
 	}
+	if len(sorts) != 0 {
+		// Sort must be applied last, after all other shapes are pushed outside
+		defer func() {
+			if IsNull(sout) {
+				return
+			}
+			// keys of other sorts can only be used to break ties
+			var keys []iterator.SortKey
+			for _, so := range sorts {
+				if len(so.Keys) == 0 {
+					keys = append(keys, iterator.SortKey{})
+				}
+				keys = append(keys, so.Keys...)
+			}
+			sout = Sort{From: sout, Keys: keys}
+		}()
+	}
 	if onlyAll {
 		return AllNodes{}, true
 	}
@@ -1435,8 +1529,10 @@ func FilterQuads(subject, predicate, object, label []quad.Value) Shape {
 	return q
 }
 
+// Sort orders results by a list of keys. If no keys are set, results are ordered by node values.
 type Sort struct {
 	From Shape
+	Keys []iterator.SortKey
 }
 
 func (s Sort) BuildIterator(qs graph.QuadStore) iterator.Shape {
@@ -1444,7 +1540,7 @@ func (s Sort) BuildIterator(qs graph.QuadStore) iterator.Shape {
 		return iterator.NewNull()
 	}
 	it := s.From.BuildIterator(qs)
-	return iterator.NewSort(qs, it)
+	return iterator.NewSort(qs, it, s.Keys...)
 }
 func (s Sort) Optimize(ctx context.Context, r Optimizer) (Shape, bool) {
 	if IsNull(s.From) {
@@ -25,6 +25,7 @@ import (
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/graph/graphmock"
 	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/memstore"
 	"github.com/cayleygraph/cayley/graph/refs"
 	. "github.com/cayleygraph/cayley/query/shape"
 	"github.com/cayleygraph/quad"
@@ -365,6 +366,39 @@ func TestOptimize(t *testing.T) {
 	}
 }
 
+func TestOptimizeStats(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("a", "follows", "b", ""),
+		quad.MakeIRI("b", "follows", "c", ""),
+		quad.MakeIRI("c", "follows", "a", ""),
+		quad.MakeIRI("c", "follows", "b", ""),
+		quad.MakeIRI("a", "status", "cool", ""),
+	)
+	nodesWith := func(pred string) NodesFrom {
+		return NodesFrom{
+			Dir: quad.Subject,
+			Quads: Quads{
+				{Dir: quad.Predicate, Values: Lookup{quad.IRI(pred)}},
+			},
+		}
+	}
+	got, _ := Optimize(context.TODO(), Intersect{
+		nodesWith("follows"),
+		nodesWith("status"),
+	}, qs)
+	in, ok := got.(Intersect)
+	require.True(t, ok, "%#v", got)
+	require.Len(t, in, 2)
+	// predicate with less subjects must go first
+	var preds []quad.Value
+	for _, s := range in {
+		qa, ok := s.(QuadsAction)
+		require.True(t, ok, "%#v", s)
+		preds = append(preds, qs.NameOf(qa.Filter[quad.Predicate]))
+	}
+	require.Equal(t, []quad.Value{quad.IRI("status"), quad.IRI("follows")}, preds)
+}
+
 func TestWalk(t *testing.T) {
 	var s Shape = NodesFrom{
 		Dir: quad.Subject,
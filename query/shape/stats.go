@@ -0,0 +1,135 @@
+package shape
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/quad"
+)
+
+// statsOptimizer reorders intersections using per-predicate statistics provided by the quad store.
+type statsOptimizer struct {
+	qs graph.QuadStore
+}
+
+func (r statsOptimizer) OptimizeShape(ctx context.Context, s Shape) (Shape, bool) {
+	in, ok := s.(Intersect)
+	if !ok || len(in) < 2 {
+		return s, false
+	}
+	sizes := make([]int64, len(in))
+	known := 0
+	for i, c := range in {
+		sz, ok := r.estimateSize(ctx, c)
+		if !ok {
+			sz = -1
+		} else {
+			known++
+		}
+		sizes[i] = sz
+	}
+	if known == 0 {
+		return s, false
+	}
+	idx := make([]int, len(in))
+	for i := range idx {
+		idx[i] = i
+	}
+	// smallest first, shapes with unknown size go last
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := sizes[idx[i]], sizes[idx[j]]
+		if a < 0 || b < 0 {
+			return a >= 0 && b < 0
+		}
+		return a < b
+	})
+	changed := false
+	out := make(Intersect, len(in))
+	for i, j := range idx {
+		out[i] = in[j]
+		changed = changed || i != j
+	}
+	if !changed {
+		return s, false
+	}
+	return out, true
+}
+
+// estimateSize returns an estimated number of nodes returned by a shape.
+func (r statsOptimizer) estimateSize(ctx context.Context, s Shape) (int64, bool) {
+	switch s := s.(type) {
+	case Fixed:
+		return int64(len(s)), true
+	case Save:
+		return r.estimateSize(ctx, s.From)
+	case QuadsAction:
+		pred, ok := s.Filter[quad.Predicate]
+		if !ok {
+			return 0, false
+		}
+		var fixed int
+		if v, ok := s.Filter[opposite(s.Result)]; ok && v != nil {
+			fixed = 1
+		}
+		return r.estimateNodes(ctx, pred, s.Result, len(s.Filter) == 1, fixed)
+	case NodesFrom:
+		q, ok := s.Quads.(Quads)
+		if !ok {
+			return 0, false
+		}
+		pred, ok := q.predicate()
+		if !ok {
+			return 0, false
+		}
+		var fixed int
+		for _, f := range q {
+			if f.Dir != opposite(s.Dir) {
+				continue
+			}
+			if fix, ok := f.Values.(Fixed); ok && (fixed == 0 || len(fix) < fixed) {
+				fixed = len(fix)
+			}
+		}
+		return r.estimateNodes(ctx, pred, s.Dir, len(q) == 1, fixed)
+	}
+	return 0, false
+}
+
+// estimateNodes estimates the number of nodes on a given direction of quads with a specific predicate.
+// If only is set, predicate is the only constraint for quads. Fixed is the number of fixed values
+// on the opposite direction, if any.
+func (r statsOptimizer) estimateNodes(ctx context.Context, pred graph.Ref, dir quad.Direction, only bool, fixed int) (int64, bool) {
+	st, ok := graph.PredicateStats(ctx, r.qs, pred)
+	if !ok {
+		return 0, false
+	}
+	if only {
+		// all nodes on a given direction
+		if n, ok := st.Distinct(dir); ok {
+			return n, true
+		}
+		return st.Quads, true
+	}
+	// other constraints can only make it smaller
+	n := st.Quads
+	if fixed > 0 {
+		if deg, ok := st.Degree(opposite(dir)); ok {
+			if v := int64(float64(fixed)*deg + 0.5); v < n {
+				n = v
+			}
+		}
+	}
+	return n, true
+}
+
+// opposite returns the other side of a subject-object link.
+func opposite(d quad.Direction) quad.Direction {
+	switch d {
+	case quad.Subject:
+		return quad.Object
+	case quad.Object:
+		return quad.Subject
+	}
+	return quad.Any
+}
@@ -30,6 +30,9 @@ import (
 
 	"github.com/cayleygraph/cayley/clog"
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/iterator"
+	"github.com/cayleygraph/cayley/graph/rdfpatch"
 	"github.com/cayleygraph/cayley/query"
 	"github.com/cayleygraph/cayley/query/shape"
 
@@ -84,6 +87,9 @@ type APIv2 struct {
 	// query
 	timeout time.Duration
 	limit   int
+	limits  governor.Limits
+	tokens  map[string]governor.Limits
+	par     int
 }
 
 // SetReadOnly sets read-only mode for the request
@@ -106,6 +112,27 @@ func (api *APIv2) SetQueryLimit(n int) {
 	api.limit = n
 }
 
+// SetQueryLimits sets default resource limits for each query
+func (api *APIv2) SetQueryLimits(l governor.Limits) {
+	api.limits = l
+}
+
+// SetQueryParallelism sets the number of branches each iterator may execute concurrently.
+// Values less than 2 disable parallel execution.
+func (api *APIv2) SetQueryParallelism(n int) {
+	api.par = n
+}
+
+// SetTokenLimits sets resource limits for queries made with a given API token.
+// Token is passed in the Authorization header as a bearer token. Limits that are not set
+// are taken from the default limits.
+func (api *APIv2) SetTokenLimits(token string, l governor.Limits) {
+	if api.tokens == nil {
+		api.tokens = make(map[string]governor.Limits)
+	}
+	api.tokens[token] = l
+}
+
 // ServeHTTP implements http.Handler
 func (api *APIv2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	api.handler.ServeHTTP(w, r)
@@ -141,6 +168,7 @@ func (api *APIv2) registerQueryOn(r *httprouter.Router) {
 func (api *APIv2) registerOn(r *httprouter.Router) {
 	api.registerDataOn(r)
 	api.registerQueryOn(r)
+	api.registerProceduresOn(r)
 }
 
 const (
@@ -228,6 +256,10 @@ func (api *APIv2) ServeWrite(w http.ResponseWriter, r *http.Request) {
 		jsonResponse(w, http.StatusForbidden, errors.New("database is read-only"))
 		return
 	}
+	if specs := ParseAccept(r.Header, hdrContentType); len(specs) != 0 && specs[0].Value == rdfpatch.ContentType {
+		api.servePatch(w, r)
+		return
+	}
 	format := getFormat(r, "", hdrContentType)
 	if format == nil || format.Reader == nil {
 		jsonResponse(w, http.StatusBadRequest, errors.New("format is not supported for reading data"))
@@ -264,6 +296,47 @@ func (api *APIv2) ServeWrite(w http.ResponseWriter, r *http.Request) {
 	encoder.Encode(response)
 }
 
+// servePatch applies an RDF Patch received in the request body to the database.
+// Each transaction of the patch is applied atomically. Responds with how many changes were applied.
+// Transactions that precede a malformed one are still applied.
+func (api *APIv2) servePatch(w http.ResponseWriter, r *http.Request) {
+	rd, err := readerFrom(r, hdrContentEncoding)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	defer rd.Close()
+	h, err := api.handleForRequest(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	// same as rdfpatch.Apply, but malformed patches are reported as client errors
+	pr := rdfpatch.NewReader(rd)
+	n := 0
+	for {
+		tx, err := pr.ReadTransaction()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			jsonResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(tx.Deltas) == 0 {
+			continue
+		}
+		if err = h.QuadWriter.ApplyTransaction(tx); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		n += len(tx.Deltas)
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	response := newWriteResponse(n)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(response)
+}
+
 // ServeDelete deletes data received in the request body from the database.
 // Responds with how many quads were deleted.
 func (api *APIv2) ServeDelete(w http.ResponseWriter, r *http.Request) {
@@ -455,8 +528,28 @@ func (api *APIv2) ServeFormats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(out)
 }
 
+// queryLimits returns resource limits for the request.
+func (api *APIv2) queryLimits(r *http.Request) governor.Limits {
+	const bearer = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, bearer) {
+		return api.limits
+	}
+	l, ok := api.tokens[strings.TrimSpace(auth[len(bearer):])]
+	if !ok {
+		return api.limits
+	}
+	return l.Merge(api.limits)
+}
+
 func (api *APIv2) queryContext(r *http.Request) (ctx context.Context, cancel func()) {
 	ctx = r.Context()
+	if l := api.queryLimits(r); !l.IsZero() {
+		ctx = governor.WithGovernor(ctx, governor.New(l))
+	}
+	if api.par > 1 {
+		ctx = iterator.WithParallelism(ctx, api.par)
+	}
 	if api.timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, api.timeout)
 	} else {
@@ -473,6 +566,44 @@ func defaultErrorFunc(w query.ResponseWriter, err error) {
 	w.Write([]byte("}\n"))
 }
 
+// writeMethodErrorFunc reports queries that attempt to modify the graph in a GET request as not allowed.
+// Only POST requests get a QuadWriter, thus a link or an image on a third-party page cannot change the database.
+func writeMethodErrorFunc(hw http.ResponseWriter, next func(w query.ResponseWriter, err error)) func(w query.ResponseWriter, err error) {
+	return func(w query.ResponseWriter, err error) {
+		if !errors.Is(err, query.ErrReadOnly) {
+			next(w, err)
+			return
+		}
+		hw.Header().Set("Allow", http.MethodPost)
+		jsonResponse(hw, http.StatusMethodNotAllowed, "queries that modify the graph must be sent with POST")
+	}
+}
+
+// limitErrorFunc reports errors about exceeded resource limits with the limit and the resource usage.
+func limitErrorFunc(next func(w query.ResponseWriter, err error)) func(w query.ResponseWriter, err error) {
+	return func(w query.ResponseWriter, err error) {
+		var e *governor.ErrLimitExceeded
+		if !errors.As(err, &e) {
+			next(w, err)
+			return
+		}
+		data, _ := json.Marshal(struct {
+			Error    string            `json:"error"`
+			Resource governor.Resource `json:"resource"`
+			Limit    int64             `json:"limit"`
+			Usage    governor.Usage    `json:"usage"`
+		}{
+			Error:    e.Error(),
+			Resource: e.Resource,
+			Limit:    e.Limit,
+			Usage:    e.Usage,
+		})
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(data)
+		w.Write([]byte("\n"))
+	}
+}
+
 func writeResults(w io.Writer, r interface{}) {
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
@@ -510,6 +641,11 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 	if l.HTTPError != nil {
 		errFunc = l.HTTPError
 	}
+	errFunc = limitErrorFunc(errFunc)
+	canWrite := !api.ro && r.Method == http.MethodPost
+	if !api.ro && !canWrite {
+		errFunc = writeMethodErrorFunc(w, errFunc)
+	}
 	select {
 	case <-ctx.Done():
 		errFunc(w, ctx.Err())
@@ -521,9 +657,17 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 		errFunc(w, err)
 		return
 	}
+	opt := api.queryOptions(r)
+	if params := vals.Get("params"); params != "" {
+		opt.Params, err = query.ParseParams([]byte(params))
+		if err != nil {
+			errFunc(w, err)
+			return
+		}
+	}
 	if l.HTTPQuery != nil {
 		defer r.Body.Close()
-		l.HTTPQuery(ctx, h.QuadStore, w, r.Body)
+		l.HTTPQuery(query.WithParams(ctx, opt.Params), h.QuadStore, w, r.Body)
 		return
 	}
 	if l.Session == nil {
@@ -531,6 +675,9 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ses := l.Session(h.QuadStore)
+	if ws, ok := ses.(query.WriterSession); ok && canWrite {
+		ws.SetWriter(h.QuadWriter)
+	}
 	var qu string
 	if r.Method == "GET" {
 		qu = vals.Get("qu")
@@ -550,6 +697,17 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 		clog.Infof("query: %s: %q", lang, qu)
 	}
 
+	it, err := ses.Execute(ctx, qu, opt)
+	if err != nil {
+		errFunc(w, err)
+		return
+	}
+	defer it.Close()
+	writeQueryResults(ctx, w, it, opt, errFunc)
+}
+
+// queryOptions returns options for a query based on the request headers.
+func (api *APIv2) queryOptions(r *http.Request) query.Options {
 	opt := query.Options{
 		Collation: query.JSON, // TODO: switch to JSON-LD by default when the time comes
 		Limit:     api.limit,
@@ -563,18 +721,26 @@ func (api *APIv2) ServeQuery(w http.ResponseWriter, r *http.Request) {
 			opt.Collation = query.JSONLD
 		}
 	}
-	it, err := ses.Execute(ctx, qu, opt)
-	if err != nil {
-		errFunc(w, err)
-		return
-	}
-	defer it.Close()
+	return opt
+}
 
-	var out []interface{}
+// writeQueryResults reads all results of a query and writes them to the response.
+func writeQueryResults(ctx context.Context, w http.ResponseWriter, it query.Iterator, opt query.Options, errFunc func(w query.ResponseWriter, err error)) {
+	gov := governor.FromContext(ctx)
+	var (
+		out []interface{}
+		err error
+	)
 	for it.Next(ctx) {
+		if err = gov.Result(1); err != nil {
+			break
+		}
 		out = append(out, it.Result())
 	}
-	if err = it.Err(); err != nil {
+	if err == nil {
+		err = it.Err()
+	}
+	if err != nil {
 		errFunc(w, err)
 		return
 	}
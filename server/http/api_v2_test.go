@@ -2,15 +2,25 @@ package cayleyhttp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/governor"
+	"github.com/cayleygraph/cayley/graph/graphtest"
 	"github.com/cayleygraph/cayley/graph/memstore"
+	"github.com/cayleygraph/cayley/graph/rdfpatch"
+	"github.com/cayleygraph/cayley/query"
+	_ "github.com/cayleygraph/cayley/query/gizmo"
+	_ "github.com/cayleygraph/cayley/query/graphql"
 	"github.com/cayleygraph/cayley/writer"
 	"github.com/cayleygraph/quad"
 	"github.com/cayleygraph/quad/jsonld"
@@ -98,6 +108,189 @@ func TestV2Read(t *testing.T) {
 
 }
 
+func TestV2QueryLimits(t *testing.T) {
+	api := makeServerV2(t, quads...)
+	api.SetQueryLimits(governor.Limits{Results: 1})
+	api.SetTokenLimits("secret", governor.Limits{Results: 5})
+
+	query := func(token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, prefix+"/query?lang=gizmo&qu=g.V().all()", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(api.ServeQuery).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := query("")
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	var resp struct {
+		Error    string
+		Resource governor.Resource
+		Limit    int64
+		Usage    governor.Usage
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, governor.Results, resp.Resource)
+	require.Equal(t, int64(1), resp.Limit)
+	require.Equal(t, int64(2), resp.Usage.Results)
+	require.NotEmpty(t, resp.Error)
+
+	rr = query("secret")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestV2ReadOnlyErrorWrapped(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeMethodErrorFunc(rr, defaultErrorFunc)(rr, fmt.Errorf("procedure failed: %w", query.ErrReadOnly))
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code, rr.Body.String())
+	require.Equal(t, http.MethodPost, rr.Header().Get("Allow"))
+}
+
+func TestV2LimitErrorWrapped(t *testing.T) {
+	err := fmt.Errorf("procedure failed: %w", &governor.ErrLimitExceeded{
+		Resource: governor.Steps, Limit: 10, Usage: governor.Usage{Steps: 11},
+	})
+	rr := httptest.NewRecorder()
+	limitErrorFunc(defaultErrorFunc)(rr, err)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	var resp struct {
+		Resource governor.Resource
+		Limit    int64
+		Usage    governor.Usage
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, governor.Steps, resp.Resource)
+	require.Equal(t, int64(10), resp.Limit)
+	require.Equal(t, int64(11), resp.Usage.Steps)
+}
+
+func TestV2QueryWrite(t *testing.T) {
+	h := makeHandle(t)
+	api := NewAPIv2(h)
+
+	size := func() int64 {
+		st, err := h.QuadStore.Stats(context.TODO(), true)
+		require.NoError(t, err)
+		return st.Quads.Value
+	}
+	const qu = `g.addQuad("<alice>", "<follows>", "<bob>")`
+	query := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, prefix+"/query?lang=gizmo", strings.NewReader(qu))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(api.ServeQuery).ServeHTTP(rr, req)
+		return rr
+	}
+	queryGet := func(qu string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, prefix+"/query?lang=gizmo&qu="+url.QueryEscape(qu), nil)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(api.ServeQuery).ServeHTTP(rr, req)
+		return rr
+	}
+
+	api.SetReadOnly(true)
+	rr := query()
+	require.NotEqual(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, int64(0), size())
+
+	api.SetReadOnly(false)
+	rr = queryGet(qu)
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code, rr.Body.String())
+	require.Equal(t, http.MethodPost, rr.Header().Get("Allow"))
+	require.Equal(t, int64(0), size())
+
+	rr = query()
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, int64(1), size())
+
+	rr = queryGet(`g.V("<alice>").out("<follows>").all()`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"result": [{"id": "<bob>"}]}`, rr.Body.String())
+}
+
+func TestV2QueryParams(t *testing.T) {
+	api := makeServerV2(t, quads...)
+
+	query := func(params string) *httptest.ResponseRecorder {
+		qu := `g.V(who).out("<http://example.com/likes>").all()`
+		req, err := http.NewRequest(http.MethodPost, prefix+"/query?lang=gizmo&params="+url.QueryEscape(params), strings.NewReader(qu))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(api.ServeQuery).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := query(`{"who": {"@id": "http://example.com/bob"}}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"result": [{"id": "<http://example.com/alice>"}]}`, rr.Body.String())
+
+	rr = query(`{"who": "<http://example.com/alice>"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"result": [{"id": "<http://example.com/bob>"}]}`, rr.Body.String())
+
+	rr = query(`["not an object"]`)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+	// languages with custom HTTP handlers get parameters from the context
+	qu := `query ($who: ID) { nodes(id: $who) { id } }`
+	params := `{"who": "<http://example.com/bob>"}`
+	req, err := http.NewRequest(http.MethodPost, prefix+"/query?lang=graphql&params="+url.QueryEscape(params), strings.NewReader(qu))
+	require.NoError(t, err)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(api.ServeQuery).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"data": {"nodes": {"id": "http://example.com/bob"}}}`, rr.Body.String())
+}
+
+func TestV2Procedures(t *testing.T) {
+	api := makeServerV2(t, quads...)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, prefix+path, strings.NewReader(body))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPut, "/procedures/likes", `{"params": ["who"], "body": "return g.V(who).out('<http://example.com/likes>')"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(http.MethodPut, "/procedures/bad", `{"body": "return ("}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+	rr = do(http.MethodGet, "/procedures", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"result": [{"name": "likes", "params": ["who"], "body": "return g.V(who).out('<http://example.com/likes>')"}]}`, rr.Body.String())
+
+	rr = do(http.MethodGet, "/procedures/likes?who=<http://example.com/bob>", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"result": [{"id": "<http://example.com/alice>"}]}`, rr.Body.String())
+	rr = do(http.MethodGet, "/procedures/unknown", "")
+	require.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+
+	rr = do(http.MethodPut, "/procedures/follow", `{"params": ["who"], "body": "g.addQuad(who, '<http://example.com/follows>', '<http://example.com/bob>')"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(http.MethodGet, "/procedures/follow?who=<http://example.com/alice>", "")
+	require.Equal(t, http.StatusMethodNotAllowed, rr.Code, rr.Body.String())
+	rr = do(http.MethodPost, "/procedures/follow?who=<http://example.com/alice>", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(http.MethodGet, "/procedures/likes?who=<http://example.com/alice>", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = do(http.MethodDelete, "/procedures/likes", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(http.MethodDelete, "/procedures/likes", "")
+	require.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+
+	api.SetReadOnly(true)
+	rr = do(http.MethodPut, "/procedures/likes", `{"body": "return g.V()"}`)
+	require.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+}
+
 func TestV2Delete(t *testing.T) {
 	api := makeServerV2(t, quads...)
 	buf, err := newQuadsBuffer(quads)
@@ -114,6 +307,54 @@ func TestV2Delete(t *testing.T) {
 	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
 }
 
+func TestV2WritePatch(t *testing.T) {
+	api := makeServerV2(t, quads[0])
+	patch := "TX .\n" +
+		"D " + quads[0].NQuad() + "\n" +
+		"A " + quads[1].NQuad() + "\n" +
+		"TC .\n"
+
+	req, err := http.NewRequest(http.MethodPost, prefix+"/write", strings.NewReader(patch))
+	require.NoError(t, err)
+	req.Header.Set(hdrContentType, rdfpatch.ContentType)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(api.ServeWrite)
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response writeResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, newWriteResponse(2), response)
+
+	qs := api.h.QuadStore
+	require.Equal(t, []quad.Quad{quads[1]}, graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator()))
+}
+
+func TestV2WritePatchErrors(t *testing.T) {
+	api := makeServerV2(t, quads[0])
+	write := func(patch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, prefix+"/write", strings.NewReader(patch))
+		require.NoError(t, err)
+		req.Header.Set(hdrContentType, rdfpatch.ContentType)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(api.ServeWrite).ServeHTTP(rr, req)
+		return rr
+	}
+	qs := api.h.QuadStore
+
+	// malformed patch is a client error
+	rr := write("TX .\nA <a> <b> .\nTC .\n")
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	require.Equal(t, []quad.Quad{quads[0]}, graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator()))
+
+	// deleting a missing quad fails in the store
+	rr = write("TX .\nD " + quads[1].NQuad() + "\nTC .\n")
+	require.Equal(t, http.StatusInternalServerError, rr.Code, rr.Body.String())
+	require.Equal(t, []quad.Quad{quads[0]}, graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator()))
+}
+
 func TestV2GetNamespaceRules(t *testing.T) {
 	api := makeServerV2(t)
 	buf := bytes.NewBuffer(nil)
@@ -0,0 +1,160 @@
+// Copyright 2020 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cayleyhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/cayleygraph/cayley/clog"
+	"github.com/cayleygraph/cayley/query/gizmo"
+)
+
+func (api *APIv2) registerProceduresOn(r *httprouter.Router) {
+	r.GET(prefix+"/procedures", toHandle(api.ServeProcedures))
+	r.GET(prefix+"/procedures/:name", api.ServeProcedureCall)
+	r.POST(prefix+"/procedures/:name", api.ServeProcedureCall)
+	if !api.ro {
+		r.PUT(prefix+"/procedures/:name", api.ServeProcedureUpdate)
+		r.DELETE(prefix+"/procedures/:name", api.ServeProcedureDelete)
+	}
+}
+
+// ServeProcedures responds with all Gizmo procedures stored in the database
+func (api *APIv2) ServeProcedures(w http.ResponseWriter, r *http.Request) {
+	h, err := api.handleForRequest(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	procs, err := gizmo.ListProcedures(r.Context(), h.QuadStore)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	writeResults(w, procs)
+}
+
+// ServeProcedureCall runs a Gizmo procedure and responds with its results.
+// Arguments of the procedure are passed as query or form parameters.
+// Procedures can modify the graph only when called with POST.
+func (api *APIv2) ServeProcedureCall(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx, cancel := api.queryContext(r)
+	defer cancel()
+	errFunc := limitErrorFunc(defaultErrorFunc)
+	canWrite := !api.ro && r.Method == http.MethodPost
+	if !api.ro && !canWrite {
+		errFunc = writeMethodErrorFunc(w, errFunc)
+	}
+	if err := r.ParseForm(); err != nil {
+		errFunc(w, err)
+		return
+	}
+	args := make(map[string]interface{}, len(r.Form))
+	for k, v := range r.Form {
+		if len(v) == 1 {
+			args[k] = v[0]
+		} else {
+			args[k] = v
+		}
+	}
+	h, err := api.handleForRequest(r)
+	if err != nil {
+		errFunc(w, err)
+		return
+	}
+	ses := gizmo.NewSession(h.QuadStore)
+	if canWrite {
+		ses.SetWriter(h.QuadWriter)
+	}
+	name := ps.ByName("name")
+	if clog.V(1) {
+		clog.Infof("procedure: %s: %v", name, args)
+	}
+	opt := api.queryOptions(r)
+	it, err := ses.ExecuteProcedure(ctx, name, args, opt)
+	if err == gizmo.ErrProcedureNotFound {
+		jsonResponse(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		errFunc(w, err)
+		return
+	}
+	defer it.Close()
+	writeQueryResults(ctx, w, it, opt, errFunc)
+}
+
+// ServeProcedureUpdate creates or replaces a Gizmo procedure.
+// Parameters and the body of the procedure are received as a JSON object in the request body.
+func (api *APIv2) ServeProcedureUpdate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	defer r.Body.Close()
+	if api.ro {
+		jsonResponse(w, http.StatusForbidden, errors.New("database is read-only"))
+		return
+	}
+	data, err := readLimit(r.Body)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	var p gizmo.Procedure
+	if err = json.Unmarshal(data, &p); err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	p.Name = ps.ByName("name")
+	if err = p.Validate(); err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	h, err := api.handleForRequest(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = gizmo.SaveProcedure(r.Context(), h.QuadStore, h.QuadWriter, p); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	writeResults(w, p)
+}
+
+// ServeProcedureDelete removes a Gizmo procedure from the database.
+func (api *APIv2) ServeProcedureDelete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if api.ro {
+		jsonResponse(w, http.StatusForbidden, errors.New("database is read-only"))
+		return
+	}
+	h, err := api.handleForRequest(r)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	err = gizmo.DeleteProcedure(r.Context(), h.QuadStore, h.QuadWriter, ps.ByName("name"))
+	if err == gizmo.ErrProcedureNotFound {
+		jsonResponse(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	writeResults(w, "Successfully deleted procedure.")
+}